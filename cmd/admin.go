@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/elliotwms/emojistats/internal/admin"
+	"github.com/elliotwms/emojistats/internal/config"
+)
+
+// runPurge implements the purge subcommand, which deletes all of a guild's data, e.g. once the bot has been removed
+// from it
+func runPurge(ctx context.Context, c *config.Config, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	guildID := fs.String("guild", "", "the guild to purge (required)")
+	yes := fs.Bool("yes", false, "confirm that the guild's data should be deleted, which cannot be undone")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *guildID == "" {
+		return fmt.Errorf("-guild is required")
+	}
+	if !*yes {
+		return fmt.Errorf("purging deletes all of the guild's data and cannot be undone, rerun with -yes to confirm")
+	}
+
+	repo, closeDB, err := openAdminRepository(c)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	purged, err := repo.PurgeGuild(ctx, *guildID)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, p := range purged {
+		if p.Rows > 0 {
+			slog.Info("purged table", "table", p.Table, "rows", p.Rows)
+		}
+		total += p.Rows
+	}
+	slog.Info("purge complete", "guild_id", *guildID, "rows", total)
+
+	return nil
+}
+
+// runVacuumDuplicates implements the vacuum-duplicates subcommand, which deletes duplicate reactions
+func runVacuumDuplicates(ctx context.Context, c *config.Config, args []string) error {
+	fs := flag.NewFlagSet("vacuum-duplicates", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "count the duplicates without deleting them")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	repo, closeDB, err := openAdminRepository(c)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if *dryRun {
+		n, err := repo.CountDuplicates(ctx)
+		if err != nil {
+			return err
+		}
+		slog.Info("found duplicate reactions", "duplicates", n)
+		return nil
+	}
+
+	n, err := repo.DeleteDuplicates(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("deleted duplicate reactions", "deleted", n)
+
+	return nil
+}
+
+func openAdminRepository(c *config.Config) (*admin.Repository, func(), error) {
+	db, useSQLite, err := openDB(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dialect := admin.Postgres
+	if useSQLite {
+		dialect = admin.SQLite
+	}
+
+	return admin.NewRepository(db, dialect), func() { _ = db.Close() }, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/config"
+	"github.com/elliotwms/emojistats/internal/export"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// runExport implements the export subcommand, which writes a guild's reactions to files in a directory
+func runExport(ctx context.Context, c *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	guildID := fs.String("guild", "", "the guild to export (required)")
+	format := fs.String("format", string(export.FormatCSV), "the file format: csv, jsonl or parquet")
+	channelID := fs.String("channel", "", "only export reactions from this channel")
+	start := fs.String("start", "", "only export reactions on or after this date (YYYY-MM-DD)")
+	end := fs.String("end", "", "only export reactions on or before this date (YYYY-MM-DD)")
+	out := fs.String("out", ".", "the directory to write files to")
+	chunkSize := fs.Int64("chunk-size", 0, "start a new file once a file reaches this many bytes (default: no limit)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *guildID == "" {
+		return fmt.Errorf("-guild is required")
+	}
+
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	dateRange, err := parseDates(*start, *end)
+	if err != nil {
+		return err
+	}
+
+	db, err := openPostgres(c)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	filter := export.Filter{GuildID: *guildID, DateRange: dateRange, ChannelID: *channelID}
+
+	result, err := export.Export(ctx, export.NewRepository(db), filter, f, *chunkSize, func(part int) (io.WriteCloser, error) {
+		name := fmt.Sprintf("reactions-%s.%s", *guildID, f.Extension())
+		if *chunkSize > 0 {
+			name = fmt.Sprintf("reactions-%s-%03d.%s", *guildID, part, f.Extension())
+		}
+
+		path := filepath.Join(*out, name)
+		slog.Info("writing file", "path", path)
+
+		return os.Create(path)
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("export complete", "guild_id", *guildID, "rows", result.Rows, "files", result.Files)
+
+	return nil
+}
+
+// parseDates parses an optional inclusive date range
+func parseDates(start, end string) (stats.DateRange, error) {
+	var dateRange stats.DateRange
+
+	if start != "" {
+		t, err := time.Parse(time.DateOnly, start)
+		if err != nil {
+			return dateRange, fmt.Errorf("invalid start date: %w", err)
+		}
+		dateRange.Start = &t
+	}
+
+	if end != "" {
+		t, err := time.Parse(time.DateOnly, end)
+		if err != nil {
+			return dateRange, fmt.Errorf("invalid end date: %w", err)
+		}
+		// the end date is inclusive
+		t = t.AddDate(0, 0, 1)
+		dateRange.End = &t
+	}
+
+	return dateRange, nil
+}
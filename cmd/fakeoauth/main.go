@@ -0,0 +1,40 @@
+// Command fakeoauth serves a fake Discord OAuth2 provider for developing the dashboard locally. Run the bot with
+// DASHBOARD_OAUTH_URL set to its address, and sign in as FAKE_OAUTH_USER_ID, a member of the guilds in
+// FAKE_OAUTH_GUILD_IDS
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/elliotwms/emojistats/internal/dashboard/fakeoauth"
+)
+
+func main() {
+	addr := getEnv("FAKE_OAUTH_ADDR", ":8081")
+	user := fakeoauth.User{ID: getEnv("FAKE_OAUTH_USER_ID", "1"), Username: getEnv("FAKE_OAUTH_USERNAME", "developer")}
+
+	var guilds []fakeoauth.Guild
+	for _, id := range strings.Split(os.Getenv("FAKE_OAUTH_GUILD_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			guilds = append(guilds, fakeoauth.Guild{ID: id, Name: "Guild " + id})
+		}
+	}
+
+	p := fakeoauth.New(getEnv("FAKE_OAUTH_CLIENT_ID", "client"), getEnv("FAKE_OAUTH_CLIENT_SECRET", "secret"), user, guilds...)
+
+	slog.Info("serving fake OAuth2 provider", "addr", addr, "user_id", user.ID, "guilds", len(guilds))
+	if err := http.ListenAndServe(addr, p.Handler()); err != nil {
+		slog.Error("failed to serve", "error", err)
+		os.Exit(1)
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
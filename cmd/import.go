@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elliotwms/emojistats/internal/config"
+	"github.com/elliotwms/emojistats/internal/importer"
+)
+
+// runImport implements the import subcommand, which loads reactions from a CSV or JSON Lines file
+func runImport(ctx context.Context, c *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("file", "", "the file to import, or - for stdin (required)")
+	format := fs.String("format", "", "the file format: csv or jsonl (default: from the file extension)")
+	mappingFile := fs.String("mapping", "", "a JSON file mapping the file's columns to reaction fields")
+	guildID := fs.String("guild", "", "the guild to import into, for files without a guild column")
+	dryRun := fs.Bool("dry-run", false, "validate the file and report what would be imported without importing anything")
+	skipInvalid := fs.Bool("skip-invalid", false, "import the valid rows of a file containing invalid rows")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+
+	f := importer.Format(*format)
+	if f == "" {
+		f = importer.Format(strings.TrimPrefix(filepath.Ext(*file), "."))
+	}
+
+	var m importer.Mapping
+	if *mappingFile != "" {
+		mf, err := os.Open(*mappingFile)
+		if err != nil {
+			return err
+		}
+		m, err = importer.LoadMapping(mf)
+		_ = mf.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if *guildID != "" {
+		if m.Defaults == nil {
+			m.Defaults = map[importer.Field]string{}
+		}
+		m.Defaults[importer.FieldGuildID] = *guildID
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		r, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = r.Close() }()
+		in = r
+	}
+
+	src, err := importer.NewRecordReader(f, in)
+	if err != nil {
+		return err
+	}
+
+	db, err := openPostgres(c)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	report, err := importer.Import(ctx, importer.NewRepository(db), src, m, importer.Options{DryRun: *dryRun, SkipInvalid: *skipInvalid})
+	fmt.Print(report)
+	if errors.Is(err, importer.ErrInvalidRows) {
+		return fmt.Errorf("%w, use -skip-invalid to import the valid rows", err)
+	}
+	return err
+}
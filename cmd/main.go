@@ -2,82 +2,143 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	// the scratch image has no zoneinfo, which guild timezones need
+	_ "time/tzdata"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/build"
+	"github.com/elliotwms/emojistats/internal/config"
 	"github.com/elliotwms/emojistats/internal/database"
-	"github.com/elliotwms/emojistats/internal/emojistats"
+	"github.com/elliotwms/emojistats/internal/sqlite"
+	"github.com/elliotwms/emojistats/internal/stats"
 )
 
+const usage = `Usage: emojistats [-config file] [command] [flags]
+
+The configuration is read from the YAML or TOML file given by -config or CONFIG_FILE, if any, and then overridden by
+the environment.
+
+Commands:
+  serve              run the bot (default)
+  migrate            apply, roll back or list database migrations: migrate up|down|status
+  stats              print a guild's stats: stats [-format text|json] <guild>
+  purge              delete all of a guild's data: purge -guild <guild> -yes
+  vacuum-duplicates  delete duplicate reactions, keeping the earliest of each
+  export             export a guild's reactions to files
+  import             import reactions from a file
+  version            print the version
+
+Run emojistats <command> -h for a command's flags.`
+
+var subcommands = map[string]func(context.Context, *config.Config, []string) error{
+	"serve":             runServe,
+	"migrate":           runMigrate,
+	"stats":             runStats,
+	"purge":             runPurge,
+	"vacuum-duplicates": runVacuumDuplicates,
+	"export":            runExport,
+	"import":            runImport,
+	"version":           runVersion,
+}
+
 func main() {
-	logLevel := getLogLevel(os.Getenv("LOG_LEVEL"))
-	slog.SetLogLoggerLevel(logLevel)
+	fs := flag.NewFlagSet("emojistats", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "the configuration file")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
 
-	db, err := database.Connect(mustGetEnv("DATABASE_URL"))
-	if err != nil {
-		slog.Error("failed to connect to database", "error", err)
-		os.Exit(1)
+	name, args := "serve", []string{}
+	if fs.NArg() > 0 {
+		name, args = fs.Arg(0), fs.Args()[1:]
 	}
-	defer func() {
-		err := db.Close()
-		if err != nil {
-			slog.Error("failed to close database", "error", err)
+
+	run, ok := subcommands[name]
+	if !ok {
+		if name != "help" && name != "-h" && name != "--help" {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
 		}
-	}()
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
 
-	if err := database.Migrate(db); err != nil {
-		slog.Error("failed to run migrations", "error", err)
+	c, err := config.Load(*configPath, os.Getenv)
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
 		os.Exit(1)
 	}
-
-	s := buildSession(logLevel)
-
-	c := emojistats.NewConfig(s, mustGetEnv("APPLICATION_ID"))
-	c.HealthCheckAddr = os.Getenv("HEALTH_CHECK_ADDR")
-	c.GuildID = os.Getenv("GUILD_ID")
-	c.Logger = slog.Default()
-	c.DB = db
+	slog.SetLogLoggerLevel(c.LogLevel)
 
 	ctx, _ := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-
-	if err := emojistats.Run(c, ctx); err != nil {
-		slog.Error("completed with error", "error", err)
+	if err := run(ctx, c, args); err != nil {
+		slog.Error(name+" failed", "error", err)
 		os.Exit(1)
 	}
 }
 
-func getLogLevel(s string) (l slog.Level) {
-	if s == "" {
-		return slog.LevelInfo
+// runVersion implements the version subcommand
+func runVersion(context.Context, *config.Config, []string) error {
+	fmt.Println(build.Version)
+	return nil
+}
+
+// openDB connects to the configured database, which is SQLite if its URL is prefixed with sqlite:// and otherwise
+// Postgres
+func openDB(c *config.Config) (db *sql.DB, useSQLite bool, err error) {
+	if err := c.ValidateDatabase(); err != nil {
+		return nil, false, err
 	}
 
-	if err := l.UnmarshalText([]byte(s)); err != nil {
-		panic(err)
+	sqlitePath, useSQLite := c.SQLite()
+	if useSQLite {
+		db, err = sqlite.Open(sqlitePath)
+	} else {
+		db, err = database.Connect(c.DatabaseURL)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	return l
+	return db, useSQLite, nil
 }
 
-func buildSession(l slog.Level) *discordgo.Session {
-	s, err := discordgo.New("Bot " + mustGetEnv("TOKEN"))
+// openPostgres connects to the configured database, for commands which only support Postgres
+func openPostgres(c *config.Config) (*sql.DB, error) {
+	db, useSQLite, err := openDB(c)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if useSQLite {
+		_ = db.Close()
+		return nil, fmt.Errorf("this command requires Postgres")
 	}
+	return db, nil
+}
 
-	if l <= slog.LevelDebug {
-		s.LogLevel = discordgo.LogDebug
+// newStore returns the stats store for a database opened by openDB
+func newStore(db *sql.DB, useSQLite bool) stats.Store {
+	if useSQLite {
+		return sqlite.NewStore(db)
 	}
-	return s
+	return stats.NewRepository(db)
 }
 
-func mustGetEnv(s string) string {
-	token := os.Getenv(s)
-	if token == "" {
-		panic(fmt.Sprintf("Missing '%s'", s))
+func buildSession(c *config.Config) (*discordgo.Session, error) {
+	s, err := discordgo.New("Bot " + c.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.LogLevel <= slog.LevelDebug {
+		s.LogLevel = discordgo.LogDebug
 	}
-	return token
+	return s, nil
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/elliotwms/emojistats/internal/config"
+	"github.com/elliotwms/emojistats/internal/database"
+	"github.com/elliotwms/emojistats/internal/sqlite"
+)
+
+// runMigrate implements the migrate subcommand, which applies all pending migrations, rolls back the latest migration,
+// or lists the migrations and whether they have been applied
+func runMigrate(ctx context.Context, c *config.Config, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: emojistats migrate up|down|status")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one of up, down or status")
+	}
+
+	db, useSQLite, err := openDB(c)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	var provider *goose.Provider
+	if useSQLite {
+		provider, err = sqlite.Provider(db)
+	} else {
+		provider, err = database.Provider(db)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch fs.Arg(0) {
+	case "up":
+		results, err := provider.Up(ctx)
+		for _, r := range results {
+			slog.Info("applied migration", "migration", r.Source.Path, "duration", r.Duration)
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			slog.Info("no migrations to apply")
+		}
+		return nil
+	case "down":
+		r, err := provider.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			slog.Info("no migrations to roll back")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		slog.Info("rolled back migration", "migration", r.Source.Path, "duration", r.Duration)
+		return nil
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printMigrationStatus(statuses)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown migrate command %q", fs.Arg(0))
+	}
+}
+
+func printMigrationStatus(statuses []*goose.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tMIGRATION")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if !s.AppliedAt.IsZero() {
+			appliedAt = s.AppliedAt.UTC().Format(time.DateTime)
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, appliedAt, s.Source.Path)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/config"
+	"github.com/elliotwms/emojistats/internal/dashboard"
+	"github.com/elliotwms/emojistats/internal/database"
+	"github.com/elliotwms/emojistats/internal/emojistats"
+	"github.com/elliotwms/emojistats/internal/sqlite"
+	"github.com/elliotwms/emojistats/internal/tracing"
+)
+
+// runServe implements the serve subcommand, which migrates the database and runs the bot until it is interrupted
+func runServe(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	db, useSQLite, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			slog.Error("failed to close database", "error", err)
+		}
+	}()
+
+	if useSQLite {
+		err = sqlite.Migrate(db)
+	} else {
+		err = database.Migrate(db)
+	}
+	if err != nil {
+		return err
+	}
+
+	s, err := buildSession(cfg)
+	if err != nil {
+		return err
+	}
+
+	c := emojistats.NewConfig(s, cfg.ApplicationID)
+	c.HealthCheckAddr = cfg.HealthCheckAddr
+	c.APIAddr = cfg.APIAddr
+	c.MetricsAddr = cfg.MetricsAddr
+	c.GuildID = cfg.GuildID
+	if c.DashboardAddr = cfg.Dashboard.Addr; c.DashboardAddr != "" {
+		c.DashboardOAuth = dashboard.OAuthConfig{
+			ClientID:     c.ApplicationID,
+			ClientSecret: cfg.Dashboard.ClientSecret,
+			RedirectURL:  strings.TrimSuffix(cfg.Dashboard.URL, "/") + "/callback",
+			URL:          cfg.Dashboard.OAuthURL,
+		}
+	}
+	c.Logger = slog.Default()
+	if useSQLite {
+		// only ingestion and the stats commands are supported with SQLite
+		c.Store = sqlite.NewStore(db)
+	} else {
+		c.DB = db
+	}
+	c.StatsCacheTTL = time.Duration(cfg.StatsCache.TTL)
+	c.StatsCacheShared = cfg.StatsCache.Shared
+	c.Features = emojistats.Features(cfg.Features)
+
+	shutdownTracing, err := tracing.Setup(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = emojistats.Run(c, ctx)
+
+	// flush the remaining spans, as the signal context is already done
+	if err := shutdownTracing(context.Background()); err != nil {
+		slog.Error("failed to shut down tracing", "error", err)
+	}
+
+	return err
+}
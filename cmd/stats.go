@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/elliotwms/emojistats/internal/config"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// runStats implements the stats subcommand, which prints a guild's stats as text or JSON
+func runStats(ctx context.Context, c *config.Config, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	format := fs.String("format", "text", "the output format: text or json")
+	start := fs.String("start", "", "only count reactions on or after this date (YYYY-MM-DD)")
+	end := fs.String("end", "", "only count reactions on or before this date (YYYY-MM-DD)")
+	limit := fs.Int("limit", stats.DefaultLeaderboardSize, "how many entries each leaderboard shows")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: emojistats stats [flags] <guild>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a guild ID")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid format %q, must be text or json", *format)
+	}
+	if *limit < 1 {
+		return fmt.Errorf("invalid limit %d, must be at least 1", *limit)
+	}
+
+	dateRange, err := parseDates(*start, *end)
+	if err != nil {
+		return err
+	}
+
+	db, useSQLite, err := openDB(c)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	guildStats, err := newStore(db, useSQLite).GetGuildStats(ctx, fs.Arg(0), dateRange, *limit)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(guildStats)
+	}
+
+	return writeGuildStats(os.Stdout, guildStats)
+}
+
+// writeGuildStats writes guild stats as plain text, with IDs in place of the mentions used in Discord
+func writeGuildStats(w io.Writer, s *stats.GuildStats) error {
+	_, err := fmt.Fprintf(w, "Total reactions: %d\n", s.TotalReactions)
+	if err != nil {
+		return err
+	}
+
+	sections := []struct {
+		title string
+		rows  []string
+	}{
+		{"Top emojis", countRows(s.TopEmojis, func(e stats.EmojiCount) (string, int) { return e.EmojiID, e.Count })},
+		{"Top senders", countRows(s.TopSenders, func(u stats.UserCount) (string, int) { return u.UserID, u.Count })},
+		{"Top receivers", countRows(s.TopReceivers, func(u stats.UserCount) (string, int) { return u.UserID, u.Count })},
+	}
+
+	for _, section := range sections {
+		if len(section.rows) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "\n%s:\n", section.title); err != nil {
+			return err
+		}
+		for _, row := range section.rows {
+			if _, err := fmt.Fprintln(w, row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func countRows[T any](items []T, fn func(T) (string, int)) []string {
+	rows := make([]string, 0, len(items))
+	for i, item := range items {
+		name, count := fn(item)
+		rows = append(rows, fmt.Sprintf("%3d. %s (%d)", i+1, name, count))
+	}
+	return rows
+}
@@ -0,0 +1,116 @@
+package achievements
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Activity summarises a user's reactions within a guild. Achievement rules are evaluated against it
+type Activity struct {
+	Given            int
+	Received         int
+	DistinctEmojis   int
+	DistinctChannels int
+	// GuildChannels is the number of channels in the guild which have any reactions
+	GuildChannels int
+	// LongestStreak is the user's longest streak, as shown by /streaks
+	LongestStreak int
+}
+
+// Rule reports whether an achievement has been earned
+type Rule func(a Activity) bool
+
+// Achievement is a badge which users unlock when its rule is met
+type Achievement struct {
+	ID          string
+	Name        string
+	Description string
+	Badge       string
+	Rule        Rule
+}
+
+// All is every achievement, in the order they are displayed
+var All = []Achievement{
+	{
+		ID:          "first-reaction",
+		Name:        "First Reaction",
+		Description: "Give your first reaction",
+		Badge:       "🐣",
+		Rule:        func(a Activity) bool { return a.Given >= 1 },
+	},
+	{
+		ID:          "centurion",
+		Name:        "Centurion",
+		Description: "Give 100 reactions",
+		Badge:       "💯",
+		Rule:        func(a Activity) bool { return a.Given >= 100 },
+	},
+	{
+		ID:          "connoisseur",
+		Name:        "Connoisseur",
+		Description: "Use 50 different emojis",
+		Badge:       "🎨",
+		Rule:        func(a Activity) bool { return a.DistinctEmojis >= 50 },
+	},
+	{
+		ID:          "on-a-roll",
+		Name:        "On a Roll",
+		Description: "Give or receive reactions on 7 days in a row",
+		Badge:       "🔥",
+		Rule:        func(a Activity) bool { return a.LongestStreak >= 7 },
+	},
+	{
+		ID:          "globetrotter",
+		Name:        "Globetrotter",
+		Description: "React in every channel",
+		Badge:       "🧭",
+		Rule:        func(a Activity) bool { return a.GuildChannels > 1 && a.DistinctChannels >= a.GuildChannels },
+	},
+	{
+		ID:          "crowd-pleaser",
+		Name:        "Crowd Pleaser",
+		Description: "Receive 100 reactions",
+		Badge:       "🌟",
+		Rule:        func(a Activity) bool { return a.Received >= 100 },
+	},
+}
+
+// Get returns the achievement with the given ID
+func Get(id string) (Achievement, bool) {
+	for _, a := range All {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Achievement{}, false
+}
+
+// Unlocked is an achievement which a user has unlocked
+type Unlocked struct {
+	AchievementID string
+	UnlockedAt    time.Time
+}
+
+// FormatBadges formats a user's unlocked and locked achievements as Discord markdown
+func FormatBadges(userID string, unlocked []Unlocked) string {
+	var sb strings.Builder
+
+	unlockedAt := make(map[string]time.Time, len(unlocked))
+	for _, u := range unlocked {
+		unlockedAt[u.AchievementID] = u.UnlockedAt
+	}
+
+	sb.WriteString(fmt.Sprintf("## Badges for <@%s>\n\n", userID))
+	sb.WriteString(fmt.Sprintf("**Unlocked:** %d/%d\n\n", len(unlockedAt), len(All)))
+
+	for _, a := range All {
+		if t, ok := unlockedAt[a.ID]; ok {
+			sb.WriteString(fmt.Sprintf("%s **%s** - %s (<t:%d:d>)\n", a.Badge, a.Name, a.Description, t.Unix()))
+		} else {
+			sb.WriteString(fmt.Sprintf("🔒 %s - %s\n", a.Name, a.Description))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,102 @@
+package achievements
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+)
+
+func TestEarned(t *testing.T) {
+	activity := Activity{
+		Given:            120,
+		Received:         3,
+		DistinctEmojis:   10,
+		DistinctChannels: 2,
+		GuildChannels:    2,
+		LongestStreak:    3,
+	}
+
+	earned := Earned(activity, []Unlocked{{AchievementID: "first-reaction"}})
+
+	ids := make([]string, len(earned))
+	for i, a := range earned {
+		ids[i] = a.ID
+	}
+	assert.Equal(t, []string{"centurion", "globetrotter"}, ids)
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		id     string
+		met    Activity
+		notMet Activity
+	}{
+		{"first-reaction", Activity{Given: 1}, Activity{}},
+		{"centurion", Activity{Given: 100}, Activity{Given: 99}},
+		{"connoisseur", Activity{DistinctEmojis: 50}, Activity{DistinctEmojis: 49}},
+		{"on-a-roll", Activity{LongestStreak: 7}, Activity{LongestStreak: 6}},
+		{"globetrotter", Activity{DistinctChannels: 3, GuildChannels: 3}, Activity{DistinctChannels: 1, GuildChannels: 1}},
+		{"crowd-pleaser", Activity{Received: 100}, Activity{Received: 99}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			a, ok := Get(tt.id)
+			require.True(t, ok)
+			assert.True(t, a.Rule(tt.met))
+			assert.False(t, a.Rule(tt.notMet))
+		})
+	}
+}
+
+func TestFormatBadges(t *testing.T) {
+	unlocked := []Unlocked{{AchievementID: "first-reaction", UnlockedAt: time.Unix(1700000000, 0)}}
+
+	result := FormatBadges("111", unlocked)
+
+	assert.Contains(t, result, "## Badges for <@111>")
+	assert.Contains(t, result, "**Unlocked:** 1/6")
+	assert.Contains(t, result, "🐣 **First Reaction** - Give your first reaction (<t:1700000000:d>)")
+	assert.Contains(t, result, "🔒 Centurion - Give 100 reactions")
+}
+
+func TestFormatAnnouncement(t *testing.T) {
+	a, _ := Get("on-a-roll")
+
+	assert.Equal(t, "🏅 <@111> unlocked 🔥 **On a Roll** - Give or receive reactions on 7 days in a row!", FormatAnnouncement(a, "111"))
+}
+
+// fakeAuthors returns the same author for every message
+type fakeAuthors string
+
+func (a fakeAuthors) GetMessageAuthor(context.Context, string, string) (string, error) {
+	return string(a), nil
+}
+
+func TestEngine_HandleReaction(t *testing.T) {
+	e := NewEngine(nil, fakeAuthors("author1"), nil)
+
+	e.HandleReaction(nil, &discordgo.MessageReaction{GuildID: "guild1", MessageID: "msg1", UserID: "user1"})
+	e.HandleReaction(nil, &discordgo.MessageReaction{GuildID: "guild1", MessageID: "msg2", UserID: "user1"})
+	e.HandleReaction(nil, &discordgo.MessageReaction{MessageID: "msg3", UserID: "user2"})
+
+	assert.ElementsMatch(t, []member{
+		{guildID: "guild1", userID: "user1"},
+		{guildID: "guild1", userID: "author1"},
+	}, e.takePending(), "each user should be pending once, and reactions outside guilds ignored")
+	assert.Empty(t, e.takePending(), "taking the pending users should clear them")
+}
+
+func TestEngine_HandleReaction_Anonymous(t *testing.T) {
+	e := NewEngine(nil, fakeAuthors(privacy.AnonymousUserID), nil)
+
+	e.HandleReaction(nil, &discordgo.MessageReaction{GuildID: "guild1", MessageID: "msg1", UserID: "user1"})
+
+	assert.Equal(t, []member{{guildID: "guild1", userID: "user1"}}, e.takePending())
+}
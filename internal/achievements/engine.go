@@ -0,0 +1,201 @@
+package achievements
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// EvaluationInterval is how often the users who have reacted since the last evaluation are evaluated
+const EvaluationInterval = time.Minute
+
+// MessageAuthors finds the authors of reacted messages. milestones.Repository is the implementation
+type MessageAuthors interface {
+	// GetMessageAuthor retrieves the author of a reacted message, returning an empty string if it has no reactions
+	GetMessageAuthor(ctx context.Context, guildID, messageID string) (string, error)
+}
+
+// Streaks finds users' reaction streaks. stats.Store is the implementation, so that badges count streaks the same way
+// as /streaks
+type Streaks interface {
+	// GetStreak retrieves a user's current and longest streaks, counting days in the guild's timezone
+	GetStreak(ctx context.Context, guildID, userID string) (stats.Streak, error)
+}
+
+// Engine evaluates achievement rules against users' reactions and unlocks any which have been earned. Reactions only
+// mark their users as pending, and Run evaluates them together, as a user's activity is summarised from all of their
+// reactions
+type Engine struct {
+	repo    *Repository
+	authors MessageAuthors
+	streaks Streaks
+
+	mu      sync.Mutex
+	pending map[member]bool
+}
+
+// member is a user within a guild
+type member struct {
+	guildID string
+	userID  string
+}
+
+// NewEngine creates a new achievements Engine
+func NewEngine(repo *Repository, authors MessageAuthors, streaks Streaks) *Engine {
+	return &Engine{
+		repo:    repo,
+		authors: authors,
+		streaks: streaks,
+		pending: make(map[member]bool),
+	}
+}
+
+// Evaluate unlocks any achievements the user has earned and returns those which were newly unlocked
+func (e *Engine) Evaluate(ctx context.Context, guildID, userID string) ([]Achievement, error) {
+	unlocked, err := e.repo.GetUnlocked(ctx, guildID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unlocked achievements: %w", err)
+	}
+
+	if len(unlocked) == len(All) {
+		return nil, nil
+	}
+
+	activity, err := e.repo.GetActivity(ctx, guildID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+
+	streak, err := e.streaks.GetStreak(ctx, guildID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get streak: %w", err)
+	}
+	activity.LongestStreak = streak.Longest
+
+	var newlyUnlocked []Achievement
+	for _, a := range Earned(activity, unlocked) {
+		ok, err := e.repo.Unlock(ctx, guildID, userID, a.ID)
+		if err != nil {
+			return newlyUnlocked, fmt.Errorf("failed to unlock achievement %s: %w", a.ID, err)
+		}
+		if ok {
+			newlyUnlocked = append(newlyUnlocked, a)
+		}
+	}
+
+	return newlyUnlocked, nil
+}
+
+// HandleReaction marks the sender and receiver of a reaction as pending evaluation. It should be called after the
+// reaction has been added to the reactions table
+func (e *Engine) HandleReaction(_ *discordgo.Session, r *discordgo.MessageReaction) {
+	if r.GuildID == "" {
+		return
+	}
+
+	authorID, err := e.authors.GetMessageAuthor(context.Background(), r.GuildID, r.MessageID)
+	if err != nil {
+		slog.Error("failed to get message author", "error", err, "guild_id", r.GuildID, "message_id", r.MessageID)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.pending[member{guildID: r.GuildID, userID: r.UserID}] = true
+	if authorID != "" && authorID != privacy.AnonymousUserID {
+		e.pending[member{guildID: r.GuildID, userID: authorID}] = true
+	}
+}
+
+// Run evaluates the pending users every EvaluationInterval until the context is done, announcing any newly unlocked
+// achievements if announcements are enabled. Users still pending when it stops are evaluated after their next reaction
+func (e *Engine) Run(ctx context.Context, s *discordgo.Session) {
+	ticker := time.NewTicker(EvaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		e.RunPending(ctx, s)
+	}
+}
+
+// RunPending evaluates the users who have reacted since the last evaluation
+func (e *Engine) RunPending(ctx context.Context, s *discordgo.Session) {
+	for _, m := range e.takePending() {
+		newlyUnlocked, err := e.Evaluate(ctx, m.guildID, m.userID)
+		if err != nil {
+			slog.Error("failed to evaluate achievements", "error", err, "guild_id", m.guildID, "user_id", m.userID)
+		}
+
+		if len(newlyUnlocked) > 0 {
+			e.announce(ctx, s, m.guildID, m.userID, newlyUnlocked)
+		}
+	}
+}
+
+// takePending returns the pending users and clears them
+func (e *Engine) takePending() []member {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	members := make([]member, 0, len(e.pending))
+	for m := range e.pending {
+		members = append(members, m)
+	}
+	clear(e.pending)
+
+	return members
+}
+
+func (e *Engine) announce(ctx context.Context, s *discordgo.Session, guildID, userID string, unlocked []Achievement) {
+	channelID, err := e.repo.GetAnnouncementChannel(ctx, guildID)
+	if err != nil {
+		slog.Error("failed to get achievement announcement channel", "error", err, "guild_id", guildID)
+		return
+	}
+
+	for _, a := range unlocked {
+		slog.Info("achievement unlocked", "guild_id", guildID, "user_id", userID, "achievement_id", a.ID)
+
+		if channelID == "" {
+			continue
+		}
+
+		if _, err := s.ChannelMessageSend(channelID, FormatAnnouncement(a, userID)); err != nil {
+			slog.Error("failed to announce achievement", "error", err, "guild_id", guildID, "user_id", userID)
+		}
+	}
+}
+
+// Earned returns the achievements whose rules are met by the activity and which have not already been unlocked
+func Earned(activity Activity, unlocked []Unlocked) []Achievement {
+	have := make(map[string]bool, len(unlocked))
+	for _, u := range unlocked {
+		have[u.AchievementID] = true
+	}
+
+	var earned []Achievement
+	for _, a := range All {
+		if !have[a.ID] && a.Rule(activity) {
+			earned = append(earned, a)
+		}
+	}
+	return earned
+}
+
+// FormatAnnouncement formats the message posted when a user unlocks an achievement
+func FormatAnnouncement(a Achievement, userID string) string {
+	return fmt.Sprintf("🏅 <@%s> unlocked %s **%s** - %s!", userID, a.Badge, a.Name, a.Description)
+}
@@ -0,0 +1,133 @@
+package achievements
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/elliotwms/emojistats/internal/database"
+)
+
+// Repository handles database queries for achievements
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository creates a new achievements repository
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetActivity summarises a user's reactions within a guild, including those which have been pruned so that badges are
+// not lost or awarded twice, and excluding those in ignored channels. Pruned reactions whose channel was not kept are not
+// counted towards the channels. The streak is not included, as it is counted by the stats store
+func (r *Repository) GetActivity(ctx context.Context, guildID, userID string) (Activity, error) {
+	var a Activity
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(count), 0), COUNT(DISTINCT emoji_id), COUNT(DISTINCT NULLIF(channel_id, ''))
+		FROM `+database.CountedReactions+`
+		WHERE guild_id = $1 AND sender_user_id = $2`+database.NotIgnored,
+		guildID,
+		userID,
+	).Scan(&a.Given, &a.DistinctEmojis, &a.DistinctChannels)
+	if err != nil {
+		return a, err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(count), 0) FROM `+database.CountedReactions+` WHERE guild_id = $1 AND receiver_user_id = $2`+database.NotIgnored,
+		guildID,
+		userID,
+	).Scan(&a.Received)
+	if err != nil {
+		return a, err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT NULLIF(channel_id, '')) FROM `+database.CountedReactions+` WHERE guild_id = $1`+database.NotIgnored,
+		guildID,
+	).Scan(&a.GuildChannels)
+	if err != nil {
+		return a, err
+	}
+
+	return a, nil
+}
+
+// GetUnlocked retrieves the achievements a user has unlocked, in the order they were unlocked
+func (r *Repository) GetUnlocked(ctx context.Context, guildID, userID string) ([]Unlocked, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT achievement_id, unlocked_at
+		FROM achievements
+		WHERE guild_id = $1 AND user_id = $2
+		ORDER BY unlocked_at, achievement_id`,
+		guildID,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []Unlocked
+	for rows.Next() {
+		var u Unlocked
+		if err := rows.Scan(&u.AchievementID, &u.UnlockedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, u)
+	}
+	return results, rows.Err()
+}
+
+// Unlock records that a user has unlocked an achievement. It returns false if it was already unlocked
+func (r *Repository) Unlock(ctx context.Context, guildID, userID, achievementID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO achievements (guild_id, user_id, achievement_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		guildID,
+		userID,
+		achievementID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// GetAnnouncementChannel retrieves the channel unlocked achievements are announced in, returning an empty string if
+// announcements are disabled
+func (r *Repository) GetAnnouncementChannel(ctx context.Context, guildID string) (string, error) {
+	var channelID string
+	err := r.db.QueryRowContext(ctx, `SELECT channel_id FROM achievement_configs WHERE guild_id = $1`, guildID).Scan(&channelID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return channelID, err
+}
+
+// SetAnnouncementChannel sets the channel unlocked achievements are announced in. An empty channel disables
+// announcements
+func (r *Repository) SetAnnouncementChannel(ctx context.Context, guildID, channelID string) error {
+	if channelID == "" {
+		_, err := r.db.ExecContext(ctx, `DELETE FROM achievement_configs WHERE guild_id = $1`, guildID)
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO achievement_configs (guild_id, channel_id)
+		VALUES ($1, $2)
+		ON CONFLICT (guild_id) DO UPDATE SET channel_id = EXCLUDED.channel_id`,
+		guildID,
+		channelID,
+	)
+	return err
+}
@@ -0,0 +1,179 @@
+package achievements
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+var testDB *sql.DB
+
+func setupTest(t *testing.T) (*Repository, string, func()) {
+	t.Helper()
+
+	testDB = dbtest.Open(t)
+
+	guildID := "test-guild-" + time.Now().Format("20060102150405.000000000")
+
+	cleanup := func() {
+		_, _ = testDB.Exec("DELETE FROM reactions WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM achievements WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM achievement_configs WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM guild_settings WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM guild_timezones WHERE guild_id = $1", guildID)
+	}
+
+	return NewRepository(testDB), guildID, cleanup
+}
+
+func insertReaction(t *testing.T, guildID, emojiID, senderID, receiverID, channelID string, createdAt time.Time) {
+	t.Helper()
+	_, err := testDB.Exec(`
+		INSERT INTO reactions (guild_id, emoji_id, sender_user_id, receiver_user_id, channel_id, message_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, 'msg1', $6)`,
+		guildID, emojiID, senderID, receiverID, channelID, createdAt)
+	require.NoError(t, err)
+}
+
+func TestGetActivity(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+
+	insertReaction(t, guildID, "👍", "user1", "user2", "chan1", day(1))
+	insertReaction(t, guildID, "❤️", "user1", "user2", "chan1", day(2))
+	insertReaction(t, guildID, "❤️", "user1", "user2", "chan2", day(2))
+	insertReaction(t, guildID, "👍", "user1", "user2", "chan1", day(3))
+	insertReaction(t, guildID, "👍", "user1", "user2", "chan1", day(10))
+	insertReaction(t, guildID, "👍", "user2", "user1", "chan3", day(10))
+
+	a, err := repo.GetActivity(context.Background(), guildID, "user1")
+
+	require.NoError(t, err)
+	assert.Equal(t, Activity{
+		Given:            5,
+		Received:         1,
+		DistinctEmojis:   2,
+		DistinctChannels: 2,
+		GuildChannels:    3,
+	}, a)
+}
+
+func TestGetActivity_IgnoredChannel(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+
+	insertReaction(t, guildID, "👍", "user1", "user2", "chan1", day(1))
+	insertReaction(t, guildID, "❤️", "user1", "user2", "chan2", day(2))
+	insertReaction(t, guildID, "❤️", "user2", "user1", "chan2", day(2))
+
+	_, err := testDB.Exec(`INSERT INTO guild_settings (guild_id, ignored_channel_ids) VALUES ($1, ARRAY['chan2'])`, guildID)
+	require.NoError(t, err)
+
+	a, err := repo.GetActivity(context.Background(), guildID, "user1")
+
+	require.NoError(t, err)
+	assert.Equal(t, Activity{
+		Given:            1,
+		DistinctEmojis:   1,
+		DistinctChannels: 1,
+		GuildChannels:    1,
+	}, a)
+}
+
+func TestUnlock(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	ok, err := repo.Unlock(ctx, guildID, "user1", "first-reaction")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.Unlock(ctx, guildID, "user1", "first-reaction")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	unlocked, err := repo.GetUnlocked(ctx, guildID, "user1")
+	require.NoError(t, err)
+	require.Len(t, unlocked, 1)
+	assert.Equal(t, "first-reaction", unlocked[0].AchievementID)
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	insertReaction(t, guildID, "👍", "user1", "user2", "chan1", time.Now())
+
+	engine := NewEngine(repo, nil, stats.NewRepository(testDB))
+
+	unlocked, err := engine.Evaluate(ctx, guildID, "user1")
+	require.NoError(t, err)
+	require.Len(t, unlocked, 1)
+	assert.Equal(t, "first-reaction", unlocked[0].ID)
+
+	unlocked, err = engine.Evaluate(ctx, guildID, "user1")
+	require.NoError(t, err)
+	assert.Empty(t, unlocked)
+}
+
+func TestEngineEvaluate_StreakTimezone(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	store := stats.NewRepository(testDB)
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	require.NoError(t, err)
+	require.NoError(t, store.SetTimezone(ctx, guildID, auckland))
+
+	// late evening in UTC is the next morning in Auckland, so these are 6 UTC days but 7 consecutive Auckland days
+	for d := 1; d <= 6; d++ {
+		insertReaction(t, guildID, "👍", "user1", "user2", "chan1", time.Date(2024, 1, d, 23, 30, 0, 0, time.UTC))
+	}
+	insertReaction(t, guildID, "👍", "user1", "user2", "chan1", time.Date(2024, 1, 8, 0, 30, 0, 0, time.UTC))
+
+	unlocked, err := NewEngine(repo, nil, store).Evaluate(ctx, guildID, "user1")
+	require.NoError(t, err)
+
+	ids := make([]string, len(unlocked))
+	for i, a := range unlocked {
+		ids[i] = a.ID
+	}
+	assert.Contains(t, ids, "on-a-roll")
+}
+
+func TestAnnouncementChannel(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	channelID, err := repo.GetAnnouncementChannel(ctx, guildID)
+	require.NoError(t, err)
+	assert.Empty(t, channelID)
+
+	require.NoError(t, repo.SetAnnouncementChannel(ctx, guildID, "chan1"))
+
+	channelID, err = repo.GetAnnouncementChannel(ctx, guildID)
+	require.NoError(t, err)
+	assert.Equal(t, "chan1", channelID)
+
+	require.NoError(t, repo.SetAnnouncementChannel(ctx, guildID, ""))
+
+	channelID, err = repo.GetAnnouncementChannel(ctx, guildID)
+	require.NoError(t, err)
+	assert.Empty(t, channelID)
+}
@@ -0,0 +1,126 @@
+// Package admin implements maintenance operations for operators, run from the command line
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Dialect is the database a Repository runs against, which determines its tables
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	SQLite
+)
+
+// guildTables are the tables holding each guild's data, in the order they are purged. Reactions come first so that
+// their triggers have updated the rollups before the rollups are purged. The privacy audit log is kept, as it records
+// requests made by users rather than guild data
+var guildTables = map[Dialect][]string{
+	Postgres: {
+		"reactions",
+		"reaction_aggregates",
+		"reaction_rollups_emoji",
+		"reaction_rollups_sender",
+		"reaction_rollups_receiver",
+		"reaction_rollups_channel",
+		"starboard_configs",
+		"starboard_messages",
+		"digests",
+		"milestone_configs",
+		"milestones",
+		"achievements",
+		"achievement_configs",
+		"role_rewards",
+		"role_reward_members",
+		"role_reward_audit",
+		"guild_timezones",
+		"guild_settings",
+		"channel_parents",
+		"retention_policies",
+		"query_cache",
+		"api_tokens",
+	},
+	SQLite: {
+		"reactions",
+		"guild_timezones",
+		"guild_settings",
+		"channel_parents",
+	},
+}
+
+// duplicates selects the IDs of reactions which duplicate an earlier reaction by the same user with the same emoji to
+// the same message, which can be stored when events are replayed or the same data is imported twice
+const duplicates = `
+	SELECT id FROM (
+		SELECT id, ROW_NUMBER() OVER (
+			PARTITION BY guild_id, channel_id, message_id, emoji_id, sender_user_id
+			ORDER BY created_at, id
+		) AS n
+		FROM reactions
+	) ranked
+	WHERE n > 1`
+
+// Purged is the number of rows purged from a table
+type Purged struct {
+	Table string
+	Rows  int64
+}
+
+// Repository runs maintenance queries
+type Repository struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewRepository creates a new admin repository
+func NewRepository(db *sql.DB, dialect Dialect) *Repository {
+	return &Repository{db: db, dialect: dialect}
+}
+
+// PurgeGuild deletes all of a guild's data in a single transaction, returning the rows deleted from each table
+func (r *Repository) PurgeGuild(ctx context.Context, guildID string) ([]Purged, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var purged []Purged
+	for _, table := range guildTables[r.dialect] {
+		res, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE guild_id = $1", guildID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		purged = append(purged, Purged{Table: table, Rows: n})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+// CountDuplicates returns the number of duplicate reactions
+func (r *Repository) CountDuplicates(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM (`+duplicates+`) d`).Scan(&n)
+	return n, err
+}
+
+// DeleteDuplicates deletes duplicate reactions, keeping the earliest of each, and returns the number deleted
+func (r *Repository) DeleteDuplicates(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM reactions WHERE id IN (`+duplicates+`)`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/sqlite"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+func newSQLiteRepository(t *testing.T) (*Repository, *sqlite.Store) {
+	t.Helper()
+
+	db, err := sqlite.Open(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	require.NoError(t, sqlite.Migrate(db))
+
+	return NewRepository(db, SQLite), sqlite.NewStore(db)
+}
+
+func addReaction(t *testing.T, s *sqlite.Store, guildID, messageID, senderID string, createdAt time.Time) {
+	t.Helper()
+	require.NoError(t, s.AddReaction(context.Background(), stats.Reaction{
+		GuildID:        guildID,
+		ChannelID:      "chan1",
+		MessageID:      messageID,
+		EmojiID:        "👍",
+		IsDefault:      true,
+		SenderUserID:   senderID,
+		ReceiverUserID: "receiver1",
+		CreatedAt:      createdAt,
+	}))
+}
+
+func TestPurgeGuild(t *testing.T) {
+	r, s := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	addReaction(t, s, "guild1", "msg1", "user1", time.Now())
+	addReaction(t, s, "guild1", "msg2", "user1", time.Now())
+	addReaction(t, s, "guild2", "msg3", "user1", time.Now())
+	require.NoError(t, s.SetTimezone(ctx, "guild1", time.UTC))
+	require.NoError(t, s.SetSettings(ctx, "guild1", stats.DefaultSettings()))
+	_, err := s.SetChannelParent(ctx, "guild1", "thread1", "chan1")
+	require.NoError(t, err)
+	_, err = s.SetChannelParent(ctx, "guild1", "chan1", "cat1")
+	require.NoError(t, err)
+
+	purged, err := r.PurgeGuild(ctx, "guild1")
+
+	require.NoError(t, err)
+	assert.Equal(t, []Purged{{Table: "reactions", Rows: 2}, {Table: "guild_timezones", Rows: 1}, {Table: "guild_settings", Rows: 1}, {Table: "channel_parents", Rows: 2}}, purged)
+
+	guild1, err := s.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Zero(t, guild1.TotalReactions)
+
+	guild2, err := s.GetGuildStats(ctx, "guild2", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, guild2.TotalReactions, "other guilds should not be purged")
+}
+
+func TestDeleteDuplicates(t *testing.T) {
+	r, s := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	addReaction(t, s, "guild1", "msg1", "user1", now)
+	addReaction(t, s, "guild1", "msg1", "user1", now.Add(time.Second))
+	addReaction(t, s, "guild1", "msg1", "user1", now.Add(2*time.Second))
+	addReaction(t, s, "guild1", "msg1", "user2", now)
+	addReaction(t, s, "guild1", "msg2", "user1", now)
+
+	n, err := r.CountDuplicates(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	n, err = r.DeleteDuplicates(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+
+	result, err := s.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.TotalReactions)
+
+	n, err = r.CountDuplicates(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// tokenPrefix makes tokens recognisable, e.g. to secret scanners
+const tokenPrefix = "emojistats_"
+
+// Token is an API token which grants read-only access to one guild's stats
+type Token struct {
+	ID        int
+	GuildID   string
+	Name      string
+	CreatedBy string
+	CreatedAt time.Time
+	// LastUsedAt is nil if the token has never been used
+	LastUsedAt *time.Time
+}
+
+// GenerateToken returns a new random token
+func GenerateToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+}
+
+// HashToken returns the hash stored for a token
+func HashToken(token string) []byte {
+	h := sha256.Sum256([]byte(token))
+	return h[:]
+}
+
+// UserStats is the response for a user, their streak and their recap of the requested year
+type UserStats struct {
+	UserID  string         `json:"user_id"`
+	Streak  stats.Streak   `json:"streak"`
+	Wrapped *stats.Wrapped `json:"wrapped"`
+}
+
+// Error is the response for a failed request
+type Error struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateToken(t *testing.T) {
+	a, b := GenerateToken(), GenerateToken()
+
+	assert.True(t, strings.HasPrefix(a, tokenPrefix))
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, HashToken(a), HashToken(a))
+	assert.NotEqual(t, HashToken(a), HashToken(b))
+}
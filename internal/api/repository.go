@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Repository handles database queries for API tokens
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository creates a new API token repository
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateToken issues a new token for a guild. The token is returned once and only its hash is stored
+func (r *Repository) CreateToken(ctx context.Context, guildID, name, createdBy string) (string, *Token, error) {
+	token := GenerateToken()
+
+	t := &Token{GuildID: guildID, Name: name, CreatedBy: createdBy}
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO api_tokens (guild_id, name, token_hash, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		guildID,
+		name,
+		HashToken(token),
+		createdBy,
+	).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, t, nil
+}
+
+// GetTokens retrieves a guild's tokens, oldest first
+func (r *Repository) GetTokens(ctx context.Context, guildID string) ([]Token, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, guild_id, name, created_by, created_at, last_used_at
+		FROM api_tokens
+		WHERE guild_id = $1
+		ORDER BY id`,
+		guildID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []Token
+	for rows.Next() {
+		var t Token
+		if err := rows.Scan(&t.ID, &t.GuildID, &t.Name, &t.CreatedBy, &t.CreatedAt, &t.LastUsedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, t)
+	}
+	return results, rows.Err()
+}
+
+// RevokeToken deletes one of a guild's tokens. It returns false if the guild has no such token
+func (r *Repository) RevokeToken(ctx context.Context, guildID string, id int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE guild_id = $1 AND id = $2`, guildID, id)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Authenticate returns the guild a token grants access to and records that it was used. It returns an empty guild ID
+// if the token is not valid
+func (r *Repository) Authenticate(ctx context.Context, token string) (string, error) {
+	var guildID string
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE api_tokens SET last_used_at = NOW()
+		WHERE token_hash = $1
+		RETURNING guild_id`,
+		HashToken(token),
+	).Scan(&guildID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return guildID, err
+}
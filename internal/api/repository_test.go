@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+)
+
+var testDB *sql.DB
+
+func setupRepositoryTest(t *testing.T) (*Repository, string) {
+	t.Helper()
+
+	testDB = dbtest.Open(t)
+
+	guildID := "test-guild-" + time.Now().Format("20060102150405.000000000")
+	t.Cleanup(func() {
+		_, _ = testDB.Exec("DELETE FROM api_tokens WHERE guild_id = $1", guildID)
+	})
+
+	return NewRepository(testDB), guildID
+}
+
+func TestTokens(t *testing.T) {
+	repo, guildID := setupRepositoryTest(t)
+	ctx := context.Background()
+
+	token, created, err := repo.CreateToken(ctx, guildID, "website", "user1")
+	require.NoError(t, err)
+	assert.Equal(t, "website", created.Name)
+
+	tokens, err := repo.GetTokens(ctx, guildID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, created.ID, tokens[0].ID)
+	assert.Equal(t, "user1", tokens[0].CreatedBy)
+	assert.Nil(t, tokens[0].LastUsedAt)
+
+	got, err := repo.Authenticate(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, guildID, got)
+
+	tokens, err = repo.GetTokens(ctx, guildID)
+	require.NoError(t, err)
+	assert.NotNil(t, tokens[0].LastUsedAt, "authenticating should record when the token was used")
+
+	got, err = repo.Authenticate(ctx, token+"x")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	revoked, err := repo.RevokeToken(ctx, "other-guild", created.ID)
+	require.NoError(t, err)
+	assert.False(t, revoked, "tokens should only be revoked by their guild")
+
+	revoked, err = repo.RevokeToken(ctx, guildID, created.ID)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	got, err = repo.Authenticate(ctx, token)
+	require.NoError(t, err)
+	assert.Empty(t, got, "revoked tokens should not authenticate")
+}
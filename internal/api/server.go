@@ -0,0 +1,319 @@
+// Package api serves a read-only HTTP JSON API for guild stats. Each request must carry an API token issued to the
+// guild with the /api-token command. The API is described by the OpenAPI spec served at /openapi.yaml
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/httpserver"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// Authenticator resolves API tokens to the guild they grant access to
+type Authenticator interface {
+	// Authenticate returns the guild a token grants access to, or an empty guild ID if the token is not valid
+	Authenticate(ctx context.Context, token string) (string, error)
+}
+
+// Server serves the stats API
+type Server struct {
+	stats stats.Querier
+	auth  Authenticator
+	now   func() time.Time
+}
+
+// NewServer creates a new API server
+func NewServer(q stats.Querier, auth Authenticator) *Server {
+	return &Server{stats: q, auth: auth, now: time.Now}
+}
+
+// Handler returns the API's routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /openapi.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(spec)
+	})
+
+	mux.Handle("GET /guilds/{guild}/stats", s.authenticated(s.guildStats))
+	mux.Handle("GET /guilds/{guild}/emojis", s.authenticated(s.topEmojis))
+	mux.Handle("GET /guilds/{guild}/emojis/{emoji}", s.authenticated(s.emojiStats))
+	mux.Handle("GET /guilds/{guild}/channels", s.authenticated(s.topChannels))
+	mux.Handle("GET /guilds/{guild}/messages", s.authenticated(s.topMessages))
+	mux.Handle("GET /guilds/{guild}/streaks", s.authenticated(s.streaks))
+	mux.Handle("GET /guilds/{guild}/users/{user}", s.authenticated(s.userStats))
+
+	return mux
+}
+
+// ListenAndServe serves the API on addr until the context is done
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	return httpserver.ListenAndServe(ctx, "API", addr, s.Handler())
+}
+
+// handlerFunc handles a request for a guild, returning the response body or an error
+type handlerFunc func(r *http.Request, guildID string) (any, error)
+
+// requestError is an error caused by the request, which is returned to the client
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...any) error {
+	return &requestError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// authenticated checks that the request's bearer token grants access to the guild in the path before calling h
+func (s *Server) authenticated(h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		guildID := r.PathValue("guild")
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, Error{Error: "missing API token"})
+			return
+		}
+
+		tokenGuildID, err := s.auth.Authenticate(r.Context(), token)
+		if err != nil {
+			slog.Error("failed to authenticate API token", "error", err)
+			writeJSON(w, http.StatusInternalServerError, Error{Error: "internal error"})
+			return
+		}
+		if tokenGuildID == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, Error{Error: "invalid API token"})
+			return
+		}
+		if tokenGuildID != guildID {
+			writeJSON(w, http.StatusForbidden, Error{Error: "API token does not grant access to this guild"})
+			return
+		}
+
+		body, err := h(r, guildID)
+		if err != nil {
+			var reqErr *requestError
+			if errors.As(err, &reqErr) {
+				writeJSON(w, reqErr.status, Error{Error: reqErr.message})
+				return
+			}
+
+			slog.Error("failed to handle API request", "error", err, "guild_id", guildID, "path", r.URL.Path)
+			writeJSON(w, http.StatusInternalServerError, Error{Error: "internal error"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, body)
+	})
+}
+
+func (s *Server) guildStats(r *http.Request, guildID string) (any, error) {
+	dateRange, err := parseDateRange(r)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		return nil, err
+	}
+
+	guildStats, err := s.stats.GetGuildStats(r.Context(), guildID, dateRange, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	guildStats.TopEmojis = nonNil(guildStats.TopEmojis)
+	guildStats.TopSenders = nonNil(guildStats.TopSenders)
+	guildStats.TopReceivers = nonNil(guildStats.TopReceivers)
+	return guildStats, nil
+}
+
+func (s *Server) topEmojis(r *http.Request, guildID string) (any, error) {
+	dateRange, err := parseDateRange(r)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		return nil, err
+	}
+
+	emojis, err := s.stats.GetTopEmojis(r.Context(), guildID, dateRange, limit)
+	return nonNil(emojis), err
+}
+
+func (s *Server) emojiStats(r *http.Request, guildID string) (any, error) {
+	dateRange, err := parseDateRange(r)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		return nil, err
+	}
+
+	emojiStats, err := s.stats.GetEmojiStats(r.Context(), guildID, r.PathValue("emoji"), dateRange, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	emojiStats.TopMessages = nonNil(emojiStats.TopMessages)
+	emojiStats.TopSenders = nonNil(emojiStats.TopSenders)
+	emojiStats.TopReceivers = nonNil(emojiStats.TopReceivers)
+	return emojiStats, nil
+}
+
+func (s *Server) topChannels(r *http.Request, guildID string) (any, error) {
+	dateRange, err := parseDateRange(r)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := s.stats.GetTopChannels(r.Context(), guildID, dateRange, limit)
+	return nonNil(channels), err
+}
+
+func (s *Server) topMessages(r *http.Request, guildID string) (any, error) {
+	dateRange, err := parseDateRange(r)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.stats.GetTopMessages(r.Context(), guildID, r.URL.Query().Get("channel_id"), dateRange, limit)
+	return nonNil(messages), err
+}
+
+func (s *Server) streaks(r *http.Request, guildID string) (any, error) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var byLongest bool
+	switch order := r.URL.Query().Get("order"); order {
+	case "", "current":
+	case "longest":
+		byLongest = true
+	default:
+		return nil, badRequest("invalid order %q, must be current or longest", order)
+	}
+
+	streaks, err := s.stats.GetStreaks(r.Context(), guildID, byLongest, limit)
+	return nonNil(streaks), err
+}
+
+func (s *Server) userStats(r *http.Request, guildID string) (any, error) {
+	userID := r.PathValue("user")
+
+	year := s.now().UTC().Year()
+	if v := r.URL.Query().Get("year"); v != "" {
+		var err error
+		year, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, badRequest("invalid year %q", v)
+		}
+	}
+
+	streak, err := s.stats.GetStreak(r.Context(), guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := s.stats.GetWrapped(r.Context(), guildID, userID, year)
+	if err != nil {
+		return nil, err
+	}
+
+	return UserStats{UserID: streak.UserID, Streak: streak, Wrapped: wrapped}, nil
+}
+
+// parseDateRange parses the start_date and end_date query parameters, as dates which are both inclusive as in the
+// stats commands
+func parseDateRange(r *http.Request) (stats.DateRange, error) {
+	var dateRange stats.DateRange
+
+	if v := r.URL.Query().Get("start_date"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return dateRange, badRequest("invalid start_date %q, must be YYYY-MM-DD", v)
+		}
+		dateRange.Start = &t
+	}
+
+	if v := r.URL.Query().Get("end_date"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return dateRange, badRequest("invalid end_date %q, must be YYYY-MM-DD", v)
+		}
+		t = t.AddDate(0, 0, 1)
+		dateRange.End = &t
+	}
+
+	return dateRange, nil
+}
+
+func parseLimit(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return defaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 || limit > maxLimit {
+		return 0, badRequest("invalid limit %q, must be between 1 and %d", v, maxLimit)
+	}
+	return limit, nil
+}
+
+// nonNil returns an empty slice for nil, so that empty lists are encoded as [] rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("failed to write API response", "error", err)
+	}
+}
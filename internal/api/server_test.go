@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/memory"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+const guildID = "guild1"
+
+// fakeAuthenticator maps tokens to guilds
+type fakeAuthenticator map[string]string
+
+func (a fakeAuthenticator) Authenticate(_ context.Context, token string) (string, error) {
+	return a[token], nil
+}
+
+func newTestServer(t *testing.T) (*Server, *memory.Store) {
+	t.Helper()
+
+	store := memory.NewStore()
+	s := NewServer(store, fakeAuthenticator{"token1": guildID, "token2": "guild2"})
+	s.now = func() time.Time { return time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC) }
+
+	return s, store
+}
+
+func addReaction(t *testing.T, store *memory.Store, emojiID, senderID, receiverID string, createdAt time.Time) {
+	t.Helper()
+	require.NoError(t, store.AddReaction(context.Background(), stats.Reaction{
+		GuildID:        guildID,
+		ChannelID:      "chan1",
+		MessageID:      "msg1",
+		EmojiID:        emojiID,
+		IsDefault:      emojiID[0] != '<',
+		SenderUserID:   senderID,
+		ReceiverUserID: receiverID,
+		CreatedAt:      createdAt,
+	}))
+}
+
+func get(t *testing.T, s *Server, path, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &v), w.Body.String())
+	return v
+}
+
+func TestAuthentication(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	tests := map[string]struct {
+		token    string
+		expected int
+	}{
+		"missing":     {"", http.StatusUnauthorized},
+		"invalid":     {"nope", http.StatusUnauthorized},
+		"other guild": {"token2", http.StatusForbidden},
+		"valid":       {"token1", http.StatusOK},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := get(t, s, "/guilds/"+guildID+"/stats", tc.token)
+
+			assert.Equal(t, tc.expected, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			if tc.expected != http.StatusOK {
+				assert.NotEmpty(t, decode[Error](t, w).Error)
+			}
+		})
+	}
+}
+
+func TestGuildStats(t *testing.T) {
+	s, store := newTestServer(t)
+
+	addReaction(t, store, "👍", "sender1", "receiver1", time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	addReaction(t, store, "👍", "sender2", "receiver1", time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC))
+	addReaction(t, store, "👍", "sender2", "receiver1", time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+
+	w := get(t, s, "/guilds/"+guildID+"/stats?start_date=2024-06-01&end_date=2024-06-30", "token1")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"total_reactions": 2,
+		"top_emojis": [{"emoji_id": "👍", "is_default": true, "count": 2}],
+		"top_senders": [{"user_id": "sender1", "count": 1}, {"user_id": "sender2", "count": 1}],
+		"top_receivers": [{"user_id": "receiver1", "count": 2}]
+	}`, w.Body.String())
+}
+
+func TestGuildStats_Empty(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	w := get(t, s, "/guilds/"+guildID+"/stats", "token1")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"total_reactions": 0, "top_emojis": [], "top_senders": [], "top_receivers": []}`, w.Body.String())
+
+	w = get(t, s, "/guilds/"+guildID+"/emojis/"+url.PathEscape("👍"), "token1")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"emoji_id": "👍",
+		"is_default": false,
+		"total_uses": 0,
+		"top_messages": [],
+		"top_senders": [],
+		"top_receivers": []
+	}`, w.Body.String())
+}
+
+func TestEmojiStats(t *testing.T) {
+	s, store := newTestServer(t)
+
+	addReaction(t, store, "<:custom:123>", "sender1", "receiver1", time.Now())
+	addReaction(t, store, "👍", "sender1", "receiver1", time.Now())
+
+	w := get(t, s, "/guilds/"+guildID+"/emojis/"+url.PathEscape("<:custom:123>"), "token1")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	result := decode[stats.EmojiStats](t, w)
+	assert.Equal(t, "<:custom:123>", result.EmojiID)
+	assert.False(t, result.IsDefault)
+	assert.Equal(t, 1, result.TotalUses)
+}
+
+func TestLists(t *testing.T) {
+	s, store := newTestServer(t)
+
+	addReaction(t, store, "👍", "sender1", "receiver1", time.Now())
+	addReaction(t, store, "<:a:1>", "sender1", "receiver1", time.Now())
+
+	emojis := decode[[]stats.EmojiCount](t, get(t, s, "/guilds/"+guildID+"/emojis?limit=1", "token1"))
+	assert.Equal(t, []stats.EmojiCount{{EmojiID: "<:a:1>", Count: 1}}, emojis)
+
+	channels := decode[[]stats.ChannelCount](t, get(t, s, "/guilds/"+guildID+"/channels", "token1"))
+	assert.Equal(t, []stats.ChannelCount{{ChannelID: "chan1", Count: 2}}, channels)
+
+	messages := decode[[]stats.TopMessage](t, get(t, s, "/guilds/"+guildID+"/messages?channel_id=chan1", "token1"))
+	require.Len(t, messages, 1)
+	assert.Equal(t, 2, messages[0].TotalReactions)
+
+	streaks := decode[[]stats.Streak](t, get(t, s, "/guilds/"+guildID+"/streaks?order=longest", "token1"))
+	assert.Len(t, streaks, 2)
+}
+
+func TestLists_Empty(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	w := get(t, s, "/guilds/"+guildID+"/emojis", "token1")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
+
+func TestUserStats(t *testing.T) {
+	s, store := newTestServer(t)
+
+	addReaction(t, store, "👍", "user1", "user2", time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC))
+	addReaction(t, store, "👍", "user1", "user2", time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	addReaction(t, store, "👍", "user1", "user2", time.Date(2024, 6, 2, 12, 0, 0, 0, time.UTC))
+
+	result := decode[UserStats](t, get(t, s, "/guilds/"+guildID+"/users/user1", "token1"))
+	assert.Equal(t, "user1", result.UserID)
+	assert.Equal(t, 2, result.Streak.Longest)
+	require.NotNil(t, result.Wrapped)
+	assert.Equal(t, 2024, result.Wrapped.Year)
+	assert.Equal(t, 2, result.Wrapped.TotalReactions)
+
+	result = decode[UserStats](t, get(t, s, "/guilds/"+guildID+"/users/user1?year=2023", "token1"))
+	assert.Equal(t, 2023, result.Wrapped.Year)
+	assert.Equal(t, 1, result.Wrapped.TotalReactions)
+}
+
+func TestBadRequests(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	for _, path := range []string{
+		"/guilds/" + guildID + "/stats?start_date=yesterday",
+		"/guilds/" + guildID + "/stats?end_date=2024-13-01",
+		"/guilds/" + guildID + "/emojis?limit=0",
+		"/guilds/" + guildID + "/emojis?limit=101",
+		"/guilds/" + guildID + "/streaks?order=random",
+		"/guilds/" + guildID + "/users/user1?year=last",
+	} {
+		t.Run(path, func(t *testing.T) {
+			w := get(t, s, path, "token1")
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.NotEmpty(t, decode[Error](t, w).Error)
+		})
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	w := get(t, s, "/openapi.yaml", "")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/guilds/{guild_id}/stats:")
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Backend stores cached query results. Entries are grouped by guild so that a guild's results can be invalidated
+// together
+type Backend interface {
+	// Get returns the value stored for a key, and false if there is none or it has expired
+	Get(ctx context.Context, guildID, key string) ([]byte, bool, error)
+	Set(ctx context.Context, guildID, key string, value []byte, ttl time.Duration) error
+	// Invalidate removes every entry for a guild
+	Invalidate(ctx context.Context, guildID string) error
+	// InvalidateAll removes every entry for every guild
+	InvalidateAll(ctx context.Context) error
+}
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// Memory is a Backend local to the process
+type Memory struct {
+	mu     sync.Mutex
+	guilds map[string]map[string]entry
+	now    func() time.Time
+}
+
+// NewMemory creates a new in-memory backend
+func NewMemory() *Memory {
+	return &Memory{
+		guilds: make(map[string]map[string]entry),
+		now:    time.Now,
+	}
+}
+
+func (m *Memory) Get(_ context.Context, guildID, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.guilds[guildID][key]
+	if !ok || !m.now().Before(e.expiresAt) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set stores a value, removing the guild's expired entries so that keys which are never read again do not build up
+func (m *Memory) Set(_ context.Context, guildID, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+
+	entries, ok := m.guilds[guildID]
+	if !ok {
+		entries = make(map[string]entry)
+		m.guilds[guildID] = entries
+	}
+
+	for k, e := range entries {
+		if !now.Before(e.expiresAt) {
+			delete(entries, k)
+		}
+	}
+
+	entries[key] = entry{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (m *Memory) Invalidate(_ context.Context, guildID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.guilds, guildID)
+	return nil
+}
+
+func (m *Memory) InvalidateAll(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clear(m.guilds)
+	return nil
+}
+
+// Postgres is a Backend shared between every replica connected to the same database
+type Postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres creates a new backend in the query_cache table
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (p *Postgres) Get(ctx context.Context, guildID, key string) ([]byte, bool, error) {
+	var value []byte
+	err := p.db.QueryRowContext(ctx, `
+		SELECT value FROM query_cache
+		WHERE guild_id = $1 AND key = $2 AND expires_at > NOW()`,
+		guildID,
+		key,
+	).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores a value, removing the guild's expired entries so that keys which are never read again do not build up
+func (p *Postgres) Set(ctx context.Context, guildID, key string, value []byte, ttl time.Duration) error {
+	if _, err := p.db.ExecContext(ctx, `DELETE FROM query_cache WHERE guild_id = $1 AND expires_at <= NOW()`, guildID); err != nil {
+		return err
+	}
+
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO query_cache (guild_id, key, value, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 millisecond')
+		ON CONFLICT (guild_id, key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at`,
+		guildID,
+		key,
+		value,
+		ttl.Milliseconds(),
+	)
+	return err
+}
+
+func (p *Postgres) Invalidate(ctx context.Context, guildID string) error {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM query_cache WHERE guild_id = $1`, guildID)
+	return err
+}
+
+func (p *Postgres) InvalidateAll(ctx context.Context) error {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM query_cache`)
+	return err
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+)
+
+func TestBackends(t *testing.T) {
+	backends := map[string]func(t *testing.T) Backend{
+		"memory":   func(*testing.T) Backend { return NewMemory() },
+		"postgres": func(t *testing.T) Backend { return NewPostgres(dbtest.Open(t)) },
+	}
+
+	for name, newBackend := range backends {
+		t.Run(name, func(t *testing.T) {
+			backend := newBackend(t)
+			ctx := context.Background()
+			guildID := "test-guild-" + time.Now().Format("20060102150405.000000000")
+			defer func() { _ = backend.Invalidate(ctx, guildID) }()
+
+			_, ok, err := backend.Get(ctx, guildID, "key")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			require.NoError(t, backend.Set(ctx, guildID, "key", []byte("value"), time.Minute))
+			require.NoError(t, backend.Set(ctx, guildID, "expired", []byte("value"), -time.Second))
+
+			value, ok, err := backend.Get(ctx, guildID, "key")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, []byte("value"), value)
+
+			_, ok, err = backend.Get(ctx, guildID, "expired")
+			require.NoError(t, err)
+			assert.False(t, ok, "expired entries should not be returned")
+
+			require.NoError(t, backend.Invalidate(ctx, guildID))
+
+			_, ok, err = backend.Get(ctx, guildID, "key")
+			require.NoError(t, err)
+			assert.False(t, ok, "invalidated entries should not be returned")
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/elliotwms/emojistats/internal/metrics"
+)
+
+// Counts is the number of cache hits and misses for a query
+type Counts struct {
+	Hits   int64
+	Misses int64
+}
+
+// HitRate returns the fraction of lookups which were hits, or zero if there have been none
+func (c Counts) HitRate() float64 {
+	total := c.Hits + c.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(c.Hits) / float64(total)
+}
+
+// lookups counts the hits and misses of a cache, which are also exported to Prometheus as metrics.CacheLookups
+type lookups struct {
+	mu     sync.Mutex
+	counts map[string]Counts
+}
+
+func (m *lookups) record(query string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	metrics.CacheLookups.WithLabelValues(query, result).Inc()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.counts == nil {
+		m.counts = make(map[string]Counts)
+	}
+
+	c := m.counts[query]
+	if hit {
+		c.Hits++
+	} else {
+		c.Misses++
+	}
+	m.counts[query] = c
+}
+
+func (m *lookups) snapshot() map[string]Counts {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[string]Counts, len(m.counts))
+	for q, c := range m.counts {
+		counts[q] = c
+	}
+	return counts
+}
@@ -0,0 +1,296 @@
+// Package cache caches stats query results, so that popular commands rerun within a short time do not repeat the same
+// queries. Results are invalidated when reactions are added or removed through the cache, or for every guild when a
+// user makes a privacy request, and otherwise expire after a TTL. Changes made elsewhere, such as imports and pruning,
+// are only picked up once the TTL has passed
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// DefaultTTL is how long results are cached for if no TTL is configured
+const DefaultTTL = time.Minute
+
+const (
+	queryGuildStats  = "guild-stats"
+	queryEmojiStats  = "emoji-stats"
+	queryTopEmojis   = "top-emojis"
+	queryTopChannels = "top-channels"
+	queryTopMessages = "top-messages"
+	queryWrapped     = "wrapped"
+	queryStreak      = "streak"
+	queryStreaks     = "streaks"
+)
+
+// Repository is a stats.Store which caches the query results of another
+type Repository struct {
+	next    stats.Store
+	backend Backend
+	ttl     time.Duration
+	metrics lookups
+}
+
+var _ stats.Store = (*Repository)(nil)
+
+// NewRepository creates a new cache around next
+func NewRepository(next stats.Store, backend Backend, ttl time.Duration) *Repository {
+	return &Repository{
+		next:    next,
+		backend: backend,
+		ttl:     ttl,
+	}
+}
+
+func (r *Repository) GetGuildStats(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) (*stats.GuildStats, error) {
+	return cached(ctx, r, guildID, queryGuildStats, key(dateRange, limit), func() (*stats.GuildStats, error) {
+		return r.next.GetGuildStats(ctx, guildID, dateRange, limit)
+	})
+}
+
+func (r *Repository) GetEmojiStats(ctx context.Context, guildID, emojiID string, dateRange stats.DateRange, limit int) (*stats.EmojiStats, error) {
+	return cached(ctx, r, guildID, queryEmojiStats, key(dateRange, emojiID, limit), func() (*stats.EmojiStats, error) {
+		return r.next.GetEmojiStats(ctx, guildID, emojiID, dateRange, limit)
+	})
+}
+
+func (r *Repository) GetTopEmojis(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) ([]stats.EmojiCount, error) {
+	return cached(ctx, r, guildID, queryTopEmojis, key(dateRange, limit), func() ([]stats.EmojiCount, error) {
+		return r.next.GetTopEmojis(ctx, guildID, dateRange, limit)
+	})
+}
+
+func (r *Repository) GetTopChannels(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) ([]stats.ChannelCount, error) {
+	return cached(ctx, r, guildID, queryTopChannels, key(dateRange, limit), func() ([]stats.ChannelCount, error) {
+		return r.next.GetTopChannels(ctx, guildID, dateRange, limit)
+	})
+}
+
+func (r *Repository) GetTopMessages(ctx context.Context, guildID, channelID string, dateRange stats.DateRange, limit int) ([]stats.TopMessage, error) {
+	return cached(ctx, r, guildID, queryTopMessages, key(dateRange, channelID, limit), func() ([]stats.TopMessage, error) {
+		return r.next.GetTopMessages(ctx, guildID, channelID, dateRange, limit)
+	})
+}
+
+func (r *Repository) GetWrapped(ctx context.Context, guildID, userID string, year int) (*stats.Wrapped, error) {
+	return cached(ctx, r, guildID, queryWrapped, key(stats.DateRange{}, userID, year), func() (*stats.Wrapped, error) {
+		return r.next.GetWrapped(ctx, guildID, userID, year)
+	})
+}
+
+func (r *Repository) GetStreak(ctx context.Context, guildID, userID string) (stats.Streak, error) {
+	return cached(ctx, r, guildID, queryStreak, key(stats.DateRange{}, userID), func() (stats.Streak, error) {
+		return r.next.GetStreak(ctx, guildID, userID)
+	})
+}
+
+func (r *Repository) GetStreaks(ctx context.Context, guildID string, byLongest bool, limit int) ([]stats.Streak, error) {
+	return cached(ctx, r, guildID, queryStreaks, key(stats.DateRange{}, byLongest, limit), func() ([]stats.Streak, error) {
+		return r.next.GetStreaks(ctx, guildID, byLongest, limit)
+	})
+}
+
+// AddReaction stores a reaction and invalidates the cached results for its guild
+func (r *Repository) AddReaction(ctx context.Context, reaction stats.Reaction) error {
+	if err := r.next.AddReaction(ctx, reaction); err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, reaction.GuildID)
+	return nil
+}
+
+// RemoveReaction deletes a reaction and invalidates the cached results for its guild
+func (r *Repository) RemoveReaction(ctx context.Context, guildID, messageID, emojiID, senderUserID string) (int, error) {
+	n, err := r.next.RemoveReaction(ctx, guildID, messageID, emojiID, senderUserID)
+	if err != nil {
+		return n, err
+	}
+
+	if n > 0 {
+		r.invalidate(ctx, guildID)
+	}
+	return n, nil
+}
+
+func (r *Repository) OptedOut(ctx context.Context, userIDs ...string) (map[string]bool, error) {
+	return r.next.OptedOut(ctx, userIDs...)
+}
+
+// OptOut opts a user out and invalidates every guild's cached results, as the user is now shown anonymously
+func (r *Repository) OptOut(ctx context.Context, guildID, userID string) error {
+	if err := r.next.OptOut(ctx, guildID, userID); err != nil {
+		return err
+	}
+
+	r.invalidateAll(ctx)
+	return nil
+}
+
+// OptIn opts a user back in and invalidates every guild's cached results, as the user is no longer anonymous
+func (r *Repository) OptIn(ctx context.Context, guildID, userID string) error {
+	if err := r.next.OptIn(ctx, guildID, userID); err != nil {
+		return err
+	}
+
+	r.invalidateAll(ctx)
+	return nil
+}
+
+// Forget forgets a user and invalidates every guild's cached results, as their reactions are forgotten in every guild
+func (r *Repository) Forget(ctx context.Context, guildID, userID string) (privacy.Forgotten, error) {
+	f, err := r.next.Forget(ctx, guildID, userID)
+	if err != nil {
+		return f, err
+	}
+
+	r.invalidateAll(ctx)
+	return f, nil
+}
+
+func (r *Repository) GetTimezone(ctx context.Context, guildID string) (*time.Location, error) {
+	return r.next.GetTimezone(ctx, guildID)
+}
+
+// SetTimezone sets a guild's timezone and invalidates its cached results, as streaks depend on it
+func (r *Repository) SetTimezone(ctx context.Context, guildID string, loc *time.Location) error {
+	if err := r.next.SetTimezone(ctx, guildID, loc); err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, guildID)
+	return nil
+}
+
+func (r *Repository) GetSettings(ctx context.Context, guildID string) (stats.Settings, error) {
+	return r.next.GetSettings(ctx, guildID)
+}
+
+// SetSettings sets a guild's settings and invalidates its cached results, as the stats exclude its ignored channels
+func (r *Repository) SetSettings(ctx context.Context, guildID string, settings stats.Settings) error {
+	if err := r.next.SetSettings(ctx, guildID, settings); err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, guildID)
+	return nil
+}
+
+// SetChannelParent records a channel's parent and, if it changed, invalidates the guild's cached results, as it may
+// change whether the channel is excluded from the stats
+func (r *Repository) SetChannelParent(ctx context.Context, guildID, channelID, parentID string) (bool, error) {
+	changed, err := r.next.SetChannelParent(ctx, guildID, channelID, parentID)
+	if err != nil {
+		return false, err
+	}
+
+	if changed {
+		r.invalidate(ctx, guildID)
+	}
+	return changed, nil
+}
+
+// Invalidate removes every cached result for a guild
+func (r *Repository) Invalidate(ctx context.Context, guildID string) error {
+	return r.backend.Invalidate(ctx, guildID)
+}
+
+// invalidate removes every cached result for a guild after it has been written to. Failures are logged rather than
+// returned as the write has already succeeded, and stale results expire after the TTL
+func (r *Repository) invalidate(ctx context.Context, guildID string) {
+	if err := r.Invalidate(ctx, guildID); err != nil {
+		slog.Error("failed to invalidate stats cache", "error", err, "guild_id", guildID)
+	}
+}
+
+// invalidateAll removes every guild's cached results after a write which applies to all of them. As with invalidate,
+// failures are logged
+func (r *Repository) invalidateAll(ctx context.Context) {
+	if err := r.backend.InvalidateAll(ctx); err != nil {
+		slog.Error("failed to invalidate stats cache", "error", err)
+	}
+}
+
+// Metrics returns the number of hits and misses for each query type since the cache was created
+func (r *Repository) Metrics() map[string]Counts {
+	return r.metrics.snapshot()
+}
+
+// LogMetrics logs the hit rate of each query type at an interval until the context is done
+func (r *Repository) LogMetrics(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for query, c := range r.Metrics() {
+			slog.Info("stats cache metrics", "query", query, "hits", c.Hits, "misses", c.Misses, "hit_rate", c.HitRate())
+		}
+	}
+}
+
+// cached returns the cached result of a query, or runs it and caches the result. Backend failures are logged and
+// the query is run uncached, so that the cache never causes a command to fail
+func cached[T any](ctx context.Context, r *Repository, guildID, query, key string, fn func() (T, error)) (T, error) {
+	key = query + ":" + key
+
+	if value, ok, err := r.backend.Get(ctx, guildID, key); err != nil {
+		slog.Error("failed to get cached stats", "error", err, "guild_id", guildID, "query", query)
+	} else if ok {
+		var result T
+		err := json.Unmarshal(value, &result)
+		if err == nil {
+			r.metrics.record(query, true)
+			return result, nil
+		}
+		slog.Error("failed to decode cached stats", "error", err, "guild_id", guildID, "query", query)
+	}
+
+	r.metrics.record(query, false)
+
+	result, err := fn()
+	if err != nil {
+		return result, err
+	}
+
+	value, err := json.Marshal(result)
+	if err != nil {
+		slog.Error("failed to encode stats for caching", "error", err, "guild_id", guildID, "query", query)
+		return result, nil
+	}
+
+	if err := r.backend.Set(ctx, guildID, key, value, r.ttl); err != nil {
+		slog.Error("failed to cache stats", "error", err, "guild_id", guildID, "query", query)
+	}
+
+	return result, nil
+}
+
+// key builds a cache key from a date range and a query's other arguments
+func key(dateRange stats.DateRange, args ...any) string {
+	parts := []string{formatTime(dateRange.Start), formatTime(dateRange.End)}
+	for _, a := range args {
+		parts = append(parts, fmt.Sprint(a))
+	}
+	return strings.Join(parts, ":")
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
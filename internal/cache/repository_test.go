@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/metrics"
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// fakeQuerier counts the queries which reach it
+type fakeQuerier struct {
+	stats.Store
+	calls int
+	err   error
+}
+
+func (f *fakeQuerier) AddReaction(context.Context, stats.Reaction) error {
+	return nil
+}
+
+func (f *fakeQuerier) Forget(context.Context, string, string) (privacy.Forgotten, error) {
+	return privacy.Forgotten{}, nil
+}
+
+func (f *fakeQuerier) GetGuildStats(_ context.Context, guildID string, _ stats.DateRange, _ int) (*stats.GuildStats, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &stats.GuildStats{
+		TotalReactions: f.calls,
+		TopEmojis:      []stats.EmojiCount{{EmojiID: "👍", IsDefault: true, Count: f.calls}},
+		TopSenders:     []stats.UserCount{{UserID: guildID + "-sender", Count: 1}},
+	}, nil
+}
+
+func (f *fakeQuerier) GetStreaks(_ context.Context, _ string, _ bool, _ int) ([]stats.Streak, error) {
+	f.calls++
+	return []stats.Streak{{UserID: "user1", Current: f.calls, Longest: 5}}, nil
+}
+
+func TestRepository_Cached(t *testing.T) {
+	q := &fakeQuerier{}
+	repo := NewRepository(q, NewMemory(), time.Minute)
+	ctx := context.Background()
+
+	first, err := repo.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+
+	second, err := repo.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, q.calls, "the second query should be served from the cache")
+	assert.Equal(t, map[string]Counts{queryGuildStats: {Hits: 1, Misses: 1}}, repo.Metrics())
+	assert.Equal(t, 0.5, repo.Metrics()[queryGuildStats].HitRate())
+}
+
+func TestRepository_PrometheusMetrics(t *testing.T) {
+	hits := metrics.CacheLookups.WithLabelValues(queryStreaks, "hit")
+	misses := metrics.CacheLookups.WithLabelValues(queryStreaks, "miss")
+	hitsBefore, missesBefore := testutil.ToFloat64(hits), testutil.ToFloat64(misses)
+
+	repo := NewRepository(&fakeQuerier{}, NewMemory(), time.Minute)
+	ctx := context.Background()
+
+	for range 3 {
+		_, err := repo.GetStreaks(ctx, "guild1", false, 10)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(hits)-hitsBefore)
+	assert.Equal(t, 1.0, testutil.ToFloat64(misses)-missesBefore)
+}
+
+func TestRepository_Keys(t *testing.T) {
+	q := &fakeQuerier{}
+	repo := NewRepository(q, NewMemory(), time.Minute)
+	ctx := context.Background()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, dateRange := range []stats.DateRange{{}, {Start: &start}, {End: &start}, {Start: &start, End: &end}} {
+		_, err := repo.GetGuildStats(ctx, "guild1", dateRange, 10)
+		require.NoError(t, err)
+	}
+	_, err := repo.GetGuildStats(ctx, "guild2", stats.DateRange{}, 10)
+	require.NoError(t, err)
+
+	_, err = repo.GetStreaks(ctx, "guild1", false, 10)
+	require.NoError(t, err)
+	_, err = repo.GetStreaks(ctx, "guild1", true, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, 7, q.calls, "each guild, query and date range should be cached separately")
+}
+
+func TestRepository_Invalidate(t *testing.T) {
+	q := &fakeQuerier{}
+	repo := NewRepository(q, NewMemory(), time.Minute)
+	ctx := context.Background()
+
+	_, err := repo.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	_, err = repo.GetGuildStats(ctx, "guild2", stats.DateRange{}, 10)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.AddReaction(ctx, stats.Reaction{GuildID: "guild1"}))
+
+	s, err := repo.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.TotalReactions, "the guild's results should be queried again")
+
+	s, err = repo.GetGuildStats(ctx, "guild2", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.TotalReactions, "other guilds' results should still be cached")
+}
+
+func TestRepository_InvalidateAll(t *testing.T) {
+	q := &fakeQuerier{}
+	repo := NewRepository(q, NewMemory(), time.Minute)
+	ctx := context.Background()
+
+	_, err := repo.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	_, err = repo.GetGuildStats(ctx, "guild2", stats.DateRange{}, 10)
+	require.NoError(t, err)
+
+	_, err = repo.Forget(ctx, "guild1", "user1")
+	require.NoError(t, err)
+
+	s, err := repo.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.TotalReactions, "the guild's results should be queried again")
+
+	s, err = repo.GetGuildStats(ctx, "guild2", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 4, s.TotalReactions, "other guilds' results should be queried again, as the user is forgotten in every guild")
+}
+
+func TestRepository_Expiry(t *testing.T) {
+	q := &fakeQuerier{}
+	backend := NewMemory()
+	now := time.Now()
+	backend.now = func() time.Time { return now }
+	repo := NewRepository(q, backend, time.Minute)
+	ctx := context.Background()
+
+	_, err := repo.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+
+	_, err = repo.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, q.calls, "expired results should be queried again")
+}
+
+func TestRepository_ErrorsNotCached(t *testing.T) {
+	q := &fakeQuerier{err: errors.New("boom")}
+	repo := NewRepository(q, NewMemory(), time.Minute)
+	ctx := context.Background()
+
+	_, err := repo.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	assert.Error(t, err)
+
+	q.err = nil
+	s, err := repo.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.TotalReactions)
+}
+
+// failingBackend fails every operation
+type failingBackend struct{}
+
+func (failingBackend) Get(context.Context, string, string) ([]byte, bool, error) {
+	return nil, false, errors.New("unavailable")
+}
+
+func (failingBackend) Set(context.Context, string, string, []byte, time.Duration) error {
+	return errors.New("unavailable")
+}
+
+func (failingBackend) Invalidate(context.Context, string) error {
+	return errors.New("unavailable")
+}
+
+func (failingBackend) InvalidateAll(context.Context) error {
+	return errors.New("unavailable")
+}
+
+func TestRepository_BackendUnavailable(t *testing.T) {
+	q := &fakeQuerier{}
+	repo := NewRepository(q, failingBackend{}, time.Minute)
+
+	s, err := repo.GetGuildStats(context.Background(), "guild1", stats.DateRange{}, 10)
+
+	require.NoError(t, err, "the query should succeed without the cache")
+	assert.Equal(t, 1, s.TotalReactions)
+}
+
+func TestCounts_HitRate(t *testing.T) {
+	assert.Equal(t, 0.0, Counts{}.HitRate())
+	assert.Equal(t, 0.75, Counts{Hits: 3, Misses: 1}.HitRate())
+}
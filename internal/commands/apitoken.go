@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/api"
+)
+
+// NewAPITokenHandler creates a handler for the /api-token command. Responses are always private, as they may contain a
+// token
+func NewAPITokenHandler(repo *api.Repository) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		guildID := i.GuildID
+		subcommand, options := parseSubcommand(data.Options)
+
+		switch subcommand {
+		case "create":
+			var name string
+			for _, opt := range options {
+				if opt.Name == "name" {
+					name = opt.StringValue()
+				}
+			}
+
+			token, t, err := repo.CreateToken(ctx, guildID, name, interactionUserID(i))
+			if err != nil {
+				slog.Error("failed to create API token", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to create the API token.")
+			}
+
+			return respond(ctx, s, i, fmt.Sprintf(
+				"Created API token **%d** (%s). Copy it now, as it will not be shown again:\n```\n%s\n```",
+				t.ID, t.Name, token,
+			))
+		case "revoke":
+			var id int
+			for _, opt := range options {
+				if opt.Name == "id" {
+					id = int(opt.IntValue())
+				}
+			}
+
+			revoked, err := repo.RevokeToken(ctx, guildID, id)
+			if err != nil {
+				slog.Error("failed to revoke API token", "error", err, "guild_id", guildID, "token_id", id)
+				return respondWithError(ctx, s, i, "Failed to revoke the API token.")
+			}
+			if !revoked {
+				return respondWithError(ctx, s, i, fmt.Sprintf("There is no API token **%d**.", id))
+			}
+
+			return respond(ctx, s, i, fmt.Sprintf("Revoked API token **%d**.", id))
+		default:
+			tokens, err := repo.GetTokens(ctx, guildID)
+			if err != nil {
+				slog.Error("failed to get API tokens", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to retrieve the API tokens.")
+			}
+
+			return respond(ctx, s, i, formatAPITokens(tokens))
+		}
+	}
+}
+
+func formatAPITokens(tokens []api.Token) string {
+	if len(tokens) == 0 {
+		return "This server has no API tokens. Use `/api-token create` to create one."
+	}
+
+	var b strings.Builder
+	b.WriteString("**API tokens**\n")
+	for _, t := range tokens {
+		lastUsed := "never used"
+		if t.LastUsedAt != nil {
+			lastUsed = fmt.Sprintf("last used <t:%d:R>", t.LastUsedAt.Unix())
+		}
+		fmt.Fprintf(&b, "**%d** %s, created by <@%s> <t:%d:R>, %s\n", t.ID, t.Name, t.CreatedBy, t.CreatedAt.Unix(), lastUsed)
+	}
+	return b.String()
+}
@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatAPITokens(t *testing.T) {
+	assert.Contains(t, formatAPITokens(nil), "no API tokens")
+
+	created := time.Unix(1700000000, 0)
+	used := time.Unix(1700003600, 0)
+	assert.Equal(t,
+		"**API tokens**\n"+
+			"**1** website, created by <@user1> <t:1700000000:R>, never used\n"+
+			"**2** bot, created by <@user2> <t:1700000000:R>, last used <t:1700003600:R>\n",
+		formatAPITokens([]api.Token{
+			{ID: 1, Name: "website", CreatedBy: "user1", CreatedAt: created},
+			{ID: 2, Name: "bot", CreatedBy: "user2", CreatedAt: created, LastUsedAt: &used},
+		}),
+	)
+}
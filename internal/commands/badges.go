@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/achievements"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// NewBadgesHandler creates a handler for the /badges command
+func NewBadgesHandler(engine *achievements.Engine, repo *achievements.Repository, settings stats.SettingsStore) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		guildID := i.GuildID
+
+		public := parsePublicOption(data.Options, guildSettings(ctx, settings, guildID).PublicResponses)
+		if err := deferResponse(ctx, s, i, public); err != nil {
+			return err
+		}
+
+		userID := interactionUserID(i)
+		for _, opt := range data.Options {
+			if opt.Name == "user" {
+				userID = opt.UserValue(nil).ID
+			}
+		}
+
+		// evaluate before displaying so that reactions given before achievements existed are counted
+		if _, err := engine.Evaluate(ctx, guildID, userID); err != nil {
+			slog.Error("failed to evaluate achievements", "error", err, "guild_id", guildID, "user_id", userID)
+		}
+
+		unlocked, err := repo.GetUnlocked(ctx, guildID, userID)
+		if err != nil {
+			slog.Error("failed to get achievements", "error", err, "guild_id", guildID, "user_id", userID)
+			return respondWithError(ctx, s, i, "Failed to retrieve badges.")
+		}
+
+		return respond(ctx, s, i, achievements.FormatBadges(userID, unlocked))
+	}
+}
+
+// NewBadgeAnnouncementsHandler creates a handler for the /badge-announcements command
+func NewBadgeAnnouncementsHandler(repo *achievements.Repository) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		guildID := i.GuildID
+		subcommand, options := parseSubcommand(data.Options)
+
+		var channelID string
+		if subcommand == "set" {
+			channelID = parseChannelOption(options)
+		}
+
+		if err := repo.SetAnnouncementChannel(ctx, guildID, channelID); err != nil {
+			slog.Error("failed to set achievement announcement channel", "error", err, "guild_id", guildID)
+			return respondWithError(ctx, s, i, "Failed to save badge announcements.")
+		}
+
+		if channelID == "" {
+			return respond(ctx, s, i, "Badge announcements disabled.")
+		}
+		return respond(ctx, s, i, "Badge unlocks will be announced in <#"+channelID+">.")
+	}
+}
+
+// interactionUserID returns the ID of the user who invoked an interaction
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
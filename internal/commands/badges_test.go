@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInteractionUserID(t *testing.T) {
+	guild := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Member: &discordgo.Member{User: &discordgo.User{ID: "111"}},
+	}}
+	dm := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		User: &discordgo.User{ID: "222"},
+	}}
+
+	assert.Equal(t, "111", interactionUserID(guild))
+	assert.Equal(t, "222", interactionUserID(dm))
+	assert.Equal(t, "", interactionUserID(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{}}))
+}
@@ -1,38 +1,97 @@
 package commands
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/achievements"
+	"github.com/elliotwms/emojistats/internal/api"
+	"github.com/elliotwms/emojistats/internal/digest"
+	"github.com/elliotwms/emojistats/internal/export"
+	"github.com/elliotwms/emojistats/internal/milestones"
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/retention"
+	"github.com/elliotwms/emojistats/internal/rolerewards"
+	"github.com/elliotwms/emojistats/internal/starboard"
 	"github.com/elliotwms/emojistats/internal/stats"
 )
 
 var (
+	manageGuildPermission int64 = discordgo.PermissionManageGuild
+	manageRolesPermission int64 = discordgo.PermissionManageRoles
+	minThreshold                = 1.0
+	minYear                     = 2015.0
+	minRetentionDays            = float64(retention.MinDays)
+	minLeaderboardSize          = float64(stats.MinLeaderboardSize)
+
 	publicOption = &discordgo.ApplicationCommandOption{
 		Type:        discordgo.ApplicationCommandOptionBoolean,
 		Name:        "public",
-		Description: "Make the response visible to everyone (default: private)",
+		Description: "Make the response visible to everyone (default: the server's /config setting, or private)",
+		Required:    false,
+	}
+
+	startDateOption = &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "start_date",
+		Description: "Start date (YYYY-MM-DD format)",
 		Required:    false,
 	}
 
+	endDateOption = &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "end_date",
+		Description: "End date (YYYY-MM-DD format)",
+		Required:    false,
+	}
+
+	digestKindOption = &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "kind",
+		Description: "The digest period",
+		Required:    true,
+		Choices: []*discordgo.ApplicationCommandOptionChoice{
+			{Name: "Weekly", Value: string(digest.KindWeekly)},
+			{Name: "Monthly", Value: string(digest.KindMonthly)},
+		},
+	}
+
+	ignoreChannelOption = &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionChannel,
+		Name:        "channel",
+		Description: "A channel, including its threads",
+		ChannelTypes: []discordgo.ChannelType{
+			discordgo.ChannelTypeGuildText,
+			discordgo.ChannelTypeGuildNews,
+			discordgo.ChannelTypeGuildForum,
+			discordgo.ChannelTypeGuildVoice,
+			discordgo.ChannelTypeGuildStageVoice,
+		},
+	}
+
+	ignoreCategoryOption = &discordgo.ApplicationCommandOption{
+		Type:         discordgo.ApplicationCommandOptionChannel,
+		Name:         "category",
+		Description:  "A category, including its channels and their threads",
+		ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildCategory},
+	}
+
+	ignoreRoleOption = &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionRole,
+		Name:        "role",
+		Description: "A role, whose members' reactions are not tracked from now on",
+	}
+
 	statsCommand = &discordgo.ApplicationCommand{
 		Type:        discordgo.ChatApplicationCommand,
 		Name:        "stats",
 		Description: "View reaction statistics for this server",
 		Options: []*discordgo.ApplicationCommandOption{
-			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "start_date",
-				Description: "Start date (YYYY-MM-DD format)",
-				Required:    false,
-			},
-			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "end_date",
-				Description: "End date (YYYY-MM-DD format)",
-				Required:    false,
-			},
+			startDateOption,
+			endDateOption,
 			publicOption,
 		},
 	}
@@ -48,29 +107,633 @@ var (
 				Description: "The emoji to analyze",
 				Required:    true,
 			},
+			startDateOption,
+			endDateOption,
+			publicOption,
+		},
+	}
+
+	hallOfFameCommand = &discordgo.ApplicationCommand{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "hall-of-fame",
+		Description: "View the most reacted messages in this server",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "channel",
+				Description:  "Only include messages from this channel",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+				Required:     false,
+			},
+			startDateOption,
+			endDateOption,
+			publicOption,
+		},
+	}
+
+	wrappedCommand = &discordgo.ApplicationCommand{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "wrapped",
+		Description: "View a year in review recap for this server or a user",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "year",
+				Description: "The year to recap (default: this year)",
+				MinValue:    &minYear,
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "The user to recap (default: the whole server)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "card",
+				Description: "Attach an image card of the recap",
+				Required:    false,
+			},
+			publicOption,
+		},
+	}
+
+	badgesCommand = &discordgo.ApplicationCommand{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "badges",
+		Description: "View the badges a user has unlocked",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "The user to view (default: you)",
+				Required:    false,
+			},
+			publicOption,
+		},
+	}
+
+	privacyCommand = &discordgo.ApplicationCommand{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "privacy",
+		Description: "Control whether your reactions are tracked",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "opt-out",
+				Description: "Stop storing your reactions and show you as anonymous in stats",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "opt-in",
+				Description: "Resume storing your reactions",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "forget-me",
+				Description: "Permanently delete your reactions in every server and opt out",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "confirm",
+						Description: "Confirm that your data should be permanently deleted",
+						Required:    true,
+					},
+				},
+			},
+		},
+	}
+
+	exportCommand = &discordgo.ApplicationCommand{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "export",
+		Description:              "Export this server's reactions",
+		DefaultMemberPermissions: &manageGuildPermission,
+		Options: []*discordgo.ApplicationCommandOption{
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "start_date",
-				Description: "Start date (YYYY-MM-DD format)",
+				Name:        "format",
+				Description: "The file format (default: CSV)",
 				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "CSV", Value: string(export.FormatCSV)},
+					{Name: "JSON Lines", Value: string(export.FormatJSONL)},
+					{Name: "Parquet", Value: string(export.FormatParquet)},
+				},
+			},
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "channel",
+				Description:  "Only export reactions from this channel",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+				Required:     false,
 			},
+			startDateOption,
+			endDateOption,
+		},
+	}
+
+	myDataCommand = &discordgo.ApplicationCommand{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "my-data",
+		Description: "Download every reaction stored for you as JSON and CSV",
+	}
+
+	streaksCommand = &discordgo.ApplicationCommand{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        "streaks",
+		Description: "View the longest runs of days reacting in this server",
+		Options: []*discordgo.ApplicationCommandOption{
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "end_date",
-				Description: "End date (YYYY-MM-DD format)",
+				Name:        "sort",
+				Description: "Rank by current or longest streak (default: current)",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "Current", Value: "current"},
+					{Name: "Longest", Value: "longest"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "View a single user's streaks instead of the leaderboard",
 				Required:    false,
 			},
 			publicOption,
 		},
 	}
+
+	configCommand = &discordgo.ApplicationCommand{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "config",
+		Description:              "Configure the bot for this server",
+		DefaultMemberPermissions: &manageGuildPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "view",
+				Description: "View this server's settings",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Change one or more settings",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "public",
+						Description: "Make stats responses visible to everyone unless a command's public option says otherwise",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "timezone",
+						Description: "An IANA timezone name used for days and dates, e.g. Europe/London",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "leaderboard_size",
+						Description: "How many entries the stats leaderboards show",
+						MinValue:    &minLeaderboardSize,
+						MaxValue:    stats.MaxLeaderboardSize,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "count_bots",
+						Description: "Track reactions made by bots",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reset",
+				Description: "Reset settings to their defaults",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "setting",
+						Description: "The setting to reset (default: all)",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "All", Value: "all"},
+							{Name: "Public responses", Value: "public"},
+							{Name: "Timezone", Value: "timezone"},
+							{Name: "Leaderboard size", Value: "leaderboard_size"},
+							{Name: "Count bots", Value: "count_bots"},
+							{Name: "Ignored channels, categories and roles", Value: "ignored"},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Name:        "ignore",
+				Description: "Manage the channels, categories and roles whose reactions are not tracked",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "add",
+						Description: "Stop tracking reactions in a channel or category, or by members with a role",
+						Options:     []*discordgo.ApplicationCommandOption{ignoreChannelOption, ignoreCategoryOption, ignoreRoleOption},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "remove",
+						Description: "Resume tracking reactions in a channel or category, or by members with a role",
+						Options:     []*discordgo.ApplicationCommandOption{ignoreChannelOption, ignoreCategoryOption, ignoreRoleOption},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "list",
+						Description: "List the ignored channels, categories and roles",
+					},
+				},
+			},
+		},
+	}
+
+	retentionCommand = &discordgo.ApplicationCommand{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "retention",
+		Description:              "Configure how long this server's reactions are kept",
+		DefaultMemberPermissions: &manageGuildPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "view",
+				Description: "View the retention policy",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Prune reactions older than a number of days",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "days",
+						Description: "The number of days to keep reactions for",
+						MinValue:    &minRetentionDays,
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "rollup",
+						Description: "Keep daily totals of pruned reactions so all-time stats stay correct (default: true)",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "disable",
+				Description: "Keep reactions forever",
+			},
+		},
+	}
+
+	apiTokenCommand = &discordgo.ApplicationCommand{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "api-token",
+		Description:              "Manage tokens for the stats API",
+		DefaultMemberPermissions: &manageGuildPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "create",
+				Description: "Create a token for reading this server's stats",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "What the token is for, e.g. the community website",
+						MaxLength:   100,
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List this server's tokens",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "revoke",
+				Description: "Revoke a token",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "The ID of the token, from /api-token list",
+						Required:    true,
+					},
+				},
+			},
+		},
+	}
+
+	badgeAnnouncementsCommand = &discordgo.ApplicationCommand{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "badge-announcements",
+		Description:              "Configure announcements when badges are unlocked",
+		DefaultMemberPermissions: &manageGuildPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Announce badge unlocks in a channel",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "The channel to announce badge unlocks in",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+						Required:     true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "disable",
+				Description: "Disable badge announcements",
+			},
+		},
+	}
+
+	starboardCommand = &discordgo.ApplicationCommand{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "starboard",
+		Description:              "Configure the starboard for this server",
+		DefaultMemberPermissions: &manageGuildPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "view",
+				Description: "View the starboard configuration",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Enable or update the starboard",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "The channel to repost messages to",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+						Required:     true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "threshold",
+						Description: "The number of reactions required",
+						MinValue:    &minThreshold,
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "emoji",
+						Description: "Only count this emoji (default: any emoji)",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "disable",
+				Description: "Disable the starboard",
+			},
+		},
+	}
+
+	milestonesCommand = &discordgo.ApplicationCommand{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "milestones",
+		Description:              "Configure milestone announcements for this server",
+		DefaultMemberPermissions: &manageGuildPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "view",
+				Description: "View the milestone configuration",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Enable or update milestone announcements",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "The channel to announce milestones in",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+						Required:     true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "givers",
+						Description: "Reactions given by a user, comma separated (\"none\" to disable)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "receivers",
+						Description: "Reactions received by a user, comma separated (\"none\" to disable)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "emojis",
+						Description: "Uses of an emoji, comma separated (\"none\" to disable)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "messages",
+						Description: "Reactions on a message, comma separated (\"none\" to disable)",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "disable",
+				Description: "Disable milestone announcements",
+			},
+		},
+	}
+
+	roleRewardsCommand = &discordgo.ApplicationCommand{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "role-rewards",
+		Description:              "Configure roles rewarded for reaction activity",
+		DefaultMemberPermissions: &manageRolesPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the role rewards",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Add or replace the reward rule for a role",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "The role to reward",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "kind",
+						Description: "Reward the top members, or every member reaching a threshold",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Top members", Value: string(rolerewards.KindTop)},
+							{Name: "Threshold", Value: string(rolerewards.KindThreshold)},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "metric",
+						Description: "The reactions to count",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Reactions received", Value: string(rolerewards.MetricReceived)},
+							{Name: "Reactions given", Value: string(rolerewards.MetricGiven)},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "count",
+						Description: "The number of top members, or the number of reactions required",
+						MinValue:    &minThreshold,
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "days",
+						Description: "Only count reactions from the last number of days (default: all-time)",
+						MinValue:    &minThreshold,
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Remove the reward rule for a role",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "The rewarded role",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "audit",
+				Description: "View recent role changes made by role rewards",
+			},
+		},
+	}
+
+	digestCommand = &discordgo.ApplicationCommand{
+		Type:                     discordgo.ChatApplicationCommand,
+		Name:                     "digest",
+		Description:              "Configure scheduled reaction digests for this server",
+		DefaultMemberPermissions: &manageGuildPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "view",
+				Description: "View the configured digests",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Enable or update a digest",
+				Options: []*discordgo.ApplicationCommandOption{
+					digestKindOption,
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "The channel to post the digest to",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+						Required:     true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "schedule",
+						Description: "Cron schedule in the server's timezone (default: Mondays or the 1st of the month at 09:00)",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "disable",
+				Description: "Disable a digest",
+				Options: []*discordgo.ApplicationCommandOption{
+					digestKindOption,
+				},
+			},
+		},
+	}
 )
 
-// Commands returns the application commands and their handlers
-func Commands(db *sql.DB) map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler {
-	repo := stats.NewRepository(db)
+// Handled wraps each command's handler to discard ErrResponded, which the router would otherwise log as unhandled. It
+// should wrap any wrappers which count failures, such as metrics.Commands
+func Handled(commands map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler) map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler {
+	for c, h := range commands {
+		commands[c] = func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+			if err := h(ctx, s, i, data); !errors.Is(err, ErrResponded) {
+				return err
+			}
+			return nil
+		}
+	}
+	return commands
+}
+
+// Commands returns the application commands and their handlers. Stats are queried through store, which may be cached.
+// If db is nil only the commands which the store supports on its own are returned, as the other features require Postgres
+func Commands(db *sql.DB, store stats.Store) map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler {
+	commands := map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler{
+		statsCommand:      NewStatsHandler(store, store),
+		emojiStatsCommand: NewEmojiStatsHandler(store, store),
+		hallOfFameCommand: NewHallOfFameHandler(store, store),
+		wrappedCommand:    NewWrappedHandler(store, store),
+		streaksCommand:    NewStreaksHandler(store, store),
+		configCommand:     NewConfigHandler(store),
+		privacyCommand:    NewPrivacyHandler(store),
+	}
 
-	return map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler{
-		statsCommand:      NewStatsHandler(repo),
-		emojiStatsCommand: NewEmojiStatsHandler(repo),
+	// the remaining features are only supported by Postgres
+	if db == nil {
+		return commands
 	}
+
+	starboardRepo := starboard.NewRepository(db)
+	digestRepo := digest.NewRepository(db)
+	milestonesRepo := milestones.NewRepository(db)
+	achievementsRepo := achievements.NewRepository(db)
+	roleRewardsRepo := rolerewards.NewRepository(db)
+
+	commands[starboardCommand] = NewStarboardHandler(starboardRepo)
+	commands[digestCommand] = NewDigestHandler(digestRepo, store)
+	commands[milestonesCommand] = NewMilestonesHandler(milestonesRepo)
+	commands[badgesCommand] = NewBadgesHandler(achievements.NewEngine(achievementsRepo, milestonesRepo, store), achievementsRepo, store)
+	commands[badgeAnnouncementsCommand] = NewBadgeAnnouncementsHandler(achievementsRepo)
+	commands[roleRewardsCommand] = NewRoleRewardsHandler(roleRewardsRepo)
+	commands[myDataCommand] = NewMyDataHandler(privacy.NewRepository(db))
+	commands[exportCommand] = NewExportHandler(export.NewRepository(db), store)
+	commands[retentionCommand] = NewRetentionHandler(retention.NewRepository(db))
+	commands[apiTokenCommand] = NewAPITokenHandler(api.NewRepository(db))
+
+	return commands
 }
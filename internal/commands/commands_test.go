@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elliotwms/emojistats/internal/metrics"
+)
+
+func TestHandled(t *testing.T) {
+	responded := &discordgo.ApplicationCommand{Name: "test-responded"}
+	broken := &discordgo.ApplicationCommand{Name: "test-broken"}
+	errBroken := errors.New("broken")
+
+	cmds := Handled(metrics.Commands(map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler{
+		responded: func(context.Context, *discordgo.Session, *discordgo.InteractionCreate, discordgo.ApplicationCommandInteractionData) error {
+			return ErrResponded
+		},
+		broken: func(context.Context, *discordgo.Session, *discordgo.InteractionCreate, discordgo.ApplicationCommandInteractionData) error {
+			return errBroken
+		},
+	}))
+
+	assert.NoError(t, cmds[responded](context.Background(), nil, nil, discordgo.ApplicationCommandInteractionData{}))
+	assert.ErrorIs(t, cmds[broken](context.Background(), nil, nil, discordgo.ApplicationCommandInteractionData{}), errBroken)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.CommandInvocations.WithLabelValues("test-responded", "error")))
+	assert.Zero(t, testutil.ToFloat64(metrics.CommandInvocations.WithLabelValues("test-responded", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.CommandInvocations.WithLabelValues("test-broken", "error")))
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// ComponentHandler handles a message component interaction, such as a button press
+type ComponentHandler func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.MessageComponentInteractionData) error
+
+// Components returns the message component handlers keyed by the prefix of their custom IDs
+func Components(statsRepo stats.Querier) map[string]ComponentHandler {
+	return map[string]ComponentHandler{
+		wrappedComponentPrefix: NewWrappedComponentHandler(statsRepo),
+	}
+}
+
+// NewComponentRouter creates an InteractionCreate handler which dispatches message component interactions to the
+// handler registered for the prefix of the component's custom ID. Custom IDs are of the form "prefix:args..."
+func NewComponentRouter(handlers map[string]ComponentHandler) func(*discordgo.Session, *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		if i.Type != discordgo.InteractionMessageComponent {
+			return
+		}
+
+		data := i.MessageComponentData()
+		prefix, _, _ := strings.Cut(data.CustomID, ":")
+
+		h, ok := handlers[prefix]
+		if !ok {
+			slog.Error("handler not found for message component", "custom_id", data.CustomID)
+			return
+		}
+
+		if err := h(context.Background(), s, i, data); err != nil && !errors.Is(err, ErrResponded) {
+			slog.Error("failed to handle message component", "error", err, "custom_id", data.CustomID)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/eventhandlers"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// NewConfigHandler creates a handler for the /config command
+func NewConfigHandler(repo stats.Store) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		// the command is hidden from members without the permission, but servers can override who may use it
+		if !canManageGuild(i) {
+			return respondWithError(ctx, s, i, "You need the Manage Server permission to configure the bot.")
+		}
+
+		guildID := i.GuildID
+		group, subcommand, options := parseSubcommandGroup(data.Options)
+		changed := subcommand == "set" || subcommand == "reset"
+
+		settings, err := repo.GetSettings(ctx, guildID)
+		if err != nil {
+			slog.Error("failed to get guild settings", "error", err, "guild_id", guildID)
+			return respondWithError(ctx, s, i, "Failed to retrieve the settings.")
+		}
+
+		var loc *time.Location
+		switch {
+		case group == "ignore":
+			if subcommand == "list" {
+				return respond(ctx, s, i, "## Ignored\n\n"+formatIgnored(settings))
+			}
+
+			if problem := applyIgnore(&settings, options, subcommand == "add"); problem != "" {
+				return respondWithError(ctx, s, i, problem)
+			}
+			changed = true
+		case subcommand == "set":
+			if len(options) == 0 {
+				return respondWithError(ctx, s, i, "Choose at least one setting to change.")
+			}
+
+			var problem string
+			if loc, problem = applySettings(&settings, options); problem != "" {
+				return respondWithError(ctx, s, i, problem)
+			}
+		case subcommand == "reset":
+			setting := "all"
+			for _, opt := range options {
+				if opt.Name == "setting" {
+					setting = opt.StringValue()
+				}
+			}
+
+			if resetSetting(&settings, setting) {
+				loc = time.UTC
+			}
+		}
+
+		if changed {
+			if err := repo.SetSettings(ctx, guildID, settings); err != nil {
+				slog.Error("failed to save guild settings", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to save the settings.")
+			}
+
+			if group == "ignore" {
+				// failures are logged rather than returned as the settings are saved, and reactions record their channel's
+				// parents as they are made
+				if err := recordChannelParents(ctx, s.State, repo, guildID, settings); err != nil {
+					slog.Error("failed to record channel parents", "error", err, "guild_id", guildID)
+				}
+				return respond(ctx, s, i, "## Ignored\n\n"+formatIgnored(settings))
+			}
+
+			if loc != nil {
+				if err := repo.SetTimezone(ctx, guildID, loc); err != nil {
+					slog.Error("failed to set timezone", "error", err, "guild_id", guildID)
+					return respondWithError(ctx, s, i, "Failed to save the timezone.")
+				}
+			}
+		}
+
+		if loc == nil {
+			if loc, err = repo.GetTimezone(ctx, guildID); err != nil {
+				slog.Error("failed to get timezone", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to retrieve the timezone.")
+			}
+		}
+
+		return respond(ctx, s, i, formatSettings(settings, loc))
+	}
+}
+
+// parseTimezone parses an IANA timezone name. Unlike time.LoadLocation it rejects the empty name and "Local"
+func parseTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %s", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// canManageGuild returns whether the member who invoked an interaction has the Manage Server permission
+func canManageGuild(i *discordgo.InteractionCreate) bool {
+	return i.Member != nil && i.Member.Permissions&discordgo.PermissionManageGuild != 0
+}
+
+// applySettings applies the options of /config set to a guild's settings. The timezone is returned separately, as it
+// is stored separately, and is nil if it is not being changed. If an option is invalid the problem is returned, to be
+// shown to the user
+func applySettings(settings *stats.Settings, options []*discordgo.ApplicationCommandInteractionDataOption) (loc *time.Location, problem string) {
+	for _, opt := range options {
+		switch opt.Name {
+		case "public":
+			settings.PublicResponses = opt.BoolValue()
+		case "leaderboard_size":
+			size := int(opt.IntValue())
+			if size < stats.MinLeaderboardSize || size > stats.MaxLeaderboardSize {
+				return nil, fmt.Sprintf("The leaderboard size must be between %d and %d.", stats.MinLeaderboardSize, stats.MaxLeaderboardSize)
+			}
+			settings.LeaderboardSize = size
+		case "count_bots":
+			settings.CountBots = opt.BoolValue()
+		case "timezone":
+			var err error
+			if loc, err = parseTimezone(opt.StringValue()); err != nil {
+				return nil, fmt.Sprintf("Unknown timezone %q. Use a name such as `Europe/London`.", opt.StringValue())
+			}
+		}
+	}
+
+	return loc, ""
+}
+
+// applyIgnore applies the options of /config ignore add, or of /config ignore remove if ignore is false, to a guild's
+// settings. If no options are given the problem is returned, to be shown to the user
+func applyIgnore(settings *stats.Settings, options []*discordgo.ApplicationCommandInteractionDataOption, ignore bool) (problem string) {
+	if len(options) == 0 {
+		return "Choose a channel, category or role."
+	}
+
+	for _, opt := range options {
+		switch opt.Name {
+		case "channel":
+			settings.IgnoredChannels = setID(settings.IgnoredChannels, opt.ChannelValue(nil).ID, ignore)
+		case "category":
+			settings.IgnoredCategories = setID(settings.IgnoredCategories, opt.ChannelValue(nil).ID, ignore)
+		case "role":
+			settings.IgnoredRoles = setID(settings.IgnoredRoles, opt.RoleValue(nil, "").ID, ignore)
+		}
+	}
+
+	return ""
+}
+
+// setID adds an ID to a list if it is not already present, or removes it if present is false
+func setID(ids []string, id string, present bool) []string {
+	if !present {
+		return slices.DeleteFunc(ids, func(c string) bool { return c == id })
+	}
+	if slices.Contains(ids, id) {
+		return ids
+	}
+	return append(ids, id)
+}
+
+// recordChannelParents records the parents of the guild's channels and threads in the state which are ignored because
+// of a parent, so that the stats exclude their earlier reactions. Archived threads are not in the state, so are
+// recorded when they are next reacted in
+func recordChannelParents(ctx context.Context, state *discordgo.State, repo stats.SettingsStore, guildID string, settings stats.Settings) error {
+	if state == nil {
+		return nil
+	}
+
+	guild, err := state.Guild(guildID)
+	if err != nil {
+		return nil
+	}
+
+	state.RLock()
+	var channelIDs []string
+	for _, c := range slices.Concat(guild.Channels, guild.Threads) {
+		channelIDs = append(channelIDs, c.ID)
+	}
+	state.RUnlock()
+
+	for _, id := range channelIDs {
+		parents := eventhandlers.ChannelParents(state, id)
+		if ignoredID, ignored := settings.IgnoringChannel(id, parents...); ignored && ignoredID != id {
+			if err := eventhandlers.RecordChannelParents(ctx, repo, guildID, id, ignoredID, parents); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseSubcommandGroup returns the subcommand group and subcommand of an interaction, and the subcommand's options.
+// The group is empty if the subcommand is not in one
+func parseSubcommandGroup(options []*discordgo.ApplicationCommandInteractionDataOption) (group, subcommand string, _ []*discordgo.ApplicationCommandInteractionDataOption) {
+	for _, opt := range options {
+		if opt.Type == discordgo.ApplicationCommandOptionSubCommandGroup {
+			subcommand, options := parseSubcommand(opt.Options)
+			return opt.Name, subcommand, options
+		}
+	}
+
+	subcommand, options = parseSubcommand(options)
+	return "", subcommand, options
+}
+
+// resetSetting resets one of a guild's settings, or all of them, to the default. It returns whether the timezone should
+// be reset, as it is stored separately
+func resetSetting(settings *stats.Settings, setting string) (resetTimezone bool) {
+	defaults := stats.DefaultSettings()
+
+	switch setting {
+	case "public":
+		settings.PublicResponses = defaults.PublicResponses
+	case "timezone":
+		return true
+	case "leaderboard_size":
+		settings.LeaderboardSize = defaults.LeaderboardSize
+	case "count_bots":
+		settings.CountBots = defaults.CountBots
+	case "ignored":
+		settings.IgnoredChannels = defaults.IgnoredChannels
+		settings.IgnoredCategories = defaults.IgnoredCategories
+		settings.IgnoredRoles = defaults.IgnoredRoles
+	default:
+		*settings = defaults
+		return true
+	}
+
+	return false
+}
+
+func formatSettings(settings stats.Settings, loc *time.Location) string {
+	var sb strings.Builder
+
+	sb.WriteString("## Settings\n\n")
+
+	responses := "private"
+	if settings.PublicResponses {
+		responses = "public"
+	}
+	sb.WriteString("**Stats responses:** " + responses + " by default\n")
+	sb.WriteString("**Timezone:** " + loc.String() + "\n")
+	sb.WriteString("**Leaderboard size:** " + strconv.Itoa(settings.LeaderboardSize) + "\n")
+
+	bots := "counted"
+	if !settings.CountBots {
+		bots = "ignored"
+	}
+	sb.WriteString("**Reactions by bots:** " + bots + "\n")
+
+	sb.WriteString(formatIgnored(settings))
+
+	return sb.String()
+}
+
+// formatIgnored lists a guild's ignored channels, categories and roles as mentions
+func formatIgnored(settings stats.Settings) string {
+	return "**Ignored channels:** " + formatMentions("<#", settings.IgnoredChannels) + "\n" +
+		"**Ignored categories:** " + formatMentions("<#", settings.IgnoredCategories) + "\n" +
+		"**Ignored roles:** " + formatMentions("<@&", settings.IgnoredRoles) + "\n"
+}
+
+func formatMentions(prefix string, ids []string) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+
+	mentions := make([]string, len(ids))
+	for i, id := range ids {
+		mentions[i] = prefix + id + ">"
+	}
+	return strings.Join(mentions, ", ")
+}
@@ -0,0 +1,208 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySettings(t *testing.T) {
+	settings := stats.DefaultSettings()
+	settings.IgnoredChannels = []string{"chan1", "chan2"}
+
+	loc, problem := applySettings(&settings, []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "public", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+		{Name: "leaderboard_size", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(5)},
+		{Name: "count_bots", Type: discordgo.ApplicationCommandOptionBoolean, Value: false},
+		{Name: "timezone", Type: discordgo.ApplicationCommandOptionString, Value: "Europe/London"},
+	})
+
+	require.Empty(t, problem)
+	require.NotNil(t, loc)
+	assert.Equal(t, "Europe/London", loc.String())
+	assert.Equal(t, stats.Settings{
+		PublicResponses: true,
+		LeaderboardSize: 5,
+		CountBots:       false,
+		IgnoredChannels: []string{"chan1", "chan2"},
+	}, settings, "the ignored channels are kept")
+}
+
+func TestApplySettings_TimezoneUnchanged(t *testing.T) {
+	settings := stats.DefaultSettings()
+
+	loc, problem := applySettings(&settings, []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "public", Type: discordgo.ApplicationCommandOptionBoolean, Value: true},
+	})
+
+	assert.Empty(t, problem)
+	assert.Nil(t, loc, "the timezone is not changed")
+}
+
+func TestApplyIgnore(t *testing.T) {
+	settings := stats.DefaultSettings()
+	settings.IgnoredChannels = []string{"chan1"}
+
+	problem := applyIgnore(&settings, []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "chan2"},
+		{Name: "category", Type: discordgo.ApplicationCommandOptionChannel, Value: "cat1"},
+		{Name: "role", Type: discordgo.ApplicationCommandOptionRole, Value: "role1"},
+	}, true)
+
+	require.Empty(t, problem)
+	assert.Equal(t, []string{"chan1", "chan2"}, settings.IgnoredChannels)
+	assert.Equal(t, []string{"cat1"}, settings.IgnoredCategories)
+	assert.Equal(t, []string{"role1"}, settings.IgnoredRoles)
+
+	problem = applyIgnore(&settings, []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "chan1"},
+		{Name: "role", Type: discordgo.ApplicationCommandOptionRole, Value: "role1"},
+	}, false)
+
+	require.Empty(t, problem)
+	assert.Equal(t, []string{"chan2"}, settings.IgnoredChannels)
+	assert.Equal(t, []string{"cat1"}, settings.IgnoredCategories, "other lists are kept")
+	assert.Empty(t, settings.IgnoredRoles)
+}
+
+func TestApplyIgnore_Twice(t *testing.T) {
+	settings := stats.DefaultSettings()
+	settings.IgnoredCategories = []string{"cat1"}
+
+	problem := applyIgnore(&settings, []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "category", Type: discordgo.ApplicationCommandOptionChannel, Value: "cat1"},
+	}, true)
+
+	assert.Empty(t, problem)
+	assert.Equal(t, []string{"cat1"}, settings.IgnoredCategories)
+}
+
+func TestApplyIgnore_NoOptions(t *testing.T) {
+	settings := stats.DefaultSettings()
+
+	assert.Equal(t, "Choose a channel, category or role.", applyIgnore(&settings, nil, true))
+}
+
+func TestParseSubcommandGroup(t *testing.T) {
+	channel := &discordgo.ApplicationCommandInteractionDataOption{Name: "channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "chan1"}
+
+	group, subcommand, options := parseSubcommandGroup([]*discordgo.ApplicationCommandInteractionDataOption{{
+		Name: "ignore",
+		Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+			Name:    "add",
+			Type:    discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{channel},
+		}},
+	}})
+	assert.Equal(t, "ignore", group)
+	assert.Equal(t, "add", subcommand)
+	assert.Equal(t, []*discordgo.ApplicationCommandInteractionDataOption{channel}, options)
+
+	group, subcommand, _ = parseSubcommandGroup([]*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "view", Type: discordgo.ApplicationCommandOptionSubCommand},
+	})
+	assert.Empty(t, group)
+	assert.Equal(t, "view", subcommand)
+}
+
+func TestApplySettings_Invalid(t *testing.T) {
+	settings := stats.DefaultSettings()
+
+	_, problem := applySettings(&settings, []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "timezone", Type: discordgo.ApplicationCommandOptionString, Value: "Mars/Olympus_Mons"},
+	})
+	assert.Contains(t, problem, "Unknown timezone")
+
+	_, problem = applySettings(&settings, []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "leaderboard_size", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(100)},
+	})
+	assert.Equal(t, "The leaderboard size must be between 1 and 15.", problem)
+}
+
+func TestResetSetting(t *testing.T) {
+	configured := stats.Settings{
+		PublicResponses:   true,
+		LeaderboardSize:   5,
+		CountBots:         false,
+		IgnoredChannels:   []string{"chan1"},
+		IgnoredCategories: []string{"cat1"},
+		IgnoredRoles:      []string{"role1"},
+	}
+
+	settings := configured
+	assert.False(t, resetSetting(&settings, "leaderboard_size"))
+	assert.Equal(t, stats.DefaultLeaderboardSize, settings.LeaderboardSize)
+	assert.True(t, settings.PublicResponses, "other settings are kept")
+
+	settings = configured
+	assert.False(t, resetSetting(&settings, "ignored"))
+	assert.Empty(t, settings.IgnoredChannels)
+	assert.Empty(t, settings.IgnoredCategories)
+	assert.Empty(t, settings.IgnoredRoles)
+	assert.Equal(t, 5, settings.LeaderboardSize, "other settings are kept")
+
+	settings = configured
+	assert.True(t, resetSetting(&settings, "timezone"))
+	assert.Equal(t, configured, settings)
+
+	settings = configured
+	assert.True(t, resetSetting(&settings, "all"))
+	assert.Equal(t, stats.DefaultSettings(), settings)
+}
+
+func TestFormatSettings(t *testing.T) {
+	assert.Equal(t, "## Settings\n\n"+
+		"**Stats responses:** private by default\n"+
+		"**Timezone:** UTC\n"+
+		"**Leaderboard size:** 10\n"+
+		"**Reactions by bots:** counted\n"+
+		"**Ignored channels:** none\n"+
+		"**Ignored categories:** none\n"+
+		"**Ignored roles:** none\n",
+		formatSettings(stats.DefaultSettings(), time.UTC))
+
+	result := formatSettings(stats.Settings{
+		PublicResponses:   true,
+		LeaderboardSize:   5,
+		IgnoredChannels:   []string{"chan1", "chan2"},
+		IgnoredCategories: []string{"cat1"},
+		IgnoredRoles:      []string{"role1"},
+	}, time.UTC)
+	assert.Contains(t, result, "**Stats responses:** public by default")
+	assert.Contains(t, result, "**Reactions by bots:** ignored")
+	assert.Contains(t, result, "**Ignored channels:** <#chan1>, <#chan2>")
+	assert.Contains(t, result, "**Ignored categories:** <#cat1>")
+	assert.Contains(t, result, "**Ignored roles:** <@&role1>")
+}
+
+func TestCanManageGuild(t *testing.T) {
+	member := func(permissions int64) *discordgo.InteractionCreate {
+		return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{Member: &discordgo.Member{Permissions: permissions}}}
+	}
+
+	assert.True(t, canManageGuild(member(discordgo.PermissionManageGuild|discordgo.PermissionSendMessages)))
+	assert.False(t, canManageGuild(member(discordgo.PermissionSendMessages)))
+	assert.False(t, canManageGuild(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{}}), "direct messages have no member")
+}
+
+func TestParseTimezone(t *testing.T) {
+	loc, err := parseTimezone("Europe/London")
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/London", loc.String())
+
+	loc, err = parseTimezone("UTC")
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", loc.String())
+}
+
+func TestParseTimezone_Invalid(t *testing.T) {
+	for _, name := range []string{"", "Local", "Mars/Olympus_Mons"} {
+		_, err := parseTimezone(name)
+		assert.Error(t, err, name)
+	}
+}
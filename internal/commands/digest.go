@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/digest"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// NewDigestHandler creates a handler for the /digest command
+func NewDigestHandler(repo *digest.Repository, settings stats.SettingsStore) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		guildID := i.GuildID
+		subcommand, options := parseSubcommand(data.Options)
+
+		switch subcommand {
+		case "set":
+			c := digest.Config{GuildID: guildID}
+			for _, opt := range options {
+				switch opt.Name {
+				case "kind":
+					c.Kind = digest.Kind(opt.StringValue())
+				case "channel":
+					c.ChannelID = opt.ChannelValue(nil).ID
+				case "schedule":
+					c.Schedule = opt.StringValue()
+				}
+			}
+
+			if c.Schedule == "" {
+				c.Schedule = c.Kind.DefaultSchedule()
+			}
+
+			if _, err := digest.ParseSchedule(c.Schedule); err != nil {
+				return respondWithError(ctx, s, i, "Invalid schedule. Please use a cron expression such as `0 9 * * 1`.")
+			}
+
+			if err := repo.SaveConfig(ctx, c); err != nil {
+				slog.Error("failed to save digest", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to save the digest.")
+			}
+
+			return respond(ctx, s, i, formatDigestConfigs([]digest.Config{c}, guildTimezone(ctx, settings, guildID)))
+		case "disable":
+			kind := digest.KindWeekly
+			for _, opt := range options {
+				if opt.Name == "kind" {
+					kind = digest.Kind(opt.StringValue())
+				}
+			}
+
+			if err := repo.DeleteConfig(ctx, guildID, kind); err != nil {
+				slog.Error("failed to delete digest", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to disable the digest.")
+			}
+
+			return respond(ctx, s, i, fmt.Sprintf("The %s digest has been disabled.", kind))
+		default:
+			configs, err := repo.GetConfigs(ctx, guildID)
+			if err != nil {
+				slog.Error("failed to get digests", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to retrieve digests.")
+			}
+
+			return respond(ctx, s, i, formatDigestConfigs(configs, guildTimezone(ctx, settings, guildID)))
+		}
+	}
+}
+
+func formatDigestConfigs(configs []digest.Config, loc *time.Location) string {
+	if len(configs) == 0 {
+		return "No digests are configured. Use `/digest set` to add one."
+	}
+
+	var sb strings.Builder
+	for _, c := range configs {
+		sb.WriteString(fmt.Sprintf("The %s digest is posted to <#%s> on the schedule `%s` (%s).\n", c.Kind, c.ChannelID, c.Schedule, loc))
+	}
+	return sb.String()
+}
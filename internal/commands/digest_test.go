@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatDigestConfigs(t *testing.T) {
+	configs := []digest.Config{
+		{Kind: digest.KindMonthly, ChannelID: "chan1", Schedule: "0 9 1 * *"},
+		{Kind: digest.KindWeekly, ChannelID: "chan2", Schedule: "0 9 * * 1"},
+	}
+
+	loc, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	result := formatDigestConfigs(configs, loc)
+
+	assert.Contains(t, result, "The monthly digest is posted to <#chan1> on the schedule `0 9 1 * *` (Europe/London).")
+	assert.Contains(t, result, "The weekly digest is posted to <#chan2> on the schedule `0 9 * * 1` (Europe/London).")
+}
+
+func TestFormatDigestConfigs_Empty(t *testing.T) {
+	assert.Contains(t, formatDigestConfigs(nil, time.UTC), "No digests are configured.")
+}
@@ -10,15 +10,16 @@ import (
 )
 
 // NewEmojiStatsHandler creates a handler for the /emoji-stats command
-func NewEmojiStatsHandler(repo *stats.Repository) router.ApplicationCommandHandler {
+func NewEmojiStatsHandler(repo stats.Querier, settings stats.SettingsStore) router.ApplicationCommandHandler {
 	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
-		public := parsePublicOption(data.Options)
-		if err := deferResponse(s, i, public); err != nil {
+		guildID := i.GuildID
+		gs := guildSettings(ctx, settings, guildID)
+
+		public := parsePublicOption(data.Options, gs.PublicResponses)
+		if err := deferResponse(ctx, s, i, public); err != nil {
 			return err
 		}
 
-		guildID := i.GuildID
-
 		var emojiID string
 		for _, opt := range data.Options {
 			if opt.Name == "emoji" {
@@ -28,25 +29,25 @@ func NewEmojiStatsHandler(repo *stats.Repository) router.ApplicationCommandHandl
 		}
 
 		if emojiID == "" {
-			return respondWithError(s, i, "Please provide an emoji.")
+			return respondWithError(ctx, s, i, "Please provide an emoji.")
 		}
 
-		dateRange, err := parseDateRange(data.Options)
+		dateRange, err := parseDateRange(data.Options, guildTimezone(ctx, settings, guildID))
 		if err != nil {
-			return respondWithError(s, i, "Invalid date format. Please use YYYY-MM-DD.")
+			return respondWithError(ctx, s, i, "Invalid date format. Please use YYYY-MM-DD.")
 		}
 
-		emojiStats, err := repo.GetEmojiStats(ctx, guildID, emojiID, dateRange)
+		emojiStats, err := repo.GetEmojiStats(ctx, guildID, emojiID, dateRange, gs.LeaderboardSize)
 		if err != nil {
 			slog.Error("failed to get emoji stats", "error", err, "guild_id", guildID, "emoji_id", emojiID)
-			return respondWithError(s, i, "Failed to retrieve emoji statistics.")
+			return respondWithError(ctx, s, i, "Failed to retrieve emoji statistics.")
 		}
 
 		if emojiStats.TotalUses == 0 {
-			return respondWithError(s, i, "No reactions found for this emoji.")
+			return respondWithError(ctx, s, i, "No reactions found for this emoji.")
 		}
 
 		content := stats.FormatEmojiStats(emojiStats, guildID)
-		return respond(s, i, content)
+		return respond(ctx, s, i, content)
 	}
 }
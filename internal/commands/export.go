@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/export"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// exportChunkSize leaves headroom below the attachment limit, as a file can grow past the chunk size by a row group
+const exportChunkSize = maxAttachmentSize * 8 / 10
+
+// NewExportHandler creates a handler for the /export command
+func NewExportHandler(repo *export.Repository, settings stats.SettingsStore) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		guildID := i.GuildID
+
+		dateRange, err := parseDateRange(data.Options, guildTimezone(ctx, settings, guildID))
+		if err != nil {
+			return respondWithError(ctx, s, i, "Invalid date format. Please use YYYY-MM-DD.")
+		}
+
+		format := export.FormatCSV
+		for _, opt := range data.Options {
+			if opt.Name == "format" {
+				format = export.Format(opt.StringValue())
+			}
+		}
+
+		filter := export.Filter{GuildID: guildID, DateRange: dateRange, ChannelID: parseChannelOption(data.Options)}
+
+		dir, err := os.MkdirTemp("", "export-*")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		name := func(part int) string {
+			return fmt.Sprintf("reactions-%s-%03d.%s", guildID, part, format.Extension())
+		}
+
+		result, err := export.Export(ctx, repo, filter, format, exportChunkSize, func(part int) (io.WriteCloser, error) {
+			return os.Create(filepath.Join(dir, name(part)))
+		})
+		if err != nil {
+			slog.Error("failed to export guild", "error", err, "guild_id", guildID)
+			return respondWithError(ctx, s, i, "Failed to export reactions.")
+		}
+
+		slog.Info("guild exported", "guild_id", guildID, "format", format, "rows", result.Rows, "files", result.Files)
+
+		for part := 1; part <= result.Files; part++ {
+			if err := sendExportFile(ctx, s, i, filepath.Join(dir, name(part)), format, part, result); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// sendExportFile attaches the first file to the deferred response and the rest to follow up messages, so that each
+// message stays within the attachment limit
+func sendExportFile(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, path string, format export.Format, part int, result export.Result) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	file := &discordgo.File{Name: filepath.Base(path), ContentType: format.ContentType(), Reader: f}
+	content := formatExportResult(result, part)
+
+	if part == 1 {
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+			Files:   []*discordgo.File{file},
+		}, discordgo.WithContext(ctx))
+		return err
+	}
+
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+		Files:   []*discordgo.File{file},
+		Flags:   discordgo.MessageFlagsEphemeral,
+	}, discordgo.WithContext(ctx))
+	return err
+}
+
+func formatExportResult(result export.Result, part int) string {
+	if part > 1 {
+		return fmt.Sprintf("Part %d of %d", part, result.Files)
+	}
+
+	content := fmt.Sprintf("Exported %d reactions.", result.Rows)
+	if result.Files > 1 {
+		content += fmt.Sprintf(" The export is split into %d files. Part 1 of %d", result.Files, result.Files)
+	}
+	return content
+}
@@ -0,0 +1,16 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/elliotwms/emojistats/internal/export"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatExportResult(t *testing.T) {
+	assert.Equal(t, "Exported 10 reactions.", formatExportResult(export.Result{Rows: 10, Files: 1}, 1))
+
+	result := export.Result{Rows: 100000, Files: 3}
+	assert.Equal(t, "Exported 100000 reactions. The export is split into 3 files. Part 1 of 3", formatExportResult(result, 1))
+	assert.Equal(t, "Part 2 of 3", formatExportResult(result, 2))
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// NewHallOfFameHandler creates a handler for the /hall-of-fame command
+func NewHallOfFameHandler(repo stats.Querier, settings stats.SettingsStore) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		guildID := i.GuildID
+		gs := guildSettings(ctx, settings, guildID)
+
+		public := parsePublicOption(data.Options, gs.PublicResponses)
+		if err := deferResponse(ctx, s, i, public); err != nil {
+			return err
+		}
+
+		dateRange, err := parseDateRange(data.Options, guildTimezone(ctx, settings, guildID))
+		if err != nil {
+			return respondWithError(ctx, s, i, "Invalid date format. Please use YYYY-MM-DD.")
+		}
+
+		channelID := parseChannelOption(data.Options)
+
+		messages, err := repo.GetTopMessages(ctx, guildID, channelID, dateRange, gs.LeaderboardSize)
+		if err != nil {
+			slog.Error("failed to get top messages", "error", err, "guild_id", guildID, "channel_id", channelID)
+			return respondWithError(ctx, s, i, "Failed to retrieve the hall of fame.")
+		}
+
+		content := stats.FormatHallOfFame(messages, guildID)
+		return respond(ctx, s, i, content)
+	}
+}
+
+func parseChannelOption(options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	for _, opt := range options {
+		if opt.Name == "channel" {
+			return opt.ChannelValue(nil).ID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChannelOption(t *testing.T) {
+	options := []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "start_date", Type: discordgo.ApplicationCommandOptionString, Value: "2024-01-15"},
+		{Name: "channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "123456789"},
+	}
+
+	assert.Equal(t, "123456789", parseChannelOption(options))
+}
+
+func TestParseChannelOption_Missing(t *testing.T) {
+	options := []*discordgo.ApplicationCommandInteractionDataOption{}
+
+	assert.Equal(t, "", parseChannelOption(options))
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/milestones"
+)
+
+// NewMilestonesHandler creates a handler for the /milestones command
+func NewMilestonesHandler(repo *milestones.Repository) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		guildID := i.GuildID
+		subcommand, options := parseSubcommand(data.Options)
+
+		switch subcommand {
+		case "set":
+			c := milestones.Config{
+				GuildID:  guildID,
+				Sender:   milestones.DefaultSenderThresholds,
+				Receiver: milestones.DefaultReceiverThresholds,
+				Emoji:    milestones.DefaultEmojiThresholds,
+				Message:  milestones.DefaultMessageThresholds,
+			}
+
+			for _, opt := range options {
+				if opt.Name == "channel" {
+					c.ChannelID = opt.ChannelValue(nil).ID
+					continue
+				}
+
+				var dst *milestones.Thresholds
+				switch opt.Name {
+				case "givers":
+					dst = &c.Sender
+				case "receivers":
+					dst = &c.Receiver
+				case "emojis":
+					dst = &c.Emoji
+				case "messages":
+					dst = &c.Message
+				default:
+					continue
+				}
+
+				t, err := milestones.ParseThresholds(opt.StringValue())
+				if err != nil {
+					return respondWithError(ctx, s, i, "Invalid thresholds. Please use a comma separated list of numbers, e.g. `100,500,1000`.")
+				}
+				*dst = t
+			}
+
+			if err := repo.SaveConfig(ctx, c); err != nil {
+				slog.Error("failed to save milestone config", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to save the milestone configuration.")
+			}
+
+			return respond(ctx, s, i, formatMilestoneConfig(&c))
+		case "disable":
+			if err := repo.DeleteConfig(ctx, guildID); err != nil {
+				slog.Error("failed to delete milestone config", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to disable milestones.")
+			}
+
+			return respond(ctx, s, i, "Milestone announcements disabled.")
+		default:
+			c, err := repo.GetConfig(ctx, guildID)
+			if err != nil {
+				slog.Error("failed to get milestone config", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to retrieve the milestone configuration.")
+			}
+
+			return respond(ctx, s, i, formatMilestoneConfig(c))
+		}
+	}
+}
+
+func formatMilestoneConfig(c *milestones.Config) string {
+	if c == nil {
+		return "Milestone announcements are not enabled. Use `/milestones set` to enable them."
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Milestones are announced in <#%s>.\n", c.ChannelID))
+	sb.WriteString(fmt.Sprintf("**Reactions given:** %s\n", formatThresholds(c.Sender)))
+	sb.WriteString(fmt.Sprintf("**Reactions received:** %s\n", formatThresholds(c.Receiver)))
+	sb.WriteString(fmt.Sprintf("**Emoji uses:** %s\n", formatThresholds(c.Emoji)))
+	sb.WriteString(fmt.Sprintf("**Message reactions:** %s\n", formatThresholds(c.Message)))
+	return sb.String()
+}
+
+func formatThresholds(t milestones.Thresholds) string {
+	if len(t) == 0 {
+		return "disabled"
+	}
+	return strings.ReplaceAll(t.String(), ",", ", ")
+}
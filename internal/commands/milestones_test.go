@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/elliotwms/emojistats/internal/milestones"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatMilestoneConfig(t *testing.T) {
+	c := &milestones.Config{
+		ChannelID: "chan1",
+		Sender:    milestones.Thresholds{100, 1000},
+		Emoji:     milestones.Thresholds{10000},
+	}
+
+	result := formatMilestoneConfig(c)
+
+	assert.Contains(t, result, "Milestones are announced in <#chan1>.")
+	assert.Contains(t, result, "**Reactions given:** 100, 1000")
+	assert.Contains(t, result, "**Reactions received:** disabled")
+	assert.Contains(t, result, "**Emoji uses:** 10000")
+}
+
+func TestFormatMilestoneConfig_NotEnabled(t *testing.T) {
+	assert.Contains(t, formatMilestoneConfig(nil), "not enabled")
+}
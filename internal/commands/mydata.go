@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/privacy"
+)
+
+// maxAttachmentSize is the largest file a bot can attach to a message without the guild being boosted
+const maxAttachmentSize = 10 * 1024 * 1024
+
+// NewMyDataHandler creates a handler for the /my-data command
+func NewMyDataHandler(repo *privacy.Repository) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		// always ephemeral, as the export contains the user's full history
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		userID := interactionUserID(i)
+
+		jsonFile, csvFile, count, err := exportMyData(ctx, repo, userID)
+		if jsonFile != nil {
+			defer removeTempFile(jsonFile)
+		}
+		if csvFile != nil {
+			defer removeTempFile(csvFile)
+		}
+		if err != nil {
+			slog.Error("failed to export user data", "error", err, "guild_id", i.GuildID)
+			return respondWithError(ctx, s, i, "Failed to export your data.")
+		}
+
+		if count == 0 {
+			return respond(ctx, s, i, "There are no reactions stored for you.")
+		}
+
+		for _, f := range []*os.File{jsonFile, csvFile} {
+			info, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			if info.Size() > maxAttachmentSize {
+				return respondWithError(ctx, s, i, "Your data is too large to attach. Please contact the bot's operator for a copy.")
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+
+		content := fmt.Sprintf("Here are the %d reactions stored for you, in JSON and CSV.", count)
+		_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+			Files: []*discordgo.File{
+				{Name: "my-data.json", ContentType: "application/json", Reader: jsonFile},
+				{Name: "my-data.csv", ContentType: "text/csv", Reader: csvFile},
+			},
+		}, discordgo.WithContext(ctx))
+		return err
+	}
+}
+
+// exportMyData streams the user's reactions into temporary JSON and CSV files. The caller must remove the files
+func exportMyData(ctx context.Context, repo *privacy.Repository, userID string) (jsonFile, csvFile *os.File, count int, err error) {
+	jsonFile, err = os.CreateTemp("", "my-data-*.json")
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	csvFile, err = os.CreateTemp("", "my-data-*.csv")
+	if err != nil {
+		return jsonFile, nil, 0, err
+	}
+
+	w, err := privacy.NewExportWriter(jsonFile, csvFile)
+	if err != nil {
+		return jsonFile, csvFile, 0, err
+	}
+
+	if err := repo.ExportReactions(ctx, userID, w.Write); err != nil {
+		return jsonFile, csvFile, 0, err
+	}
+
+	if err := w.Close(); err != nil {
+		return jsonFile, csvFile, 0, err
+	}
+
+	return jsonFile, csvFile, w.Count(), nil
+}
+
+func removeTempFile(f *os.File) {
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/privacy"
+)
+
+// NewPrivacyHandler creates a handler for the /privacy command
+func NewPrivacyHandler(repo privacy.Store) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		guildID := i.GuildID
+		userID := interactionUserID(i)
+		subcommand, options := parseSubcommand(data.Options)
+
+		switch subcommand {
+		case "opt-out":
+			if err := repo.OptOut(ctx, guildID, userID); err != nil {
+				slog.Error("failed to opt out", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to opt you out.")
+			}
+
+			slog.Info("user opted out", "guild_id", guildID)
+
+			return respond(ctx, s, i, "You have opted out. Your reactions will no longer be stored and you will be shown as anonymous in stats. "+
+				"Use `/privacy forget-me` to also remove the reactions stored before you opted out.")
+		case "opt-in":
+			if err := repo.OptIn(ctx, guildID, userID); err != nil {
+				slog.Error("failed to opt in", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to opt you in.")
+			}
+
+			slog.Info("user opted in", "guild_id", guildID)
+
+			return respond(ctx, s, i, "You have opted in. Your reactions will be stored from now on.")
+		case "forget-me":
+			var confirm bool
+			for _, opt := range options {
+				if opt.Name == "confirm" {
+					confirm = opt.BoolValue()
+				}
+			}
+
+			if !confirm {
+				return respond(ctx, s, i, "Nothing has been deleted. Set `confirm` to True to permanently delete your data.")
+			}
+
+			f, err := repo.Forget(ctx, guildID, userID)
+			if err != nil {
+				slog.Error("failed to forget user", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to delete your data.")
+			}
+
+			slog.Info("user forgotten", "guild_id", guildID, "deleted", f.Deleted, "anonymised", f.Anonymised, "reposts", len(f.Reposts))
+
+			deleteReposts(ctx, s, f.Reposts)
+
+			return respond(ctx, s, i, formatForgotten(f))
+		default:
+			return respondWithError(ctx, s, i, "Unknown subcommand.")
+		}
+	}
+}
+
+// deleteReposts deletes the starboard reposts of a forgotten user's messages. Failures are logged rather than returned,
+// as the user has already been forgotten
+func deleteReposts(ctx context.Context, s *discordgo.Session, reposts []privacy.Repost) {
+	for _, p := range reposts {
+		if err := s.ChannelMessageDelete(p.ChannelID, p.MessageID, discordgo.WithContext(ctx)); err != nil {
+			slog.Error("failed to delete starboard repost", "error", err, "channel_id", p.ChannelID, "message_id", p.MessageID)
+		}
+	}
+}
+
+func formatForgotten(f privacy.Forgotten) string {
+	return fmt.Sprintf("Your data has been deleted in every server. %d reactions you gave were deleted and %d reactions you received were anonymised. "+
+		"You have also been opted out so nothing new is stored; use `/privacy opt-in` to undo this.", f.Deleted, f.Anonymised)
+}
@@ -0,0 +1,16 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatForgotten(t *testing.T) {
+	result := formatForgotten(privacy.Forgotten{Deleted: 12, Anonymised: 30})
+
+	assert.Contains(t, result, "12 reactions you gave were deleted")
+	assert.Contains(t, result, "30 reactions you received were anonymised")
+	assert.Contains(t, result, "/privacy opt-in")
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/retention"
+)
+
+// NewRetentionHandler creates a handler for the /retention command
+func NewRetentionHandler(repo *retention.Repository) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		guildID := i.GuildID
+		subcommand, options := parseSubcommand(data.Options)
+
+		switch subcommand {
+		case "set":
+			p := retention.Policy{GuildID: guildID, Rollup: true}
+			for _, opt := range options {
+				switch opt.Name {
+				case "days":
+					p.Days = int(opt.IntValue())
+				case "rollup":
+					p.Rollup = opt.BoolValue()
+				}
+			}
+
+			if p.Days < retention.MinDays {
+				return respondWithError(ctx, s, i, fmt.Sprintf("Reactions must be kept for at least %d days.", retention.MinDays))
+			}
+
+			if err := repo.SavePolicy(ctx, p); err != nil {
+				slog.Error("failed to save retention policy", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to save the retention policy.")
+			}
+
+			return respond(ctx, s, i, formatRetentionPolicy(&p))
+		case "disable":
+			if err := repo.DeletePolicy(ctx, guildID); err != nil {
+				slog.Error("failed to delete retention policy", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to disable the retention policy.")
+			}
+
+			return respond(ctx, s, i, "Retention policy disabled. Reactions will be kept forever.")
+		default:
+			p, err := repo.GetPolicy(ctx, guildID)
+			if err != nil {
+				slog.Error("failed to get retention policy", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to retrieve the retention policy.")
+			}
+
+			return respond(ctx, s, i, formatRetentionPolicy(p))
+		}
+	}
+}
+
+func formatRetentionPolicy(p *retention.Policy) string {
+	if p == nil {
+		return "Reactions are kept forever. Use `/retention set` to prune old reactions."
+	}
+
+	if !p.Rollup {
+		return p.Describe()
+	}
+
+	// the daily totals have no message, and are by UTC day rather than the server's timezone
+	return p.Describe() + "\nStreaks, the hall of fame and message milestones only count the reactions which are kept."
+}
@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/elliotwms/emojistats/internal/retention"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatRetentionPolicy(t *testing.T) {
+	assert.Contains(t, formatRetentionPolicy(nil), "kept forever")
+	assert.Equal(t,
+		"Reactions are kept for **365** days, then rolled up into daily totals.\n"+
+			"Streaks, the hall of fame and message milestones only count the reactions which are kept.",
+		formatRetentionPolicy(&retention.Policy{Days: 365, Rollup: true}),
+	)
+	assert.Equal(t,
+		"Reactions are kept for **90** days, then deleted.",
+		formatRetentionPolicy(&retention.Policy{Days: 90}),
+	)
+}
@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/rolerewards"
+)
+
+const auditLogLimit = 20
+
+// NewRoleRewardsHandler creates a handler for the /role-rewards command
+func NewRoleRewardsHandler(repo *rolerewards.Repository) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		guildID := i.GuildID
+		subcommand, options := parseSubcommand(data.Options)
+
+		switch subcommand {
+		case "add":
+			rule := rolerewards.Rule{GuildID: guildID}
+			for _, opt := range options {
+				switch opt.Name {
+				case "role":
+					rule.RoleID = opt.RoleValue(nil, "").ID
+				case "kind":
+					rule.Kind = rolerewards.Kind(opt.StringValue())
+				case "metric":
+					rule.Metric = rolerewards.Metric(opt.StringValue())
+				case "count":
+					rule.Count = int(opt.IntValue())
+				case "days":
+					rule.WindowDays = int(opt.IntValue())
+				}
+			}
+
+			guild, bot, err := rewardRoleContext(ctx, s, guildID)
+			if err != nil {
+				slog.Error("failed to get guild roles", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to check the role.")
+			}
+
+			if problem := rewardRoleProblem(guild, i.Member, bot, rule.RoleID); problem != "" {
+				return respondWithError(ctx, s, i, problem)
+			}
+
+			if err := repo.SaveRule(ctx, rule); err != nil {
+				slog.Error("failed to save role reward", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to save the role reward.")
+			}
+
+			return respond(ctx, s, i, fmt.Sprintf("<@&%s> will be given to the %s. Roles are updated hourly.", rule.RoleID, rule.Describe()))
+		case "remove":
+			roleID := ""
+			for _, opt := range options {
+				if opt.Name == "role" {
+					roleID = opt.RoleValue(nil, "").ID
+				}
+			}
+
+			if err := repo.DeleteRule(ctx, guildID, roleID); err != nil {
+				slog.Error("failed to delete role reward", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to remove the role reward.")
+			}
+
+			return respond(ctx, s, i, fmt.Sprintf("<@&%s> is no longer a reward. Members who have it will keep it.", roleID))
+		case "audit":
+			entries, err := repo.GetAuditLog(ctx, guildID, auditLogLimit)
+			if err != nil {
+				slog.Error("failed to get role reward audit log", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to retrieve the audit log.")
+			}
+
+			return respond(ctx, s, i, formatAuditLog(entries))
+		default:
+			rules, err := repo.GetRules(ctx, guildID)
+			if err != nil {
+				slog.Error("failed to get role rewards", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to retrieve role rewards.")
+			}
+
+			return respond(ctx, s, i, formatRoleRewards(rules))
+		}
+	}
+}
+
+// rewardRoleContext retrieves the guild, for its roles and owner, and the bot's own member in it
+func rewardRoleContext(ctx context.Context, s *discordgo.Session, guildID string) (*discordgo.Guild, *discordgo.Member, error) {
+	guild, err := s.State.Guild(guildID)
+	if err != nil {
+		if guild, err = s.Guild(guildID, discordgo.WithContext(ctx)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	bot, err := s.GuildMember(guildID, s.State.User.ID, discordgo.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return guild, bot, nil
+}
+
+// rewardRoleProblem returns why a role cannot be rewarded, or an empty string if it can. Roles must be below both the
+// invoker's and the bot's highest roles, so that members cannot use role rewards to grant roles they could not grant
+// themselves. The guild owner may reward any role the bot can grant
+func rewardRoleProblem(guild *discordgo.Guild, invoker, bot *discordgo.Member, roleID string) string {
+	if roleID == guild.ID {
+		return "@everyone cannot be rewarded."
+	}
+
+	role := findRole(guild.Roles, roleID)
+	if role == nil {
+		return "That role no longer exists."
+	}
+
+	if role.Managed {
+		return fmt.Sprintf("<@&%s> is managed by an integration and cannot be rewarded.", roleID)
+	}
+
+	if invoker == nil {
+		return "Role rewards can only be added in a server."
+	}
+
+	owner := invoker.User != nil && invoker.User.ID == guild.OwnerID
+	if !owner && role.Position >= highestRolePosition(guild.Roles, invoker) {
+		return fmt.Sprintf("<@&%s> must be below your highest role to be rewarded.", roleID)
+	}
+
+	if role.Position >= highestRolePosition(guild.Roles, bot) {
+		return fmt.Sprintf("<@&%s> must be below the bot's highest role to be rewarded.", roleID)
+	}
+
+	return ""
+}
+
+// highestRolePosition returns the position of the member's highest role, which is 0, the position of @everyone, if
+// they have no roles
+func highestRolePosition(roles []*discordgo.Role, m *discordgo.Member) int {
+	highest := 0
+	for _, roleID := range m.Roles {
+		if role := findRole(roles, roleID); role != nil && role.Position > highest {
+			highest = role.Position
+		}
+	}
+	return highest
+}
+
+func findRole(roles []*discordgo.Role, roleID string) *discordgo.Role {
+	for _, role := range roles {
+		if role.ID == roleID {
+			return role
+		}
+	}
+	return nil
+}
+
+func formatRoleRewards(rules []rolerewards.Rule) string {
+	if len(rules) == 0 {
+		return "No role rewards are configured. Use `/role-rewards add` to add one."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Role Rewards\n")
+	for _, r := range rules {
+		sb.WriteString(fmt.Sprintf("- <@&%s> - %s\n", r.RoleID, r.Describe()))
+	}
+	return sb.String()
+}
+
+func formatAuditLog(entries []rolerewards.AuditEntry) string {
+	if len(entries) == 0 {
+		return "No roles have been changed by role rewards yet."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Role Reward Changes\n")
+	for _, e := range entries {
+		verb := "given"
+		if e.Action == rolerewards.ActionRemove {
+			verb = "removed from"
+		}
+		sb.WriteString(fmt.Sprintf("<t:%d:f> <@&%s> %s %s (%s)\n", e.CreatedAt.Unix(), e.RoleID, verb, privacy.FormatUser(e.UserID), e.Reason))
+	}
+	return sb.String()
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/rolerewards"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatRoleRewards(t *testing.T) {
+	result := formatRoleRewards([]rolerewards.Rule{
+		{RoleID: "role1", Kind: rolerewards.KindTop, Metric: rolerewards.MetricReceived, Count: 3, WindowDays: 30},
+	})
+
+	assert.Contains(t, result, "- <@&role1> - top 3 receivers in the last 30 days")
+}
+
+func TestFormatRoleRewards_Empty(t *testing.T) {
+	assert.Contains(t, formatRoleRewards(nil), "No role rewards are configured")
+}
+
+func TestFormatAuditLog(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+
+	result := formatAuditLog([]rolerewards.AuditEntry{
+		{RoleID: "role1", UserID: "user1", Action: rolerewards.ActionRemove, Reason: "top 1 givers all-time", CreatedAt: at},
+		{RoleID: "role1", UserID: "user2", Action: rolerewards.ActionAdd, Reason: "top 1 givers all-time", CreatedAt: at},
+	})
+
+	assert.Contains(t, result, "<t:1700000000:f> <@&role1> removed from <@user1> (top 1 givers all-time)")
+	assert.Contains(t, result, "<@&role1> given <@user2>")
+}
+
+func TestRewardRoleProblem(t *testing.T) {
+	guild := &discordgo.Guild{
+		ID:      "guild1",
+		OwnerID: "owner",
+		Roles: []*discordgo.Role{
+			{ID: "guild1", Position: 0},
+			{ID: "member", Position: 1},
+			{ID: "moderator", Position: 2},
+			{ID: "integration", Position: 3, Managed: true},
+			{ID: "bot", Position: 4},
+			{ID: "admin", Position: 5},
+		},
+	}
+	moderator := &discordgo.Member{User: &discordgo.User{ID: "mod"}, Roles: []string{"moderator"}}
+	owner := &discordgo.Member{User: &discordgo.User{ID: "owner"}}
+	bot := &discordgo.Member{User: &discordgo.User{ID: "bot"}, Roles: []string{"bot"}}
+
+	tests := []struct {
+		name    string
+		invoker *discordgo.Member
+		roleID  string
+		problem string
+	}{
+		{"below both", moderator, "member", ""},
+		{"everyone", moderator, "guild1", "@everyone cannot be rewarded."},
+		{"unknown", moderator, "deleted", "That role no longer exists."},
+		{"managed", owner, "integration", "<@&integration> is managed by an integration and cannot be rewarded."},
+		{"invoker's highest", moderator, "moderator", "<@&moderator> must be below your highest role to be rewarded."},
+		{"above invoker", moderator, "admin", "<@&admin> must be below your highest role to be rewarded."},
+		{"owner", owner, "moderator", ""},
+		{"bot's highest", owner, "bot", "<@&bot> must be below the bot's highest role to be rewarded."},
+		{"above bot", owner, "admin", "<@&admin> must be below the bot's highest role to be rewarded."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.problem, rewardRoleProblem(guild, tt.invoker, bot, tt.roleID))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/starboard"
+)
+
+// NewStarboardHandler creates a handler for the /starboard command
+func NewStarboardHandler(repo *starboard.Repository) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		if err := deferResponse(ctx, s, i, false); err != nil {
+			return err
+		}
+
+		guildID := i.GuildID
+		subcommand, options := parseSubcommand(data.Options)
+
+		switch subcommand {
+		case "set":
+			c := starboard.Config{GuildID: guildID}
+			for _, opt := range options {
+				switch opt.Name {
+				case "channel":
+					c.ChannelID = opt.ChannelValue(nil).ID
+				case "threshold":
+					c.Threshold = int(opt.IntValue())
+				case "emoji":
+					c.EmojiID = opt.StringValue()
+				}
+			}
+
+			if err := repo.SaveConfig(ctx, c); err != nil {
+				slog.Error("failed to save starboard config", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to save the starboard configuration.")
+			}
+
+			return respond(ctx, s, i, formatStarboardConfig(&c))
+		case "disable":
+			if err := repo.DeleteConfig(ctx, guildID); err != nil {
+				slog.Error("failed to delete starboard config", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to disable the starboard.")
+			}
+
+			return respond(ctx, s, i, "Starboard disabled.")
+		default:
+			c, err := repo.GetConfig(ctx, guildID)
+			if err != nil {
+				slog.Error("failed to get starboard config", "error", err, "guild_id", guildID)
+				return respondWithError(ctx, s, i, "Failed to retrieve the starboard configuration.")
+			}
+
+			return respond(ctx, s, i, formatStarboardConfig(c))
+		}
+	}
+}
+
+func formatStarboardConfig(c *starboard.Config) string {
+	if c == nil {
+		return "The starboard is not configured. Use `/starboard set` to enable it."
+	}
+
+	emoji := c.EmojiID
+	if emoji == "" {
+		emoji = "any emoji"
+	}
+
+	return fmt.Sprintf("Messages with **%d** reactions (%s) will be posted to <#%s>.", c.Threshold, emoji, c.ChannelID)
+}
+
+func parseSubcommand(options []*discordgo.ApplicationCommandInteractionDataOption) (string, []*discordgo.ApplicationCommandInteractionDataOption) {
+	for _, opt := range options {
+		if opt.Type == discordgo.ApplicationCommandOptionSubCommand {
+			return opt.Name, opt.Options
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/starboard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSubcommand(t *testing.T) {
+	options := []*discordgo.ApplicationCommandInteractionDataOption{
+		{
+			Name: "set",
+			Type: discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "threshold", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(5)},
+			},
+		},
+	}
+
+	name, subOptions := parseSubcommand(options)
+
+	assert.Equal(t, "set", name)
+	require.Len(t, subOptions, 1)
+	assert.Equal(t, "threshold", subOptions[0].Name)
+}
+
+func TestParseSubcommand_Missing(t *testing.T) {
+	name, subOptions := parseSubcommand(nil)
+
+	assert.Equal(t, "", name)
+	assert.Nil(t, subOptions)
+}
+
+func TestFormatStarboardConfig(t *testing.T) {
+	assert.Contains(t, formatStarboardConfig(nil), "not configured")
+	assert.Equal(t,
+		"Messages with **5** reactions (⭐) will be posted to <#chan1>.",
+		formatStarboardConfig(&starboard.Config{ChannelID: "chan1", EmojiID: "⭐", Threshold: 5}),
+	)
+	assert.Contains(t, formatStarboardConfig(&starboard.Config{ChannelID: "chan1", Threshold: 5}), "any emoji")
+}
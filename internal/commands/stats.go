@@ -2,7 +2,9 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -10,45 +12,51 @@ import (
 	"github.com/elliotwms/emojistats/internal/stats"
 )
 
+// ErrResponded is returned by handlers which failed and have already told the user why. It needs no further handling,
+// so Handled discards it once any wrappers counting failures have seen it
+var ErrResponded = errors.New("responded with an error")
+
 // NewStatsHandler creates a handler for the /stats command
-func NewStatsHandler(repo *stats.Repository) router.ApplicationCommandHandler {
+func NewStatsHandler(repo stats.Querier, settings stats.SettingsStore) router.ApplicationCommandHandler {
 	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
-		public := parsePublicOption(data.Options)
-		if err := deferResponse(s, i, public); err != nil {
+		guildID := i.GuildID
+		gs := guildSettings(ctx, settings, guildID)
+
+		public := parsePublicOption(data.Options, gs.PublicResponses)
+		if err := deferResponse(ctx, s, i, public); err != nil {
 			return err
 		}
 
-		guildID := i.GuildID
-
-		dateRange, err := parseDateRange(data.Options)
+		dateRange, err := parseDateRange(data.Options, guildTimezone(ctx, settings, guildID))
 		if err != nil {
-			return respondWithError(s, i, "Invalid date format. Please use YYYY-MM-DD.")
+			return respondWithError(ctx, s, i, "Invalid date format. Please use YYYY-MM-DD.")
 		}
 
-		guildStats, err := repo.GetGuildStats(ctx, guildID, dateRange)
+		guildStats, err := repo.GetGuildStats(ctx, guildID, dateRange, gs.LeaderboardSize)
 		if err != nil {
 			slog.Error("failed to get guild stats", "error", err, "guild_id", guildID)
-			return respondWithError(s, i, "Failed to retrieve statistics.")
+			return respondWithError(ctx, s, i, "Failed to retrieve statistics.")
 		}
 
 		content := stats.FormatGuildStats(guildStats, guildID)
-		return respond(s, i, content)
+		return respond(ctx, s, i, content)
 	}
 }
 
-func parseDateRange(options []*discordgo.ApplicationCommandInteractionDataOption) (stats.DateRange, error) {
+// parseDateRange parses the start_date and end_date options as days in the given timezone
+func parseDateRange(options []*discordgo.ApplicationCommandInteractionDataOption, loc *time.Location) (stats.DateRange, error) {
 	var dateRange stats.DateRange
 
 	for _, opt := range options {
 		switch opt.Name {
 		case "start_date":
-			t, err := time.Parse("2006-01-02", opt.StringValue())
+			t, err := time.ParseInLocation("2006-01-02", opt.StringValue(), loc)
 			if err != nil {
 				return dateRange, err
 			}
 			dateRange.Start = &t
 		case "end_date":
-			t, err := time.Parse("2006-01-02", opt.StringValue())
+			t, err := time.ParseInLocation("2006-01-02", opt.StringValue(), loc)
 			if err != nil {
 				return dateRange, err
 			}
@@ -61,16 +69,39 @@ func parseDateRange(options []*discordgo.ApplicationCommandInteractionDataOption
 	return dateRange, nil
 }
 
-func parsePublicOption(options []*discordgo.ApplicationCommandInteractionDataOption) bool {
+// parsePublicOption returns the public option, or the guild's default if it is not given
+func parsePublicOption(options []*discordgo.ApplicationCommandInteractionDataOption, def bool) bool {
 	for _, opt := range options {
 		if opt.Name == "public" {
 			return opt.BoolValue()
 		}
 	}
-	return false
+	return def
+}
+
+// guildSettings retrieves a guild's settings for a command. The defaults are used if they cannot be retrieved, so that
+// the command still responds
+func guildSettings(ctx context.Context, repo stats.SettingsStore, guildID string) stats.Settings {
+	settings, err := repo.GetSettings(ctx, guildID)
+	if err != nil {
+		slog.Error("failed to get guild settings", "error", err, "guild_id", guildID)
+		return stats.DefaultSettings()
+	}
+	return settings
+}
+
+// guildTimezone retrieves a guild's timezone for a command. UTC is used if it cannot be retrieved, so that the command
+// still responds
+func guildTimezone(ctx context.Context, repo stats.SettingsStore, guildID string) *time.Location {
+	loc, err := repo.GetTimezone(ctx, guildID)
+	if err != nil {
+		slog.Error("failed to get guild timezone", "error", err, "guild_id", guildID)
+		return time.UTC
+	}
+	return loc
 }
 
-func deferResponse(s *discordgo.Session, i *discordgo.InteractionCreate, public bool) error {
+func deferResponse(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, public bool) error {
 	var flags discordgo.MessageFlags
 	if !public {
 		flags = discordgo.MessageFlagsEphemeral
@@ -81,16 +112,41 @@ func deferResponse(s *discordgo.Session, i *discordgo.InteractionCreate, public
 		Data: &discordgo.InteractionResponseData{
 			Flags: flags,
 		},
-	})
+	}, discordgo.WithContext(ctx))
 }
 
-func respond(s *discordgo.Session, i *discordgo.InteractionCreate, content string) error {
+// maxMessageLength is the most characters Discord allows in a message's content
+const maxMessageLength = 2000
+
+// respond edits the deferred response. Content longer than Discord allows, e.g. the largest leaderboards, is cut after
+// the last line which fits
+func respond(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, content string) error {
+	content = truncateLines(content, maxMessageLength)
 	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 		Content: &content,
-	})
+	}, discordgo.WithContext(ctx))
 	return err
 }
 
-func respondWithError(s *discordgo.Session, i *discordgo.InteractionCreate, message string) error {
-	return respond(s, i, message)
+// truncateLines cuts content after the last whole line within limit characters
+func truncateLines(content string, limit int) string {
+	runes := []rune(content)
+	if len(runes) <= limit {
+		return content
+	}
+
+	cut := string(runes[:limit])
+	if i := strings.LastIndexByte(cut, '\n'); i >= 0 {
+		return cut[:i+1]
+	}
+	return cut
+}
+
+// respondWithError tells the user why the command failed. It returns ErrResponded once they have been told, so that the
+// failure is counted
+func respondWithError(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, message string) error {
+	if err := respond(ctx, s, i, message); err != nil {
+		return err
+	}
+	return ErrResponded
 }
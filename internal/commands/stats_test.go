@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -12,7 +13,7 @@ import (
 func TestParseDateRange_Empty(t *testing.T) {
 	options := []*discordgo.ApplicationCommandInteractionDataOption{}
 
-	dateRange, err := parseDateRange(options)
+	dateRange, err := parseDateRange(options, time.UTC)
 
 	require.NoError(t, err)
 	assert.Nil(t, dateRange.Start)
@@ -24,7 +25,7 @@ func TestParseDateRange_StartDateOnly(t *testing.T) {
 		{Name: "start_date", Type: discordgo.ApplicationCommandOptionString, Value: "2024-01-15"},
 	}
 
-	dateRange, err := parseDateRange(options)
+	dateRange, err := parseDateRange(options, time.UTC)
 
 	require.NoError(t, err)
 	require.NotNil(t, dateRange.Start)
@@ -39,7 +40,7 @@ func TestParseDateRange_EndDateOnly(t *testing.T) {
 		{Name: "end_date", Type: discordgo.ApplicationCommandOptionString, Value: "2024-01-20"},
 	}
 
-	dateRange, err := parseDateRange(options)
+	dateRange, err := parseDateRange(options, time.UTC)
 
 	require.NoError(t, err)
 	assert.Nil(t, dateRange.Start)
@@ -56,7 +57,7 @@ func TestParseDateRange_BothDates(t *testing.T) {
 		{Name: "end_date", Type: discordgo.ApplicationCommandOptionString, Value: "2024-01-31"},
 	}
 
-	dateRange, err := parseDateRange(options)
+	dateRange, err := parseDateRange(options, time.UTC)
 
 	require.NoError(t, err)
 	require.NotNil(t, dateRange.Start)
@@ -71,7 +72,7 @@ func TestParseDateRange_InvalidStartDate(t *testing.T) {
 		{Name: "start_date", Type: discordgo.ApplicationCommandOptionString, Value: "not-a-date"},
 	}
 
-	_, err := parseDateRange(options)
+	_, err := parseDateRange(options, time.UTC)
 
 	assert.Error(t, err)
 }
@@ -81,7 +82,7 @@ func TestParseDateRange_InvalidEndDate(t *testing.T) {
 		{Name: "end_date", Type: discordgo.ApplicationCommandOptionString, Value: "01/20/2024"},
 	}
 
-	_, err := parseDateRange(options)
+	_, err := parseDateRange(options, time.UTC)
 
 	assert.Error(t, err)
 }
@@ -92,9 +93,38 @@ func TestParseDateRange_IgnoresUnknownOptions(t *testing.T) {
 		{Name: "start_date", Type: discordgo.ApplicationCommandOptionString, Value: "2024-01-15"},
 	}
 
-	dateRange, err := parseDateRange(options)
+	dateRange, err := parseDateRange(options, time.UTC)
 
 	require.NoError(t, err)
 	require.NotNil(t, dateRange.Start)
 	assert.Equal(t, 15, dateRange.Start.Day())
 }
+
+func TestParseDateRange_Timezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	options := []*discordgo.ApplicationCommandInteractionDataOption{
+		{Name: "start_date", Type: discordgo.ApplicationCommandOptionString, Value: "2024-01-15"},
+		{Name: "end_date", Type: discordgo.ApplicationCommandOptionString, Value: "2024-01-15"},
+	}
+
+	dateRange, err := parseDateRange(options, loc)
+
+	require.NoError(t, err)
+	require.NotNil(t, dateRange.Start)
+	require.NotNil(t, dateRange.End)
+	assert.Equal(t, time.Date(2024, time.January, 15, 5, 0, 0, 0, time.UTC), dateRange.Start.UTC())
+	assert.Equal(t, time.Date(2024, time.January, 16, 5, 0, 0, 0, time.UTC), dateRange.End.UTC())
+}
+
+func TestTruncateLines(t *testing.T) {
+	assert.Equal(t, "short\n", truncateLines("short\n", 10))
+	assert.Equal(t, "1. one\n", truncateLines("1. one\n2. two\n", 10))
+	assert.Equal(t, "ab", truncateLines("abcdef", 2))
+
+	long := strings.Repeat("🥇 <@123456789012345678> - 10\n", 100)
+	truncated := truncateLines(long, maxMessageLength)
+	assert.LessOrEqual(t, len([]rune(truncated)), maxMessageLength)
+	assert.True(t, strings.HasSuffix(truncated, "- 10\n"))
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// NewStreaksHandler creates a handler for the /streaks command
+func NewStreaksHandler(repo stats.Querier, settings stats.SettingsStore) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		guildID := i.GuildID
+		gs := guildSettings(ctx, settings, guildID)
+
+		public := parsePublicOption(data.Options, gs.PublicResponses)
+		if err := deferResponse(ctx, s, i, public); err != nil {
+			return err
+		}
+
+		var userID string
+		var byLongest bool
+		for _, opt := range data.Options {
+			switch opt.Name {
+			case "user":
+				userID = opt.UserValue(nil).ID
+			case "sort":
+				byLongest = opt.StringValue() == "longest"
+			}
+		}
+
+		if userID != "" {
+			streak, err := repo.GetStreak(ctx, guildID, userID)
+			if err != nil {
+				slog.Error("failed to get streak", "error", err, "guild_id", guildID, "user_id", userID)
+				return respondWithError(ctx, s, i, "Failed to retrieve streaks.")
+			}
+
+			return respond(ctx, s, i, stats.FormatStreak(streak))
+		}
+
+		streaks, err := repo.GetStreaks(ctx, guildID, byLongest, gs.LeaderboardSize)
+		if err != nil {
+			slog.Error("failed to get streaks", "error", err, "guild_id", guildID)
+			return respondWithError(ctx, s, i, "Failed to retrieve streaks.")
+		}
+
+		return respond(ctx, s, i, stats.FormatStreaks(streaks, byLongest))
+	}
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+const wrappedComponentPrefix = "wrapped"
+
+// NewWrappedHandler creates a handler for the /wrapped command
+func NewWrappedHandler(repo stats.Querier, settings stats.SettingsStore) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		guildID := i.GuildID
+
+		public := parsePublicOption(data.Options, guildSettings(ctx, settings, guildID).PublicResponses)
+		if err := deferResponse(ctx, s, i, public); err != nil {
+			return err
+		}
+
+		year := time.Now().Year()
+		var userID string
+		var card bool
+
+		for _, opt := range data.Options {
+			switch opt.Name {
+			case "year":
+				year = int(opt.IntValue())
+			case "user":
+				userID = opt.UserValue(nil).ID
+			case "card":
+				card = opt.BoolValue()
+			}
+		}
+
+		w, err := repo.GetWrapped(ctx, guildID, userID, year)
+		if err != nil {
+			slog.Error("failed to get wrapped", "error", err, "guild_id", guildID, "user_id", userID, "year", year)
+			return respondWithError(ctx, s, i, "Failed to retrieve your Wrapped.")
+		}
+
+		pages := stats.FormatWrappedPages(w, guildID)
+		components := wrappedPageComponents(0, len(pages), year, userID)
+
+		edit := &discordgo.WebhookEdit{
+			Content:    &pages[0],
+			Components: &components,
+		}
+
+		if card {
+			b, err := stats.RenderWrappedCard(w)
+			if err != nil {
+				slog.Error("failed to render wrapped card", "error", err, "guild_id", guildID)
+			} else {
+				edit.Files = []*discordgo.File{{Name: "wrapped.png", ContentType: "image/png", Reader: bytes.NewReader(b)}}
+			}
+		}
+
+		_, err = s.InteractionResponseEdit(i.Interaction, edit, discordgo.WithContext(ctx))
+		return err
+	}
+}
+
+// NewWrappedComponentHandler creates a handler for the pagination buttons of a /wrapped response
+func NewWrappedComponentHandler(repo stats.Querier) ComponentHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.MessageComponentInteractionData) error {
+		page, year, userID, err := parseWrappedCustomID(data.CustomID)
+		if err != nil {
+			return err
+		}
+
+		w, err := repo.GetWrapped(ctx, i.GuildID, userID, year)
+		if err != nil {
+			return fmt.Errorf("failed to get wrapped: %w", err)
+		}
+
+		pages := stats.FormatWrappedPages(w, i.GuildID)
+		page = min(max(page, 0), len(pages)-1)
+
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    pages[page],
+				Components: wrappedPageComponents(page, len(pages), year, userID),
+			},
+		}, discordgo.WithContext(ctx))
+	}
+}
+
+func wrappedPageComponents(page, total, year int, userID string) []discordgo.MessageComponent {
+	if total <= 1 {
+		return []discordgo.MessageComponent{}
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Previous",
+					Style:    discordgo.SecondaryButton,
+					Disabled: page == 0,
+					CustomID: formatWrappedCustomID(page-1, year, userID),
+				},
+				discordgo.Button{
+					Label:    fmt.Sprintf("%d/%d", page+1, total),
+					Style:    discordgo.SecondaryButton,
+					Disabled: true,
+					// custom IDs must be unique within a message, and the counter is never pressed
+					CustomID: fmt.Sprintf("%s:noop:%d:%s", wrappedComponentPrefix, year, userID),
+				},
+				discordgo.Button{
+					Label:    "Next",
+					Style:    discordgo.PrimaryButton,
+					Disabled: page == total-1,
+					CustomID: formatWrappedCustomID(page+1, year, userID),
+				},
+			},
+		},
+	}
+}
+
+func formatWrappedCustomID(page, year int, userID string) string {
+	return fmt.Sprintf("%s:%d:%d:%s", wrappedComponentPrefix, page, year, userID)
+}
+
+func parseWrappedCustomID(customID string) (page, year int, userID string, err error) {
+	parts := strings.Split(customID, ":")
+	if len(parts) != 4 || parts[0] != wrappedComponentPrefix {
+		return 0, 0, "", fmt.Errorf("invalid wrapped custom id %q", customID)
+	}
+
+	if page, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, "", fmt.Errorf("invalid wrapped page: %w", err)
+	}
+
+	if year, err = strconv.Atoi(parts[2]); err != nil {
+		return 0, 0, "", fmt.Errorf("invalid wrapped year: %w", err)
+	}
+
+	return page, year, parts[3], nil
+}
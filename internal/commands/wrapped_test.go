@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrappedCustomID_RoundTrip(t *testing.T) {
+	customID := formatWrappedCustomID(2, 2024, "111")
+
+	page, year, userID, err := parseWrappedCustomID(customID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, page)
+	assert.Equal(t, 2024, year)
+	assert.Equal(t, "111", userID)
+}
+
+func TestWrappedCustomID_GuildRecap(t *testing.T) {
+	page, year, userID, err := parseWrappedCustomID("wrapped:0:2023:")
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, page)
+	assert.Equal(t, 2023, year)
+	assert.Equal(t, "", userID)
+}
+
+func TestParseWrappedCustomID_Invalid(t *testing.T) {
+	for _, customID := range []string{"wrapped", "starboard:1:2024:", "wrapped:x:2024:", "wrapped:1:y:"} {
+		_, _, _, err := parseWrappedCustomID(customID)
+		assert.Error(t, err, customID)
+	}
+}
+
+func TestWrappedPageComponents(t *testing.T) {
+	components := wrappedPageComponents(0, 3, 2024, "111")
+
+	require.Len(t, components, 1)
+	row := components[0].(discordgo.ActionsRow)
+	require.Len(t, row.Components, 3)
+
+	previous := row.Components[0].(discordgo.Button)
+	assert.True(t, previous.Disabled)
+
+	label := row.Components[1].(discordgo.Button)
+	assert.Equal(t, "1/3", label.Label)
+
+	next := row.Components[2].(discordgo.Button)
+	assert.False(t, next.Disabled)
+	assert.Equal(t, "wrapped:1:2024:111", next.CustomID)
+}
+
+func TestWrappedPageComponents_UniqueCustomIDs(t *testing.T) {
+	for page := range 3 {
+		components := wrappedPageComponents(page, 3, 2024, "111")
+		require.Len(t, components, 1)
+
+		seen := make(map[string]bool)
+		for _, c := range components[0].(discordgo.ActionsRow).Components {
+			id := c.(discordgo.Button).CustomID
+			assert.False(t, seen[id], "page %d has duplicate custom ID %q", page, id)
+			seen[id] = true
+		}
+	}
+}
+
+func TestWrappedPageComponents_SinglePage(t *testing.T) {
+	assert.Empty(t, wrappedPageComponents(0, 1, 2024, ""))
+}
@@ -0,0 +1,379 @@
+// Package config loads the bot's configuration from an optional YAML or TOML file, overridden by environment variables.
+// Secrets may be read from files, either with the *_file keys or the *_FILE environment variables, e.g. TOKEN_FILE for
+// Docker and Kubernetes secrets
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elliotwms/emojistats/internal/cache"
+)
+
+// Config is the bot's configuration
+type Config struct {
+	Token         string `yaml:"token" toml:"token"`
+	TokenFile     string `yaml:"token_file" toml:"token_file"`
+	ApplicationID string `yaml:"application_id" toml:"application_id"`
+	// GuildID registers commands in a single guild rather than globally, for development
+	GuildID string `yaml:"guild_id" toml:"guild_id"`
+	// DatabaseURL is a Postgres connection string, or a SQLite database path prefixed with sqlite://
+	DatabaseURL     string     `yaml:"database_url" toml:"database_url"`
+	DatabaseURLFile string     `yaml:"database_url_file" toml:"database_url_file"`
+	LogLevel        slog.Level `yaml:"log_level" toml:"log_level"`
+
+	HealthCheckAddr string `yaml:"health_check_addr" toml:"health_check_addr"`
+	APIAddr         string `yaml:"api_addr" toml:"api_addr"`
+	MetricsAddr     string `yaml:"metrics_addr" toml:"metrics_addr"`
+
+	Dashboard  Dashboard  `yaml:"dashboard" toml:"dashboard"`
+	StatsCache StatsCache `yaml:"stats_cache" toml:"stats_cache"`
+	Features   Features   `yaml:"features" toml:"features"`
+}
+
+// Dashboard configures the web dashboard
+type Dashboard struct {
+	Addr string `yaml:"addr" toml:"addr"`
+	// URL is the dashboard's public URL, which its OAuth2 callback is relative to
+	URL              string `yaml:"url" toml:"url"`
+	ClientSecret     string `yaml:"client_secret" toml:"client_secret"`
+	ClientSecretFile string `yaml:"client_secret_file" toml:"client_secret_file"`
+	// OAuthURL overrides Discord as the OAuth2 provider, e.g. to sign in with a fake provider locally
+	OAuthURL string `yaml:"oauth_url" toml:"oauth_url"`
+}
+
+// StatsCache configures the stats query cache
+type StatsCache struct {
+	TTL    Duration `yaml:"ttl" toml:"ttl"`
+	Shared bool     `yaml:"shared" toml:"shared"`
+}
+
+// Features toggles the optional features, which are all enabled by default
+type Features struct {
+	Starboard    bool `yaml:"starboard" toml:"starboard"`
+	Milestones   bool `yaml:"milestones" toml:"milestones"`
+	Achievements bool `yaml:"achievements" toml:"achievements"`
+	Digests      bool `yaml:"digests" toml:"digests"`
+	RoleRewards  bool `yaml:"role_rewards" toml:"role_rewards"`
+	Retention    bool `yaml:"retention" toml:"retention"`
+}
+
+// Duration is a time.Duration written as a string such as "1m30s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Errors is every problem found with a configuration
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// Default returns the configuration used for anything which is not configured
+func Default() *Config {
+	return &Config{
+		LogLevel:   slog.LevelInfo,
+		StatsCache: StatsCache{TTL: Duration(cache.DefaultTTL)},
+		Features: Features{
+			Starboard:    true,
+			Milestones:   true,
+			Achievements: true,
+			Digests:      true,
+			RoleRewards:  true,
+			Retention:    true,
+		},
+	}
+}
+
+// Load loads the configuration file at path, if path is not empty, and then applies the environment. Every problem
+// found while loading is returned together as Errors. The configuration should then be checked with Validate
+func Load(path string, getenv func(string) string) (*Config, error) {
+	c := Default()
+	var errs Errors
+
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			// the environment is still applied to report any problems with it too
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, c.loadSecretFiles()...)
+	errs = append(errs, c.loadEnv(getenv)...)
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return c, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		// an empty file is valid, and decodes as EOF
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(b), c)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported file type %q, must be .yaml, .yml or .toml", path, ext)
+	}
+
+	return nil
+}
+
+// secrets are the settings which may be read from files
+func (c *Config) secrets() []struct {
+	key, env    string
+	value, file *string
+} {
+	return []struct {
+		key, env    string
+		value, file *string
+	}{
+		{"token", "TOKEN", &c.Token, &c.TokenFile},
+		{"database_url", "DATABASE_URL", &c.DatabaseURL, &c.DatabaseURLFile},
+		{"dashboard.client_secret", "DISCORD_CLIENT_SECRET", &c.Dashboard.ClientSecret, &c.Dashboard.ClientSecretFile},
+	}
+}
+
+// loadSecretFiles reads the secrets configured as files in the configuration file
+func (c *Config) loadSecretFiles() Errors {
+	var errs Errors
+	for _, s := range c.secrets() {
+		if *s.file == "" {
+			continue
+		}
+		if *s.value != "" {
+			errs = append(errs, fmt.Errorf("only one of %s and %s_file may be set", s.key, s.key))
+			continue
+		}
+
+		v, err := readSecret(*s.file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s_file: %w", s.key, err))
+			continue
+		}
+		*s.value = v
+	}
+	return errs
+}
+
+// loadEnv overrides the configuration with the environment
+func (c *Config) loadEnv(getenv func(string) string) Errors {
+	var errs Errors
+
+	for _, s := range c.secrets() {
+		v, file := getenv(s.env), getenv(s.env+"_FILE")
+		switch {
+		case v != "" && file != "":
+			errs = append(errs, fmt.Errorf("only one of %s and %s_FILE may be set", s.env, s.env))
+		case v != "":
+			*s.value = v
+		case file != "":
+			secret, err := readSecret(file)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %w", s.env, err))
+				continue
+			}
+			*s.value = secret
+		}
+	}
+
+	strs := map[string]*string{
+		"APPLICATION_ID":      &c.ApplicationID,
+		"GUILD_ID":            &c.GuildID,
+		"HEALTH_CHECK_ADDR":   &c.HealthCheckAddr,
+		"API_ADDR":            &c.APIAddr,
+		"METRICS_ADDR":        &c.MetricsAddr,
+		"DASHBOARD_ADDR":      &c.Dashboard.Addr,
+		"DASHBOARD_URL":       &c.Dashboard.URL,
+		"DASHBOARD_OAUTH_URL": &c.Dashboard.OAuthURL,
+	}
+	for env, p := range strs {
+		if v := getenv(env); v != "" {
+			*p = v
+		}
+	}
+
+	if v := getenv("LOG_LEVEL"); v != "" {
+		if err := c.LogLevel.UnmarshalText([]byte(v)); err != nil {
+			errs = append(errs, fmt.Errorf("LOG_LEVEL: %w", err))
+		}
+	}
+
+	if v := getenv("STATS_CACHE_TTL"); v != "" {
+		if err := c.StatsCache.TTL.UnmarshalText([]byte(v)); err != nil {
+			errs = append(errs, fmt.Errorf("STATS_CACHE_TTL: %w", err))
+		}
+	}
+
+	bools := map[string]*bool{
+		"STATS_CACHE_SHARED":   &c.StatsCache.Shared,
+		"FEATURE_STARBOARD":    &c.Features.Starboard,
+		"FEATURE_MILESTONES":   &c.Features.Milestones,
+		"FEATURE_ACHIEVEMENTS": &c.Features.Achievements,
+		"FEATURE_DIGESTS":      &c.Features.Digests,
+		"FEATURE_ROLE_REWARDS": &c.Features.RoleRewards,
+		"FEATURE_RETENTION":    &c.Features.Retention,
+	}
+	for env, p := range bools {
+		v := getenv(env)
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid boolean %q", env, v))
+			continue
+		}
+		*p = b
+	}
+
+	return errs
+}
+
+// readSecret reads a secret from a file, ignoring surrounding whitespace such as a trailing newline
+func readSecret(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// SQLite returns whether the database is SQLite, and its path
+func (c *Config) SQLite() (string, bool) {
+	return strings.CutPrefix(c.DatabaseURL, "sqlite://")
+}
+
+// ValidateDatabase checks the configuration needed to connect to the database, for commands which only need the
+// database
+func (c *Config) ValidateDatabase() error {
+	if c.DatabaseURL == "" {
+		return Errors{errors.New("database_url is required")}
+	}
+	return nil
+}
+
+// Validate checks the configuration needed to run the bot, returning every problem found as Errors
+func (c *Config) Validate() error {
+	var errs Errors
+
+	if c.Token == "" {
+		errs = append(errs, errors.New("token is required"))
+	}
+	if c.ApplicationID == "" {
+		errs = append(errs, errors.New("application_id is required"))
+	} else if !isSnowflake(c.ApplicationID) {
+		errs = append(errs, fmt.Errorf("application_id %q must be a Discord ID", c.ApplicationID))
+	}
+	if c.GuildID != "" && !isSnowflake(c.GuildID) {
+		errs = append(errs, fmt.Errorf("guild_id %q must be a Discord ID", c.GuildID))
+	}
+	if c.DatabaseURL == "" {
+		errs = append(errs, errors.New("database_url is required"))
+	}
+	if c.StatsCache.TTL < 0 {
+		errs = append(errs, errors.New("stats_cache.ttl must not be negative"))
+	}
+
+	addrs := map[string]string{}
+	for _, a := range []struct{ key, addr string }{
+		{"health_check_addr", c.HealthCheckAddr},
+		{"api_addr", c.APIAddr},
+		{"metrics_addr", c.MetricsAddr},
+		{"dashboard.addr", c.Dashboard.Addr},
+	} {
+		if a.addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(a.addr); err != nil {
+			errs = append(errs, fmt.Errorf("%s %q must be a host:port address", a.key, a.addr))
+			continue
+		}
+		if other, ok := addrs[a.addr]; ok {
+			errs = append(errs, fmt.Errorf("%s and %s must be different addresses", other, a.key))
+			continue
+		}
+		addrs[a.addr] = a.key
+	}
+
+	if _, sqlite := c.SQLite(); sqlite && c.APIAddr != "" {
+		errs = append(errs, errors.New("api_addr requires a Postgres database_url, as API tokens are stored in Postgres"))
+	}
+
+	if c.Dashboard.Addr != "" {
+		if err := validateURL(c.Dashboard.URL); err != nil {
+			errs = append(errs, fmt.Errorf("dashboard.url is required with dashboard.addr: %w", err))
+		}
+		if c.Dashboard.ClientSecret == "" {
+			errs = append(errs, errors.New("dashboard.client_secret is required with dashboard.addr"))
+		}
+		if c.Dashboard.OAuthURL != "" {
+			if err := validateURL(c.Dashboard.OAuthURL); err != nil {
+				errs = append(errs, fmt.Errorf("dashboard.oauth_url: %w", err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q must be an absolute http or https URL", s)
+	}
+	return nil
+}
+
+func isSnowflake(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_YAML(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+token: abc
+application_id: "123"
+database_url: postgres://localhost/emojistats
+log_level: debug
+metrics_addr: :9090
+dashboard:
+  addr: :8000
+  url: https://stats.example.com
+  client_secret: secret
+stats_cache:
+  ttl: 5m
+  shared: true
+features:
+  role_rewards: false
+`)
+
+	c, err := Load(path, env(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc", c.Token)
+	assert.Equal(t, "123", c.ApplicationID)
+	assert.Equal(t, slog.LevelDebug, c.LogLevel)
+	assert.Equal(t, ":9090", c.MetricsAddr)
+	assert.Equal(t, "https://stats.example.com", c.Dashboard.URL)
+	assert.Equal(t, Duration(5*time.Minute), c.StatsCache.TTL)
+	assert.True(t, c.StatsCache.Shared)
+	assert.False(t, c.Features.RoleRewards)
+	assert.True(t, c.Features.Starboard, "unset features keep their default")
+	assert.NoError(t, c.Validate())
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+token = "abc"
+application_id = "123"
+database_url = "sqlite:///data/emojistats.db"
+
+[stats_cache]
+ttl = "0s"
+
+[features]
+digests = false
+`)
+
+	c, err := Load(path, env(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc", c.Token)
+	assert.Equal(t, Duration(0), c.StatsCache.TTL)
+	assert.False(t, c.Features.Digests)
+
+	sqlitePath, ok := c.SQLite()
+	assert.True(t, ok)
+	assert.Equal(t, "/data/emojistats.db", sqlitePath)
+}
+
+func TestLoad_UnknownKeys(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.yaml": "tokne: abc\n",
+		"config.toml": "tokne = \"abc\"\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(writeFile(t, name, content), env(nil))
+			assert.ErrorContains(t, err, "tokne")
+		})
+	}
+}
+
+func TestLoad_UnsupportedFileType(t *testing.T) {
+	_, err := Load(writeFile(t, "config.json", "{}"), env(nil))
+	assert.ErrorContains(t, err, "unsupported file type")
+}
+
+func TestLoad_EmptyFile(t *testing.T) {
+	c, err := Load(writeFile(t, "config.yaml", ""), env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, Default(), c)
+}
+
+func TestLoad_EnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", "token: file\nlog_level: debug\nfeatures:\n  starboard: false\n")
+
+	c, err := Load(path, env(map[string]string{
+		"TOKEN":             "env",
+		"LOG_LEVEL":         "warn",
+		"STATS_CACHE_TTL":   "1m",
+		"FEATURE_STARBOARD": "true",
+		"FEATURE_RETENTION": "false",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "env", c.Token)
+	assert.Equal(t, slog.LevelWarn, c.LogLevel)
+	assert.Equal(t, Duration(time.Minute), c.StatsCache.TTL)
+	assert.True(t, c.Features.Starboard)
+	assert.False(t, c.Features.Retention)
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	token := writeFile(t, "token", "abc\n")
+	secret := writeFile(t, "secret", "shh")
+	path := writeFile(t, "config.yaml", "dashboard:\n  client_secret_file: "+secret+"\n")
+
+	c, err := Load(path, env(map[string]string{"TOKEN_FILE": token}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc", c.Token, "surrounding whitespace is trimmed")
+	assert.Equal(t, "shh", c.Dashboard.ClientSecret)
+}
+
+func TestLoad_AggregatesErrors(t *testing.T) {
+	path := writeFile(t, "config.yaml", "token: abc\ntoken_file: /run/secrets/token\n")
+
+	_, err := Load(path, env(map[string]string{
+		"DATABASE_URL":       "postgres://localhost",
+		"DATABASE_URL_FILE":  "/run/secrets/database_url",
+		"LOG_LEVEL":          "loud",
+		"STATS_CACHE_SHARED": "maybe",
+	}))
+
+	var errs Errors
+	require.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 4)
+	assert.ErrorContains(t, err, "only one of token and token_file may be set")
+	assert.ErrorContains(t, err, "only one of DATABASE_URL and DATABASE_URL_FILE may be set")
+	assert.ErrorContains(t, err, "LOG_LEVEL")
+	assert.ErrorContains(t, err, `STATS_CACHE_SHARED: invalid boolean "maybe"`)
+}
+
+func TestValidate(t *testing.T) {
+	c := Default()
+	c.ApplicationID = "not-an-id"
+	c.DatabaseURL = "sqlite://emojistats.db"
+	c.APIAddr = ":8080"
+	c.MetricsAddr = ":8080"
+	c.HealthCheckAddr = "localhost"
+	c.Dashboard.Addr = ":8000"
+	c.Dashboard.URL = "stats.example.com"
+	c.StatsCache.TTL = Duration(-time.Second)
+
+	err := c.Validate()
+
+	var errs Errors
+	require.True(t, errors.As(err, &errs))
+	assert.Equal(t, "invalid configuration: "+
+		"token is required; "+
+		`application_id "not-an-id" must be a Discord ID; `+
+		"stats_cache.ttl must not be negative; "+
+		`health_check_addr "localhost" must be a host:port address; `+
+		"api_addr and metrics_addr must be different addresses; "+
+		"api_addr requires a Postgres database_url, as API tokens are stored in Postgres; "+
+		`dashboard.url is required with dashboard.addr: "stats.example.com" must be an absolute http or https URL; `+
+		"dashboard.client_secret is required with dashboard.addr", err.Error())
+}
+
+func TestValidateDatabase(t *testing.T) {
+	c := Default()
+	assert.EqualError(t, c.ValidateDatabase(), "invalid configuration: database_url is required")
+
+	c.DatabaseURL = "postgres://localhost"
+	assert.NoError(t, c.ValidateDatabase())
+}
@@ -0,0 +1,445 @@
+// Package dashboard serves a web dashboard of guild stats: trends, leaderboards and an emoji gallery. Users sign in
+// with Discord, and can only see the stats of guilds they are a member of
+package dashboard
+
+import (
+	"context"
+	"embed"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/elliotwms/emojistats/internal/httpserver"
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+const (
+	sessionCookie = "emojistats_session"
+	stateCookie   = "emojistats_oauth_state"
+	sessionTTL    = 24 * time.Hour
+	stateTTL      = 10 * time.Minute
+
+	gallerySize = 50
+	moversSize  = 5
+	trendWeeks  = 8
+)
+
+var (
+	//go:embed templates/*.html
+	templateFS embed.FS
+	//go:embed static
+	staticFS embed.FS
+
+	templates = template.Must(template.New("").Funcs(template.FuncMap{"initial": initial}).ParseFS(templateFS, "templates/*.html"))
+)
+
+// Directory resolves the IDs in stats to names, and knows which guilds the bot is in
+type Directory interface {
+	// HasGuild returns whether the bot is in the guild
+	HasGuild(guildID string) bool
+	UserName(guildID, userID string) string
+	ChannelName(guildID, channelID string) string
+}
+
+// Server serves the dashboard
+type Server struct {
+	stats     stats.Querier
+	settings  stats.SettingsStore
+	directory Directory
+	oauth     *oauthClient
+	sessions  *sessions
+	secure    bool
+	now       func() time.Time
+}
+
+// NewServer creates a new dashboard server. Guilds' leaderboard sizes and timezones are read from settings
+func NewServer(q stats.Querier, settings stats.SettingsStore, directory Directory, config OAuthConfig) *Server {
+	return &Server{
+		stats:     q,
+		settings:  settings,
+		directory: directory,
+		oauth:     newOAuthClient(config),
+		sessions:  newSessions(sessionTTL),
+		secure:    strings.HasPrefix(config.RedirectURL, "https://"),
+		now:       time.Now,
+	}
+}
+
+// Handler returns the dashboard's routes
+func (s *Server) Handler() http.Handler {
+	static, _ := fs.Sub(staticFS, "static")
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	mux.HandleFunc("GET /{$}", s.index)
+	mux.HandleFunc("GET /login", s.login)
+	mux.HandleFunc("GET /callback", s.callback)
+	mux.HandleFunc("POST /logout", s.logout)
+	mux.HandleFunc("GET /guilds/{guild}", s.guild)
+
+	return mux
+}
+
+// ListenAndServe serves the dashboard on addr until the context is done
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	return httpserver.ListenAndServe(ctx, "dashboard", addr, s.Handler())
+}
+
+type indexPage struct {
+	User   *User
+	Guilds []guildLink
+}
+
+type guildLink struct {
+	Guild
+	IconURL string
+}
+
+func (s *Server) index(w http.ResponseWriter, r *http.Request) {
+	sess := s.session(r)
+	if sess == nil {
+		s.render(w, "index.html", indexPage{})
+		return
+	}
+
+	page := indexPage{User: &sess.user}
+	for _, g := range sess.guilds {
+		if s.directory.HasGuild(g.ID) {
+			page.Guilds = append(page.Guilds, guildLink{Guild: g, IconURL: guildIconURL(g)})
+		}
+	}
+
+	s.render(w, "index.html", page)
+}
+
+// login sends the user to Discord to sign in
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	state := randomString()
+	http.SetCookie(w, s.cookie(stateCookie, state, stateTTL))
+
+	http.Redirect(w, r, s.oauth.authorizeURL(state), http.StatusFound)
+}
+
+// callback completes signing in once the user has authorised the dashboard
+func (s *Server) callback(w http.ResponseWriter, r *http.Request) {
+	state, err := r.Cookie(stateCookie)
+	if err != nil || state.Value == "" || state.Value != r.URL.Query().Get("state") {
+		http.Error(w, "Invalid sign in state, please try again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, s.cookie(stateCookie, "", -1))
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "Sign in was cancelled", http.StatusBadRequest)
+		return
+	}
+
+	accessToken, err := s.oauth.exchange(r.Context(), code)
+	if err != nil {
+		slog.Error("failed to sign in", "error", err)
+		http.Error(w, "Failed to sign in with Discord", http.StatusBadGateway)
+		return
+	}
+
+	user, err := s.oauth.getUser(r.Context(), accessToken)
+	if err != nil {
+		slog.Error("failed to get user", "error", err)
+		http.Error(w, "Failed to sign in with Discord", http.StatusBadGateway)
+		return
+	}
+
+	guilds, err := s.oauth.getGuilds(r.Context(), accessToken)
+	if err != nil {
+		slog.Error("failed to get guilds", "error", err, "user_id", user.ID)
+		http.Error(w, "Failed to sign in with Discord", http.StatusBadGateway)
+		return
+	}
+
+	id := s.sessions.create(user, guilds)
+	http.SetCookie(w, s.cookie(sessionCookie, id, sessionTTL))
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		s.sessions.delete(c.Value)
+	}
+	http.SetCookie(w, s.cookie(sessionCookie, "", -1))
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+type guildPage struct {
+	User      User
+	Guild     guildLink
+	Total     int
+	Weeks     []week
+	Movers    []mover
+	Senders   []entry
+	Receivers []entry
+	Channels  []entry
+	Streaks   []streak
+	Emojis    []emoji
+}
+
+// week is the number of reactions in a week, with its height in the trend chart as a percentage of the busiest week
+type week struct {
+	Start   time.Time
+	Count   int
+	Percent int
+}
+
+type mover struct {
+	emoji
+	PreviousCount int
+}
+
+type entry struct {
+	Name  string
+	Count int
+}
+
+type streak struct {
+	Name    string
+	Current int
+	Longest int
+}
+
+// emoji is an emoji to display, either as text for unicode emojis or as an image for custom emojis
+type emoji struct {
+	Text     string
+	Name     string
+	ImageURL string
+	Count    int
+}
+
+func (s *Server) guild(w http.ResponseWriter, r *http.Request) {
+	sess := s.session(r)
+	if sess == nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	guildID := r.PathValue("guild")
+	g, ok := sess.guild(guildID)
+	if !ok || !s.directory.HasGuild(guildID) {
+		// guilds the user is not a member of are indistinguishable from guilds which do not exist
+		http.NotFound(w, r)
+		return
+	}
+
+	page, err := s.guildPage(r.Context(), sess.user, g)
+	if err != nil {
+		slog.Error("failed to get dashboard stats", "error", err, "guild_id", guildID)
+		http.Error(w, "Failed to get stats", http.StatusInternalServerError)
+		return
+	}
+
+	s.render(w, "guild.html", page)
+}
+
+func (s *Server) guildPage(ctx context.Context, user User, g Guild) (*guildPage, error) {
+	page := &guildPage{User: user, Guild: guildLink{Guild: g, IconURL: guildIconURL(g)}}
+
+	settings, err := s.settings.GetSettings(ctx, g.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := s.settings.GetTimezone(ctx, g.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	guildStats, err := s.stats.GetGuildStats(ctx, g.ID, stats.DateRange{}, settings.LeaderboardSize)
+	if err != nil {
+		return nil, err
+	}
+	page.Total = guildStats.TotalReactions
+	page.Senders = s.userEntries(g.ID, guildStats.TopSenders)
+	page.Receivers = s.userEntries(g.ID, guildStats.TopReceivers)
+
+	if page.Weeks, err = s.weeks(ctx, g.ID, loc); err != nil {
+		return nil, err
+	}
+
+	if page.Movers, err = s.movers(ctx, g.ID, loc); err != nil {
+		return nil, err
+	}
+
+	channels, err := s.stats.GetTopChannels(ctx, g.ID, stats.DateRange{}, settings.LeaderboardSize)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range channels {
+		page.Channels = append(page.Channels, entry{Name: "#" + s.directory.ChannelName(g.ID, c.ChannelID), Count: c.Count})
+	}
+
+	streaks, err := s.stats.GetStreaks(ctx, g.ID, false, settings.LeaderboardSize)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range streaks {
+		page.Streaks = append(page.Streaks, streak{Name: s.userName(g.ID, st.UserID), Current: st.Current, Longest: st.Longest})
+	}
+
+	emojis, err := s.stats.GetTopEmojis(ctx, g.ID, stats.DateRange{}, gallerySize)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range emojis {
+		page.Emojis = append(page.Emojis, newEmoji(e))
+	}
+
+	return page, nil
+}
+
+// weeks returns the reactions in each of the last trendWeeks weeks, oldest first. Weeks end today, in the guild's
+// timezone as in /stats
+func (s *Server) weeks(ctx context.Context, guildID string, loc *time.Location) ([]week, error) {
+	end := s.tomorrow(loc)
+
+	weeks := make([]week, trendWeeks)
+	busiest := 0
+	for i := range weeks {
+		start := end.AddDate(0, 0, -7*(trendWeeks-i))
+		weekEnd := start.AddDate(0, 0, 7)
+
+		// only the total is shown
+		guildStats, err := s.stats.GetGuildStats(ctx, guildID, stats.DateRange{Start: &start, End: &weekEnd}, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		weeks[i] = week{Start: start, Count: guildStats.TotalReactions}
+		busiest = max(busiest, guildStats.TotalReactions)
+	}
+
+	if busiest > 0 {
+		for i := range weeks {
+			weeks[i].Percent = weeks[i].Count * 100 / busiest
+		}
+	}
+
+	return weeks, nil
+}
+
+// movers returns the emojis whose usage grew the most in the last week compared to the week before, in the guild's
+// timezone
+func (s *Server) movers(ctx context.Context, guildID string, loc *time.Location) ([]mover, error) {
+	end := s.tomorrow(loc)
+	start := end.AddDate(0, 0, -7)
+	previousStart := start.AddDate(0, 0, -7)
+
+	current, err := s.stats.GetTopEmojis(ctx, guildID, stats.DateRange{Start: &start, End: &end}, gallerySize)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.stats.GetTopEmojis(ctx, guildID, stats.DateRange{Start: &previousStart, End: &start}, gallerySize)
+	if err != nil {
+		return nil, err
+	}
+
+	var movers []mover
+	for _, m := range stats.BiggestMovers(current, previous, moversSize) {
+		movers = append(movers, mover{
+			emoji:         newEmoji(stats.EmojiCount{EmojiID: m.EmojiID, IsDefault: m.IsDefault, Count: m.Count}),
+			PreviousCount: m.PreviousCount,
+		})
+	}
+
+	return movers, nil
+}
+
+// tomorrow returns the start of tomorrow in loc, which is the end of ranges which include today
+func (s *Server) tomorrow(loc *time.Location) time.Time {
+	now := s.now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+}
+
+func (s *Server) userEntries(guildID string, users []stats.UserCount) []entry {
+	entries := make([]entry, 0, len(users))
+	for _, u := range users {
+		entries = append(entries, entry{Name: s.userName(guildID, u.UserID), Count: u.Count})
+	}
+	return entries
+}
+
+func (s *Server) userName(guildID, userID string) string {
+	if userID == privacy.AnonymousUserID {
+		return privacy.AnonymousName
+	}
+	return s.directory.UserName(guildID, userID)
+}
+
+// session returns the signed in user's session, or nil if they are not signed in
+func (s *Server) session(r *http.Request) *session {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	return s.sessions.get(c.Value)
+}
+
+// cookie returns a cookie which expires after ttl, or is deleted if ttl is negative
+func (s *Server) cookie(name, value string, ttl time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (s *Server) render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		slog.Error("failed to render dashboard", "error", err, "template", name)
+	}
+}
+
+// newEmoji parses an emoji in message format, e.g. 👍 or <:name:id>
+func newEmoji(e stats.EmojiCount) emoji {
+	if e.IsDefault {
+		return emoji{Text: e.EmojiID, Name: e.EmojiID, Count: e.Count}
+	}
+
+	parts := strings.Split(strings.Trim(e.EmojiID, "<>"), ":")
+	if len(parts) != 3 {
+		return emoji{Text: e.EmojiID, Name: e.EmojiID, Count: e.Count}
+	}
+
+	imageURL := discordgo.EndpointEmoji(parts[2])
+	if parts[0] == "a" {
+		imageURL = discordgo.EndpointEmojiAnimated(parts[2])
+	}
+
+	return emoji{Name: parts[1], ImageURL: imageURL, Count: e.Count}
+}
+
+// initial returns the first character of a name, for guilds without an icon
+func initial(name string) string {
+	for _, r := range name {
+		return string(r)
+	}
+	return ""
+}
+
+func guildIconURL(g Guild) string {
+	if g.Icon == "" {
+		return ""
+	}
+	return discordgo.EndpointGuildIcon(g.ID, g.Icon)
+}
@@ -0,0 +1,326 @@
+package dashboard
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/dashboard/fakeoauth"
+	"github.com/elliotwms/emojistats/internal/memory"
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+const (
+	guildID      = "guild1"
+	clientID     = "client"
+	clientSecret = "secret"
+)
+
+// fakeDirectory is a bot in guild1 and guild3, which knows the names of user1 and chan1
+type fakeDirectory struct{}
+
+func (fakeDirectory) HasGuild(guildID string) bool {
+	return guildID == "guild1" || guildID == "guild3"
+}
+
+func (fakeDirectory) UserName(_, userID string) string {
+	if userID == "user1" {
+		return "Alice"
+	}
+	return userID
+}
+
+func (fakeDirectory) ChannelName(_, channelID string) string {
+	if channelID == "chan1" {
+		return "general"
+	}
+	return channelID
+}
+
+type testDashboard struct {
+	server *Server
+	store  *memory.Store
+	url    string
+}
+
+// newTestDashboard serves a dashboard which signs in with a fake OAuth2 provider. The user is a member of guild1 and
+// guild2, but not guild3
+func newTestDashboard(t *testing.T) *testDashboard {
+	t.Helper()
+
+	provider := fakeoauth.New(clientID, clientSecret,
+		fakeoauth.User{ID: "user1", Username: "alice"},
+		fakeoauth.Guild{ID: "guild1", Name: "Test Guild"},
+		fakeoauth.Guild{ID: "guild2", Name: "Guild Without Bot"},
+	)
+	oauthServer := httptest.NewServer(provider.Handler())
+	t.Cleanup(oauthServer.Close)
+
+	d := &testDashboard{store: memory.NewStore()}
+
+	srv := httptest.NewUnstartedServer(nil)
+	d.url = "http://" + srv.Listener.Addr().String()
+	d.server = NewServer(d.store, d.store, fakeDirectory{}, OAuthConfig{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  d.url + "/callback",
+		URL:          oauthServer.URL,
+	})
+	srv.Config.Handler = d.server.Handler()
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return d
+}
+
+// client returns a client with a cookie jar which follows redirects
+func (d *testDashboard) client(t *testing.T) *http.Client {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	return &http.Client{Jar: jar}
+}
+
+// signIn signs in, returning the signed in client and the page it is redirected to
+func (d *testDashboard) signIn(t *testing.T) (*http.Client, string) {
+	t.Helper()
+
+	c := d.client(t)
+	status, body := get(t, c, d.url+"/login")
+	require.Equal(t, http.StatusOK, status, body)
+
+	return c, body
+}
+
+func (d *testDashboard) addReaction(t *testing.T, emojiID, senderID, receiverID string, createdAt time.Time) {
+	t.Helper()
+	require.NoError(t, d.store.AddReaction(context.Background(), stats.Reaction{
+		GuildID:        guildID,
+		ChannelID:      "chan1",
+		MessageID:      "msg1",
+		EmojiID:        emojiID,
+		IsDefault:      !strings.HasPrefix(emojiID, "<"),
+		SenderUserID:   senderID,
+		ReceiverUserID: receiverID,
+		CreatedAt:      createdAt,
+	}))
+}
+
+func get(t *testing.T, c *http.Client, url string) (int, string) {
+	t.Helper()
+
+	res, err := c.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	return res.StatusCode, string(body)
+}
+
+func TestIndex_SignedOut(t *testing.T) {
+	d := newTestDashboard(t)
+
+	status, body := get(t, d.client(t), d.url+"/")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `href="/login"`)
+}
+
+func TestSignIn(t *testing.T) {
+	d := newTestDashboard(t)
+
+	_, body := d.signIn(t)
+
+	assert.Contains(t, body, "Signed in as <strong>alice</strong>")
+	assert.Contains(t, body, `<a href="/guilds/guild1">`)
+	assert.NotContains(t, body, "guild2", "guilds without the bot should not be listed")
+}
+
+func TestSignOut(t *testing.T) {
+	d := newTestDashboard(t)
+	c, _ := d.signIn(t)
+
+	res, err := c.Post(d.url+"/logout", "", nil)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	status, body := get(t, c, d.url+"/")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `href="/login"`)
+	assert.Empty(t, d.server.sessions.sessions)
+}
+
+func TestCallback_InvalidState(t *testing.T) {
+	d := newTestDashboard(t)
+
+	status, _ := get(t, d.client(t), d.url+"/callback?state=forged&code=code")
+
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestCallback_InvalidCode(t *testing.T) {
+	d := newTestDashboard(t)
+	c := d.client(t)
+
+	// start signing in to set the state cookie, without following the redirect to the provider
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	res, err := c.Get(d.url + "/login")
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	location, err := res.Location()
+	require.NoError(t, err)
+
+	status, _ := get(t, c, d.url+"/callback?code=forged&state="+location.Query().Get("state"))
+
+	assert.Equal(t, http.StatusBadGateway, status)
+}
+
+func TestGuild(t *testing.T) {
+	d := newTestDashboard(t)
+	now := time.Now()
+	d.addReaction(t, "👍", "user1", "user2", now)
+	d.addReaction(t, "👍", "user2", "user1", now)
+	d.addReaction(t, "<:party:123>", "user1", "user2", now)
+	d.addReaction(t, "<a:dance:456>", privacy.AnonymousUserID, "user2", now.AddDate(0, 0, -10))
+	c, _ := d.signIn(t)
+
+	status, body := get(t, c, d.url+"/guilds/"+guildID)
+
+	require.Equal(t, http.StatusOK, status, body)
+	assert.Contains(t, body, "4 reactions")
+	assert.Contains(t, body, "Alice")
+	assert.Contains(t, body, privacy.AnonymousName)
+	assert.Contains(t, body, "#general")
+	assert.Contains(t, body, `<span class="emoji">👍</span>`)
+	assert.Contains(t, body, `src="https://cdn.discordapp.com/emojis/123.png"`)
+	assert.Contains(t, body, `src="https://cdn.discordapp.com/emojis/456.gif"`)
+}
+
+func TestGuild_SignedOut(t *testing.T) {
+	d := newTestDashboard(t)
+	c := d.client(t)
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	res, err := c.Get(d.url + "/guilds/" + guildID)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	assert.Equal(t, http.StatusFound, res.StatusCode)
+	assert.Equal(t, "/login", res.Header.Get("Location"))
+}
+
+func TestGuild_NotFound(t *testing.T) {
+	d := newTestDashboard(t)
+	c, _ := d.signIn(t)
+
+	for name, id := range map[string]string{
+		"not a member": "guild3",
+		"bot missing":  "guild2",
+	} {
+		t.Run(name, func(t *testing.T) {
+			status, _ := get(t, c, d.url+"/guilds/"+id)
+
+			assert.Equal(t, http.StatusNotFound, status)
+		})
+	}
+}
+
+func TestStatic(t *testing.T) {
+	d := newTestDashboard(t)
+
+	status, body := get(t, d.client(t), d.url+"/static/style.css")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, ".gallery")
+}
+
+func TestWeeks(t *testing.T) {
+	d := newTestDashboard(t)
+	today := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	d.server.now = func() time.Time { return today }
+
+	d.addReaction(t, "👍", "user1", "user2", today)
+	d.addReaction(t, "👍", "user1", "user2", today.AddDate(0, 0, -6))
+	d.addReaction(t, "👍", "user1", "user2", today.AddDate(0, 0, -7))
+	d.addReaction(t, "👍", "user1", "user2", today.AddDate(0, 0, -7*trendWeeks))
+
+	weeks, err := d.server.weeks(context.Background(), guildID, time.UTC)
+
+	require.NoError(t, err)
+	require.Len(t, weeks, trendWeeks)
+	assert.Equal(t, time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC), weeks[trendWeeks-1].Start)
+	assert.Equal(t, week{Start: weeks[trendWeeks-1].Start, Count: 2, Percent: 100}, weeks[trendWeeks-1])
+	assert.Equal(t, week{Start: weeks[trendWeeks-2].Start, Count: 1, Percent: 50}, weeks[trendWeeks-2])
+	assert.Zero(t, weeks[0].Count)
+}
+
+func TestWeeks_Timezone(t *testing.T) {
+	d := newTestDashboard(t)
+	ctx := context.Background()
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	require.NoError(t, err)
+	require.NoError(t, d.store.SetTimezone(ctx, guildID, auckland))
+
+	// 20:00 UTC on 10 June is 08:00 on 11 June in Auckland
+	now := time.Date(2024, 6, 10, 20, 0, 0, 0, time.UTC)
+	d.server.now = func() time.Time { return now }
+
+	// the day before the last week in Auckland, which would be within it in UTC
+	d.addReaction(t, "👍", "user1", "user2", time.Date(2024, 6, 4, 23, 0, 0, 0, auckland))
+
+	weeks, err := d.server.weeks(ctx, guildID, auckland)
+
+	require.NoError(t, err)
+	assert.True(t, time.Date(2024, 6, 5, 0, 0, 0, 0, auckland).Equal(weeks[trendWeeks-1].Start))
+	assert.Zero(t, weeks[trendWeeks-1].Count)
+	assert.Equal(t, 1, weeks[trendWeeks-2].Count)
+}
+
+func TestGuild_LeaderboardSize(t *testing.T) {
+	d := newTestDashboard(t)
+	now := time.Now()
+	d.addReaction(t, "👍", "user1", "user2", now)
+	d.addReaction(t, "👍", "user1", "user2", now)
+	d.addReaction(t, "👍", "user3", "user4", now)
+
+	settings := stats.DefaultSettings()
+	settings.LeaderboardSize = 1
+	require.NoError(t, d.store.SetSettings(context.Background(), guildID, settings))
+	c, _ := d.signIn(t)
+
+	status, body := get(t, c, d.url+"/guilds/"+guildID)
+
+	require.Equal(t, http.StatusOK, status, body)
+	assert.Contains(t, body, "Alice")
+	assert.NotContains(t, body, "user3", "only the top sender should be listed")
+}
+
+func TestNewEmoji(t *testing.T) {
+	tests := map[string]struct {
+		in       stats.EmojiCount
+		expected emoji
+	}{
+		"unicode":  {stats.EmojiCount{EmojiID: "👍", IsDefault: true, Count: 1}, emoji{Text: "👍", Name: "👍", Count: 1}},
+		"custom":   {stats.EmojiCount{EmojiID: "<:party:123>", Count: 2}, emoji{Name: "party", ImageURL: "https://cdn.discordapp.com/emojis/123.png", Count: 2}},
+		"animated": {stats.EmojiCount{EmojiID: "<a:dance:456>", Count: 3}, emoji{Name: "dance", ImageURL: "https://cdn.discordapp.com/emojis/456.gif", Count: 3}},
+		"invalid":  {stats.EmojiCount{EmojiID: "<party>", Count: 4}, emoji{Text: "<party>", Name: "<party>", Count: 4}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, newEmoji(tc.in))
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package dashboard
+
+import "github.com/bwmarrin/discordgo"
+
+// StateDirectory resolves names from the bot's state cache, falling back to IDs for users and channels which are not
+// cached
+type StateDirectory struct {
+	State *discordgo.State
+}
+
+// HasGuild returns whether the guild is in the state, which it is for every guild the bot is in
+func (d StateDirectory) HasGuild(guildID string) bool {
+	_, err := d.State.Guild(guildID)
+	return err == nil
+}
+
+// UserName returns the member's display name in the guild
+func (d StateDirectory) UserName(guildID, userID string) string {
+	m, err := d.State.Member(guildID, userID)
+	if err != nil || m.User == nil {
+		return userID
+	}
+	return m.DisplayName()
+}
+
+// ChannelName returns the channel's name
+func (d StateDirectory) ChannelName(_, channelID string) string {
+	c, err := d.State.Channel(channelID)
+	if err != nil {
+		return channelID
+	}
+	return c.Name
+}
@@ -0,0 +1,155 @@
+// Package fakeoauth is a fake of Discord's OAuth2 provider for testing the dashboard locally. It signs in a single
+// configured user without prompting, who is a member of the configured guilds
+package fakeoauth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// User is the signed in user
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// Guild is a guild the user is a member of
+type Guild struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Icon string `json:"icon,omitempty"`
+}
+
+// Provider implements the authorization code flow and the user endpoints of the Discord API used by the dashboard
+type Provider struct {
+	ClientID     string
+	ClientSecret string
+	User         User
+	Guilds       []Guild
+
+	mu sync.Mutex
+	// codes maps authorization codes to the redirect URI they were issued for
+	codes  map[string]string
+	tokens map[string]bool
+}
+
+// New creates a provider which accepts the given client credentials
+func New(clientID, clientSecret string, user User, guilds ...Guild) *Provider {
+	return &Provider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		User:         user,
+		Guilds:       guilds,
+		codes:        map[string]string{},
+		tokens:       map[string]bool{},
+	}
+}
+
+// Handler returns the provider's routes, which are served at the same paths as Discord's
+func (p *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /oauth2/authorize", p.authorize)
+	mux.HandleFunc("POST /api/oauth2/token", p.token)
+	mux.HandleFunc("GET /api/v10/users/@me", p.authenticated(func() any { return p.User }))
+	mux.HandleFunc("GET /api/v10/users/@me/guilds", p.authenticated(func() any { return p.Guilds }))
+	return mux
+}
+
+// authorize immediately redirects back to the client with an authorization code, as if the user had approved it
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.ClientID || q.Get("response_type") != "code" {
+		http.Error(w, "invalid client or response type", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = redirectURI.String()
+	p.mu.Unlock()
+
+	v := redirectURI.Query()
+	v.Set("code", code)
+	v.Set("state", q.Get("state"))
+	redirectURI.RawQuery = v.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// token exchanges an authorization code for an access token. Codes may only be used once
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != p.ClientID || clientSecret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	code := r.PostFormValue("code")
+
+	p.mu.Lock()
+	redirectURI, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	if !ok || redirectURI != r.PostFormValue("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	accessToken := randomString()
+	p.mu.Lock()
+	p.tokens[accessToken] = true
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   604800,
+		"scope":        "identify guilds",
+	})
+}
+
+func (p *Provider) authenticated(body func() any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		p.mu.Lock()
+		ok := p.tokens[token]
+		p.mu.Unlock()
+
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"message": "401: Unauthorized", "code": 0})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, body())
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,146 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultOAuthURL is the base URL of Discord's OAuth2 authorization server and API
+const DefaultOAuthURL = "https://discord.com"
+
+// scopes are the OAuth2 scopes requested from users: their identity and the guilds they are members of
+const scopes = "identify guilds"
+
+// OAuthConfig configures signing in with Discord
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the dashboard's callback URL, which must be registered with the Discord application
+	RedirectURL string
+	// URL is the base URL of the OAuth2 provider. It defaults to DefaultOAuthURL, and may be overridden to use a fake
+	// provider
+	URL string
+}
+
+// User is a Discord user, as returned by the API
+type User struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+}
+
+// Name returns the user's display name
+func (u User) Name() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+// Guild is a guild the user is a member of, as returned by the API
+type Guild struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Icon string `json:"icon"`
+}
+
+// oauthClient signs users in with the OAuth2 authorization code flow
+type oauthClient struct {
+	config OAuthConfig
+	http   *http.Client
+}
+
+func newOAuthClient(config OAuthConfig) *oauthClient {
+	if config.URL == "" {
+		config.URL = DefaultOAuthURL
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+
+	return &oauthClient{config: config, http: http.DefaultClient}
+}
+
+// authorizeURL returns the URL to send users to in order to sign in
+func (c *oauthClient) authorizeURL(state string) string {
+	v := url.Values{
+		"client_id":     {c.config.ClientID},
+		"redirect_uri":  {c.config.RedirectURL},
+		"response_type": {"code"},
+		"scope":         {scopes},
+		"state":         {state},
+		"prompt":        {"none"},
+	}
+	return c.config.URL + "/oauth2/authorize?" + v.Encode()
+}
+
+// exchange exchanges an authorization code for an access token
+func (c *oauthClient) exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {c.config.RedirectURL},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.URL+"/api/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.config.ClientID, c.config.ClientSecret)
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.do(req, &token); err != nil {
+		return "", fmt.Errorf("exchange code: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("exchange code: no access token")
+	}
+
+	return token.AccessToken, nil
+}
+
+// getUser returns the user who granted the access token
+func (c *oauthClient) getUser(ctx context.Context, accessToken string) (User, error) {
+	var user User
+	err := c.get(ctx, accessToken, "/users/@me", &user)
+	return user, err
+}
+
+// getGuilds returns the guilds the user who granted the access token is a member of
+func (c *oauthClient) getGuilds(ctx context.Context, accessToken string) ([]Guild, error) {
+	var guilds []Guild
+	err := c.get(ctx, accessToken, "/users/@me/guilds", &guilds)
+	return guilds, err
+}
+
+func (c *oauthClient) get(ctx context.Context, accessToken, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.URL+"/api/v10"+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	if err := c.do(req, v); err != nil {
+		return fmt.Errorf("get %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *oauthClient) do(req *http.Request, v any) error {
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,91 @@
+package dashboard
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// session is a signed in user and the guilds they were a member of when they signed in
+type session struct {
+	user      User
+	guilds    []Guild
+	expiresAt time.Time
+}
+
+// guild returns the user's guild with the given ID
+func (s *session) guild(guildID string) (Guild, bool) {
+	for _, g := range s.guilds {
+		if g.ID == guildID {
+			return g, true
+		}
+	}
+	return Guild{}, false
+}
+
+// sessions stores sessions in memory, so users must sign in again after a restart
+type sessions struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+func newSessions(ttl time.Duration) *sessions {
+	return &sessions{sessions: map[string]*session{}, ttl: ttl, now: time.Now}
+}
+
+// create stores a new session and returns its ID
+func (s *sessions) create(user User, guilds []Guild) string {
+	id := randomString()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune()
+	s.sessions[id] = &session{user: user, guilds: guilds, expiresAt: s.now().Add(s.ttl)}
+
+	return id
+}
+
+// get returns the session with the given ID, or nil if it does not exist or has expired
+func (s *sessions) get(id string) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil
+	}
+	if !s.now().Before(sess.expiresAt) {
+		delete(s.sessions, id)
+		return nil
+	}
+
+	return sess
+}
+
+func (s *sessions) delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+}
+
+// prune deletes expired sessions. It must be called with the lock held
+func (s *sessions) prune() {
+	now := s.now()
+	for id, sess := range s.sessions {
+		if !now.Before(sess.expiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// randomString returns a random URL safe string, for session IDs and OAuth2 states
+func randomString() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
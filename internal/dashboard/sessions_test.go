@@ -0,0 +1,28 @@
+package dashboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions_Expiry(t *testing.T) {
+	s := newSessions(time.Hour)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	id := s.create(User{ID: "user1"}, []Guild{{ID: "guild1"}})
+
+	sess := s.get(id)
+	require.NotNil(t, sess)
+	_, ok := sess.guild("guild1")
+	assert.True(t, ok)
+	_, ok = sess.guild("guild2")
+	assert.False(t, ok)
+
+	now = now.Add(time.Hour)
+	assert.Nil(t, s.get(id))
+	assert.Empty(t, s.sessions)
+}
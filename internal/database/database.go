@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"embed"
 	"fmt"
+	"io/fs"
 
 	_ "github.com/lib/pq"
 	"github.com/pressly/goose/v3"
@@ -38,3 +39,19 @@ func Migrate(db *sql.DB) error {
 
 	return nil
 }
+
+// Provider returns a migration provider for the Postgres migrations, for applying, rolling back and reporting on
+// individual migrations
+func Provider(db *sql.DB) (*goose.Provider, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, fsys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration provider: %w", err)
+	}
+
+	return provider, nil
+}
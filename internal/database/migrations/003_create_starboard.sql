@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE starboard_configs (
+    guild_id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL,
+    emoji_id TEXT NOT NULL DEFAULT '',
+    threshold INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- starboard_messages records which messages have been reposted so restarts do not post them twice
+CREATE TABLE starboard_messages (
+    guild_id TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    channel_id TEXT NOT NULL,
+    starboard_message_id TEXT,
+    reaction_count INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (guild_id, message_id)
+);
+
+-- +goose Down
+DROP TABLE starboard_messages;
+DROP TABLE starboard_configs;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- last_run_at is used to claim each run so restarts and multiple replicas do not post a digest twice
+CREATE TABLE digests (
+    guild_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    channel_id TEXT NOT NULL,
+    schedule TEXT NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (guild_id, kind)
+);
+
+-- +goose Down
+DROP TABLE digests;
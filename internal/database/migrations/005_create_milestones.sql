@@ -0,0 +1,25 @@
+-- +goose Up
+-- thresholds are stored as comma separated lists of counts
+CREATE TABLE milestone_configs (
+    guild_id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL,
+    sender_thresholds TEXT NOT NULL,
+    receiver_thresholds TEXT NOT NULL,
+    emoji_thresholds TEXT NOT NULL,
+    message_thresholds TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- milestones records each announced milestone so that it is only announced once
+CREATE TABLE milestones (
+    guild_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    threshold INTEGER NOT NULL,
+    announced_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (guild_id, kind, subject_id, threshold)
+);
+
+-- +goose Down
+DROP TABLE milestones;
+DROP TABLE milestone_configs;
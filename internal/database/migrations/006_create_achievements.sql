@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE achievements (
+    guild_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    achievement_id TEXT NOT NULL,
+    unlocked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (guild_id, user_id, achievement_id)
+);
+
+-- achievement_configs holds the channel unlocked achievements are announced in, if announcements are enabled
+CREATE TABLE achievement_configs (
+    guild_id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE achievement_configs;
+DROP TABLE achievements;
@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE role_rewards (
+    guild_id TEXT NOT NULL,
+    role_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    metric TEXT NOT NULL,
+    count INTEGER NOT NULL,
+    window_days INTEGER NOT NULL DEFAULT 0,
+    last_evaluated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (guild_id, role_id)
+);
+
+-- role_reward_members tracks which members hold a role because of a reward, so only those are removed
+CREATE TABLE role_reward_members (
+    guild_id TEXT NOT NULL,
+    role_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    PRIMARY KEY (guild_id, role_id, user_id)
+);
+
+CREATE TABLE role_reward_audit (
+    id BIGSERIAL PRIMARY KEY,
+    guild_id TEXT NOT NULL,
+    role_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_role_reward_audit_guild_created ON role_reward_audit (guild_id, created_at);
+
+-- +goose Down
+DROP TABLE role_reward_audit;
+DROP TABLE role_reward_members;
+DROP TABLE role_rewards;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- guild_timezones holds the timezone used to decide which day a reaction belongs to. Guilds without a row use UTC
+CREATE TABLE guild_timezones (
+    guild_id TEXT PRIMARY KEY,
+    timezone TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE guild_timezones;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- privacy_opt_outs holds the users whose reactions are not stored. Opting out applies in every guild
+CREATE TABLE privacy_opt_outs (
+    user_id TEXT PRIMARY KEY,
+    opted_out_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- privacy_audit records every privacy request, including those which deleted the user's data
+CREATE TABLE privacy_audit (
+    id BIGSERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    guild_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    deleted INTEGER NOT NULL DEFAULT 0,
+    anonymised INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_privacy_audit_user ON privacy_audit (user_id, created_at);
+
+-- +goose Down
+DROP TABLE privacy_audit;
+DROP TABLE privacy_opt_outs;
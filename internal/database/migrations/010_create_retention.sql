@@ -0,0 +1,26 @@
+-- +goose Up
+-- retention_policies holds how long each guild keeps raw reactions. Guilds without a policy keep them forever
+CREATE TABLE retention_policies (
+    guild_id TEXT PRIMARY KEY,
+    days INTEGER NOT NULL CHECK (days > 0),
+    rollup BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- reaction_aggregates holds daily counts of pruned reactions so that long-range totals survive pruning.
+-- created_at is the start of the UTC day the reactions were made on
+CREATE TABLE reaction_aggregates (
+    guild_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    emoji_id TEXT NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    sender_user_id TEXT NOT NULL,
+    receiver_user_id TEXT NOT NULL,
+    count INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, created_at, emoji_id, sender_user_id, receiver_user_id)
+);
+
+-- +goose Down
+DROP TABLE reaction_aggregates;
+DROP TABLE retention_policies;
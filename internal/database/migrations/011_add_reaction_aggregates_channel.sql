@@ -0,0 +1,19 @@
+-- +goose Up
+-- Keep the channel of pruned reactions so that channel totals survive pruning. Existing aggregates have no channel
+ALTER TABLE reaction_aggregates ADD COLUMN channel_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE reaction_aggregates DROP CONSTRAINT reaction_aggregates_pkey;
+ALTER TABLE reaction_aggregates ADD PRIMARY KEY (guild_id, created_at, emoji_id, sender_user_id, receiver_user_id, channel_id);
+
+-- +goose Down
+-- Merge aggregates which only differ by channel before restoring the original key
+CREATE TEMPORARY TABLE merged_aggregates AS
+SELECT guild_id, created_at, emoji_id, bool_or(is_default) AS is_default, sender_user_id, receiver_user_id, SUM(count)::integer AS count
+FROM reaction_aggregates
+GROUP BY guild_id, created_at, emoji_id, sender_user_id, receiver_user_id;
+DELETE FROM reaction_aggregates;
+ALTER TABLE reaction_aggregates DROP CONSTRAINT reaction_aggregates_pkey;
+ALTER TABLE reaction_aggregates DROP COLUMN channel_id;
+ALTER TABLE reaction_aggregates ADD PRIMARY KEY (guild_id, created_at, emoji_id, sender_user_id, receiver_user_id);
+INSERT INTO reaction_aggregates (guild_id, created_at, emoji_id, is_default, sender_user_id, receiver_user_id, count)
+SELECT guild_id, created_at, emoji_id, is_default, sender_user_id, receiver_user_id, count FROM merged_aggregates;
+DROP TABLE merged_aggregates;
@@ -0,0 +1,146 @@
+-- +goose Up
+-- Daily rollups of reactions and pruned reaction aggregates, by UTC day. They are maintained by triggers so that they
+-- always match the rows they summarise, whichever code path writes them
+CREATE TABLE reaction_rollups_emoji (
+    guild_id TEXT NOT NULL,
+    day DATE NOT NULL,
+    emoji_id TEXT NOT NULL,
+    is_default BOOLEAN NOT NULL,
+    count INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, day, emoji_id, is_default)
+);
+
+CREATE TABLE reaction_rollups_sender (
+    guild_id TEXT NOT NULL,
+    day DATE NOT NULL,
+    emoji_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    count INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, day, emoji_id, user_id)
+);
+
+CREATE TABLE reaction_rollups_receiver (
+    guild_id TEXT NOT NULL,
+    day DATE NOT NULL,
+    emoji_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    count INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, day, emoji_id, user_id)
+);
+
+CREATE TABLE reaction_rollups_channel (
+    guild_id TEXT NOT NULL,
+    day DATE NOT NULL,
+    channel_id TEXT NOT NULL,
+    count INTEGER NOT NULL,
+    PRIMARY KEY (guild_id, day, channel_id)
+);
+
+-- +goose StatementBegin
+CREATE FUNCTION rollup_reactions(
+    p_guild TEXT, p_day DATE, p_emoji TEXT, p_default BOOLEAN, p_sender TEXT, p_receiver TEXT, p_channel TEXT, p_count INTEGER
+) RETURNS void AS $$
+BEGIN
+    INSERT INTO reaction_rollups_emoji (guild_id, day, emoji_id, is_default, count)
+    VALUES (p_guild, p_day, p_emoji, p_default, p_count)
+    ON CONFLICT (guild_id, day, emoji_id, is_default) DO UPDATE SET count = reaction_rollups_emoji.count + EXCLUDED.count;
+
+    INSERT INTO reaction_rollups_sender (guild_id, day, emoji_id, user_id, count)
+    VALUES (p_guild, p_day, p_emoji, p_sender, p_count)
+    ON CONFLICT (guild_id, day, emoji_id, user_id) DO UPDATE SET count = reaction_rollups_sender.count + EXCLUDED.count;
+
+    INSERT INTO reaction_rollups_receiver (guild_id, day, emoji_id, user_id, count)
+    VALUES (p_guild, p_day, p_emoji, p_receiver, p_count)
+    ON CONFLICT (guild_id, day, emoji_id, user_id) DO UPDATE SET count = reaction_rollups_receiver.count + EXCLUDED.count;
+
+    INSERT INTO reaction_rollups_channel (guild_id, day, channel_id, count)
+    VALUES (p_guild, p_day, p_channel, p_count)
+    ON CONFLICT (guild_id, day, channel_id) DO UPDATE SET count = reaction_rollups_channel.count + EXCLUDED.count;
+
+    -- remove emptied rollups so that they match the raw rows exactly
+    IF p_count < 0 THEN
+        DELETE FROM reaction_rollups_emoji
+        WHERE guild_id = p_guild AND day = p_day AND emoji_id = p_emoji AND is_default = p_default AND count <= 0;
+        DELETE FROM reaction_rollups_sender
+        WHERE guild_id = p_guild AND day = p_day AND emoji_id = p_emoji AND user_id = p_sender AND count <= 0;
+        DELETE FROM reaction_rollups_receiver
+        WHERE guild_id = p_guild AND day = p_day AND emoji_id = p_emoji AND user_id = p_receiver AND count <= 0;
+        DELETE FROM reaction_rollups_channel
+        WHERE guild_id = p_guild AND day = p_day AND channel_id = p_channel AND count <= 0;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION rollup_reactions_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM rollup_reactions(OLD.guild_id, (OLD.created_at AT TIME ZONE 'UTC')::date, COALESCE(OLD.emoji_id, ''),
+            OLD.is_default, OLD.sender_user_id, OLD.receiver_user_id, OLD.channel_id, -1);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM rollup_reactions(NEW.guild_id, (NEW.created_at AT TIME ZONE 'UTC')::date, COALESCE(NEW.emoji_id, ''),
+            NEW.is_default, NEW.sender_user_id, NEW.receiver_user_id, NEW.channel_id, 1);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION rollup_reaction_aggregates_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM rollup_reactions(OLD.guild_id, (OLD.created_at AT TIME ZONE 'UTC')::date, OLD.emoji_id,
+            OLD.is_default, OLD.sender_user_id, OLD.receiver_user_id, OLD.channel_id, -OLD.count);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM rollup_reactions(NEW.guild_id, (NEW.created_at AT TIME ZONE 'UTC')::date, NEW.emoji_id,
+            NEW.is_default, NEW.sender_user_id, NEW.receiver_user_id, NEW.channel_id, NEW.count);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Backfill from the existing rows before the triggers take over
+WITH counted AS (
+    SELECT guild_id, (created_at AT TIME ZONE 'UTC')::date AS day, COALESCE(emoji_id, '') AS emoji_id, is_default,
+        sender_user_id, receiver_user_id, channel_id, 1 AS count
+    FROM reactions
+    UNION ALL
+    SELECT guild_id, (created_at AT TIME ZONE 'UTC')::date, emoji_id, is_default,
+        sender_user_id, receiver_user_id, channel_id, count
+    FROM reaction_aggregates
+), emoji AS (
+    INSERT INTO reaction_rollups_emoji (guild_id, day, emoji_id, is_default, count)
+    SELECT guild_id, day, emoji_id, is_default, SUM(count) FROM counted GROUP BY guild_id, day, emoji_id, is_default
+), sender AS (
+    INSERT INTO reaction_rollups_sender (guild_id, day, emoji_id, user_id, count)
+    SELECT guild_id, day, emoji_id, sender_user_id, SUM(count) FROM counted GROUP BY guild_id, day, emoji_id, sender_user_id
+), receiver AS (
+    INSERT INTO reaction_rollups_receiver (guild_id, day, emoji_id, user_id, count)
+    SELECT guild_id, day, emoji_id, receiver_user_id, SUM(count) FROM counted GROUP BY guild_id, day, emoji_id, receiver_user_id
+)
+INSERT INTO reaction_rollups_channel (guild_id, day, channel_id, count)
+SELECT guild_id, day, channel_id, SUM(count) FROM counted GROUP BY guild_id, day, channel_id;
+
+CREATE TRIGGER reactions_rollup
+AFTER INSERT OR UPDATE OR DELETE ON reactions
+FOR EACH ROW EXECUTE FUNCTION rollup_reactions_trigger();
+
+CREATE TRIGGER reaction_aggregates_rollup
+AFTER INSERT OR UPDATE OR DELETE ON reaction_aggregates
+FOR EACH ROW EXECUTE FUNCTION rollup_reaction_aggregates_trigger();
+
+-- +goose Down
+DROP TRIGGER reaction_aggregates_rollup ON reaction_aggregates;
+DROP TRIGGER reactions_rollup ON reactions;
+DROP FUNCTION rollup_reaction_aggregates_trigger();
+DROP FUNCTION rollup_reactions_trigger();
+DROP FUNCTION rollup_reactions(TEXT, DATE, TEXT, BOOLEAN, TEXT, TEXT, TEXT, INTEGER);
+DROP TABLE reaction_rollups_channel;
+DROP TABLE reaction_rollups_receiver;
+DROP TABLE reaction_rollups_sender;
+DROP TABLE reaction_rollups_emoji;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- query_cache is the shared stats cache for running multiple replicas. It is unlogged as it can be rebuilt at any time
+CREATE UNLOGGED TABLE query_cache (
+    guild_id TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (guild_id, key)
+);
+
+-- +goose Down
+DROP TABLE query_cache;
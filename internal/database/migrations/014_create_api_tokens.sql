@@ -0,0 +1,16 @@
+-- +goose Up
+-- api_tokens authorise read-only access to a guild's stats over the HTTP API. Only a hash of each token is stored
+CREATE TABLE api_tokens (
+    id SERIAL PRIMARY KEY,
+    guild_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    token_hash BYTEA NOT NULL UNIQUE,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_api_tokens_guild ON api_tokens (guild_id);
+
+-- +goose Down
+DROP TABLE api_tokens;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- guild_settings are each guild's preferences, set with /config. Guilds without a row use the defaults
+CREATE TABLE guild_settings (
+    guild_id TEXT PRIMARY KEY,
+    public_responses BOOLEAN NOT NULL DEFAULT FALSE,
+    leaderboard_size INTEGER NOT NULL DEFAULT 10,
+    count_bots BOOLEAN NOT NULL DEFAULT TRUE,
+    excluded_channel_ids TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE guild_settings;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Milestones total a user's or an emoji's reactions across every day as reactions are ingested
+CREATE INDEX idx_reaction_rollups_emoji_subject ON reaction_rollups_emoji (guild_id, emoji_id);
+CREATE INDEX idx_reaction_rollups_sender_subject ON reaction_rollups_sender (guild_id, user_id);
+CREATE INDEX idx_reaction_rollups_receiver_subject ON reaction_rollups_receiver (guild_id, user_id);
+
+-- +goose Down
+DROP INDEX idx_reaction_rollups_receiver_subject;
+DROP INDEX idx_reaction_rollups_sender_subject;
+DROP INDEX idx_reaction_rollups_emoji_subject;
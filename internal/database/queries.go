@@ -0,0 +1,14 @@
+package database
+
+// CountedReactions is the raw reactions together with the daily aggregates of reactions which have been pruned, with a
+// count column to sum in place of COUNT(*), so that counts survive pruning. Pruned reactions are counted as made at the
+// start of their UTC day, and have no message. Aggregates from before channels were kept have an empty channel ID
+const CountedReactions = `(
+		SELECT guild_id, emoji_id, is_default, sender_user_id, receiver_user_id, channel_id, created_at, 1 AS count FROM reactions
+		UNION ALL
+		SELECT guild_id, emoji_id, is_default, sender_user_id, receiver_user_id, channel_id, created_at, count FROM reaction_aggregates
+	) counted`
+
+// NotIgnored excludes reactions in the channels a guild ignores, including historical reactions made before the channel
+// was ignored. It must follow a filter on the guild ID, which must be the first argument
+const NotIgnored = ` AND channel_id NOT IN (SELECT channel_id FROM ignored_channels WHERE guild_id = $1)`
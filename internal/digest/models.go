@@ -0,0 +1,54 @@
+package digest
+
+import "time"
+
+// Kind is the period a digest covers
+type Kind string
+
+const (
+	KindWeekly  Kind = "weekly"
+	KindMonthly Kind = "monthly"
+)
+
+// DefaultSchedule returns the cron schedule used when none is configured
+func (k Kind) DefaultSchedule() string {
+	if k == KindMonthly {
+		return "0 9 1 * *"
+	}
+	return "0 9 * * 1"
+}
+
+// Start returns the start of the period covered by a digest ending at end, counting days and months in end's location
+func (k Kind) Start(end time.Time) time.Time {
+	if k == KindMonthly {
+		return end.AddDate(0, -1, 0)
+	}
+	return end.AddDate(0, 0, -7)
+}
+
+// Period returns the period covered by a digest posted at the given time. It covers whole days in the guild's timezone,
+// ending at the start of the day it is posted, so that its totals match /stats for the same dates
+func (k Kind) Period(at time.Time, loc *time.Location) (start, end time.Time) {
+	local := at.In(loc)
+	end = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	return k.Start(end), end
+}
+
+// Title returns the heading used for a digest post
+func (k Kind) Title() string {
+	if k == KindMonthly {
+		return "Monthly Reaction Digest"
+	}
+	return "Weekly Reaction Digest"
+}
+
+// Config is a digest configured for a guild
+type Config struct {
+	GuildID   string
+	Kind      Kind
+	ChannelID string
+	// Schedule is a standard five field cron expression, evaluated in the guild's timezone
+	Schedule string
+	// LastRunAt is when the digest was last posted, or when it was configured if it has never run
+	LastRunAt time.Time
+}
@@ -0,0 +1,107 @@
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Repository handles database queries for digests
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository creates a new digest repository
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetConfigs retrieves the digests configured for a guild. If guildID is empty digests for all guilds are returned
+func (r *Repository) GetConfigs(ctx context.Context, guildID string) ([]Config, error) {
+	query := `
+		SELECT guild_id, kind, channel_id, schedule, COALESCE(last_run_at, created_at)
+		FROM digests`
+	var args []any
+
+	if guildID != "" {
+		args = append(args, guildID)
+		query += ` WHERE guild_id = $1`
+	}
+	query += ` ORDER BY guild_id, kind`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []Config
+	for rows.Next() {
+		var c Config
+		if err := rows.Scan(&c.GuildID, &c.Kind, &c.ChannelID, &c.Schedule, &c.LastRunAt); err != nil {
+			return nil, err
+		}
+		results = append(results, c)
+	}
+	return results, rows.Err()
+}
+
+// SaveConfig creates or updates a digest for a guild
+func (r *Repository) SaveConfig(ctx context.Context, c Config) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO digests (guild_id, kind, channel_id, schedule)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (guild_id, kind) DO UPDATE
+		SET channel_id = EXCLUDED.channel_id, schedule = EXCLUDED.schedule`,
+		c.GuildID,
+		c.Kind,
+		c.ChannelID,
+		c.Schedule,
+	)
+	return err
+}
+
+// DeleteConfig removes a digest from a guild
+func (r *Repository) DeleteConfig(ctx context.Context, guildID string, kind Kind) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM digests WHERE guild_id = $1 AND kind = $2`, guildID, kind)
+	return err
+}
+
+// ClaimRun marks a digest as run at the given time. It returns false if the digest has been run since it was read,
+// for example by another replica, in which case it should not be posted
+func (r *Repository) ClaimRun(ctx context.Context, c Config, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE digests
+		SET last_run_at = $3
+		WHERE guild_id = $1 AND kind = $2 AND COALESCE(last_run_at, created_at) = $4`,
+		c.GuildID,
+		c.Kind,
+		at,
+		c.LastRunAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// ReleaseRun restores a digest's last run after the run claimed at the given time failed, so that it is tried again. It
+// does nothing if the digest has been run since
+func (r *Repository) ReleaseRun(ctx context.Context, c Config, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE digests
+		SET last_run_at = $4
+		WHERE guild_id = $1 AND kind = $2 AND last_run_at = $3`,
+		c.GuildID,
+		c.Kind,
+		at,
+		c.LastRunAt,
+	)
+	return err
+}
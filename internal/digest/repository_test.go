@@ -0,0 +1,83 @@
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+)
+
+var testDB *sql.DB
+
+func setupTest(t *testing.T) (*Repository, string, func()) {
+	t.Helper()
+
+	testDB = dbtest.Open(t)
+
+	guildID := "test-guild-" + time.Now().Format("20060102150405.000000000")
+
+	cleanup := func() {
+		_, _ = testDB.Exec("DELETE FROM digests WHERE guild_id = $1", guildID)
+	}
+
+	return NewRepository(testDB), guildID, cleanup
+}
+
+func TestSaveConfig(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveConfig(ctx, Config{GuildID: guildID, Kind: KindWeekly, ChannelID: "chan1", Schedule: "0 9 * * 1"}))
+	require.NoError(t, repo.SaveConfig(ctx, Config{GuildID: guildID, Kind: KindWeekly, ChannelID: "chan2", Schedule: "0 12 * * 5"}))
+	require.NoError(t, repo.SaveConfig(ctx, Config{GuildID: guildID, Kind: KindMonthly, ChannelID: "chan1", Schedule: "0 9 1 * *"}))
+
+	configs, err := repo.GetConfigs(ctx, guildID)
+
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	assert.Equal(t, KindMonthly, configs[0].Kind)
+	assert.Equal(t, KindWeekly, configs[1].Kind)
+	assert.Equal(t, "chan2", configs[1].ChannelID)
+	assert.Equal(t, "0 12 * * 5", configs[1].Schedule)
+	assert.False(t, configs[1].LastRunAt.IsZero(), "last run should default to when the digest was created")
+
+	require.NoError(t, repo.DeleteConfig(ctx, guildID, KindMonthly))
+
+	configs, err = repo.GetConfigs(ctx, guildID)
+	require.NoError(t, err)
+	assert.Len(t, configs, 1)
+}
+
+func TestClaimRun(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveConfig(ctx, Config{GuildID: guildID, Kind: KindWeekly, ChannelID: "chan1", Schedule: "0 9 * * 1"}))
+
+	configs, err := repo.GetConfigs(ctx, guildID)
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+
+	runAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	claimed, err := repo.ClaimRun(ctx, configs[0], runAt)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	// a second replica holding the same stale config should not be able to claim the run
+	claimed, err = repo.ClaimRun(ctx, configs[0], runAt)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	configs, err = repo.GetConfigs(ctx, guildID)
+	require.NoError(t, err)
+	assert.True(t, runAt.Equal(configs[0].LastRunAt))
+}
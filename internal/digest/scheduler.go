@@ -0,0 +1,169 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/stats"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	checkInterval = time.Minute
+	moversLimit   = 3
+	// moversPool is how many of each period's top emojis are compared when finding the biggest movers
+	moversPool = 50
+)
+
+// Scheduler periodically posts any digests which are due
+type Scheduler struct {
+	repo    *Repository
+	stats   *stats.Repository
+	session *discordgo.Session
+	now     func() time.Time
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler(repo *Repository, statsRepo *stats.Repository, s *discordgo.Session) *Scheduler {
+	return &Scheduler{
+		repo:    repo,
+		stats:   statsRepo,
+		session: s,
+		now:     time.Now,
+	}
+}
+
+// Run checks for due digests every minute until the context is done
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		s.RunDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue posts every digest whose next scheduled run has passed
+func (s *Scheduler) RunDue(ctx context.Context) {
+	configs, err := s.repo.GetConfigs(ctx, "")
+	if err != nil {
+		slog.Error("failed to get digests", "error", err)
+		return
+	}
+
+	now := s.now().UTC()
+
+	for _, c := range configs {
+		loc, err := s.stats.GetTimezone(ctx, c.GuildID)
+		if err != nil {
+			slog.Error("failed to get guild timezone", "error", err, "guild_id", c.GuildID)
+			continue
+		}
+
+		due, err := IsDue(c, now, loc)
+		if err != nil {
+			slog.Error("invalid digest schedule", "error", err, "guild_id", c.GuildID, "kind", c.Kind)
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		claimed, err := s.repo.ClaimRun(ctx, c, now)
+		if err != nil {
+			slog.Error("failed to claim digest", "error", err, "guild_id", c.GuildID, "kind", c.Kind)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.post(ctx, c, now, loc); err != nil {
+			slog.Error("failed to post digest", "error", err, "guild_id", c.GuildID, "kind", c.Kind)
+			s.release(ctx, c, now)
+			continue
+		}
+
+		slog.Info("digest posted", "guild_id", c.GuildID, "kind", c.Kind)
+	}
+}
+
+// release releases the claim on a run which could not be posted, so that the next check tries again
+func (s *Scheduler) release(ctx context.Context, c Config, at time.Time) {
+	if err := s.repo.ReleaseRun(ctx, c, at); err != nil {
+		slog.Error("failed to release digest", "error", err, "guild_id", c.GuildID, "kind", c.Kind)
+	}
+}
+
+func (s *Scheduler) post(ctx context.Context, c Config, now time.Time, loc *time.Location) error {
+	d, err := s.build(ctx, c, now, loc)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.session.ChannelMessageSend(c.ChannelID, stats.FormatDigest(d))
+	return err
+}
+
+func (s *Scheduler) build(ctx context.Context, c Config, at time.Time, loc *time.Location) (*stats.Digest, error) {
+	settings, err := s.stats.GetSettings(ctx, c.GuildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild settings: %w", err)
+	}
+
+	start, end := c.Kind.Period(at, loc)
+	previousStart := c.Kind.Start(start)
+
+	current := stats.DateRange{Start: &start, End: &end}
+	previous := stats.DateRange{Start: &previousStart, End: &start}
+
+	guildStats, err := s.stats.GetGuildStats(ctx, c.GuildID, current, settings.LeaderboardSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild stats: %w", err)
+	}
+
+	currentEmojis, err := s.stats.GetTopEmojis(ctx, c.GuildID, current, moversPool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current top emojis: %w", err)
+	}
+
+	previousEmojis, err := s.stats.GetTopEmojis(ctx, c.GuildID, previous, moversPool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous top emojis: %w", err)
+	}
+
+	return &stats.Digest{
+		Title:  c.Kind.Title(),
+		Stats:  guildStats,
+		Movers: stats.BiggestMovers(currentEmojis, previousEmojis, moversLimit),
+	}, nil
+}
+
+// IsDue reports whether the digest's next scheduled run after its last run has passed, evaluating the schedule in the
+// guild's timezone
+func IsDue(c Config, now time.Time, loc *time.Location) (bool, error) {
+	schedule, err := ParseSchedule(c.Schedule)
+	if err != nil {
+		return false, err
+	}
+
+	return !schedule.Next(c.LastRunAt.In(loc)).After(now), nil
+}
+
+// ParseSchedule parses a standard five field cron expression
+func ParseSchedule(s string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", s, err)
+	}
+	return schedule, nil
+}
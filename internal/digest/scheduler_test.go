@@ -0,0 +1,151 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+func TestIsDue(t *testing.T) {
+	// 2024-06-10 is a Monday
+	lastRun := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected bool
+	}{
+		{"before next run", time.Date(2024, 6, 10, 8, 59, 0, 0, time.UTC), false},
+		{"at next run", time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC), true},
+		{"after next run", time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{Schedule: KindWeekly.DefaultSchedule(), LastRunAt: lastRun}
+
+			due, err := IsDue(c, tt.now, time.UTC)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, due)
+		})
+	}
+}
+
+func TestIsDue_InvalidSchedule(t *testing.T) {
+	_, err := IsDue(Config{Schedule: "every monday"}, time.Now(), time.UTC)
+
+	assert.Error(t, err)
+}
+
+func TestIsDue_Timezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 09:00 on Monday 2024-06-10 in New York is 13:00 UTC
+	c := Config{Schedule: KindWeekly.DefaultSchedule(), LastRunAt: time.Date(2024, 6, 3, 13, 0, 0, 0, time.UTC)}
+
+	due, err := IsDue(c, time.Date(2024, 6, 10, 12, 59, 0, 0, time.UTC), newYork)
+	require.NoError(t, err)
+	assert.False(t, due)
+
+	due, err = IsDue(c, time.Date(2024, 6, 10, 13, 0, 0, 0, time.UTC), newYork)
+	require.NoError(t, err)
+	assert.True(t, due)
+}
+
+func TestKindPeriod(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 09:00 on Monday 2024-03-11 in New York, the day after the clocks went forward
+	at := time.Date(2024, 3, 11, 13, 0, 0, 0, time.UTC)
+
+	start, end := KindWeekly.Period(at, newYork)
+	assert.True(t, time.Date(2024, 3, 4, 0, 0, 0, 0, newYork).Equal(start))
+	assert.True(t, time.Date(2024, 3, 11, 0, 0, 0, 0, newYork).Equal(end))
+
+	start, end = KindMonthly.Period(at, newYork)
+	assert.True(t, time.Date(2024, 2, 11, 0, 0, 0, 0, newYork).Equal(start))
+	assert.True(t, time.Date(2024, 3, 11, 0, 0, 0, 0, newYork).Equal(end))
+}
+
+func TestKindStart(t *testing.T) {
+	end := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2024, 2, 23, 9, 0, 0, 0, time.UTC), KindWeekly.Start(end))
+	assert.Equal(t, time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC), KindMonthly.Start(end))
+}
+
+func TestKindDefaultSchedule(t *testing.T) {
+	for _, k := range []Kind{KindWeekly, KindMonthly} {
+		_, err := ParseSchedule(k.DefaultSchedule())
+		assert.NoError(t, err)
+	}
+}
+
+// failingTransport fails the first fail requests to Discord and accepts the rest as posted messages
+type failingTransport struct {
+	fail     int
+	requests int
+}
+
+func (f *failingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.requests++
+	if f.requests <= f.fail {
+		return nil, errors.New("connection refused")
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"id": "msg1"}`)),
+		Request:    r,
+	}, nil
+}
+
+func TestScheduler_RetriesFailedPost(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveConfig(ctx, Config{GuildID: guildID, Kind: KindWeekly, ChannelID: "chan1", Schedule: "* * * * *"}))
+	configs, err := repo.GetConfigs(ctx, guildID)
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	created := configs[0].LastRunAt
+
+	transport := &failingTransport{fail: 1}
+	session, err := discordgo.New("Bot token")
+	require.NoError(t, err)
+	session.Client = &http.Client{Transport: transport}
+
+	now := created.Add(2 * time.Minute)
+	scheduler := NewScheduler(repo, stats.NewRepository(testDB), session)
+	scheduler.now = func() time.Time { return now }
+
+	scheduler.RunDue(ctx)
+
+	require.Equal(t, 1, transport.requests)
+	configs, err = repo.GetConfigs(ctx, guildID)
+	require.NoError(t, err)
+	assert.True(t, created.Equal(configs[0].LastRunAt), "a failed post should not count as a run")
+
+	now = now.Add(checkInterval)
+	scheduler.RunDue(ctx)
+
+	assert.Equal(t, 2, transport.requests, "the failed post should be retried on the next check")
+	configs, err = repo.GetConfigs(ctx, guildID)
+	require.NoError(t, err)
+	assert.True(t, now.Equal(configs[0].LastRunAt))
+}
@@ -4,12 +4,26 @@ import (
 	"context"
 	"database/sql"
 	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/elliotwms/bot"
 	"github.com/elliotwms/bot/interactions/router"
+	"github.com/elliotwms/emojistats/internal/achievements"
+	"github.com/elliotwms/emojistats/internal/api"
+	"github.com/elliotwms/emojistats/internal/cache"
 	"github.com/elliotwms/emojistats/internal/commands"
+	"github.com/elliotwms/emojistats/internal/dashboard"
+	"github.com/elliotwms/emojistats/internal/digest"
 	"github.com/elliotwms/emojistats/internal/eventhandlers"
+	"github.com/elliotwms/emojistats/internal/metrics"
+	"github.com/elliotwms/emojistats/internal/milestones"
+	"github.com/elliotwms/emojistats/internal/retention"
+	"github.com/elliotwms/emojistats/internal/rolerewards"
+	"github.com/elliotwms/emojistats/internal/starboard"
+	"github.com/elliotwms/emojistats/internal/stats"
+	"github.com/elliotwms/emojistats/internal/tracing"
 )
 
 const intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMessageReactions
@@ -18,30 +32,147 @@ type Config struct {
 	Session         *discordgo.Session
 	ApplicationID   string
 	HealthCheckAddr string
-	GuildID         string
-	Logger          *slog.Logger
-	DB              *sql.DB
+	// APIAddr is the address to serve the stats API on. The API is disabled if it is empty, or if DB is nil as API
+	// tokens are stored in Postgres
+	APIAddr string
+	// MetricsAddr is the address to serve Prometheus metrics on at /metrics. Metrics are disabled if it is empty
+	MetricsAddr string
+	// DashboardAddr is the address to serve the web dashboard on. The dashboard is disabled if it is empty
+	DashboardAddr string
+	// DashboardOAuth configures signing in to the dashboard with Discord
+	DashboardOAuth dashboard.OAuthConfig
+	GuildID        string
+	Logger         *slog.Logger
+	// DB is the Postgres database. It may be nil if Store is set, in which case only ingestion and the stats commands
+	// are enabled
+	DB *sql.DB
+	// Store stores reactions and answers stats queries. It defaults to the Postgres stats.Repository
+	Store stats.Store
+	// StatsCacheTTL is how long stats query results are cached for. Zero disables the cache
+	StatsCacheTTL time.Duration
+	// StatsCacheShared caches results in the database so that they are shared between replicas
+	StatsCacheShared bool
+	// Features toggles the optional features which require Postgres
+	Features Features
+}
+
+// Features toggles the optional features. A disabled feature's commands are not registered, and its reaction hooks and
+// schedulers do not run
+type Features struct {
+	Starboard    bool
+	Milestones   bool
+	Achievements bool
+	Digests      bool
+	RoleRewards  bool
+	Retention    bool
+}
+
+// AllFeatures enables every feature
+var AllFeatures = Features{
+	Starboard:    true,
+	Milestones:   true,
+	Achievements: true,
+	Digests:      true,
+	RoleRewards:  true,
+	Retention:    true,
+}
+
+// disabledCommands returns the names of the commands of the disabled features
+func (f Features) disabledCommands() []string {
+	var names []string
+	if !f.Starboard {
+		names = append(names, "starboard")
+	}
+	if !f.Milestones {
+		names = append(names, "milestones")
+	}
+	if !f.Achievements {
+		names = append(names, "badges", "badge-announcements")
+	}
+	if !f.Digests {
+		names = append(names, "digest")
+	}
+	if !f.RoleRewards {
+		names = append(names, "role-rewards")
+	}
+	if !f.Retention {
+		names = append(names, "retention")
+	}
+	return names
 }
 
 func NewConfig(s *discordgo.Session, appID string) Config {
 	return Config{
 		Session:       s,
 		ApplicationID: appID,
+		Features:      AllFeatures,
 	}
 }
 
 func Run(config Config, ctx context.Context) error {
 	r := router.New()
 
+	store := config.Store
+	if store == nil {
+		store = stats.NewRepository(config.DB)
+	}
+	// record and trace the queries which reach the store, rather than those answered by the cache
+	store = tracing.NewStore(metrics.NewStore(store))
+
+	// trace Discord REST calls, as children of the command or event being handled
+	config.Session.Client.Transport = tracing.Transport(config.Session.Client.Transport)
+
+	var addHooks, removeHooks []eventhandlers.ReactionHook
+	if config.DB != nil {
+		if config.Features.Starboard {
+			sb := starboard.New(starboard.NewRepository(config.DB))
+			addHooks = append(addHooks, sb.HandleReaction)
+			removeHooks = append(removeHooks, sb.HandleReaction)
+		}
+		milestonesRepo := milestones.NewRepository(config.DB)
+		if config.Features.Milestones {
+			addHooks = append(addHooks, milestones.NewEngine(milestonesRepo).HandleReaction)
+		}
+		if config.Features.Achievements {
+			engine := achievements.NewEngine(achievements.NewRepository(config.DB), milestonesRepo, store)
+			addHooks = append(addHooks, engine.HandleReaction)
+			go engine.Run(ctx, config.Session)
+		}
+	}
+
+	if config.StatsCacheTTL > 0 {
+		var backend cache.Backend = cache.NewMemory()
+		if config.StatsCacheShared && config.DB != nil {
+			backend = cache.NewPostgres(config.DB)
+		}
+
+		c := cache.NewRepository(store, backend, config.StatsCacheTTL)
+		store = c
+
+		go c.LogMetrics(ctx, time.Hour)
+	}
+
+	cmds := commands.Commands(config.DB, store)
+	for cmd := range cmds {
+		if slices.Contains(config.Features.disabledCommands(), cmd.Name) {
+			delete(cmds, cmd)
+		}
+	}
+
 	b := bot.
 		New(config.ApplicationID, config.Session).
 		WithLogger(config.Logger).
 		WithIntents(intents).
 		WithHandler(eventhandlers.Ready).
-		WithHandler(eventhandlers.NewReactionAddHandler(config.DB)).
-		WithHandler(eventhandlers.NewReactionRemoveHandler(config.DB)).
+		WithHandler(metrics.Connect).
+		WithHandler(metrics.Disconnect).
+		WithHandler(metrics.Resumed).
+		WithHandler(eventhandlers.NewReactionAddHandler(store, addHooks...)).
+		WithHandler(eventhandlers.NewReactionRemoveHandler(store, removeHooks...)).
+		WithHandler(eventhandlers.NewChannelUpdateHandler(store)).
+		WithHandler(commands.NewComponentRouter(commands.Components(store))).
 		WithRouter(r).
-		WithApplicationCommands(commands.Commands(config.DB)).
+		WithApplicationCommands(commands.Handled(tracing.Commands(metrics.Commands(cmds)))).
 		WithMigrationEnabled(true)
 
 	if config.HealthCheckAddr != "" {
@@ -52,5 +183,48 @@ func Run(config Config, ctx context.Context) error {
 		b.WithGuildID(config.GuildID)
 	}
 
+	if config.APIAddr != "" && config.DB != nil {
+		server := api.NewServer(store, api.NewRepository(config.DB))
+		go func() {
+			if err := server.ListenAndServe(ctx, config.APIAddr); err != nil {
+				slog.Error("failed to serve API", "error", err)
+			}
+		}()
+	}
+
+	if config.MetricsAddr != "" {
+		go func() {
+			if err := metrics.ListenAndServe(ctx, config.MetricsAddr); err != nil {
+				slog.Error("failed to serve metrics", "error", err)
+			}
+		}()
+	}
+
+	if config.DashboardAddr != "" {
+		server := dashboard.NewServer(store, store, dashboard.StateDirectory{State: config.Session.State}, config.DashboardOAuth)
+		go func() {
+			if err := server.ListenAndServe(ctx, config.DashboardAddr); err != nil {
+				slog.Error("failed to serve dashboard", "error", err)
+			}
+		}()
+	}
+
+	if config.DB != nil {
+		if config.Features.Digests {
+			scheduler := digest.NewScheduler(digest.NewRepository(config.DB), stats.NewRepository(config.DB), config.Session)
+			go scheduler.Run(ctx)
+		}
+
+		if config.Features.RoleRewards {
+			rewards := rolerewards.NewScheduler(rolerewards.NewRepository(config.DB), config.Session)
+			go rewards.Run(ctx)
+		}
+
+		if config.Features.Retention {
+			pruner := retention.NewPruner(retention.NewRepository(config.DB))
+			go pruner.Run(ctx)
+		}
+	}
+
 	return b.Build().Run(ctx)
 }
@@ -0,0 +1,80 @@
+package eventhandlers
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// ChannelParents returns a channel's parents from the state, nearest first: the category it is in, or for a thread its
+// channel and that channel's category. Parents which are not in the state are omitted
+func ChannelParents(state *discordgo.State, channelID string) []string {
+	if state == nil {
+		return nil
+	}
+
+	var parents []string
+	id := channelID
+	// threads are the most deeply nested channels, in a channel in a category
+	for range 2 {
+		c, err := state.Channel(id)
+		if err != nil || c.ParentID == "" {
+			break
+		}
+		parents = append(parents, c.ParentID)
+		id = c.ParentID
+	}
+	return parents
+}
+
+// RecordChannelParents records the parents of a channel up to the ancestor it is ignored because of, so that the stats
+// also exclude its earlier reactions. parentIDs are nearest first, as returned by ChannelParents
+func RecordChannelParents(ctx context.Context, store stats.SettingsStore, guildID, channelID, ignoredID string, parentIDs []string) error {
+	childID := channelID
+	for _, id := range parentIDs {
+		if _, err := store.SetChannelParent(ctx, guildID, childID, id); err != nil {
+			return err
+		}
+		if id == ignoredID {
+			break
+		}
+		childID = id
+	}
+	return nil
+}
+
+// NewChannelUpdateHandler creates a handler which keeps the recorded parent of a channel up to date when it is moved, so
+// that a channel moved out of an ignored category is counted again, and one moved into an ignored category is not
+func NewChannelUpdateHandler(store stats.SettingsStore) func(*discordgo.Session, *discordgo.ChannelUpdate) {
+	return func(s *discordgo.Session, c *discordgo.ChannelUpdate) {
+		if c.GuildID == "" {
+			return
+		}
+
+		ctx := context.Background()
+
+		settings, err := store.GetSettings(ctx, c.GuildID)
+		if err != nil {
+			slog.Error("failed to get guild settings", "error", err, "guild_id", c.GuildID)
+			return
+		}
+
+		var parents []string
+		if c.ParentID != "" {
+			parents = append([]string{c.ParentID}, ChannelParents(s.State, c.ParentID)...)
+		}
+
+		ignoredID, ignored := settings.IgnoringChannel(c.ID, parents...)
+		if ignored && ignoredID != c.ID {
+			err = RecordChannelParents(ctx, store, c.GuildID, c.ID, ignoredID, parents)
+		} else {
+			// a recorded parent is only needed while it is ignored
+			_, err = store.SetChannelParent(ctx, c.GuildID, c.ID, "")
+		}
+		if err != nil {
+			slog.Error("failed to record channel parents", "error", err, "channel_id", c.ID)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package eventhandlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/memory"
+	"github.com/elliotwms/emojistats/internal/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelParents(t *testing.T) {
+	state := discordgo.NewState()
+	require.NoError(t, state.GuildAdd(&discordgo.Guild{
+		ID: "guild1",
+		Channels: []*discordgo.Channel{
+			{ID: "cat1", GuildID: "guild1", Type: discordgo.ChannelTypeGuildCategory},
+			{ID: "chan1", GuildID: "guild1", ParentID: "cat1"},
+		},
+		Threads: []*discordgo.Channel{
+			{ID: "thread1", GuildID: "guild1", ParentID: "chan1"},
+		},
+	}))
+
+	assert.Equal(t, []string{"chan1", "cat1"}, ChannelParents(state, "thread1"))
+	assert.Equal(t, []string{"cat1"}, ChannelParents(state, "chan1"))
+	assert.Empty(t, ChannelParents(state, "cat1"))
+	assert.Empty(t, ChannelParents(nil, "chan1"))
+}
+
+func TestChannelUpdateHandler_Moved(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	session := &discordgo.Session{State: discordgo.NewState()}
+
+	require.NoError(t, store.AddReaction(ctx, stats.Reaction{GuildID: "guild1", ChannelID: "chan1", MessageID: "msg1", EmojiID: "👍"}))
+	settings := stats.DefaultSettings()
+	settings.IgnoredCategories = []string{"cat1"}
+	require.NoError(t, store.SetSettings(ctx, "guild1", settings))
+
+	total := func() int {
+		t.Helper()
+		result, err := store.GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+		require.NoError(t, err)
+		return result.TotalReactions
+	}
+
+	handler := NewChannelUpdateHandler(store)
+	move := func(parentID string) {
+		handler(session, &discordgo.ChannelUpdate{Channel: &discordgo.Channel{ID: "chan1", GuildID: "guild1", ParentID: parentID}})
+	}
+
+	move("cat1")
+	assert.Zero(t, total(), "the channel was moved into an ignored category")
+
+	move("cat2")
+	assert.Equal(t, 1, total(), "the channel was moved out of the ignored category")
+
+	move("cat1")
+	move("")
+	assert.Equal(t, 1, total(), "the channel was moved out of any category")
+}
@@ -1,16 +1,28 @@
 package eventhandlers
 
 import (
-	"database/sql"
+	"context"
 	"log/slog"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/metrics"
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+	"github.com/elliotwms/emojistats/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func NewReactionAddHandler(db *sql.DB) func(*discordgo.Session, *discordgo.MessageReactionAdd) {
+// ReactionHook is called after a reaction event has been persisted
+type ReactionHook func(s *discordgo.Session, r *discordgo.MessageReaction)
+
+func NewReactionAddHandler(store stats.Store, hooks ...ReactionHook) func(*discordgo.Session, *discordgo.MessageReactionAdd) {
 	return func(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
 		id := r.Emoji.MessageFormat()
 
+		ctx, span := startReactionSpan("reaction add", r.MessageReaction, id)
+		defer span.End()
+
 		slog.Debug("reaction add event received",
 			"emoji_id", id,
 			"emoji_name", r.Emoji.Name,
@@ -20,40 +32,104 @@ func NewReactionAddHandler(db *sql.DB) func(*discordgo.Session, *discordgo.Messa
 			"guild_id", r.GuildID,
 		)
 
-		msg, err := s.ChannelMessage(r.ChannelID, r.MessageID)
+		settings, err := store.GetSettings(ctx, r.GuildID)
+		if err != nil {
+			slog.Error("failed to get guild settings", "error", err)
+			metrics.ReactionsFailed.WithLabelValues(r.GuildID, "add").Inc()
+			tracing.RecordError(span, err)
+			return
+		}
+		parents := ChannelParents(s.State, r.ChannelID)
+		if ignoredID, ignored := settings.IgnoringChannel(r.ChannelID, parents...); ignored {
+			slog.Debug("reaction ignored as the channel is ignored", "message_id", r.MessageID, "ignored_id", ignoredID)
+
+			// record the parents so that the stats also exclude the channel's earlier reactions
+			if ignoredID != r.ChannelID {
+				if err := RecordChannelParents(ctx, store, r.GuildID, r.ChannelID, ignoredID, parents); err != nil {
+					slog.Error("failed to record channel parents", "error", err, "channel_id", r.ChannelID)
+					tracing.RecordError(span, err)
+				}
+			}
+			return
+		}
+		if r.Member != nil && settings.IgnoresRoles(r.Member.Roles) {
+			slog.Debug("reaction ignored as the sender has an ignored role", "message_id", r.MessageID)
+			return
+		}
+		if !settings.CountBots && r.Member != nil && r.Member.User != nil && r.Member.User.Bot {
+			slog.Debug("reaction ignored as the sender is a bot", "message_id", r.MessageID)
+			return
+		}
+
+		optedOut, err := store.OptedOut(ctx, r.UserID)
+		if err != nil {
+			slog.Error("failed to check privacy opt-out", "error", err)
+			metrics.ReactionsFailed.WithLabelValues(r.GuildID, "add").Inc()
+			tracing.RecordError(span, err)
+			return
+		}
+		if optedOut[r.UserID] {
+			slog.Debug("reaction ignored as sender has opted out", "message_id", r.MessageID)
+			return
+		}
+
+		msg, err := metrics.ChannelMessage(ctx, s, r.ChannelID, r.MessageID)
 		if err != nil {
 			slog.Error("failed to get message", "error", err)
+			metrics.ReactionsFailed.WithLabelValues(r.GuildID, "add").Inc()
+			tracing.RecordError(span, err)
 			return
 		}
 
-		_, err = db.Exec(`
-			INSERT INTO reactions (emoji_id, sender_user_id, receiver_user_id, channel_id, message_id, guild_id, is_default)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-			id,
-			r.UserID,
-			msg.Author.ID,
-			r.ChannelID,
-			r.MessageID,
-			r.GuildID,
-			r.Emoji.ID == "", // isDefault is reserved for future query use
-		)
+		receiverID := msg.Author.ID
+		optedOut, err = store.OptedOut(ctx, receiverID)
+		if err != nil {
+			slog.Error("failed to check privacy opt-out", "error", err)
+			metrics.ReactionsFailed.WithLabelValues(r.GuildID, "add").Inc()
+			tracing.RecordError(span, err)
+			return
+		}
+		if optedOut[receiverID] {
+			receiverID = privacy.AnonymousUserID
+		}
+
+		err = store.AddReaction(ctx, stats.Reaction{
+			GuildID:        r.GuildID,
+			ChannelID:      r.ChannelID,
+			MessageID:      r.MessageID,
+			EmojiID:        id,
+			IsDefault:      r.Emoji.ID == "",
+			SenderUserID:   r.UserID,
+			ReceiverUserID: receiverID,
+		})
 		if err != nil {
 			slog.Error("failed to insert reaction", "error", err)
+			metrics.ReactionsFailed.WithLabelValues(r.GuildID, "add").Inc()
+			tracing.RecordError(span, err)
 			return
 		}
 
+		metrics.ReactionsIngested.WithLabelValues(r.GuildID).Inc()
+
 		slog.Info("reaction saved",
 			"emoji_id", id,
 			"sender", r.UserID,
-			"receiver", msg.Author.ID,
+			"receiver", receiverID,
 		)
+
+		for _, h := range hooks {
+			h(s, r.MessageReaction)
+		}
 	}
 }
 
-func NewReactionRemoveHandler(db *sql.DB) func(*discordgo.Session, *discordgo.MessageReactionRemove) {
+func NewReactionRemoveHandler(store stats.Store, hooks ...ReactionHook) func(*discordgo.Session, *discordgo.MessageReactionRemove) {
 	return func(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
 		id := r.Emoji.MessageFormat()
 
+		ctx, span := startReactionSpan("reaction remove", r.MessageReaction, id)
+		defer span.End()
+
 		slog.Debug("reaction remove event received",
 			"emoji_id", id,
 			"emoji_name", r.Emoji.Name,
@@ -63,23 +139,15 @@ func NewReactionRemoveHandler(db *sql.DB) func(*discordgo.Session, *discordgo.Me
 			"guild_id", r.GuildID,
 		)
 
-		res, err := db.Exec(`
-			DELETE FROM reactions
-			WHERE emoji_id = $1 AND sender_user_id = $2 AND message_id = $3`,
-			id,
-			r.UserID,
-			r.MessageID,
-		)
+		rows, err := store.RemoveReaction(ctx, r.GuildID, r.MessageID, id, r.UserID)
 		if err != nil {
 			slog.Error("failed to delete reaction", "error", err)
+			metrics.ReactionsFailed.WithLabelValues(r.GuildID, "remove").Inc()
+			tracing.RecordError(span, err)
 			return
 		}
 
-		rows, err := res.RowsAffected()
-		if err != nil {
-			slog.Error("failed to get rows affected", "error", err)
-			return
-		}
+		metrics.ReactionsRemoved.WithLabelValues(r.GuildID).Add(float64(rows))
 
 		if rows != 1 {
 			slog.Warn("unexpected number of reactions deleted",
@@ -96,5 +164,20 @@ func NewReactionRemoveHandler(db *sql.DB) func(*discordgo.Session, *discordgo.Me
 			"emoji_id", id,
 			"sender", r.UserID,
 		)
+
+		for _, h := range hooks {
+			h(s, r.MessageReaction)
+		}
 	}
 }
+
+// startReactionSpan starts the span of handling a reaction event, which the queries and Discord REST calls it makes are
+// children of
+func startReactionSpan(name string, r *discordgo.MessageReaction, emojiID string) (context.Context, trace.Span) {
+	return tracing.Start(context.Background(), name, trace.WithAttributes(
+		attribute.String("discord.guild_id", r.GuildID),
+		attribute.String("discord.channel_id", r.ChannelID),
+		attribute.String("discord.message_id", r.MessageID),
+		attribute.String("discord.emoji_id", emojiID),
+	))
+}
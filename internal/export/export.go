@@ -0,0 +1,73 @@
+// Package export dumps a guild's reactions to CSV, JSON Lines or Parquet files. There is no emoji catalogue table in
+// the database, so the reactions table is the only one exported
+package export
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// Format is an export file format
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+// Formats is every supported format
+var Formats = []Format{FormatCSV, FormatJSONL, FormatParquet}
+
+// ParseFormat parses a format name
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q, expected one of csv, jsonl or parquet", s)
+}
+
+// Extension returns the file extension for the format, without a leading dot
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// ContentType returns the MIME type of files in the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatJSONL:
+		return "application/jsonl"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// Filter selects the reactions to export
+type Filter struct {
+	GuildID   string
+	DateRange stats.DateRange
+	// ChannelID limits the export to a single channel if set
+	ChannelID string
+}
+
+// Row is an exported reaction. Users who have opted out are exported as privacy.AnonymousUserID
+type Row struct {
+	ID             string    `json:"id" parquet:"id"`
+	GuildID        string    `json:"guild_id" parquet:"guild_id"`
+	ChannelID      string    `json:"channel_id" parquet:"channel_id"`
+	MessageID      string    `json:"message_id" parquet:"message_id"`
+	EmojiID        string    `json:"emoji_id" parquet:"emoji_id"`
+	IsDefault      bool      `json:"is_default" parquet:"is_default"`
+	SenderUserID   string    `json:"sender_user_id" parquet:"sender_user_id"`
+	ReceiverUserID string    `json:"receiver_user_id" parquet:"receiver_user_id"`
+	CreatedAt      time.Time `json:"created_at" parquet:"created_at,timestamp(microsecond)"`
+}
+
+// columns are the CSV header, in the order Row's fields are written
+var columns = []string{"id", "guild_id", "channel_id", "message_id", "emoji_id", "is_default", "sender_user_id", "receiver_user_id", "created_at"}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Source streams the reactions matching a filter. It is implemented by Repository
+type Source interface {
+	Stream(ctx context.Context, filter Filter, fn func(Row) error) error
+}
+
+// Opener creates the file for the numbered part of an export, starting at 1
+type Opener func(part int) (io.WriteCloser, error)
+
+// Result summarises a completed export
+type Result struct {
+	Rows int
+	// Files is the number of files written. It is always at least 1, even if no rows were exported
+	Files int
+}
+
+// Export streams the reactions matching the filter into files in the given format. If chunkSize is greater than zero
+// a new file is started once the current file reaches chunkSize bytes. A file can exceed chunkSize by up to one row, or
+// one row group for Parquet, so callers with a hard limit should leave headroom
+func Export(ctx context.Context, src Source, filter Filter, format Format, chunkSize int64, open Opener) (Result, error) {
+	e := &exporter{format: format, chunkSize: chunkSize, open: open}
+
+	if err := e.next(); err != nil {
+		return e.result, err
+	}
+
+	err := src.Stream(ctx, filter, e.write)
+	if err != nil {
+		_ = e.close()
+		return e.result, err
+	}
+
+	return e.result, e.close()
+}
+
+type exporter struct {
+	format    Format
+	chunkSize int64
+	open      Opener
+
+	file   io.WriteCloser
+	count  *countingWriter
+	rows   RowWriter
+	result Result
+}
+
+func (e *exporter) write(row Row) error {
+	if e.chunkSize > 0 && e.count.n >= e.chunkSize {
+		if err := e.close(); err != nil {
+			return err
+		}
+		if err := e.next(); err != nil {
+			return err
+		}
+	}
+
+	if err := e.rows.Write(row); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
+	}
+	e.result.Rows++
+
+	return nil
+}
+
+// next opens the next file
+func (e *exporter) next() error {
+	f, err := e.open(e.result.Files + 1)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+
+	e.file = f
+	e.count = &countingWriter{w: f}
+	e.result.Files++
+
+	e.rows, err = NewRowWriter(e.format, e.count)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return nil
+}
+
+// close finishes and closes the current file
+func (e *exporter) close() error {
+	if err := e.rows.Close(); err != nil {
+		_ = e.file.Close()
+		return fmt.Errorf("failed to finish file: %w", err)
+	}
+	return e.file.Close()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,154 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource []Row
+
+func (f fakeSource) Stream(_ context.Context, _ Filter, fn func(Row) error) error {
+	for _, r := range f {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type buffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *buffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func rows(n int) fakeSource {
+	start := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	var result fakeSource
+	for i := range n {
+		result = append(result, Row{
+			ID:             fmt.Sprintf("id-%d", i),
+			GuildID:        "guild1",
+			ChannelID:      "chan1",
+			MessageID:      fmt.Sprintf("msg%d", i),
+			EmojiID:        "👍",
+			IsDefault:      true,
+			SenderUserID:   "111",
+			ReceiverUserID: "222",
+			CreatedAt:      start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	return result
+}
+
+func export(t *testing.T, src Source, format Format, chunkSize int64) (Result, []*buffer) {
+	t.Helper()
+
+	var files []*buffer
+	result, err := Export(context.Background(), src, Filter{GuildID: "guild1"}, format, chunkSize, func(part int) (io.WriteCloser, error) {
+		assert.Equal(t, len(files)+1, part)
+		b := &buffer{}
+		files = append(files, b)
+		return b, nil
+	})
+	require.NoError(t, err)
+
+	for _, f := range files {
+		assert.True(t, f.closed)
+	}
+
+	return result, files
+}
+
+func TestExport_CSV(t *testing.T) {
+	src := rows(2)
+
+	result, files := export(t, src, FormatCSV, 0)
+
+	assert.Equal(t, Result{Rows: 2, Files: 1}, result)
+	records, err := csv.NewReader(files[0]).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, columns, records[0])
+	assert.Equal(t, []string{"id-1", "guild1", "chan1", "msg1", "👍", "true", "111", "222", "2024-06-10T12:01:00Z"}, records[2])
+}
+
+func TestExport_JSONL(t *testing.T) {
+	src := rows(2)
+
+	result, files := export(t, src, FormatJSONL, 0)
+
+	assert.Equal(t, Result{Rows: 2, Files: 1}, result)
+
+	var decoded []Row
+	scanner := bufio.NewScanner(files[0])
+	for scanner.Scan() {
+		var r Row
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		decoded = append(decoded, r)
+	}
+	assert.Equal(t, []Row(src), decoded)
+}
+
+func TestExport_Parquet(t *testing.T) {
+	src := rows(parquetRowGroupSize + 5)
+
+	result, files := export(t, src, FormatParquet, 0)
+
+	assert.Equal(t, Result{Rows: len(src), Files: 1}, result)
+
+	b := files[0].Bytes()
+	decoded, err := parquet.Read[Row](bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+	assert.Equal(t, []Row(src), decoded)
+}
+
+func TestExport_Empty(t *testing.T) {
+	result, files := export(t, fakeSource{}, FormatCSV, 100)
+
+	assert.Equal(t, Result{Rows: 0, Files: 1}, result)
+	assert.Equal(t, "id,guild_id,channel_id,message_id,emoji_id,is_default,sender_user_id,receiver_user_id,created_at\n", files[0].String())
+}
+
+func TestExport_Chunked(t *testing.T) {
+	src := rows(10)
+
+	result, files := export(t, src, FormatJSONL, 300)
+
+	assert.Equal(t, 10, result.Rows)
+	assert.Greater(t, result.Files, 1)
+	require.Len(t, files, result.Files)
+
+	var total int
+	for _, f := range files {
+		assert.Less(t, f.Len(), 600, "a file should exceed the chunk size by at most one row")
+		total += bytes.Count(f.Bytes(), []byte("\n"))
+	}
+	assert.Equal(t, 10, total)
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		parsed, err := ParseFormat(string(f))
+		require.NoError(t, err)
+		assert.Equal(t, f, parsed)
+	}
+
+	_, err := ParseFormat("xlsx")
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+)
+
+// Repository handles database queries for exports
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository creates a new export repository
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Stream calls fn for every reaction matching the filter, oldest first. Rows are read one at a time so that large
+// guilds are never held in memory
+func (r *Repository) Stream(ctx context.Context, filter Filter, fn func(Row) error) error {
+	query := `
+		SELECT id, guild_id, channel_id, message_id, COALESCE(emoji_id, ''), is_default,
+			CASE WHEN sender_user_id IN (SELECT user_id FROM privacy_opt_outs) THEN $2 ELSE sender_user_id END,
+			CASE WHEN receiver_user_id IN (SELECT user_id FROM privacy_opt_outs) THEN $2 ELSE receiver_user_id END,
+			created_at
+		FROM reactions
+		WHERE guild_id = $1`
+	args := []any{filter.GuildID, privacy.AnonymousUserID}
+
+	if filter.ChannelID != "" {
+		args = append(args, filter.ChannelID)
+		query += ` AND channel_id = $3`
+	}
+	if filter.DateRange.Start != nil {
+		args = append(args, *filter.DateRange.Start)
+		query += ` AND created_at >= $` + argNum(len(args))
+	}
+	if filter.DateRange.End != nil {
+		args = append(args, *filter.DateRange.End)
+		query += ` AND created_at < $` + argNum(len(args))
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var row Row
+		err := rows.Scan(&row.ID, &row.GuildID, &row.ChannelID, &row.MessageID, &row.EmojiID, &row.IsDefault,
+			&row.SenderUserID, &row.ReceiverUserID, &row.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func argNum(n int) string {
+	return strconv.Itoa(n)
+}
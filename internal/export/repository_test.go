@@ -0,0 +1,96 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+var testDB *sql.DB
+
+func setupTest(t *testing.T) (*Repository, string, func()) {
+	t.Helper()
+
+	testDB = dbtest.Open(t)
+
+	guildID := "test-guild-" + time.Now().Format("20060102150405.000000000")
+
+	cleanup := func() {
+		_, _ = testDB.Exec("DELETE FROM reactions WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM privacy_opt_outs WHERE user_id = $1", "opted-out-"+guildID)
+	}
+
+	return NewRepository(testDB), guildID, cleanup
+}
+
+func insertReaction(t *testing.T, guildID, senderID, channelID string, createdAt time.Time) {
+	t.Helper()
+	_, err := testDB.Exec(`
+		INSERT INTO reactions (guild_id, emoji_id, sender_user_id, receiver_user_id, channel_id, message_id, is_default, created_at)
+		VALUES ($1, '👍', $2, 'receiver1', $3, 'msg1', true, $4)`,
+		guildID, senderID, channelID, createdAt)
+	require.NoError(t, err)
+}
+
+func stream(t *testing.T, repo *Repository, filter Filter) []Row {
+	t.Helper()
+
+	var rows []Row
+	require.NoError(t, repo.Stream(context.Background(), filter, func(r Row) error {
+		rows = append(rows, r)
+		return nil
+	}))
+	return rows
+}
+
+func TestStream(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+
+	old := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	recent := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	insertReaction(t, guildID, "sender1", "chan1", old)
+	insertReaction(t, guildID, "sender1", "chan2", recent)
+	insertReaction(t, guildID, "sender2", "chan1", recent)
+
+	rows := stream(t, repo, Filter{GuildID: guildID})
+	require.Len(t, rows, 3)
+	assert.Equal(t, old, rows[0].CreatedAt.UTC(), "rows should be exported oldest first")
+	assert.NotEmpty(t, rows[0].ID)
+	assert.Equal(t, "👍", rows[0].EmojiID)
+	assert.True(t, rows[0].IsDefault)
+
+	rows = stream(t, repo, Filter{GuildID: guildID, ChannelID: "chan1"})
+	assert.Len(t, rows, 2)
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	rows = stream(t, repo, Filter{GuildID: guildID, ChannelID: "chan1", DateRange: stats.DateRange{Start: &start}})
+	require.Len(t, rows, 1)
+	assert.Equal(t, "sender2", rows[0].SenderUserID)
+}
+
+func TestStream_OptedOut(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+
+	optedOutID := "opted-out-" + guildID
+	_, err := testDB.Exec(`INSERT INTO privacy_opt_outs (user_id) VALUES ($1)`, optedOutID)
+	require.NoError(t, err)
+
+	insertReaction(t, guildID, optedOutID, "chan1", time.Now())
+
+	rows := stream(t, repo, Filter{GuildID: guildID})
+	require.Len(t, rows, 1)
+	assert.Equal(t, privacy.AnonymousUserID, rows[0].SenderUserID)
+	assert.Equal(t, "receiver1", rows[0].ReceiverUserID)
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize is how many rows are buffered before a Parquet row group is written. It bounds both memory use
+// and how far past the chunk size a Parquet file can grow
+const parquetRowGroupSize = 1000
+
+// RowWriter writes rows in a file format
+type RowWriter interface {
+	Write(row Row) error
+	// Close writes anything buffered and the end of the file. It does not close the underlying writer
+	Close() error
+}
+
+// NewRowWriter creates a RowWriter for the format
+func NewRowWriter(format Format, w io.Writer) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatJSONL:
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return &parquetWriter{w: parquet.NewGenericWriter[Row](w)}, nil
+	default:
+		_, err := ParseFormat(string(format))
+		return nil, err
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	c := &csvWriter{w: csv.NewWriter(w)}
+	if err := c.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvWriter) Write(row Row) error {
+	err := c.w.Write([]string{
+		row.ID,
+		row.GuildID,
+		row.ChannelID,
+		row.MessageID,
+		row.EmojiID,
+		strconv.FormatBool(row.IsDefault),
+		row.SenderUserID,
+		row.ReceiverUserID,
+		row.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return err
+	}
+
+	// flush every row so that the size of the output is always known when chunking
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(row Row) error {
+	return j.enc.Encode(row)
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
+
+type parquetWriter struct {
+	w        *parquet.GenericWriter[Row]
+	buffered int
+}
+
+func (p *parquetWriter) Write(row Row) error {
+	if _, err := p.w.Write([]Row{row}); err != nil {
+		return err
+	}
+
+	p.buffered++
+	if p.buffered < parquetRowGroupSize {
+		return nil
+	}
+
+	p.buffered = 0
+	return p.w.Flush()
+}
+
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
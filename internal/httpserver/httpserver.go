@@ -0,0 +1,40 @@
+// Package httpserver runs the bot's HTTP servers, such as the API, dashboard and metrics
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+	// shutdownTimeout is how long requests in progress have to finish once the context is done
+	shutdownTimeout = 5 * time.Second
+)
+
+// ListenAndServe serves h on addr until the context is done, then shuts down gracefully. name identifies the server in
+// logs
+func ListenAndServe(ctx context.Context, name, addr string, h http.Handler) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("serving "+name, "addr", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenAndServe_Shutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() { done <- ListenAndServe(ctx, "test", "127.0.0.1:0", http.NotFoundHandler()) }()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err, "shutting down should not be an error")
+	case <-time.After(shutdownTimeout + time.Second):
+		t.Fatal("the server did not shut down")
+	}
+}
+
+func TestListenAndServe_InvalidAddr(t *testing.T) {
+	err := ListenAndServe(context.Background(), "test", "invalid", http.NotFoundHandler())
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,250 @@
+// Package importer loads historic reactions from CSV or JSON Lines files, such as those exported by this bot or by
+// other stats bots
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+)
+
+// maxReportedErrors limits how many invalid rows are listed in a report
+const maxReportedErrors = 20
+
+// Store begins the transaction an import runs in. It is implemented by Repository
+type Store interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// Tx inserts reactions within a transaction
+type Tx interface {
+	// Insert inserts a reaction, returning false if it is a duplicate of an existing reaction
+	Insert(ctx context.Context, r Reaction) (bool, error)
+	// OptedOut returns which of the given users have opted out of having their reactions stored
+	OptedOut(ctx context.Context, userIDs ...string) (map[string]bool, error)
+	Commit() error
+	Rollback() error
+}
+
+// Reaction is a validated reaction ready to be inserted
+type Reaction struct {
+	GuildID        string
+	ChannelID      string
+	MessageID      string
+	EmojiID        string
+	IsDefault      bool
+	SenderUserID   string
+	ReceiverUserID string
+	CreatedAt      time.Time
+}
+
+// Options control how an import runs
+type Options struct {
+	// DryRun validates and deduplicates every row, then rolls back so that nothing is imported
+	DryRun bool
+	// SkipInvalid imports the valid rows of a file containing invalid rows. By default nothing is imported
+	SkipInvalid bool
+}
+
+// RowError is a row which failed validation
+type RowError struct {
+	Line int
+	Err  error
+}
+
+// Report summarises an import
+type Report struct {
+	DryRun bool
+	// Committed is true if the imported rows were saved
+	Committed  bool
+	Read       int
+	Imported   int
+	Duplicates int
+	// OptedOut is the number of reactions skipped because their sender has opted out
+	OptedOut int
+	Invalid  int
+	// Errors lists the first invalid rows
+	Errors []RowError
+}
+
+// String formats the report for the command line
+func (r Report) String() string {
+	var sb strings.Builder
+
+	imported := "Imported"
+	if !r.Committed {
+		imported = "Would import"
+	}
+
+	sb.WriteString(fmt.Sprintf("Read: %d\n%s: %d\nDuplicates skipped: %d\nOpted out skipped: %d\nInvalid: %d\n",
+		r.Read, imported, r.Imported, r.Duplicates, r.OptedOut, r.Invalid))
+
+	for _, e := range r.Errors {
+		sb.WriteString(fmt.Sprintf("  line %d: %s\n", e.Line, e.Err))
+	}
+	if r.Invalid > len(r.Errors) {
+		sb.WriteString(fmt.Sprintf("  ...and %d more\n", r.Invalid-len(r.Errors)))
+	}
+
+	switch {
+	case r.DryRun:
+		sb.WriteString("Dry run: nothing was imported\n")
+	case !r.Committed:
+		sb.WriteString("Nothing was imported\n")
+	}
+
+	return sb.String()
+}
+
+// ErrInvalidRows is returned when a file contains invalid rows and Options.SkipInvalid is not set
+var ErrInvalidRows = errors.New("the file contains invalid rows")
+
+// Import validates every record and inserts the valid, non-duplicate reactions in a single transaction. As with live
+// reactions, reactions given by users who have opted out are skipped and those they received are anonymised
+func Import(ctx context.Context, store Store, src RecordReader, m Mapping, opts Options) (Report, error) {
+	report := Report{DryRun: opts.DryRun}
+
+	tx, err := store.Begin(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	optOuts := optOuts{tx: tx, checked: make(map[string]bool)}
+
+	for {
+		rec, err := src.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to read file: %w", err)
+		}
+		report.Read++
+
+		r, err := Parse(rec, m)
+		if err != nil {
+			report.Invalid++
+			if len(report.Errors) < maxReportedErrors {
+				report.Errors = append(report.Errors, RowError{Line: rec.Line, Err: err})
+			}
+			continue
+		}
+
+		senderOptedOut, err := optOuts.optedOut(ctx, r.SenderUserID)
+		if err != nil {
+			return report, fmt.Errorf("line %d: failed to check privacy opt-out: %w", rec.Line, err)
+		}
+		if senderOptedOut {
+			report.OptedOut++
+			continue
+		}
+
+		receiverOptedOut, err := optOuts.optedOut(ctx, r.ReceiverUserID)
+		if err != nil {
+			return report, fmt.Errorf("line %d: failed to check privacy opt-out: %w", rec.Line, err)
+		}
+		if receiverOptedOut {
+			r.ReceiverUserID = privacy.AnonymousUserID
+		}
+
+		inserted, err := tx.Insert(ctx, r)
+		if err != nil {
+			return report, fmt.Errorf("line %d: failed to insert reaction: %w", rec.Line, err)
+		}
+		if inserted {
+			report.Imported++
+		} else {
+			report.Duplicates++
+		}
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	if report.Invalid > 0 && !opts.SkipInvalid {
+		return report, ErrInvalidRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("failed to commit: %w", err)
+	}
+	report.Committed = true
+
+	return report, nil
+}
+
+// optOuts caches which users have opted out, as a file usually repeats the same users many times
+type optOuts struct {
+	tx      Tx
+	checked map[string]bool
+}
+
+func (o *optOuts) optedOut(ctx context.Context, userID string) (bool, error) {
+	if userID == privacy.AnonymousUserID {
+		return false, nil
+	}
+
+	if optedOut, ok := o.checked[userID]; ok {
+		return optedOut, nil
+	}
+
+	results, err := o.tx.OptedOut(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	o.checked[userID] = results[userID]
+	return results[userID], nil
+}
+
+// Parse maps and validates a record
+func Parse(rec Record, m Mapping) (Reaction, error) {
+	values := make(map[Field]string, len(Fields))
+	for _, f := range Fields {
+		v := strings.TrimSpace(m.Value(rec, f))
+		if v == "" {
+			return Reaction{}, fmt.Errorf("%s is missing (column %q)", f, m.Column(f))
+		}
+		values[f] = v
+	}
+
+	for _, f := range []Field{FieldGuildID, FieldChannelID, FieldMessageID} {
+		if err := ValidateSnowflake(values[f]); err != nil {
+			return Reaction{}, fmt.Errorf("%s: %w", f, err)
+		}
+	}
+
+	for _, f := range []Field{FieldSenderUserID, FieldReceiverUserID} {
+		if err := validateUserID(values[f]); err != nil {
+			return Reaction{}, fmt.Errorf("%s: %w", f, err)
+		}
+	}
+
+	emojiID, isDefault, err := NormaliseEmoji(values[FieldEmojiID])
+	if err != nil {
+		return Reaction{}, fmt.Errorf("%s: %w", FieldEmojiID, err)
+	}
+
+	createdAt, err := parseTime(values[FieldCreatedAt], m.TimeFormat)
+	if err != nil {
+		return Reaction{}, fmt.Errorf("%s: %w", FieldCreatedAt, err)
+	}
+
+	return Reaction{
+		GuildID:        values[FieldGuildID],
+		ChannelID:      values[FieldChannelID],
+		MessageID:      values[FieldMessageID],
+		EmojiID:        emojiID,
+		IsDefault:      isDefault,
+		SenderUserID:   values[FieldSenderUserID],
+		ReceiverUserID: values[FieldReceiverUserID],
+		CreatedAt:      createdAt,
+	}, nil
+}
@@ -0,0 +1,207 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+)
+
+type fakeStore struct {
+	saved    []Reaction
+	optedOut map[string]bool
+}
+
+func (s *fakeStore) Begin(context.Context) (Tx, error) {
+	return &fakeTx{store: s}, nil
+}
+
+type fakeTx struct {
+	store   *fakeStore
+	pending []Reaction
+}
+
+func (t *fakeTx) Insert(_ context.Context, r Reaction) (bool, error) {
+	for _, existing := range append(t.store.saved, t.pending...) {
+		if existing.GuildID == r.GuildID && existing.MessageID == r.MessageID && existing.EmojiID == r.EmojiID && existing.SenderUserID == r.SenderUserID {
+			return false, nil
+		}
+	}
+	t.pending = append(t.pending, r)
+	return true, nil
+}
+
+func (t *fakeTx) OptedOut(_ context.Context, userIDs ...string) (map[string]bool, error) {
+	results := make(map[string]bool)
+	for _, id := range userIDs {
+		if t.store.optedOut[id] {
+			results[id] = true
+		}
+	}
+	return results, nil
+}
+
+func (t *fakeTx) Commit() error {
+	t.store.saved = append(t.store.saved, t.pending...)
+	t.pending = nil
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.pending = nil
+	return nil
+}
+
+const (
+	guildID   = "100000000000000001"
+	channelID = "100000000000000002"
+	senderID  = "100000000000000003"
+	authorID  = "100000000000000004"
+)
+
+func csvFile(rows ...string) RecordReader {
+	header := "guild_id,channel_id,message_id,emoji_id,sender_user_id,receiver_user_id,created_at\n"
+	r, err := NewRecordReader(FormatCSV, strings.NewReader(header+strings.Join(rows, "\n")))
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func row(messageID, emoji string) string {
+	return fmt.Sprintf("%s,%s,%s,%s,%s,%s,2024-06-10T12:00:00Z", guildID, channelID, messageID, emoji, senderID, authorID)
+}
+
+func TestImport(t *testing.T) {
+	store := &fakeStore{}
+
+	src := csvFile(
+		row("200000000000000001", "👍"),
+		row("200000000000000001", "pepe:123456789012345678"),
+		row("200000000000000001", "👍"), // duplicate within the file
+	)
+
+	report, err := Import(context.Background(), store, src, Mapping{}, Options{})
+
+	require.NoError(t, err)
+	assert.Equal(t, Report{Committed: true, Read: 3, Imported: 2, Duplicates: 1}, report)
+	require.Len(t, store.saved, 2)
+	assert.Equal(t, Reaction{
+		GuildID:        guildID,
+		ChannelID:      channelID,
+		MessageID:      "200000000000000001",
+		EmojiID:        "<:pepe:123456789012345678>",
+		IsDefault:      false,
+		SenderUserID:   senderID,
+		ReceiverUserID: authorID,
+		CreatedAt:      time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC),
+	}, store.saved[1])
+}
+
+func TestImport_ExistingDuplicates(t *testing.T) {
+	store := &fakeStore{}
+	_, err := Import(context.Background(), store, csvFile(row("200000000000000001", "👍")), Mapping{}, Options{})
+	require.NoError(t, err)
+
+	report, err := Import(context.Background(), store, csvFile(row("200000000000000001", "👍")), Mapping{}, Options{})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Imported)
+	assert.Equal(t, 1, report.Duplicates)
+	assert.Len(t, store.saved, 1)
+}
+
+func TestImport_DryRun(t *testing.T) {
+	store := &fakeStore{}
+
+	report, err := Import(context.Background(), store, csvFile(row("200000000000000001", "👍")), Mapping{}, Options{DryRun: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, Report{DryRun: true, Read: 1, Imported: 1}, report)
+	assert.Empty(t, store.saved)
+	assert.Contains(t, report.String(), "Would import: 1")
+	assert.Contains(t, report.String(), "Dry run: nothing was imported")
+}
+
+func TestImport_Invalid(t *testing.T) {
+	src := func() RecordReader {
+		return csvFile(
+			row("200000000000000001", "👍"),
+			row("not-a-snowflake", "👍"),
+			row("200000000000000002", "thumbsup"),
+		)
+	}
+
+	store := &fakeStore{}
+	report, err := Import(context.Background(), store, src(), Mapping{}, Options{})
+
+	assert.ErrorIs(t, err, ErrInvalidRows)
+	assert.False(t, report.Committed)
+	assert.Equal(t, 2, report.Invalid)
+	require.Len(t, report.Errors, 2)
+	assert.Equal(t, 3, report.Errors[0].Line)
+	assert.ErrorContains(t, report.Errors[0].Err, "message_id")
+	assert.ErrorContains(t, report.Errors[1].Err, "emoji_id")
+	assert.Empty(t, store.saved, "nothing should be imported from a file with invalid rows")
+
+	report, err = Import(context.Background(), store, src(), Mapping{}, Options{SkipInvalid: true})
+
+	require.NoError(t, err)
+	assert.True(t, report.Committed)
+	assert.Equal(t, 1, report.Imported)
+	assert.Len(t, store.saved, 1)
+}
+
+func TestImport_Mapping(t *testing.T) {
+	m, err := LoadMapping(strings.NewReader(`{
+		"columns": {"message_id": "msg", "emoji_id": "emoji", "sender_user_id": "reactor", "receiver_user_id": "author", "created_at": "ts", "channel_id": "channel"},
+		"defaults": {"guild_id": "` + guildID + `"},
+		"time_format": "unix"
+	}`))
+	require.NoError(t, err)
+
+	src, err := NewRecordReader(FormatJSONL, strings.NewReader(
+		`{"msg": "200000000000000001", "emoji": "👍", "reactor": "`+senderID+`", "author": "`+authorID+`", "ts": 1718020800, "channel": "`+channelID+`"}`,
+	))
+	require.NoError(t, err)
+
+	store := &fakeStore{}
+	report, err := Import(context.Background(), store, src, m, Options{})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Imported)
+	require.Len(t, store.saved, 1)
+	assert.Equal(t, guildID, store.saved[0].GuildID)
+	assert.Equal(t, time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC), store.saved[0].CreatedAt)
+}
+
+func TestLoadMapping_UnknownField(t *testing.T) {
+	_, err := LoadMapping(strings.NewReader(`{"columns": {"emoji": "e"}}`))
+	assert.ErrorContains(t, err, "unknown field")
+
+	_, err = LoadMapping(strings.NewReader(`{"colums": {}}`))
+	assert.Error(t, err)
+}
+
+func TestImport_OptedOut(t *testing.T) {
+	store := &fakeStore{optedOut: map[string]bool{senderID: true, authorID: true}}
+
+	src := csvFile(
+		row("200000000000000001", "👍"),
+		fmt.Sprintf("%s,%s,200000000000000002,👍,%s,%s,2024-06-10T12:00:00Z", guildID, channelID, "100000000000000005", authorID),
+	)
+
+	report, err := Import(context.Background(), store, src, Mapping{}, Options{})
+
+	require.NoError(t, err)
+	assert.Equal(t, Report{Committed: true, Read: 2, Imported: 1, OptedOut: 1}, report)
+	require.Len(t, store.saved, 1)
+	assert.Equal(t, "100000000000000005", store.saved[0].SenderUserID)
+	assert.Equal(t, privacy.AnonymousUserID, store.saved[0].ReceiverUserID, "reactions received by opted out users should be anonymised")
+}
@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Field is a column of the reactions table which can be imported
+type Field string
+
+const (
+	FieldGuildID        Field = "guild_id"
+	FieldChannelID      Field = "channel_id"
+	FieldMessageID      Field = "message_id"
+	FieldEmojiID        Field = "emoji_id"
+	FieldSenderUserID   Field = "sender_user_id"
+	FieldReceiverUserID Field = "receiver_user_id"
+	FieldCreatedAt      Field = "created_at"
+)
+
+// Fields is every importable field. All of them are required
+var Fields = []Field{FieldGuildID, FieldChannelID, FieldMessageID, FieldEmojiID, FieldSenderUserID, FieldReceiverUserID, FieldCreatedAt}
+
+// Time formats accepted by Mapping.TimeFormat in addition to Go time layouts
+const (
+	TimeFormatRFC3339   = "RFC3339"
+	TimeFormatUnix      = "unix"
+	TimeFormatUnixMilli = "unix_ms"
+)
+
+// Mapping maps the columns of a file to the fields of a reaction, so that files exported by other bots can be
+// imported. For example:
+//
+//	{
+//	  "columns": {"message_id": "msg", "sender_user_id": "reactor", "receiver_user_id": "author"},
+//	  "defaults": {"guild_id": "123456789012345678"},
+//	  "time_format": "unix"
+//	}
+//
+// Fields without a column use the column of the same name, as written by the export command
+type Mapping struct {
+	Columns map[Field]string `json:"columns"`
+	// Defaults are used for fields which are missing or empty in a row, for example when a file covers a single guild
+	Defaults map[Field]string `json:"defaults"`
+	// TimeFormat is "RFC3339" (the default), "unix", "unix_ms" or a Go time layout
+	TimeFormat string `json:"time_format"`
+}
+
+// LoadMapping reads a JSON mapping
+func LoadMapping(r io.Reader) (Mapping, error) {
+	var m Mapping
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return m, fmt.Errorf("invalid mapping: %w", err)
+	}
+
+	for f := range m.Columns {
+		if !slices.Contains(Fields, f) {
+			return m, fmt.Errorf("invalid mapping: unknown field %q", f)
+		}
+	}
+	for f := range m.Defaults {
+		if !slices.Contains(Fields, f) {
+			return m, fmt.Errorf("invalid mapping: unknown field %q", f)
+		}
+	}
+
+	return m, nil
+}
+
+// Column returns the column a field is read from
+func (m Mapping) Column(f Field) string {
+	if c, ok := m.Columns[f]; ok {
+		return c
+	}
+	return string(f)
+}
+
+// Value returns a field's value from a record, falling back to its default
+func (m Mapping) Value(record Record, f Field) string {
+	if v := record.Values[m.Column(f)]; v != "" {
+		return v
+	}
+	return m.Defaults[f]
+}
@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Format is an import file format
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// maxJSONLineLength is the longest line a JSON Lines file may contain
+const maxJSONLineLength = 1024 * 1024
+
+// Record is a row read from an import file, keyed by column
+type Record struct {
+	// Line is the line of the file the record was read from, for reporting errors
+	Line   int
+	Values map[string]string
+}
+
+// RecordReader reads records one at a time. Read returns io.EOF once every record has been read
+type RecordReader interface {
+	Read() (Record, error)
+}
+
+// NewRecordReader creates a RecordReader for the format
+func NewRecordReader(format Format, r io.Reader) (RecordReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSONL:
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, maxJSONLineLength)
+		return &jsonlReader{s: s}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected csv or jsonl", format)
+	}
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	header []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	c := &csvReader{r: csv.NewReader(r)}
+	c.r.ReuseRecord = true
+
+	header, err := c.r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	c.header = append([]string(nil), header...)
+
+	return c, nil
+}
+
+func (c *csvReader) Read() (Record, error) {
+	row, err := c.r.Read()
+	if err != nil {
+		return Record{}, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+	rec := Record{Line: line, Values: make(map[string]string, len(c.header))}
+	for i, col := range c.header {
+		if i < len(row) {
+			rec.Values[col] = row[i]
+		}
+	}
+	return rec, nil
+}
+
+type jsonlReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func (j *jsonlReader) Read() (Record, error) {
+	for j.s.Scan() {
+		j.line++
+
+		b := bytes.TrimSpace(j.s.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			return Record{}, fmt.Errorf("line %d: invalid JSON: %w", j.line, err)
+		}
+
+		rec := Record{Line: j.line, Values: make(map[string]string, len(obj))}
+		for k, v := range obj {
+			switch v := v.(type) {
+			case string:
+				rec.Values[k] = v
+			case json.Number:
+				rec.Values[k] = v.String()
+			case bool:
+				rec.Values[k] = strconv.FormatBool(v)
+			case nil:
+				rec.Values[k] = ""
+			default:
+				return Record{}, fmt.Errorf("line %d: %s must be a string or number", j.line, k)
+			}
+		}
+		return rec, nil
+	}
+
+	if err := j.s.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
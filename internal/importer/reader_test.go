@@ -0,0 +1,65 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, r RecordReader) []Record {
+	t.Helper()
+
+	var records []Record
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	r, err := NewRecordReader(FormatCSV, strings.NewReader("msg,emoji\n111,👍\n222,\"<:pepe:123456789012345678>\"\n"))
+	require.NoError(t, err)
+
+	records := readAll(t, r)
+
+	require.Len(t, records, 2)
+	assert.Equal(t, Record{Line: 2, Values: map[string]string{"msg": "111", "emoji": "👍"}}, records[0])
+	assert.Equal(t, 3, records[1].Line)
+	assert.Equal(t, "<:pepe:123456789012345678>", records[1].Values["emoji"])
+}
+
+func TestJSONLReader(t *testing.T) {
+	input := `{"msg": "111", "emoji": "👍", "ts": 1718022600}
+
+{"msg": 123456789012345678901, "emoji": null, "default": true}
+`
+	r, err := NewRecordReader(FormatJSONL, strings.NewReader(input))
+	require.NoError(t, err)
+
+	records := readAll(t, r)
+
+	require.Len(t, records, 2)
+	assert.Equal(t, Record{Line: 1, Values: map[string]string{"msg": "111", "emoji": "👍", "ts": "1718022600"}}, records[0])
+	assert.Equal(t, Record{Line: 3, Values: map[string]string{"msg": "123456789012345678901", "emoji": "", "default": "true"}}, records[1])
+}
+
+func TestJSONLReader_Invalid(t *testing.T) {
+	r, err := NewRecordReader(FormatJSONL, strings.NewReader("{\"msg\": [1]}\n"))
+	require.NoError(t, err)
+
+	_, err = r.Read()
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestNewRecordReader_UnknownFormat(t *testing.T) {
+	_, err := NewRecordReader("xlsx", strings.NewReader(""))
+	assert.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// Repository handles database queries for imports
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository creates a new import repository
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Begin starts the transaction an import runs in
+func (r *Repository) Begin(ctx context.Context) (Tx, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{tx: tx}, nil
+}
+
+type sqlTx struct {
+	tx *sql.Tx
+}
+
+// Insert inserts a reaction unless the same user has already reacted to the message with the same emoji, which also
+// catches duplicates earlier in the same import
+func (t *sqlTx) Insert(ctx context.Context, r Reaction) (bool, error) {
+	res, err := t.tx.ExecContext(ctx, `
+		INSERT INTO reactions (guild_id, channel_id, message_id, emoji_id, is_default, sender_user_id, receiver_user_id, created_at)
+		SELECT $1::text, $2::text, $3::text, $4::text, $5::boolean, $6::text, $7::text, $8::timestamptz
+		WHERE NOT EXISTS (
+			SELECT 1 FROM reactions
+			WHERE guild_id = $1 AND message_id = $3 AND emoji_id = $4 AND sender_user_id = $6
+		)`,
+		r.GuildID,
+		r.ChannelID,
+		r.MessageID,
+		r.EmojiID,
+		r.IsDefault,
+		r.SenderUserID,
+		r.ReceiverUserID,
+		r.CreatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (t *sqlTx) OptedOut(ctx context.Context, userIDs ...string) (map[string]bool, error) {
+	rows, err := t.tx.QueryContext(ctx, `SELECT user_id FROM privacy_opt_outs WHERE user_id = ANY($1)`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	results := make(map[string]bool)
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		results[userID] = true
+	}
+	return results, rows.Err()
+}
+
+func (t *sqlTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *sqlTx) Rollback() error {
+	return t.tx.Rollback()
+}
@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+)
+
+var testDB *sql.DB
+
+func setupTest(t *testing.T) (*Repository, string, func()) {
+	t.Helper()
+
+	testDB = dbtest.Open(t)
+
+	// imported guild IDs must be snowflakes
+	guildID := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	cleanup := func() {
+		_, _ = testDB.Exec("DELETE FROM reactions WHERE guild_id = $1", guildID)
+	}
+
+	return NewRepository(testDB), guildID, cleanup
+}
+
+func countReactions(t *testing.T, guildID string) int {
+	t.Helper()
+
+	var count int
+	require.NoError(t, testDB.QueryRow("SELECT COUNT(*) FROM reactions WHERE guild_id = $1", guildID).Scan(&count))
+	return count
+}
+
+func TestInsert(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	r := Reaction{
+		GuildID:        guildID,
+		ChannelID:      "channel1",
+		MessageID:      "msg1",
+		EmojiID:        "👍",
+		IsDefault:      true,
+		SenderUserID:   "sender1",
+		ReceiverUserID: "receiver1",
+		CreatedAt:      time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC),
+	}
+
+	tx, err := repo.Begin(ctx)
+	require.NoError(t, err)
+
+	inserted, err := tx.Insert(ctx, r)
+	require.NoError(t, err)
+	assert.True(t, inserted)
+
+	inserted, err = tx.Insert(ctx, r)
+	require.NoError(t, err)
+	assert.False(t, inserted, "duplicate within the transaction should be skipped")
+
+	other := r
+	other.SenderUserID = "sender2"
+	inserted, err = tx.Insert(ctx, other)
+	require.NoError(t, err)
+	assert.True(t, inserted)
+
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, 2, countReactions(t, guildID))
+
+	tx, err = repo.Begin(ctx)
+	require.NoError(t, err)
+
+	inserted, err = tx.Insert(ctx, r)
+	require.NoError(t, err)
+	assert.False(t, inserted, "existing reaction should be skipped")
+	require.NoError(t, tx.Rollback())
+}
+
+func TestOptedOut(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	userID := "opted-out-" + guildID
+	_, err := testDB.Exec(`INSERT INTO privacy_opt_outs (user_id) VALUES ($1)`, userID)
+	require.NoError(t, err)
+	defer func() { _, _ = testDB.Exec(`DELETE FROM privacy_opt_outs WHERE user_id = $1`, userID) }()
+
+	tx, err := repo.Begin(ctx)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+
+	optedOut, err := tx.OptedOut(ctx, userID, "other")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{userID: true}, optedOut)
+}
+
+func TestImport_Rollback(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+
+	src, err := NewRecordReader(FormatCSV, strings.NewReader(
+		"guild_id,channel_id,message_id,emoji_id,sender_user_id,receiver_user_id,created_at\n"+
+			guildID+",100000000000000002,100000000000000003,👍,100000000000000004,100000000000000005,2024-06-10T12:00:00Z\n",
+	))
+	require.NoError(t, err)
+
+	report, err := Import(context.Background(), repo, src, Mapping{}, Options{DryRun: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Imported)
+	assert.Equal(t, 0, countReactions(t, guildID), "dry run should not write any rows")
+}
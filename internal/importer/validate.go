@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+)
+
+var (
+	// customEmojiPattern matches the message format stored by ingestion, e.g. <:name:id> or <a:name:id>
+	customEmojiPattern = regexp.MustCompile(`^<(a?):([A-Za-z0-9_]{2,32}):([0-9]{17,20})>$`)
+	// apiEmojiPattern matches the API format of a custom emoji, name:id, which does not say whether it is animated
+	apiEmojiPattern = regexp.MustCompile(`^([A-Za-z0-9_]{2,32}):([0-9]{17,20})$`)
+)
+
+// maxUnicodeEmojiLength is long enough for the longest emoji ZWJ sequences
+const maxUnicodeEmojiLength = 64
+
+// ValidateSnowflake checks that an ID is a Discord snowflake
+func ValidateSnowflake(id string) error {
+	if len(id) < 17 || len(id) > 20 {
+		return fmt.Errorf("%q is not a snowflake ID", id)
+	}
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return fmt.Errorf("%q is not a snowflake ID", id)
+	}
+	return nil
+}
+
+// validateUserID checks that an ID is a snowflake, or the ID used for anonymised users
+func validateUserID(id string) error {
+	if id == privacy.AnonymousUserID {
+		return nil
+	}
+	return ValidateSnowflake(id)
+}
+
+// NormaliseEmoji validates an emoji and returns it in the format stored in the reactions table, along with whether it is
+// a default (unicode) emoji. Custom emojis are accepted as <:name:id>, <a:name:id> or name:id
+func NormaliseEmoji(s string) (string, bool, error) {
+	if customEmojiPattern.MatchString(s) {
+		return s, false, nil
+	}
+	if m := apiEmojiPattern.FindStringSubmatch(s); m != nil {
+		return fmt.Sprintf("<:%s:%s>", m[1], m[2]), false, nil
+	}
+
+	if s == "" || len(s) > maxUnicodeEmojiLength || !utf8.ValidString(s) {
+		return "", false, fmt.Errorf("%q is not a valid emoji", s)
+	}
+
+	nonASCII := false
+	for _, r := range s {
+		if unicode.IsSpace(r) || unicode.IsLetter(r) {
+			return "", false, fmt.Errorf("%q is not a valid emoji", s)
+		}
+		if r >= utf8.RuneSelf {
+			nonASCII = true
+		}
+	}
+	if !nonASCII {
+		return "", false, fmt.Errorf("%q is not a valid emoji", s)
+	}
+
+	return s, true, nil
+}
+
+// parseTime parses a timestamp in the mapping's time format
+func parseTime(s, format string) (time.Time, error) {
+	switch format {
+	case "", TimeFormatRFC3339:
+		return time.Parse(time.RFC3339Nano, s)
+	case TimeFormatUnix, TimeFormatUnixMilli:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q is not a unix timestamp", s)
+		}
+		if format == TimeFormatUnix {
+			return time.Unix(n, 0).UTC(), nil
+		}
+		return time.UnixMilli(n).UTC(), nil
+	default:
+		return time.Parse(format, s)
+	}
+}
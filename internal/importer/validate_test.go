@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSnowflake(t *testing.T) {
+	assert.NoError(t, ValidateSnowflake("123456789012345678"))
+	assert.NoError(t, ValidateSnowflake("12345678901234567890"))
+
+	for _, id := range []string{"", "123", "12345678901234567a", "123456789012345678901", "-12345678901234567"} {
+		assert.Error(t, ValidateSnowflake(id), id)
+	}
+}
+
+func TestNormaliseEmoji(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		isDefault bool
+	}{
+		{"👍", "👍", true},
+		{"❤️", "❤️", true},
+		{"1️⃣", "1️⃣", true},
+		{"👨‍👩‍👧", "👨‍👩‍👧", true},
+		{"<:pepe:123456789012345678>", "<:pepe:123456789012345678>", false},
+		{"<a:party:123456789012345678>", "<a:party:123456789012345678>", false},
+		{"angry:123456789012345678", "<:angry:123456789012345678>", false},
+	}
+
+	for _, tt := range tests {
+		emoji, isDefault, err := NormaliseEmoji(tt.input)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, emoji)
+		assert.Equal(t, tt.isDefault, isDefault, tt.input)
+	}
+}
+
+func TestNormaliseEmoji_Invalid(t *testing.T) {
+	for _, input := range []string{"", "thumbsup", ":thumbsup:", "<:pepe:123>", "<:pepe:123456789012345678", "👍 👍", "é"} {
+		_, _, err := NormaliseEmoji(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	expected := time.Date(2024, 6, 10, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input  string
+		format string
+	}{
+		{"2024-06-10T12:30:00Z", ""},
+		{"2024-06-10T13:30:00+01:00", TimeFormatRFC3339},
+		{"1718022600", TimeFormatUnix},
+		{"1718022600000", TimeFormatUnixMilli},
+		{"2024-06-10 12:30", "2006-01-02 15:04"},
+	}
+
+	for _, tt := range tests {
+		parsed, err := parseTime(tt.input, tt.format)
+		require.NoError(t, err, tt.input)
+		assert.True(t, expected.Equal(parsed), tt.input)
+	}
+
+	_, err := parseTime("yesterday", TimeFormatUnix)
+	assert.Error(t, err)
+}
@@ -0,0 +1,560 @@
+// Package memory is an in-memory stats.Store, for unit tests which do not need a database. Its results are identical
+// to those of the database stores, which is checked by the conformance tests in storetest
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// Store is a stats.Store which holds reactions in memory. It is safe for concurrent use
+type Store struct {
+	mu        sync.RWMutex
+	reactions []stats.Reaction
+	timezones map[string]*time.Location
+	settings  map[string]stats.Settings
+	// parents are each guild's recorded channel parents, by channel ID
+	parents  map[string]map[string]string
+	optedOut map[string]bool
+	now      func() time.Time
+}
+
+var _ stats.Store = (*Store)(nil)
+
+// NewStore creates a new empty store
+func NewStore() *Store {
+	return &Store{
+		timezones: make(map[string]*time.Location),
+		settings:  make(map[string]stats.Settings),
+		parents:   make(map[string]map[string]string),
+		optedOut:  make(map[string]bool),
+		now:       time.Now,
+	}
+}
+
+// AddReaction stores a reaction. A zero CreatedAt is stored as the current time
+func (s *Store) AddReaction(_ context.Context, reaction stats.Reaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reaction.CreatedAt.IsZero() {
+		reaction.CreatedAt = s.now()
+	}
+	s.reactions = append(s.reactions, reaction)
+	return nil
+}
+
+// RemoveReaction deletes a user's reaction to a message and returns the number of reactions deleted
+func (s *Store) RemoveReaction(_ context.Context, guildID, messageID, emojiID, senderUserID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.reactions)
+	s.reactions = slices.DeleteFunc(s.reactions, func(r stats.Reaction) bool {
+		return r.GuildID == guildID && r.MessageID == messageID && r.EmojiID == emojiID && r.SenderUserID == senderUserID
+	})
+	return n - len(s.reactions), nil
+}
+
+// OptOut marks a user as opted out, so that they are shown anonymously
+func (s *Store) OptOut(_ context.Context, _, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.optedOut[userID] = true
+	return nil
+}
+
+// OptIn removes a user's opt-out
+func (s *Store) OptIn(_ context.Context, _, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.optedOut, userID)
+	return nil
+}
+
+// Forget deletes the reactions a user gave and anonymises those they received in every guild, then opts them out
+func (s *Store) Forget(_ context.Context, _, userID string) (privacy.Forgotten, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var f privacy.Forgotten
+
+	n := len(s.reactions)
+	s.reactions = slices.DeleteFunc(s.reactions, func(r stats.Reaction) bool {
+		return r.SenderUserID == userID
+	})
+	f.Deleted = n - len(s.reactions)
+
+	for i := range s.reactions {
+		if s.reactions[i].ReceiverUserID == userID {
+			s.reactions[i].ReceiverUserID = privacy.AnonymousUserID
+			f.Anonymised++
+		}
+	}
+
+	s.optedOut[userID] = true
+	return f, nil
+}
+
+// OptedOut returns which of the given users have opted out of having their reactions stored
+func (s *Store) OptedOut(_ context.Context, userIDs ...string) (map[string]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make(map[string]bool)
+	for _, id := range userIDs {
+		if s.optedOut[id] {
+			results[id] = true
+		}
+	}
+	return results, nil
+}
+
+// GetTimezone retrieves the timezone used to group a guild's reactions into days. It defaults to UTC
+func (s *Store) GetTimezone(_ context.Context, guildID string) (*time.Location, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if loc, ok := s.timezones[guildID]; ok {
+		return loc, nil
+	}
+	return time.UTC, nil
+}
+
+// SetTimezone sets the timezone used to group a guild's reactions into days
+func (s *Store) SetTimezone(_ context.Context, guildID string, loc *time.Location) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.timezones[guildID] = loc
+	return nil
+}
+
+// GetSettings retrieves a guild's settings. A guild which has not configured any has stats.DefaultSettings
+func (s *Store) GetSettings(_ context.Context, guildID string) (stats.Settings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if settings, ok := s.settings[guildID]; ok {
+		return cloneSettings(settings), nil
+	}
+	return stats.DefaultSettings(), nil
+}
+
+// SetSettings stores a guild's settings
+func (s *Store) SetSettings(_ context.Context, guildID string, settings stats.Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.settings[guildID] = cloneSettings(settings)
+	return nil
+}
+
+// SetChannelParent records a channel's parent, so that its reactions are excluded from the stats while an ancestor is
+// ignored. It returns whether the recorded parent changed
+func (s *Store) SetChannelParent(_ context.Context, guildID, channelID, parentID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.parents[guildID][channelID] == parentID {
+		return false, nil
+	}
+
+	if parentID == "" {
+		delete(s.parents[guildID], channelID)
+		return true, nil
+	}
+
+	if s.parents[guildID] == nil {
+		s.parents[guildID] = make(map[string]string)
+	}
+	s.parents[guildID][channelID] = parentID
+	return true, nil
+}
+
+// ancestors returns a channel's recorded parent, its parent's parent and so on. The caller must hold the lock
+func (s *Store) ancestors(guildID, channelID string) []string {
+	var ids []string
+	for id := s.parents[guildID][channelID]; id != "" && !slices.Contains(ids, id); id = s.parents[guildID][id] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func cloneSettings(settings stats.Settings) stats.Settings {
+	settings.IgnoredChannels = slices.Clone(settings.IgnoredChannels)
+	settings.IgnoredCategories = slices.Clone(settings.IgnoredCategories)
+	settings.IgnoredRoles = slices.Clone(settings.IgnoredRoles)
+	return settings
+}
+
+// GetGuildStats retrieves aggregated stats for a guild
+func (s *Store) GetGuildStats(_ context.Context, guildID string, dateRange stats.DateRange, n int) (*stats.GuildStats, error) {
+	reactions := s.find(guildID, dateRange, nil)
+
+	result := &stats.GuildStats{
+		TotalReactions: len(reactions),
+		TopEmojis:      topEmojis(reactions, n),
+		TopSenders:     topUsers(reactions, sender, n),
+		TopReceivers:   topUsers(reactions, receiver, n),
+	}
+
+	s.anonymise(append(userIDs(result.TopSenders), userIDs(result.TopReceivers)...)...)
+	return result, nil
+}
+
+// GetEmojiStats retrieves detailed stats for a specific emoji
+func (s *Store) GetEmojiStats(_ context.Context, guildID, emojiID string, dateRange stats.DateRange, n int) (*stats.EmojiStats, error) {
+	reactions := s.find(guildID, dateRange, func(r stats.Reaction) bool { return r.EmojiID == emojiID })
+
+	result := &stats.EmojiStats{
+		EmojiID:      emojiID,
+		TotalUses:    len(reactions),
+		IsDefault:    slices.ContainsFunc(reactions, func(r stats.Reaction) bool { return r.IsDefault }),
+		TopSenders:   topUsers(reactions, sender, n),
+		TopReceivers: topUsers(reactions, receiver, n),
+	}
+
+	type message struct{ messageID, channelID string }
+	counts := countBy(reactions, func(r stats.Reaction) message { return message{r.MessageID, r.ChannelID} })
+	for m, c := range counts {
+		result.TopMessages = append(result.TopMessages, stats.MessageCount{MessageID: m.messageID, ChannelID: m.channelID, Count: c})
+	}
+	slices.SortFunc(result.TopMessages, func(a, b stats.MessageCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.MessageID, b.MessageID), cmp.Compare(a.ChannelID, b.ChannelID))
+	})
+	result.TopMessages = limit(result.TopMessages, n)
+
+	s.anonymise(append(userIDs(result.TopSenders), userIDs(result.TopReceivers)...)...)
+	return result, nil
+}
+
+// GetTopEmojis retrieves the most used emojis in a guild
+func (s *Store) GetTopEmojis(_ context.Context, guildID string, dateRange stats.DateRange, n int) ([]stats.EmojiCount, error) {
+	return topEmojis(s.find(guildID, dateRange, nil), n), nil
+}
+
+// GetTopChannels retrieves the channels with the most reactions in a guild
+func (s *Store) GetTopChannels(_ context.Context, guildID string, dateRange stats.DateRange, n int) ([]stats.ChannelCount, error) {
+	var results []stats.ChannelCount
+	for channelID, c := range countBy(s.find(guildID, dateRange, nil), func(r stats.Reaction) string { return r.ChannelID }) {
+		results = append(results, stats.ChannelCount{ChannelID: channelID, Count: c})
+	}
+	slices.SortFunc(results, func(a, b stats.ChannelCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.ChannelID, b.ChannelID))
+	})
+	return limit(results, n), nil
+}
+
+// GetTopMessages retrieves the most reacted messages in a guild across all emojis, optionally filtered by channel
+func (s *Store) GetTopMessages(_ context.Context, guildID, channelID string, dateRange stats.DateRange, n int) ([]stats.TopMessage, error) {
+	messages := topMessages(s.find(guildID, dateRange, func(r stats.Reaction) bool {
+		return channelID == "" || r.ChannelID == channelID
+	}), s.find(guildID, dateRange, nil), n)
+
+	ids := make([]*string, len(messages))
+	for i := range messages {
+		ids[i] = &messages[i].AuthorID
+	}
+
+	s.anonymise(ids...)
+	return messages, nil
+}
+
+// GetWrapped retrieves a year in review recap for a guild. If userID is set the recap is for that user. The year and its
+// months are in the guild's timezone
+func (s *Store) GetWrapped(ctx context.Context, guildID, userID string, year int) (*stats.Wrapped, error) {
+	loc, err := s.GetTimezone(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(1, 0, 0)
+	dateRange := stats.DateRange{Start: &start, End: &end}
+
+	all := s.find(guildID, dateRange, nil)
+	given := all
+	if userID != "" {
+		given = s.find(guildID, dateRange, func(r stats.Reaction) bool { return r.SenderUserID == userID })
+	}
+
+	w := &stats.Wrapped{Year: year, UserID: userID, TotalReactions: len(given)}
+
+	if len(given) > 0 {
+		favourite := topEmojis(given, 1)
+		w.FavouriteEmoji = &favourite[0]
+
+		for _, r := range given {
+			w.MonthlyCounts[r.CreatedAt.In(loc).Month()-1]++
+		}
+	}
+
+	received := all
+	if userID == "" {
+		if topSenders := topUsers(all, sender, 1); len(topSenders) > 0 {
+			w.TopFan = &topSenders[0]
+		}
+	} else {
+		received = s.find(guildID, dateRange, func(r stats.Reaction) bool { return r.ReceiverUserID == userID })
+		w.TotalReceived = len(received)
+
+		fans := slices.DeleteFunc(slices.Clone(received), func(r stats.Reaction) bool { return r.SenderUserID == userID })
+		if topFans := topUsers(fans, sender, 1); len(topFans) > 0 {
+			w.TopFan = &topFans[0]
+		}
+
+		w.SenderRank = rank(all, sender, w.TotalReactions)
+		w.ReceiverRank = rank(all, receiver, w.TotalReceived)
+
+		if streaks := s.streaks(all, userID, loc, false, 1); len(streaks) > 0 {
+			w.Streak = streaks[0]
+		}
+	}
+
+	if topMessages := topMessages(received, all, 1); len(topMessages) > 0 {
+		w.TopMessage = &topMessages[0]
+	}
+
+	ids := []*string{&w.UserID}
+	if w.TopFan != nil {
+		ids = append(ids, &w.TopFan.UserID)
+	}
+	if w.TopMessage != nil {
+		ids = append(ids, &w.TopMessage.AuthorID)
+	}
+
+	s.anonymise(ids...)
+	return w, nil
+}
+
+// GetStreak retrieves a user's current and longest streaks, counting days in the guild's timezone
+func (s *Store) GetStreak(ctx context.Context, guildID, userID string) (stats.Streak, error) {
+	loc, err := s.GetTimezone(ctx, guildID)
+	if err != nil {
+		return stats.Streak{}, err
+	}
+
+	streak := stats.Streak{UserID: userID}
+	if streaks := s.streaks(s.find(guildID, stats.DateRange{}, nil), userID, loc, false, 1); len(streaks) > 0 {
+		streak = streaks[0]
+	}
+
+	s.anonymise(&streak.UserID)
+	return streak, nil
+}
+
+// GetStreaks retrieves the users with the highest current streaks, or the highest longest streaks if byLongest is set
+func (s *Store) GetStreaks(ctx context.Context, guildID string, byLongest bool, n int) ([]stats.Streak, error) {
+	loc, err := s.GetTimezone(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	streaks := s.streaks(s.find(guildID, stats.DateRange{}, nil), "", loc, byLongest, n)
+
+	ids := make([]*string, len(streaks))
+	for i := range streaks {
+		ids[i] = &streaks[i].UserID
+	}
+
+	s.anonymise(ids...)
+	return streaks, nil
+}
+
+// find returns the reactions in a guild within the date range which match the predicate, if it is set, excluding those
+// in the channels the guild ignores
+func (s *Store) find(guildID string, dateRange stats.DateRange, match func(stats.Reaction) bool) []stats.Reaction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := s.settings[guildID]
+
+	var results []stats.Reaction
+	for _, r := range s.reactions {
+		if r.GuildID != guildID || !inRange(r.CreatedAt, dateRange) {
+			continue
+		}
+		if _, ignored := settings.IgnoringChannel(r.ChannelID, s.ancestors(guildID, r.ChannelID)...); ignored {
+			continue
+		}
+		if match != nil && !match(r) {
+			continue
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// streaks computes the streaks of the users who gave or received the reactions, or only of userID if it is set
+func (s *Store) streaks(reactions []stats.Reaction, userID string, loc *time.Location, byLongest bool, n int) []stats.Streak {
+	days := make(map[string][]time.Time)
+	for _, r := range reactions {
+		for _, id := range []string{r.SenderUserID, r.ReceiverUserID} {
+			if userID == "" || id == userID {
+				days[id] = append(days[id], stats.Day(r.CreatedAt, loc))
+			}
+		}
+	}
+
+	return stats.ComputeStreaks(days, stats.Day(s.now(), loc), byLongest, n)
+}
+
+// anonymise replaces the IDs of users who have opted out with privacy.AnonymousUserID
+func (s *Store) anonymise(ids ...*string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, id := range ids {
+		if s.optedOut[*id] {
+			*id = privacy.AnonymousUserID
+		}
+	}
+}
+
+func sender(r stats.Reaction) string   { return r.SenderUserID }
+func receiver(r stats.Reaction) string { return r.ReceiverUserID }
+
+func topEmojis(reactions []stats.Reaction, n int) []stats.EmojiCount {
+	type emoji struct {
+		id        string
+		isDefault bool
+	}
+
+	var results []stats.EmojiCount
+	for e, c := range countBy(reactions, func(r stats.Reaction) emoji { return emoji{r.EmojiID, r.IsDefault} }) {
+		results = append(results, stats.EmojiCount{EmojiID: e.id, IsDefault: e.isDefault, Count: c})
+	}
+	slices.SortFunc(results, func(a, b stats.EmojiCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.EmojiID, b.EmojiID), compareBool(a.IsDefault, b.IsDefault))
+	})
+	return limit(results, n)
+}
+
+// topUsers counts the reactions for each user returned by user
+func topUsers(reactions []stats.Reaction, user func(stats.Reaction) string, n int) []stats.UserCount {
+	results := userCounts(reactions, user)
+	slices.SortFunc(results, func(a, b stats.UserCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.UserID, b.UserID))
+	})
+	return limit(results, n)
+}
+
+// topMessages ranks the messages of the reactions. The top emojis of each message are counted from all of the
+// guild's reactions, as a message's reactions may have been filtered out by the ranking
+func topMessages(reactions, all []stats.Reaction, n int) []stats.TopMessage {
+	type message struct{ messageID, channelID string }
+
+	byMessage := make(map[message][]stats.Reaction)
+	for _, r := range reactions {
+		m := message{r.MessageID, r.ChannelID}
+		byMessage[m] = append(byMessage[m], r)
+	}
+
+	var results []stats.TopMessage
+	for m, rs := range byMessage {
+		tm := stats.TopMessage{
+			MessageID:      m.messageID,
+			ChannelID:      m.channelID,
+			TotalReactions: len(rs),
+			UniqueReactors: len(countBy(rs, sender)),
+		}
+		for _, r := range rs {
+			tm.AuthorID = max(tm.AuthorID, r.ReceiverUserID)
+		}
+		results = append(results, tm)
+	}
+	slices.SortFunc(results, func(a, b stats.TopMessage) int {
+		return cmp.Or(
+			cmp.Compare(b.TotalReactions, a.TotalReactions),
+			cmp.Compare(b.UniqueReactors, a.UniqueReactors),
+			cmp.Compare(a.MessageID, b.MessageID),
+			cmp.Compare(a.ChannelID, b.ChannelID),
+		)
+	})
+	results = limit(results, n)
+
+	for i := range results {
+		onMessage := slices.DeleteFunc(slices.Clone(all), func(r stats.Reaction) bool { return r.MessageID != results[i].MessageID })
+		results[i].TopEmojis = topEmojis(onMessage, 3)
+	}
+	return results
+}
+
+// rank ranks a count against the per-user counts of the reactions
+func rank(reactions []stats.Reaction, user func(stats.Reaction) string, count int) stats.Rank {
+	counts := userCounts(reactions, user)
+
+	r := stats.Rank{Total: len(counts), Position: 1}
+	for _, uc := range counts {
+		if uc.Count > count {
+			r.Position++
+		}
+	}
+
+	// users without any reactions are not included in the counts, so are ranked last
+	if r.Position > r.Total {
+		r.Total = r.Position
+	}
+	return r
+}
+
+func userCounts(reactions []stats.Reaction, user func(stats.Reaction) string) []stats.UserCount {
+	var results []stats.UserCount
+	for id, c := range countBy(reactions, user) {
+		results = append(results, stats.UserCount{UserID: id, Count: c})
+	}
+	return results
+}
+
+func countBy[K comparable](reactions []stats.Reaction, key func(stats.Reaction) K) map[K]int {
+	counts := make(map[K]int)
+	for _, r := range reactions {
+		counts[key(r)]++
+	}
+	return counts
+}
+
+func userIDs(counts []stats.UserCount) []*string {
+	ids := make([]*string, len(counts))
+	for i := range counts {
+		ids[i] = &counts[i].UserID
+	}
+	return ids
+}
+
+func inRange(t time.Time, dateRange stats.DateRange) bool {
+	if dateRange.Start != nil && t.Before(*dateRange.Start) {
+		return false
+	}
+	if dateRange.End != nil && !t.Before(*dateRange.End) {
+		return false
+	}
+	return true
+}
+
+func limit[T any](s []T, n int) []T {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,16 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/elliotwms/emojistats/internal/stats"
+	"github.com/elliotwms/emojistats/internal/stats/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, storetest.Backend{
+		NewStore: func(*testing.T, string) stats.Store {
+			return NewStore()
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+)
+
+// Commands wraps each command's handler to record its invocations and latency
+func Commands(commands map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler) map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler {
+	for c, h := range commands {
+		commands[c] = Command(c.Name, h)
+	}
+	return commands
+}
+
+// Command wraps a command handler to record its invocations and latency
+func Command(name string, h router.ApplicationCommandHandler) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		start := time.Now()
+		err := h(ctx, s, i, data)
+		CommandDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+
+		status := "ok"
+		if err != nil {
+			status = "error"
+		}
+		CommandInvocations.WithLabelValues(name, status).Inc()
+
+		return err
+	}
+}
@@ -0,0 +1,148 @@
+// Package metrics exposes Prometheus metrics for operating the bot: reaction ingestion, Discord API lookups, commands,
+// database queries, the stats cache and the gateway connection
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/elliotwms/emojistats/internal/httpserver"
+)
+
+const namespace = "emojistats"
+
+// Registry is the registry of all of the bot's metrics, together with the Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	ReactionsIngested = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reactions_ingested_total",
+		Help:      "Reactions stored, by guild",
+	}, []string{"guild_id"})
+
+	ReactionsRemoved = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reactions_removed_total",
+		Help:      "Reactions deleted after being removed in Discord, by guild",
+	}, []string{"guild_id"})
+
+	// ReactionsFailed is reaction events which could not be handled, by guild and event, which is either add or remove
+	ReactionsFailed = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reactions_failed_total",
+		Help:      "Reaction events which failed to be handled, by guild and event",
+	}, []string{"guild_id", "event"})
+
+	ChannelMessageDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "channel_message_duration_seconds",
+		Help:      "Latency of looking up messages with the Discord API",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	ChannelMessageFailures = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_message_failures_total",
+		Help:      "Failed message lookups with the Discord API",
+	})
+
+	// CommandInvocations is command invocations, by command and status, which is either ok or error. Commands which
+	// respond to the user with an error count as errors
+	CommandInvocations = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "command_invocations_total",
+		Help:      "Command invocations, by command and status",
+	}, []string{"command", "status"})
+
+	CommandDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_duration_seconds",
+		Help:      "Latency of handling commands, by command",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command"})
+
+	QueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of stats database queries, by repository method",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// CacheLookups is stats cache lookups, by query type and result, which is either hit or miss
+	CacheLookups = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Stats cache lookups, by query type and result",
+	}, []string{"query", "result"})
+
+	GatewayConnected = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gateway_connected",
+		Help:      "Whether the bot is connected to the Discord gateway",
+	})
+
+	GatewayDisconnects = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gateway_disconnects_total",
+		Help:      "Disconnections from the Discord gateway",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ListenAndServe serves the metrics at /metrics on addr until the context is done
+func ListenAndServe(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler())
+
+	return httpserver.ListenAndServe(ctx, "metrics", addr, mux)
+}
+
+// ChannelMessage gets a message from the Discord API, recording the latency and failures of the lookup
+func ChannelMessage(ctx context.Context, s *discordgo.Session, channelID, messageID string) (*discordgo.Message, error) {
+	start := time.Now()
+	msg, err := s.ChannelMessage(channelID, messageID, discordgo.WithContext(ctx))
+	ChannelMessageDuration.Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		ChannelMessageFailures.Inc()
+	}
+
+	return msg, err
+}
+
+// Connect records that the gateway connected
+func Connect(*discordgo.Session, *discordgo.Connect) {
+	GatewayConnected.Set(1)
+}
+
+// Disconnect records that the gateway disconnected
+func Disconnect(*discordgo.Session, *discordgo.Disconnect) {
+	GatewayConnected.Set(0)
+	GatewayDisconnects.Inc()
+}
+
+// Resumed records that the gateway reconnected by resuming its session
+func Resumed(*discordgo.Session, *discordgo.Resumed) {
+	GatewayConnected.Set(1)
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/memory"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+func TestCommand(t *testing.T) {
+	ok := Command("test-ok", func(context.Context, *discordgo.Session, *discordgo.InteractionCreate, discordgo.ApplicationCommandInteractionData) error {
+		return nil
+	})
+	failing := Command("test-error", func(context.Context, *discordgo.Session, *discordgo.InteractionCreate, discordgo.ApplicationCommandInteractionData) error {
+		return errors.New("failed")
+	})
+
+	require.NoError(t, ok(context.Background(), nil, nil, discordgo.ApplicationCommandInteractionData{}))
+	require.NoError(t, ok(context.Background(), nil, nil, discordgo.ApplicationCommandInteractionData{}))
+	require.Error(t, failing(context.Background(), nil, nil, discordgo.ApplicationCommandInteractionData{}))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(CommandInvocations.WithLabelValues("test-ok", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(CommandInvocations.WithLabelValues("test-error", "error")))
+	assert.Zero(t, testutil.ToFloat64(CommandInvocations.WithLabelValues("test-error", "ok")))
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore(memory.NewStore())
+
+	_, err := s.GetGuildStats(context.Background(), "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Contains(t, w.Body.String(), `emojistats_db_query_duration_seconds_count{method="GetGuildStats"} 1`)
+}
+
+func TestGateway(t *testing.T) {
+	Connect(nil, nil)
+	assert.Equal(t, 1.0, testutil.ToFloat64(GatewayConnected))
+
+	disconnects := testutil.ToFloat64(GatewayDisconnects)
+	Disconnect(nil, nil)
+	assert.Zero(t, testutil.ToFloat64(GatewayConnected))
+	assert.Equal(t, disconnects+1, testutil.ToFloat64(GatewayDisconnects))
+
+	Resumed(nil, nil)
+	assert.Equal(t, 1.0, testutil.ToFloat64(GatewayConnected))
+}
+
+func TestHandler(t *testing.T) {
+	ReactionsIngested.WithLabelValues("guild1").Inc()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `emojistats_reactions_ingested_total{guild_id="guild1"} 1`)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
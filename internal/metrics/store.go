@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// Store is a stats.Store which records the duration of each of another store's methods
+type Store struct {
+	next stats.Store
+}
+
+var _ stats.Store = (*Store)(nil)
+
+// NewStore records the query durations of next
+func NewStore(next stats.Store) *Store {
+	return &Store{next: next}
+}
+
+func (s *Store) GetGuildStats(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) (*stats.GuildStats, error) {
+	defer observe("GetGuildStats", time.Now())
+	return s.next.GetGuildStats(ctx, guildID, dateRange, limit)
+}
+
+func (s *Store) GetEmojiStats(ctx context.Context, guildID, emojiID string, dateRange stats.DateRange, limit int) (*stats.EmojiStats, error) {
+	defer observe("GetEmojiStats", time.Now())
+	return s.next.GetEmojiStats(ctx, guildID, emojiID, dateRange, limit)
+}
+
+func (s *Store) GetTopEmojis(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) ([]stats.EmojiCount, error) {
+	defer observe("GetTopEmojis", time.Now())
+	return s.next.GetTopEmojis(ctx, guildID, dateRange, limit)
+}
+
+func (s *Store) GetTopChannels(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) ([]stats.ChannelCount, error) {
+	defer observe("GetTopChannels", time.Now())
+	return s.next.GetTopChannels(ctx, guildID, dateRange, limit)
+}
+
+func (s *Store) GetTopMessages(ctx context.Context, guildID, channelID string, dateRange stats.DateRange, limit int) ([]stats.TopMessage, error) {
+	defer observe("GetTopMessages", time.Now())
+	return s.next.GetTopMessages(ctx, guildID, channelID, dateRange, limit)
+}
+
+func (s *Store) GetWrapped(ctx context.Context, guildID, userID string, year int) (*stats.Wrapped, error) {
+	defer observe("GetWrapped", time.Now())
+	return s.next.GetWrapped(ctx, guildID, userID, year)
+}
+
+func (s *Store) GetStreak(ctx context.Context, guildID, userID string) (stats.Streak, error) {
+	defer observe("GetStreak", time.Now())
+	return s.next.GetStreak(ctx, guildID, userID)
+}
+
+func (s *Store) GetStreaks(ctx context.Context, guildID string, byLongest bool, limit int) ([]stats.Streak, error) {
+	defer observe("GetStreaks", time.Now())
+	return s.next.GetStreaks(ctx, guildID, byLongest, limit)
+}
+
+func (s *Store) AddReaction(ctx context.Context, reaction stats.Reaction) error {
+	defer observe("AddReaction", time.Now())
+	return s.next.AddReaction(ctx, reaction)
+}
+
+func (s *Store) RemoveReaction(ctx context.Context, guildID, messageID, emojiID, senderUserID string) (int, error) {
+	defer observe("RemoveReaction", time.Now())
+	return s.next.RemoveReaction(ctx, guildID, messageID, emojiID, senderUserID)
+}
+
+func (s *Store) OptedOut(ctx context.Context, userIDs ...string) (map[string]bool, error) {
+	defer observe("OptedOut", time.Now())
+	return s.next.OptedOut(ctx, userIDs...)
+}
+
+func (s *Store) OptOut(ctx context.Context, guildID, userID string) error {
+	defer observe("OptOut", time.Now())
+	return s.next.OptOut(ctx, guildID, userID)
+}
+
+func (s *Store) OptIn(ctx context.Context, guildID, userID string) error {
+	defer observe("OptIn", time.Now())
+	return s.next.OptIn(ctx, guildID, userID)
+}
+
+func (s *Store) Forget(ctx context.Context, guildID, userID string) (privacy.Forgotten, error) {
+	defer observe("Forget", time.Now())
+	return s.next.Forget(ctx, guildID, userID)
+}
+
+func (s *Store) GetTimezone(ctx context.Context, guildID string) (*time.Location, error) {
+	defer observe("GetTimezone", time.Now())
+	return s.next.GetTimezone(ctx, guildID)
+}
+
+func (s *Store) SetTimezone(ctx context.Context, guildID string, loc *time.Location) error {
+	defer observe("SetTimezone", time.Now())
+	return s.next.SetTimezone(ctx, guildID, loc)
+}
+
+func (s *Store) GetSettings(ctx context.Context, guildID string) (stats.Settings, error) {
+	defer observe("GetSettings", time.Now())
+	return s.next.GetSettings(ctx, guildID)
+}
+
+func (s *Store) SetSettings(ctx context.Context, guildID string, settings stats.Settings) error {
+	defer observe("SetSettings", time.Now())
+	return s.next.SetSettings(ctx, guildID, settings)
+}
+
+func (s *Store) SetChannelParent(ctx context.Context, guildID, channelID, parentID string) (bool, error) {
+	defer observe("SetChannelParent", time.Now())
+	return s.next.SetChannelParent(ctx, guildID, channelID, parentID)
+}
+
+func observe(method string, start time.Time) {
+	QueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
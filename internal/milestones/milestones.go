@@ -0,0 +1,135 @@
+package milestones
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/privacy"
+)
+
+// Engine evaluates milestones as reactions are ingested and announces any which have been reached
+type Engine struct {
+	repo *Repository
+}
+
+// NewEngine creates a new milestone Engine
+func NewEngine(repo *Repository) *Engine {
+	return &Engine{repo: repo}
+}
+
+// HandleReaction evaluates the milestones affected by a reaction. It should be called after the reaction has been
+// added to the reactions table
+func (e *Engine) HandleReaction(s *discordgo.Session, r *discordgo.MessageReaction) {
+	if r.GuildID == "" {
+		return
+	}
+
+	ctx := context.Background()
+
+	c, err := e.repo.GetConfig(ctx, r.GuildID)
+	if err != nil {
+		slog.Error("failed to get milestone config", "error", err, "guild_id", r.GuildID)
+		return
+	}
+	if c == nil {
+		return
+	}
+
+	authorID, err := e.repo.GetMessageAuthor(ctx, r.GuildID, r.MessageID)
+	if err != nil {
+		slog.Error("failed to get message author", "error", err, "guild_id", r.GuildID, "message_id", r.MessageID)
+		return
+	}
+
+	subjects := map[Kind]string{
+		KindSender:   r.UserID,
+		KindReceiver: authorID,
+		KindEmoji:    r.Emoji.MessageFormat(),
+		KindMessage:  r.MessageID,
+	}
+
+	for _, kind := range []Kind{KindSender, KindReceiver, KindEmoji, KindMessage} {
+		subjectID := subjects[kind]
+		if subjectID == "" || subjectID == privacy.AnonymousUserID {
+			continue
+		}
+
+		m, err := e.evaluate(ctx, c, kind, subjectID)
+		if err != nil {
+			slog.Error("failed to evaluate milestone", "error", err, "guild_id", r.GuildID, "kind", kind, "subject_id", subjectID)
+			continue
+		}
+		if m == nil {
+			continue
+		}
+
+		content := FormatAnnouncement(*m, r.ChannelID, authorID)
+		if _, err := s.ChannelMessageSend(c.ChannelID, content); err != nil {
+			slog.Error("failed to announce milestone", "error", err, "guild_id", r.GuildID, "kind", kind, "subject_id", subjectID)
+			continue
+		}
+
+		slog.Info("milestone announced", "guild_id", r.GuildID, "kind", kind, "subject_id", subjectID, "threshold", m.Threshold)
+	}
+}
+
+// evaluate returns the milestone reached by the subject if it has not been announced before
+func (e *Engine) evaluate(ctx context.Context, c *Config, kind Kind, subjectID string) (*Milestone, error) {
+	thresholds := c.Thresholds(kind)
+	if len(thresholds) == 0 {
+		return nil, nil
+	}
+
+	count, err := e.repo.Count(ctx, c.GuildID, kind, subjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count reactions: %w", err)
+	}
+
+	threshold := thresholds.Reached(count)
+	if threshold == 0 {
+		return nil, nil
+	}
+
+	m := Milestone{GuildID: c.GuildID, Kind: kind, SubjectID: subjectID, Threshold: threshold}
+
+	claimed, err := e.repo.Claim(ctx, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim milestone: %w", err)
+	}
+	if !claimed {
+		return nil, nil
+	}
+
+	return &m, nil
+}
+
+// FormatAnnouncement formats the message posted when a milestone is reached
+func FormatAnnouncement(m Milestone, channelID, authorID string) string {
+	n := formatNumber(m.Threshold)
+
+	switch m.Kind {
+	case KindSender:
+		return fmt.Sprintf("🎉 %s has given **%s** reactions!", privacy.FormatUser(m.SubjectID), n)
+	case KindReceiver:
+		return fmt.Sprintf("🎉 %s has received **%s** reactions!", privacy.FormatUser(m.SubjectID), n)
+	case KindEmoji:
+		return fmt.Sprintf("🎉 %s has been used **%s** times!", m.SubjectID, n)
+	case KindMessage:
+		link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", m.GuildID, channelID, m.SubjectID)
+		return fmt.Sprintf("🎉 [This message](%s) by %s has **%s** reactions!", link, privacy.FormatUser(authorID), n)
+	default:
+		return fmt.Sprintf("🎉 %s reached **%s**!", m.SubjectID, n)
+	}
+}
+
+// formatNumber formats a number with thousands separators
+func formatNumber(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
@@ -0,0 +1,56 @@
+package milestones
+
+import (
+	"testing"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatAnnouncement(t *testing.T) {
+	tests := []struct {
+		name      string
+		milestone Milestone
+		expected  string
+	}{
+		{
+			"sender",
+			Milestone{Kind: KindSender, SubjectID: "111", Threshold: 1000},
+			"🎉 <@111> has given **1,000** reactions!",
+		},
+		{
+			"receiver",
+			Milestone{Kind: KindReceiver, SubjectID: "111", Threshold: 500},
+			"🎉 <@111> has received **500** reactions!",
+		},
+		{
+			"emoji",
+			Milestone{Kind: KindEmoji, SubjectID: "<:pepe:123456789>", Threshold: 10000},
+			"🎉 <:pepe:123456789> has been used **10,000** times!",
+		},
+		{
+			"message",
+			Milestone{GuildID: "guild1", Kind: KindMessage, SubjectID: "msg1", Threshold: 50},
+			"🎉 [This message](https://discord.com/channels/guild1/chan1/msg1) by <@222> has **50** reactions!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FormatAnnouncement(tt.milestone, "chan1", "222"))
+		})
+	}
+}
+
+func TestFormatAnnouncement_AnonymousAuthor(t *testing.T) {
+	m := Milestone{GuildID: "guild1", Kind: KindMessage, SubjectID: "msg1", Threshold: 50}
+
+	assert.Contains(t, FormatAnnouncement(m, "chan1", privacy.AnonymousUserID), "by anonymous has **50** reactions!")
+}
+
+func TestFormatNumber(t *testing.T) {
+	assert.Equal(t, "5", formatNumber(5))
+	assert.Equal(t, "999", formatNumber(999))
+	assert.Equal(t, "1,000", formatNumber(1000))
+	assert.Equal(t, "1,234,567", formatNumber(1234567))
+}
@@ -0,0 +1,111 @@
+package milestones
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Kind is the type of count a milestone is reached for
+type Kind string
+
+const (
+	// KindSender is reached when a user has given a number of reactions
+	KindSender Kind = "sender"
+	// KindReceiver is reached when a user has received a number of reactions
+	KindReceiver Kind = "receiver"
+	// KindEmoji is reached when an emoji has been used a number of times
+	KindEmoji Kind = "emoji"
+	// KindMessage is reached when a message has a number of reactions
+	KindMessage Kind = "message"
+)
+
+// Thresholds is an ascending list of counts at which milestones are reached
+type Thresholds []int
+
+var (
+	DefaultSenderThresholds   = Thresholds{100, 500, 1000, 5000, 10000}
+	DefaultReceiverThresholds = Thresholds{100, 500, 1000, 5000, 10000}
+	DefaultEmojiThresholds    = Thresholds{1000, 5000, 10000, 50000, 100000}
+	DefaultMessageThresholds  = Thresholds{10, 25, 50, 100}
+)
+
+// ParseThresholds parses a comma separated list of positive counts. "none" parses as no thresholds
+func ParseThresholds(s string) (Thresholds, error) {
+	var t Thresholds
+
+	if strings.EqualFold(strings.TrimSpace(s), "none") {
+		return t, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(part)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid threshold %q", part)
+		}
+		t = append(t, n)
+	}
+
+	slices.Sort(t)
+	return slices.Compact(t), nil
+}
+
+// String formats the thresholds as a comma separated list
+func (t Thresholds) String() string {
+	parts := make([]string, len(t))
+	for i, n := range t {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Reached returns the highest threshold which count has reached, or 0 if none have been reached
+func (t Thresholds) Reached(count int) int {
+	reached := 0
+	for _, n := range t {
+		if n <= count {
+			reached = n
+		}
+	}
+	return reached
+}
+
+// Config is the milestone configuration for a guild
+type Config struct {
+	GuildID   string
+	ChannelID string
+	Sender    Thresholds
+	Receiver  Thresholds
+	Emoji     Thresholds
+	Message   Thresholds
+}
+
+// Thresholds returns the configured thresholds for a kind of milestone
+func (c *Config) Thresholds(k Kind) Thresholds {
+	switch k {
+	case KindSender:
+		return c.Sender
+	case KindReceiver:
+		return c.Receiver
+	case KindEmoji:
+		return c.Emoji
+	case KindMessage:
+		return c.Message
+	default:
+		return nil
+	}
+}
+
+// Milestone is a threshold which has been reached
+type Milestone struct {
+	GuildID   string
+	Kind      Kind
+	SubjectID string
+	Threshold int
+}
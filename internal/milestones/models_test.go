@@ -0,0 +1,40 @@
+package milestones
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThresholds(t *testing.T) {
+	thresholds, err := ParseThresholds(" 1000, 100,500,100 ")
+
+	require.NoError(t, err)
+	assert.Equal(t, Thresholds{100, 500, 1000}, thresholds)
+	assert.Equal(t, "100,500,1000", thresholds.String())
+}
+
+func TestParseThresholds_None(t *testing.T) {
+	thresholds, err := ParseThresholds("None")
+
+	require.NoError(t, err)
+	assert.Empty(t, thresholds)
+	assert.Equal(t, "", thresholds.String())
+}
+
+func TestParseThresholds_Invalid(t *testing.T) {
+	for _, s := range []string{"abc", "100,-5", "0"} {
+		_, err := ParseThresholds(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestThresholdsReached(t *testing.T) {
+	thresholds := Thresholds{10, 25, 50}
+
+	assert.Equal(t, 0, thresholds.Reached(9))
+	assert.Equal(t, 10, thresholds.Reached(10))
+	assert.Equal(t, 25, thresholds.Reached(49))
+	assert.Equal(t, 50, thresholds.Reached(1000))
+}
@@ -0,0 +1,169 @@
+package milestones
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/elliotwms/emojistats/internal/database"
+)
+
+// Repository handles database queries for milestones
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository creates a new milestones repository
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetConfig retrieves the milestone config for a guild, returning nil if milestones are not enabled
+func (r *Repository) GetConfig(ctx context.Context, guildID string) (*Config, error) {
+	c := &Config{GuildID: guildID}
+
+	var sender, receiver, emoji, message string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT channel_id, sender_thresholds, receiver_thresholds, emoji_thresholds, message_thresholds
+		FROM milestone_configs
+		WHERE guild_id = $1`,
+		guildID,
+	).Scan(&c.ChannelID, &sender, &receiver, &emoji, &message)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range []struct {
+		dst *Thresholds
+		src string
+	}{
+		{&c.Sender, sender},
+		{&c.Receiver, receiver},
+		{&c.Emoji, emoji},
+		{&c.Message, message},
+	} {
+		if *t.dst, err = ParseThresholds(t.src); err != nil {
+			return nil, fmt.Errorf("invalid stored thresholds: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// SaveConfig creates or replaces the milestone config for a guild
+func (r *Repository) SaveConfig(ctx context.Context, c Config) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO milestone_configs (guild_id, channel_id, sender_thresholds, receiver_thresholds, emoji_thresholds, message_thresholds)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (guild_id) DO UPDATE
+		SET channel_id = EXCLUDED.channel_id,
+			sender_thresholds = EXCLUDED.sender_thresholds,
+			receiver_thresholds = EXCLUDED.receiver_thresholds,
+			emoji_thresholds = EXCLUDED.emoji_thresholds,
+			message_thresholds = EXCLUDED.message_thresholds,
+			updated_at = NOW()`,
+		c.GuildID,
+		c.ChannelID,
+		c.Sender.String(),
+		c.Receiver.String(),
+		c.Emoji.String(),
+		c.Message.String(),
+	)
+	return err
+}
+
+// DeleteConfig disables milestones for a guild
+func (r *Repository) DeleteConfig(ctx context.Context, guildID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM milestone_configs WHERE guild_id = $1`, guildID)
+	return err
+}
+
+// Count counts the reactions in a guild for the subject of a kind of milestone, including those which have been pruned
+// and excluding those in ignored channels. It runs as reactions are ingested, so counts users and emojis from the daily
+// rollups rather than every reaction. The rollups are not split by channel, so guilds which ignore channels are counted
+// from the reactions. The daily totals of pruned reactions have no message, so message milestones only count reactions
+// which are kept
+func (r *Repository) Count(ctx context.Context, guildID string, kind Kind, subjectID string) (int, error) {
+	var rollup, rollupColumn, column string
+	switch kind {
+	case KindSender:
+		rollup, rollupColumn, column = "reaction_rollups_sender", "user_id", "sender_user_id"
+	case KindReceiver:
+		rollup, rollupColumn, column = "reaction_rollups_receiver", "user_id", "receiver_user_id"
+	case KindEmoji:
+		rollup, rollupColumn, column = "reaction_rollups_emoji", "emoji_id", "emoji_id"
+	case KindMessage:
+	default:
+		return 0, fmt.Errorf("unknown milestone kind %q", kind)
+	}
+
+	query := `SELECT COUNT(*) FROM reactions WHERE guild_id = $1 AND message_id = $2` + database.NotIgnored
+	if rollup != "" {
+		ignoring, err := r.ignoresChannels(ctx, guildID)
+		if err != nil {
+			return 0, err
+		}
+
+		query = `SELECT COALESCE(SUM(count), 0) FROM ` + rollup + ` WHERE guild_id = $1 AND ` + rollupColumn + ` = $2`
+		if ignoring {
+			query = `SELECT COALESCE(SUM(count), 0) FROM ` + database.CountedReactions + ` WHERE guild_id = $1 AND ` + column + ` = $2` + database.NotIgnored
+		}
+	}
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query,
+		guildID,
+		subjectID,
+	).Scan(&count)
+	return count, err
+}
+
+// ignoresChannels reports whether a guild ignores any channels
+func (r *Repository) ignoresChannels(ctx context.Context, guildID string) (bool, error) {
+	var ignoring bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM ignored_channels WHERE guild_id = $1)`, guildID).Scan(&ignoring)
+	return ignoring, err
+}
+
+// GetMessageAuthor retrieves the author of a reacted message, returning an empty string if it has no reactions
+func (r *Repository) GetMessageAuthor(ctx context.Context, guildID, messageID string) (string, error) {
+	var authorID string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT receiver_user_id FROM reactions
+		WHERE guild_id = $1 AND message_id = $2
+		LIMIT 1`,
+		guildID,
+		messageID,
+	).Scan(&authorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return authorID, err
+}
+
+// Claim records that a milestone has been announced. It returns false if the milestone has already been announced
+func (r *Repository) Claim(ctx context.Context, m Milestone) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO milestones (guild_id, kind, subject_id, threshold)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`,
+		m.GuildID,
+		m.Kind,
+		m.SubjectID,
+		m.Threshold,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
@@ -0,0 +1,210 @@
+package milestones
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+)
+
+var testDB *sql.DB
+
+func setupTest(t *testing.T) (*Repository, string, func()) {
+	t.Helper()
+
+	testDB = dbtest.Open(t)
+
+	guildID := "test-guild-" + time.Now().Format("20060102150405.000000000")
+
+	cleanup := func() {
+		_, _ = testDB.Exec("DELETE FROM reactions WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM reaction_aggregates WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM milestones WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM milestone_configs WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM guild_settings WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM reaction_rollups_emoji WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM reaction_rollups_sender WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM reaction_rollups_receiver WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM reaction_rollups_channel WHERE guild_id = $1", guildID)
+	}
+
+	return NewRepository(testDB), guildID, cleanup
+}
+
+func insertReaction(t *testing.T, guildID, emojiID, senderID, receiverID, messageID string) {
+	t.Helper()
+	_, err := testDB.Exec(`
+		INSERT INTO reactions (guild_id, emoji_id, sender_user_id, receiver_user_id, channel_id, message_id)
+		VALUES ($1, $2, $3, $4, 'chan1', $5)`,
+		guildID, emojiID, senderID, receiverID, messageID)
+	require.NoError(t, err)
+}
+
+func TestConfig(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	c, err := repo.GetConfig(ctx, guildID)
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	require.NoError(t, repo.SaveConfig(ctx, Config{
+		GuildID:   guildID,
+		ChannelID: "chan1",
+		Sender:    Thresholds{100, 1000},
+		Emoji:     Thresholds{10000},
+		Message:   Thresholds{50},
+	}))
+
+	c, err = repo.GetConfig(ctx, guildID)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, "chan1", c.ChannelID)
+	assert.Equal(t, Thresholds{100, 1000}, c.Sender)
+	assert.Empty(t, c.Receiver)
+	assert.Equal(t, Thresholds{10000}, c.Emoji)
+	assert.Equal(t, Thresholds{50}, c.Message)
+
+	require.NoError(t, repo.DeleteConfig(ctx, guildID))
+
+	c, err = repo.GetConfig(ctx, guildID)
+	require.NoError(t, err)
+	assert.Nil(t, c)
+}
+
+func TestCount(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	insertReaction(t, guildID, "👍", "user1", "user2", "msg1")
+	insertReaction(t, guildID, "❤️", "user1", "user2", "msg1")
+	insertReaction(t, guildID, "👍", "user2", "user1", "msg2")
+
+	tests := []struct {
+		kind      Kind
+		subjectID string
+		expected  int
+	}{
+		{KindSender, "user1", 2},
+		{KindReceiver, "user1", 1},
+		{KindEmoji, "👍", 2},
+		{KindMessage, "msg1", 2},
+	}
+
+	for _, tt := range tests {
+		count, err := repo.Count(ctx, guildID, tt.kind, tt.subjectID)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, count, tt.kind)
+	}
+
+	authorID, err := repo.GetMessageAuthor(ctx, guildID, "msg2")
+	require.NoError(t, err)
+	assert.Equal(t, "user1", authorID)
+}
+
+func TestCount_Pruned(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	insertReaction(t, guildID, "👍", "user1", "user2", "msg1")
+	_, err := testDB.Exec(`
+		INSERT INTO reaction_aggregates (guild_id, created_at, emoji_id, is_default, sender_user_id, receiver_user_id, count)
+		VALUES ($1, '2023-01-01', '👍', true, 'user1', 'user2', 5)`,
+		guildID)
+	require.NoError(t, err)
+
+	count, err := repo.Count(ctx, guildID, KindSender, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, 6, count, "pruned reactions are counted")
+
+	count, err = repo.Count(ctx, guildID, KindEmoji, "👍")
+	require.NoError(t, err)
+	assert.Equal(t, 6, count)
+
+	count, err = repo.Count(ctx, guildID, KindMessage, "msg1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "pruned reactions have no message")
+}
+
+func TestCount_Rollups(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	insertReaction(t, guildID, "👍", "user1", "user2", "msg1")
+	insertReaction(t, guildID, "👍", "user1", "user2", "msg2")
+
+	// rollups without raw reactions behind them show the counts are read from the rollups
+	_, err := testDB.Exec(`
+		INSERT INTO reaction_rollups_sender (guild_id, day, emoji_id, user_id, count)
+		VALUES ($1, '2020-01-01', '👍', 'user1', 10)`,
+		guildID)
+	require.NoError(t, err)
+
+	count, err := repo.Count(ctx, guildID, KindSender, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, 12, count)
+
+	// guilds which ignore channels are counted from the reactions, as the rollups are not split by channel
+	_, err = testDB.Exec(`INSERT INTO guild_settings (guild_id, ignored_channel_ids) VALUES ($1, ARRAY['chan2'])`, guildID)
+	require.NoError(t, err)
+
+	count, err = repo.Count(ctx, guildID, KindSender, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestCount_IgnoredChannel(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	insertReaction(t, guildID, "👍", "user1", "user2", "msg1")
+	_, err := testDB.Exec(`
+		INSERT INTO reaction_aggregates (guild_id, created_at, emoji_id, is_default, sender_user_id, receiver_user_id, channel_id, count)
+		VALUES ($1, '2023-01-01', '👍', true, 'user1', 'user2', 'chan2', 5)`,
+		guildID)
+	require.NoError(t, err)
+
+	// reactions made before the channel was ignored are excluded too
+	_, err = testDB.Exec(`INSERT INTO guild_settings (guild_id, ignored_channel_ids) VALUES ($1, ARRAY['chan1'])`, guildID)
+	require.NoError(t, err)
+
+	count, err := repo.Count(ctx, guildID, KindSender, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	count, err = repo.Count(ctx, guildID, KindMessage, "msg1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestClaim(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	m := Milestone{GuildID: guildID, Kind: KindMessage, SubjectID: "msg1", Threshold: 50}
+
+	claimed, err := repo.Claim(ctx, m)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = repo.Claim(ctx, m)
+	require.NoError(t, err)
+	assert.False(t, claimed, "a milestone should only be announced once")
+
+	m.Threshold = 100
+	claimed, err = repo.Claim(ctx, m)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
@@ -0,0 +1,99 @@
+package privacy
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ExportedReaction is a reaction included in a user's data export
+type ExportedReaction struct {
+	GuildID        string    `json:"guild_id"`
+	ChannelID      string    `json:"channel_id"`
+	MessageID      string    `json:"message_id"`
+	EmojiID        string    `json:"emoji_id"`
+	SenderUserID   string    `json:"sender_user_id"`
+	ReceiverUserID string    `json:"receiver_user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	MessageLink    string    `json:"message_link"`
+}
+
+var exportHeader = []string{"guild_id", "channel_id", "message_id", "emoji_id", "sender_user_id", "receiver_user_id", "created_at", "message_link"}
+
+// ExportWriter writes reactions as a JSON array and a CSV file at the same time, one row at a time, so that an export
+// never holds more than a single reaction in memory
+type ExportWriter struct {
+	json  io.Writer
+	csv   *csv.Writer
+	count int
+}
+
+// NewExportWriter creates an ExportWriter and writes the opening of each file
+func NewExportWriter(jsonW, csvW io.Writer) (*ExportWriter, error) {
+	w := &ExportWriter{json: jsonW, csv: csv.NewWriter(csvW)}
+
+	if _, err := io.WriteString(jsonW, "["); err != nil {
+		return nil, err
+	}
+
+	if err := w.csv.Write(exportHeader); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write writes a reaction to both files
+func (w *ExportWriter) Write(r ExportedReaction) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if w.count == 0 {
+		sep = "\n"
+	}
+	if _, err := fmt.Fprintf(w.json, "%s  %s", sep, b); err != nil {
+		return err
+	}
+
+	err = w.csv.Write([]string{
+		r.GuildID,
+		r.ChannelID,
+		r.MessageID,
+		r.EmojiID,
+		r.SenderUserID,
+		r.ReceiverUserID,
+		r.CreatedAt.UTC().Format(time.RFC3339),
+		r.MessageLink,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.count++
+
+	return nil
+}
+
+// Close writes the end of each file. It does not close the underlying writers
+func (w *ExportWriter) Close() error {
+	end := "\n]\n"
+	if w.count == 0 {
+		end = "]\n"
+	}
+	if _, err := io.WriteString(w.json, end); err != nil {
+		return err
+	}
+
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// Count returns the number of reactions written
+func (w *ExportWriter) Count() int {
+	return w.count
+}
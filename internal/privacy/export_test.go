@@ -0,0 +1,52 @@
+package privacy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportWriter(t *testing.T) {
+	var jsonBuf, csvBuf bytes.Buffer
+
+	w, err := NewExportWriter(&jsonBuf, &csvBuf)
+	require.NoError(t, err)
+
+	reactions := []ExportedReaction{
+		{GuildID: "guild1", ChannelID: "chan1", MessageID: "msg1", EmojiID: "👍", SenderUserID: "111", ReceiverUserID: "222", CreatedAt: time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC), MessageLink: "https://discord.com/channels/guild1/chan1/msg1"},
+		{GuildID: "guild1", ChannelID: "chan1", MessageID: "msg2", EmojiID: "<:pepe:123>", SenderUserID: "222", ReceiverUserID: "111", CreatedAt: time.Date(2024, 6, 11, 12, 0, 0, 0, time.UTC), MessageLink: "https://discord.com/channels/guild1/chan1/msg2"},
+	}
+	for _, r := range reactions {
+		require.NoError(t, w.Write(r))
+	}
+	require.NoError(t, w.Close())
+	assert.Equal(t, 2, w.Count())
+
+	var decoded []ExportedReaction
+	require.NoError(t, json.Unmarshal(jsonBuf.Bytes(), &decoded))
+	assert.Equal(t, reactions, decoded)
+
+	records, err := csv.NewReader(&csvBuf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, exportHeader, records[0])
+	assert.Equal(t, []string{"guild1", "chan1", "msg2", "<:pepe:123>", "222", "111", "2024-06-11T12:00:00Z", "https://discord.com/channels/guild1/chan1/msg2"}, records[2])
+}
+
+func TestExportWriter_Empty(t *testing.T) {
+	var jsonBuf, csvBuf bytes.Buffer
+
+	w, err := NewExportWriter(&jsonBuf, &csvBuf)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	var decoded []ExportedReaction
+	require.NoError(t, json.Unmarshal(jsonBuf.Bytes(), &decoded))
+	assert.Empty(t, decoded)
+	assert.Equal(t, "guild_id,channel_id,message_id,emoji_id,sender_user_id,receiver_user_id,created_at,message_link\n", csvBuf.String())
+}
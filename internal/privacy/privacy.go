@@ -0,0 +1,58 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+)
+
+// AnonymousUserID replaces the ID of a user who has opted out or asked to be forgotten
+const AnonymousUserID = "anonymous"
+
+// AnonymousName is how a user whose ID has been anonymised is rendered
+const AnonymousName = "anonymous"
+
+// Action is a privacy request made by a user
+type Action string
+
+const (
+	ActionOptOut Action = "opt-out"
+	ActionOptIn  Action = "opt-in"
+	ActionForget Action = "forget-me"
+)
+
+// Store stores which users have opted out, and forgets users who ask. Repository is the Postgres implementation
+type Store interface {
+	// OptedOut returns which of the given users have opted out of having their reactions stored
+	OptedOut(ctx context.Context, userIDs ...string) (map[string]bool, error)
+	// OptOut stops a user's reactions from being stored in every guild
+	OptOut(ctx context.Context, guildID, userID string) error
+	// OptIn resumes storing a user's reactions
+	OptIn(ctx context.Context, guildID, userID string) error
+	// Forget deletes the reactions a user gave and anonymises those they received in every guild, then opts them out
+	Forget(ctx context.Context, guildID, userID string) (Forgotten, error)
+}
+
+// Forgotten summarises the rows changed when a user asked to be forgotten
+type Forgotten struct {
+	// Deleted is the number of reactions the user gave, which were deleted
+	Deleted int
+	// Anonymised is the number of reactions the user received, which were kept but attributed to AnonymousUserID
+	Anonymised int
+	// Reposts are the starboard reposts of the user's messages. Their entries have been deleted, but the messages
+	// must be deleted from Discord by the caller
+	Reposts []Repost
+}
+
+// Repost is a message posted by the bot in a guild's starboard channel
+type Repost struct {
+	ChannelID string
+	MessageID string
+}
+
+// FormatUser mentions a user, or renders them as AnonymousName if their ID has been anonymised
+func FormatUser(userID string) string {
+	if userID == AnonymousUserID {
+		return AnonymousName
+	}
+	return fmt.Sprintf("<@%s>", userID)
+}
@@ -0,0 +1,12 @@
+package privacy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatUser(t *testing.T) {
+	assert.Equal(t, "<@111>", FormatUser("111"))
+	assert.Equal(t, "anonymous", FormatUser(AnonymousUserID))
+}
@@ -0,0 +1,247 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Repository handles database queries for privacy requests
+type Repository struct {
+	db *sql.DB
+}
+
+var _ Store = (*Repository)(nil)
+
+// NewRepository creates a new privacy repository
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// OptedOut returns which of the given users have opted out
+func (r *Repository) OptedOut(ctx context.Context, userIDs ...string) (map[string]bool, error) {
+	results := make(map[string]bool)
+	if len(userIDs) == 0 {
+		return results, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id FROM privacy_opt_outs WHERE user_id = ANY($1)`,
+		pq.Array(userIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		results[userID] = true
+	}
+	return results, rows.Err()
+}
+
+// OptOut stops a user's reactions from being stored. Reactions stored before they opted out are kept, but are shown
+// anonymously
+func (r *Repository) OptOut(ctx context.Context, guildID, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := optOut(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := audit(ctx, tx, guildID, userID, ActionOptOut, Forgotten{}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// OptIn resumes storing a user's reactions
+func (r *Repository) OptIn(ctx context.Context, guildID, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM privacy_opt_outs WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	if err := audit(ctx, tx, guildID, userID, ActionOptIn, Forgotten{}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Forget deletes the reactions a user gave and anonymises those they received in every guild, removes their
+// achievements, milestones and the starboard entries of their messages, and opts them out so that nothing new is
+// stored. Roles already granted by role rewards are not removed from the user, and messages whose reactions have all
+// been pruned are not found on the starboard, as pruning drops the message
+func (r *Repository) Forget(ctx context.Context, guildID, userID string) (Forgotten, error) {
+	var f Forgotten
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return f, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM reactions WHERE sender_user_id = $1`, userID)
+	if err != nil {
+		return f, err
+	}
+	f.Deleted, err = rowsAffected(res)
+	if err != nil {
+		return f, err
+	}
+
+	// find the user's messages on the starboard before their reactions are anonymised
+	f.Reposts, err = deleteReposts(ctx, tx, userID)
+	if err != nil {
+		return f, err
+	}
+
+	res, err = tx.ExecContext(ctx, `UPDATE reactions SET receiver_user_id = $2 WHERE receiver_user_id = $1`, userID, AnonymousUserID)
+	if err != nil {
+		return f, err
+	}
+	f.Anonymised, err = rowsAffected(res)
+	if err != nil {
+		return f, err
+	}
+
+	statements := []string{
+		`DELETE FROM reaction_aggregates WHERE sender_user_id = $1`,
+		// merge the user's received aggregates into the anonymous user's, which may already exist for the same day
+		`INSERT INTO reaction_aggregates (guild_id, created_at, emoji_id, is_default, sender_user_id, receiver_user_id, channel_id, count)
+		SELECT guild_id, created_at, emoji_id, is_default, sender_user_id, '` + AnonymousUserID + `', channel_id, count
+		FROM reaction_aggregates WHERE receiver_user_id = $1
+		ON CONFLICT (guild_id, created_at, emoji_id, sender_user_id, receiver_user_id, channel_id)
+		DO UPDATE SET count = reaction_aggregates.count + EXCLUDED.count`,
+		`DELETE FROM reaction_aggregates WHERE receiver_user_id = $1`,
+		`DELETE FROM achievements WHERE user_id = $1`,
+		`DELETE FROM milestones WHERE kind IN ('sender', 'receiver') AND subject_id = $1`,
+		`DELETE FROM role_reward_members WHERE user_id = $1`,
+		`UPDATE role_reward_audit SET user_id = '` + AnonymousUserID + `' WHERE user_id = $1`,
+	}
+	for _, s := range statements {
+		if _, err := tx.ExecContext(ctx, s, userID); err != nil {
+			return f, err
+		}
+	}
+
+	if err := optOut(ctx, tx, userID); err != nil {
+		return f, err
+	}
+
+	if err := audit(ctx, tx, guildID, userID, ActionForget, f); err != nil {
+		return f, err
+	}
+
+	return f, tx.Commit()
+}
+
+// ExportReactions calls fn for every reaction in every guild which the user gave or received, oldest first. The other
+// user of each reaction is exported as AnonymousUserID if they have opted out. Rows are read one at a time so that
+// large histories are never held in memory
+func (r *Repository) ExportReactions(ctx context.Context, userID string, fn func(ExportedReaction) error) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT guild_id, channel_id, message_id, COALESCE(emoji_id, ''),
+			CASE WHEN sender_user_id <> $1 AND sender_user_id IN (SELECT user_id FROM privacy_opt_outs) THEN $2 ELSE sender_user_id END,
+			CASE WHEN receiver_user_id <> $1 AND receiver_user_id IN (SELECT user_id FROM privacy_opt_outs) THEN $2 ELSE receiver_user_id END,
+			created_at
+		FROM reactions
+		WHERE sender_user_id = $1 OR receiver_user_id = $1
+		ORDER BY created_at, id`,
+		userID,
+		AnonymousUserID,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var e ExportedReaction
+		if err := rows.Scan(&e.GuildID, &e.ChannelID, &e.MessageID, &e.EmojiID, &e.SenderUserID, &e.ReceiverUserID, &e.CreatedAt); err != nil {
+			return err
+		}
+		e.MessageLink = fmt.Sprintf("https://discord.com/channels/%s/%s/%s", e.GuildID, e.ChannelID, e.MessageID)
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// deleteReposts deletes the starboard entries of the messages a user received reactions on, returning the reposts
+// which were posted to a starboard which is still configured
+func deleteReposts(ctx context.Context, tx *sql.Tx, userID string) ([]Repost, error) {
+	rows, err := tx.QueryContext(ctx, `
+		WITH deleted AS (
+			DELETE FROM starboard_messages
+			WHERE (guild_id, message_id) IN (SELECT guild_id, message_id FROM reactions WHERE receiver_user_id = $1)
+			RETURNING guild_id, starboard_message_id
+		)
+		SELECT c.channel_id, d.starboard_message_id
+		FROM deleted d
+		JOIN starboard_configs c ON c.guild_id = d.guild_id
+		WHERE d.starboard_message_id IS NOT NULL`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var reposts []Repost
+	for rows.Next() {
+		var p Repost
+		if err := rows.Scan(&p.ChannelID, &p.MessageID); err != nil {
+			return nil, err
+		}
+		reposts = append(reposts, p)
+	}
+	return reposts, rows.Err()
+}
+
+func optOut(ctx context.Context, tx *sql.Tx, userID string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO privacy_opt_outs (user_id)
+		VALUES ($1)
+		ON CONFLICT DO NOTHING`,
+		userID,
+	)
+	return err
+}
+
+func audit(ctx context.Context, tx *sql.Tx, guildID, userID string, action Action, f Forgotten) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO privacy_audit (user_id, guild_id, action, deleted, anonymised)
+		VALUES ($1, $2, $3, $4, $5)`,
+		userID,
+		guildID,
+		action,
+		f.Deleted,
+		f.Anonymised,
+	)
+	return err
+}
+
+func rowsAffected(res sql.Result) (int, error) {
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,246 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+)
+
+var testDB *sql.DB
+
+// setupTest returns a unique guild and user, as opt-outs apply to users across every guild
+func setupTest(t *testing.T) (*Repository, string, string, func()) {
+	t.Helper()
+
+	testDB = dbtest.Open(t)
+
+	suffix := time.Now().Format("20060102150405.000000000")
+	guildID := "test-guild-" + suffix
+	userID := "test-user-" + suffix
+
+	cleanup := func() {
+		_, _ = testDB.Exec("DELETE FROM reactions WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM reaction_aggregates WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM achievements WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM starboard_messages WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM starboard_configs WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM privacy_opt_outs WHERE user_id = $1", userID)
+		_, _ = testDB.Exec("DELETE FROM privacy_audit WHERE user_id = $1", userID)
+	}
+
+	return NewRepository(testDB), guildID, userID, cleanup
+}
+
+func insertReaction(t *testing.T, guildID, senderID, receiverID string) {
+	t.Helper()
+	_, err := testDB.Exec(`
+		INSERT INTO reactions (guild_id, emoji_id, sender_user_id, receiver_user_id, channel_id, message_id)
+		VALUES ($1, '👍', $2, $3, 'chan1', 'msg1')`,
+		guildID, senderID, receiverID)
+	require.NoError(t, err)
+}
+
+func auditActions(t *testing.T, userID string) []Action {
+	t.Helper()
+	rows, err := testDB.Query(`SELECT action FROM privacy_audit WHERE user_id = $1 ORDER BY id`, userID)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+
+	var actions []Action
+	for rows.Next() {
+		var a Action
+		require.NoError(t, rows.Scan(&a))
+		actions = append(actions, a)
+	}
+	require.NoError(t, rows.Err())
+	return actions
+}
+
+func TestOptOut(t *testing.T) {
+	repo, guildID, userID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	optedOut, err := repo.OptedOut(ctx, userID, "other")
+	require.NoError(t, err)
+	assert.Empty(t, optedOut)
+
+	require.NoError(t, repo.OptOut(ctx, guildID, userID))
+	require.NoError(t, repo.OptOut(ctx, guildID, userID), "opting out twice should succeed")
+
+	optedOut, err = repo.OptedOut(ctx, userID, "other")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{userID: true}, optedOut)
+
+	require.NoError(t, repo.OptIn(ctx, guildID, userID))
+
+	optedOut, err = repo.OptedOut(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, optedOut)
+
+	assert.Equal(t, []Action{ActionOptOut, ActionOptOut, ActionOptIn}, auditActions(t, userID))
+}
+
+func TestForget(t *testing.T) {
+	repo, guildID, userID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	insertReaction(t, guildID, userID, "other")
+	insertReaction(t, guildID, userID, "other")
+	insertReaction(t, guildID, "other", userID)
+	insertReaction(t, guildID, "other", "someone")
+
+	_, err := testDB.Exec(`INSERT INTO achievements (guild_id, user_id, achievement_id) VALUES ($1, $2, 'first-reaction')`, guildID, userID)
+	require.NoError(t, err)
+
+	f, err := repo.Forget(ctx, guildID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, Forgotten{Deleted: 2, Anonymised: 1}, f)
+
+	var remaining int
+	require.NoError(t, testDB.QueryRow(`
+		SELECT COUNT(*) FROM reactions
+		WHERE guild_id = $1 AND (sender_user_id = $2 OR receiver_user_id = $2)`,
+		guildID, userID,
+	).Scan(&remaining))
+	assert.Zero(t, remaining)
+
+	var anonymous int
+	require.NoError(t, testDB.QueryRow(`
+		SELECT COUNT(*) FROM reactions WHERE guild_id = $1 AND receiver_user_id = $2`,
+		guildID, AnonymousUserID,
+	).Scan(&anonymous))
+	assert.Equal(t, 1, anonymous)
+
+	var achievements int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM achievements WHERE user_id = $1`, userID).Scan(&achievements))
+	assert.Zero(t, achievements)
+
+	optedOut, err := repo.OptedOut(ctx, userID)
+	require.NoError(t, err)
+	assert.True(t, optedOut[userID], "forgotten users should be opted out")
+
+	assert.Equal(t, []Action{ActionForget}, auditActions(t, userID))
+}
+
+func TestForget_Aggregates(t *testing.T) {
+	repo, guildID, userID, cleanup := setupTest(t)
+	defer cleanup()
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, a := range []struct {
+		sender, receiver string
+		count            int
+	}{
+		{userID, "other", 3},
+		{"other", userID, 2},
+		{"other", AnonymousUserID, 1},
+	} {
+		_, err := testDB.Exec(`
+			INSERT INTO reaction_aggregates (guild_id, created_at, emoji_id, is_default, sender_user_id, receiver_user_id, count)
+			VALUES ($1, $2, '👍', true, $3, $4, $5)`,
+			guildID, day, a.sender, a.receiver, a.count)
+		require.NoError(t, err)
+	}
+
+	_, err := repo.Forget(context.Background(), guildID, userID)
+	require.NoError(t, err)
+
+	var remaining int
+	require.NoError(t, testDB.QueryRow(`
+		SELECT COUNT(*) FROM reaction_aggregates
+		WHERE guild_id = $1 AND (sender_user_id = $2 OR receiver_user_id = $2)`,
+		guildID, userID,
+	).Scan(&remaining))
+	assert.Zero(t, remaining)
+
+	var anonymous int
+	require.NoError(t, testDB.QueryRow(`
+		SELECT count FROM reaction_aggregates WHERE guild_id = $1 AND receiver_user_id = $2`,
+		guildID, AnonymousUserID,
+	).Scan(&anonymous))
+	assert.Equal(t, 3, anonymous, "received aggregates should be merged into the anonymous user's")
+}
+
+func TestForget_Starboard(t *testing.T) {
+	repo, guildID, userID, cleanup := setupTest(t)
+	defer cleanup()
+
+	insertReaction(t, guildID, "other", userID)
+
+	_, err := testDB.Exec(`INSERT INTO starboard_configs (guild_id, channel_id, threshold) VALUES ($1, 'starboard', 1)`, guildID)
+	require.NoError(t, err)
+	_, err = testDB.Exec(`
+		INSERT INTO starboard_messages (guild_id, message_id, channel_id, starboard_message_id, reaction_count)
+		VALUES ($1, 'msg1', 'chan1', 'repost1', 1)`,
+		guildID)
+	require.NoError(t, err)
+
+	f, err := repo.Forget(context.Background(), guildID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []Repost{{ChannelID: "starboard", MessageID: "repost1"}}, f.Reposts)
+
+	var entries int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM starboard_messages WHERE guild_id = $1`, guildID).Scan(&entries))
+	assert.Zero(t, entries)
+}
+
+func TestExportReactions(t *testing.T) {
+	repo, guildID, userID, cleanup := setupTest(t)
+	defer cleanup()
+
+	insertReaction(t, guildID, userID, "other")
+	insertReaction(t, guildID, "other", userID)
+	insertReaction(t, guildID, "other", "someone")
+
+	var exported []ExportedReaction
+	err := repo.ExportReactions(context.Background(), userID, func(r ExportedReaction) error {
+		exported = append(exported, r)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, exported, 2)
+	for _, r := range exported {
+		assert.True(t, r.SenderUserID == userID || r.ReceiverUserID == userID)
+		assert.Equal(t, "https://discord.com/channels/"+guildID+"/chan1/msg1", r.MessageLink)
+	}
+}
+
+func TestExportReactions_OptedOut(t *testing.T) {
+	repo, guildID, userID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	otherID := userID + "-other"
+	require.NoError(t, repo.OptOut(ctx, guildID, otherID))
+	defer func() {
+		_, _ = testDB.Exec("DELETE FROM privacy_opt_outs WHERE user_id = $1", otherID)
+		_, _ = testDB.Exec("DELETE FROM privacy_audit WHERE user_id = $1", otherID)
+	}()
+	require.NoError(t, repo.OptOut(ctx, guildID, userID))
+
+	insertReaction(t, guildID, userID, otherID)
+	insertReaction(t, guildID, otherID, userID)
+
+	var exported []ExportedReaction
+	err := repo.ExportReactions(ctx, userID, func(r ExportedReaction) error {
+		exported = append(exported, r)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, exported, 2)
+	assert.Equal(t, userID, exported[0].SenderUserID, "the user should see their own ID even though they opted out")
+	assert.Equal(t, AnonymousUserID, exported[0].ReceiverUserID)
+	assert.Equal(t, AnonymousUserID, exported[1].SenderUserID)
+	assert.Equal(t, userID, exported[1].ReceiverUserID)
+}
@@ -0,0 +1,33 @@
+package retention
+
+import (
+	"fmt"
+	"time"
+)
+
+// MinDays is the shortest retention period a guild can set
+const MinDays = 30
+
+// Policy is how long a guild keeps raw reactions before they are pruned
+type Policy struct {
+	GuildID string
+	// Days is the number of whole days raw reactions are kept for
+	Days int
+	// Rollup adds pruned reactions to the daily aggregates so that they still count towards long-range totals
+	Rollup bool
+}
+
+// Cutoff returns the time before which reactions are pruned. It is always the start of a UTC day, so that a day's
+// reactions are either all raw or all aggregated
+func (p Policy) Cutoff(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()-p.Days, 0, 0, 0, 0, time.UTC)
+}
+
+// Describe describes the policy in plain English
+func (p Policy) Describe() string {
+	if p.Rollup {
+		return fmt.Sprintf("Reactions are kept for **%d** days, then rolled up into daily totals.", p.Days)
+	}
+	return fmt.Sprintf("Reactions are kept for **%d** days, then deleted.", p.Days)
+}
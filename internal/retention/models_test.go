@@ -0,0 +1,24 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Cutoff(t *testing.T) {
+	p := Policy{Days: 30}
+
+	now := time.Date(2024, 3, 15, 18, 45, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 2, 14, 0, 0, 0, 0, time.UTC), p.Cutoff(now))
+
+	// the cutoff is a UTC day regardless of the time's location
+	tokyo := time.FixedZone("JST", 9*60*60)
+	assert.Equal(t, time.Date(2024, 2, 14, 0, 0, 0, 0, time.UTC), p.Cutoff(time.Date(2024, 3, 16, 2, 0, 0, 0, tokyo)))
+}
+
+func TestPolicy_Describe(t *testing.T) {
+	assert.Equal(t, "Reactions are kept for **365** days, then rolled up into daily totals.", Policy{Days: 365, Rollup: true}.Describe())
+	assert.Equal(t, "Reactions are kept for **90** days, then deleted.", Policy{Days: 90}.Describe())
+}
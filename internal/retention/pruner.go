@@ -0,0 +1,90 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	pruneInterval = time.Hour
+	// BatchSize is the number of reactions deleted per statement, which keeps locks and transactions short
+	BatchSize = 1000
+)
+
+// Pruner periodically deletes reactions older than each guild's retention policy
+type Pruner struct {
+	repo      *Repository
+	now       func() time.Time
+	batchSize int
+}
+
+// NewPruner creates a new Pruner
+func NewPruner(repo *Repository) *Pruner {
+	return &Pruner{
+		repo:      repo,
+		now:       time.Now,
+		batchSize: BatchSize,
+	}
+}
+
+// Run prunes every guild with a retention policy every hour until the context is done
+func (p *Pruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		p.PruneAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PruneAll prunes every guild with a retention policy
+func (p *Pruner) PruneAll(ctx context.Context) {
+	policies, err := p.repo.GetPolicies(ctx)
+	if err != nil {
+		slog.Error("failed to get retention policies", "error", err)
+		return
+	}
+
+	now := p.now()
+
+	for _, policy := range policies {
+		pruned, err := p.Prune(ctx, policy, now)
+		if err != nil {
+			slog.Error("failed to prune reactions", "error", err, "guild_id", policy.GuildID, "pruned", pruned)
+			continue
+		}
+		if pruned > 0 {
+			slog.Info("pruned reactions", "guild_id", policy.GuildID, "pruned", pruned, "rollup", policy.Rollup)
+		}
+	}
+}
+
+// Prune deletes a guild's reactions older than its policy in batches until none remain, and returns how many were
+// deleted
+func (p *Pruner) Prune(ctx context.Context, policy Policy, now time.Time) (int, error) {
+	cutoff := policy.Cutoff(now)
+
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		n, err := p.repo.PruneBatch(ctx, policy.GuildID, cutoff, policy.Rollup, p.batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+
+		if n < p.batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Repository handles database queries for retention policies
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository creates a new retention repository
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetPolicy retrieves the retention policy for a guild, returning nil if the guild keeps reactions forever
+func (r *Repository) GetPolicy(ctx context.Context, guildID string) (*Policy, error) {
+	p := &Policy{GuildID: guildID}
+
+	err := r.db.QueryRowContext(ctx, `SELECT days, rollup FROM retention_policies WHERE guild_id = $1`, guildID).Scan(&p.Days, &p.Rollup)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetPolicies retrieves every guild's retention policy
+func (r *Repository) GetPolicies(ctx context.Context) ([]Policy, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT guild_id, days, rollup FROM retention_policies ORDER BY guild_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var policies []Policy
+	for rows.Next() {
+		var p Policy
+		if err := rows.Scan(&p.GuildID, &p.Days, &p.Rollup); err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+// SavePolicy creates or replaces the retention policy for a guild
+func (r *Repository) SavePolicy(ctx context.Context, p Policy) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO retention_policies (guild_id, days, rollup)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (guild_id) DO UPDATE
+		SET days = EXCLUDED.days, rollup = EXCLUDED.rollup, updated_at = NOW()`,
+		p.GuildID,
+		p.Days,
+		p.Rollup,
+	)
+	return err
+}
+
+// DeletePolicy removes a guild's retention policy so that its reactions are kept forever
+func (r *Repository) DeletePolicy(ctx context.Context, guildID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM retention_policies WHERE guild_id = $1`, guildID)
+	return err
+}
+
+// PruneBatch deletes up to limit of a guild's reactions made before the cutoff and returns how many were deleted. If
+// rollup is set they are added to the daily aggregates in the same statement, so a batch is never lost or counted
+// twice. Rows locked by a concurrent prune are skipped
+func (r *Repository) PruneBatch(ctx context.Context, guildID string, cutoff time.Time, rollup bool, limit int) (int, error) {
+	var deleted int
+	err := r.db.QueryRowContext(ctx, `
+		WITH batch AS (
+			SELECT id FROM reactions
+			WHERE guild_id = $1 AND created_at < $2
+			ORDER BY created_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		), deleted AS (
+			DELETE FROM reactions
+			USING batch
+			WHERE reactions.id = batch.id
+			RETURNING reactions.*
+		), rolled_up AS (
+			INSERT INTO reaction_aggregates (guild_id, created_at, emoji_id, is_default, sender_user_id, receiver_user_id, channel_id, count)
+			SELECT guild_id, date_trunc('day', created_at, 'UTC'), COALESCE(emoji_id, ''), bool_or(is_default), sender_user_id, receiver_user_id, channel_id, COUNT(*)
+			FROM deleted
+			WHERE $3
+			GROUP BY guild_id, date_trunc('day', created_at, 'UTC'), COALESCE(emoji_id, ''), sender_user_id, receiver_user_id, channel_id
+			ON CONFLICT (guild_id, created_at, emoji_id, sender_user_id, receiver_user_id, channel_id)
+			DO UPDATE SET count = reaction_aggregates.count + EXCLUDED.count
+		)
+		SELECT COUNT(*) FROM deleted`,
+		guildID,
+		cutoff,
+		rollup,
+		limit,
+	).Scan(&deleted)
+	return deleted, err
+}
@@ -0,0 +1,145 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+var testDB *sql.DB
+
+func setupTest(t *testing.T) (*Repository, string, func()) {
+	t.Helper()
+
+	testDB = dbtest.Open(t)
+
+	guildID := "test-guild-" + time.Now().Format("20060102150405.000000000")
+
+	cleanup := func() {
+		_, _ = testDB.Exec("DELETE FROM reactions WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM reaction_aggregates WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM retention_policies WHERE guild_id = $1", guildID)
+	}
+
+	return NewRepository(testDB), guildID, cleanup
+}
+
+func insertReaction(t *testing.T, guildID, emojiID, senderID string, createdAt time.Time) {
+	t.Helper()
+	_, err := testDB.Exec(`
+		INSERT INTO reactions (guild_id, emoji_id, sender_user_id, receiver_user_id, channel_id, message_id, is_default, created_at)
+		VALUES ($1, $2, $3, 'receiver1', 'chan1', 'msg1', true, $4)`,
+		guildID, emojiID, senderID, createdAt)
+	require.NoError(t, err)
+}
+
+func countRaw(t *testing.T, guildID string) int {
+	t.Helper()
+	var count int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM reactions WHERE guild_id = $1`, guildID).Scan(&count))
+	return count
+}
+
+func TestPolicies(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	p, err := repo.GetPolicy(ctx, guildID)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	require.NoError(t, repo.SavePolicy(ctx, Policy{GuildID: guildID, Days: 365, Rollup: true}))
+	require.NoError(t, repo.SavePolicy(ctx, Policy{GuildID: guildID, Days: 90, Rollup: false}))
+
+	p, err = repo.GetPolicy(ctx, guildID)
+	require.NoError(t, err)
+	assert.Equal(t, &Policy{GuildID: guildID, Days: 90}, p)
+
+	policies, err := repo.GetPolicies(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, policies, *p)
+
+	require.NoError(t, repo.DeletePolicy(ctx, guildID))
+
+	p, err = repo.GetPolicy(ctx, guildID)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestPrune_Rollup(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	insertReaction(t, guildID, "👍", "sender1", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	insertReaction(t, guildID, "👍", "sender1", time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC))
+	insertReaction(t, guildID, "👍", "sender2", time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC))
+	insertReaction(t, guildID, "❤️", "sender1", time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC))
+	insertReaction(t, guildID, "❤️", "sender2", now.Add(-time.Hour))
+
+	statsRepo := stats.NewRepository(testDB)
+	before, err := statsRepo.GetGuildStats(ctx, guildID, stats.DateRange{}, 10)
+	require.NoError(t, err)
+
+	pruner := NewPruner(repo)
+	pruner.batchSize = 2
+
+	pruned, err := pruner.Prune(ctx, Policy{GuildID: guildID, Days: 30, Rollup: true}, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, pruned)
+	assert.Equal(t, 1, countRaw(t, guildID))
+
+	var aggregates, total int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*), SUM(count) FROM reaction_aggregates WHERE guild_id = $1`, guildID).Scan(&aggregates, &total))
+	assert.Equal(t, 3, aggregates, "reactions should be grouped by day, emoji, users and channel")
+	assert.Equal(t, 4, total)
+
+	after, err := statsRepo.GetGuildStats(ctx, guildID, stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, before, after, "pruning with rollup should not change long-range totals")
+}
+
+func TestPrune_NoRollup(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	insertReaction(t, guildID, "👍", "sender1", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	insertReaction(t, guildID, "👍", "sender1", now)
+
+	pruned, err := NewPruner(repo).Prune(ctx, Policy{GuildID: guildID, Days: 30}, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+	assert.Equal(t, 1, countRaw(t, guildID))
+
+	var aggregates int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM reaction_aggregates WHERE guild_id = $1`, guildID).Scan(&aggregates))
+	assert.Zero(t, aggregates)
+}
+
+func TestPruneAll(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	insertReaction(t, guildID, "👍", "sender1", time.Now().AddDate(-2, 0, 0))
+	insertReaction(t, guildID, "👍", "sender1", time.Now())
+	require.NoError(t, repo.SavePolicy(ctx, Policy{GuildID: guildID, Days: 365, Rollup: true}))
+
+	NewPruner(repo).PruneAll(ctx)
+
+	assert.Equal(t, 1, countRaw(t, guildID))
+}
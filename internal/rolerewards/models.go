@@ -0,0 +1,76 @@
+package rolerewards
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kind is how a reward rule selects members
+type Kind string
+
+const (
+	// KindTop rewards the members with the highest counts
+	KindTop Kind = "top"
+	// KindThreshold rewards every member whose count reaches a threshold
+	KindThreshold Kind = "threshold"
+)
+
+// Metric is the reaction count a reward rule is evaluated against
+type Metric string
+
+const (
+	MetricReceived Metric = "received"
+	MetricGiven    Metric = "given"
+)
+
+// Rule grants a role to the members who meet it, and removes it from those who no longer do
+type Rule struct {
+	GuildID string
+	RoleID  string
+	Kind    Kind
+	Metric  Metric
+	// Count is the number of members rewarded for KindTop, or the threshold for KindThreshold
+	Count int
+	// WindowDays limits the rule to reactions in the last number of days. Zero counts all reactions
+	WindowDays int
+	// LastEvaluatedAt is when the rule was last evaluated, or when it was created if it has never been evaluated
+	LastEvaluatedAt time.Time
+}
+
+// Describe describes the rule in plain English, e.g. "top 3 receivers in the last 30 days"
+func (r Rule) Describe() string {
+	noun := "receivers"
+	verb := "received"
+	if r.Metric == MetricGiven {
+		noun = "givers"
+		verb = "given"
+	}
+
+	window := "all-time"
+	if r.WindowDays > 0 {
+		window = fmt.Sprintf("in the last %d days", r.WindowDays)
+	}
+
+	if r.Kind == KindTop {
+		return fmt.Sprintf("top %d %s %s", r.Count, noun, window)
+	}
+	return fmt.Sprintf("%d reactions %s %s", r.Count, verb, window)
+}
+
+// Action is a change made to a member's roles
+type Action string
+
+const (
+	ActionAdd    Action = "add"
+	ActionRemove Action = "remove"
+)
+
+// AuditEntry records a role change made by a reward rule
+type AuditEntry struct {
+	GuildID   string
+	RoleID    string
+	UserID    string
+	Action    Action
+	Reason    string
+	CreatedAt time.Time
+}
@@ -0,0 +1,23 @@
+package rolerewards
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRule_Describe(t *testing.T) {
+	tests := []struct {
+		rule     Rule
+		expected string
+	}{
+		{Rule{Kind: KindTop, Metric: MetricReceived, Count: 3, WindowDays: 30}, "top 3 receivers in the last 30 days"},
+		{Rule{Kind: KindTop, Metric: MetricGiven, Count: 1}, "top 1 givers all-time"},
+		{Rule{Kind: KindThreshold, Metric: MetricReceived, Count: 500}, "500 reactions received all-time"},
+		{Rule{Kind: KindThreshold, Metric: MetricGiven, Count: 50, WindowDays: 7}, "50 reactions given in the last 7 days"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.rule.Describe())
+	}
+}
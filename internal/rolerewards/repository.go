@@ -0,0 +1,258 @@
+package rolerewards
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/database"
+	"github.com/elliotwms/emojistats/internal/privacy"
+)
+
+// Repository handles database queries for role rewards
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository creates a new role rewards repository
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetRules retrieves the reward rules for a guild. If guildID is empty rules for all guilds are returned
+func (r *Repository) GetRules(ctx context.Context, guildID string) ([]Rule, error) {
+	query := `
+		SELECT guild_id, role_id, kind, metric, count, window_days, COALESCE(last_evaluated_at, created_at)
+		FROM role_rewards`
+	var args []any
+
+	if guildID != "" {
+		args = append(args, guildID)
+		query += ` WHERE guild_id = $1`
+	}
+	query += ` ORDER BY guild_id, role_id`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []Rule
+	for rows.Next() {
+		var rule Rule
+		if err := rows.Scan(&rule.GuildID, &rule.RoleID, &rule.Kind, &rule.Metric, &rule.Count, &rule.WindowDays, &rule.LastEvaluatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, rule)
+	}
+	return results, rows.Err()
+}
+
+// SaveRule creates or replaces the reward rule for a role
+func (r *Repository) SaveRule(ctx context.Context, rule Rule) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO role_rewards (guild_id, role_id, kind, metric, count, window_days)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (guild_id, role_id) DO UPDATE
+		SET kind = EXCLUDED.kind, metric = EXCLUDED.metric, count = EXCLUDED.count, window_days = EXCLUDED.window_days,
+			last_evaluated_at = NULL, created_at = NOW()`,
+		rule.GuildID,
+		rule.RoleID,
+		rule.Kind,
+		rule.Metric,
+		rule.Count,
+		rule.WindowDays,
+	)
+	return err
+}
+
+// DeleteRule removes the reward rule for a role. Members who hold the role keep it
+func (r *Repository) DeleteRule(ctx context.Context, guildID, roleID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_rewards WHERE guild_id = $1 AND role_id = $2`, guildID, roleID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_reward_members WHERE guild_id = $1 AND role_id = $2`, guildID, roleID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ClaimEvaluation marks a rule as evaluated at the given time. It returns false if the rule has been evaluated since
+// it was read, for example by another replica, in which case it should not be evaluated
+func (r *Repository) ClaimEvaluation(ctx context.Context, rule Rule, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE role_rewards
+		SET last_evaluated_at = $3
+		WHERE guild_id = $1 AND role_id = $2 AND COALESCE(last_evaluated_at, created_at) = $4`,
+		rule.GuildID,
+		rule.RoleID,
+		at,
+		rule.LastEvaluatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// GetCandidates retrieves the users who could currently meet a rule, before excluding those who are no longer in the
+// guild. For KindTop every user is returned, ranked from the highest count, as the top of the ranking may have left. For
+// KindThreshold the users who reach the threshold are returned. Pruned reactions are counted, so that members keep
+// their rewards when old reactions are pruned, and reactions in ignored channels are not
+func (r *Repository) GetCandidates(ctx context.Context, rule Rule, now time.Time) ([]string, error) {
+	column := "receiver_user_id"
+	if rule.Metric == MetricGiven {
+		column = "sender_user_id"
+	}
+
+	// users who have opted out of tracking are never rewarded
+	query := `
+		SELECT ` + column + `, SUM(count) as count FROM ` + database.CountedReactions + `
+		WHERE guild_id = $1
+		AND ` + column + ` <> '` + privacy.AnonymousUserID + `'
+		AND ` + column + ` NOT IN (SELECT user_id FROM privacy_opt_outs)` + database.NotIgnored
+	args := []any{rule.GuildID}
+
+	if rule.WindowDays > 0 {
+		args = append(args, now.AddDate(0, 0, -rule.WindowDays))
+		query += ` AND created_at >= $2`
+	}
+
+	query += ` GROUP BY ` + column
+
+	switch rule.Kind {
+	case KindTop:
+		query += ` ORDER BY count DESC, ` + column
+	case KindThreshold:
+		args = append(args, rule.Count)
+		query += fmt.Sprintf(` HAVING SUM(count) >= $%d`, len(args))
+	default:
+		return nil, fmt.Errorf("unknown rule kind %q", rule.Kind)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []string
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		results = append(results, userID)
+	}
+	return results, rows.Err()
+}
+
+// GetMembers retrieves the users who hold a role because of its reward rule
+func (r *Repository) GetMembers(ctx context.Context, guildID, roleID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id FROM role_reward_members
+		WHERE guild_id = $1 AND role_id = $2
+		ORDER BY user_id`,
+		guildID,
+		roleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		results = append(results, userID)
+	}
+	return results, rows.Err()
+}
+
+// RecordChange records that a role was added to or removed from a member, updating the members and audit log
+func (r *Repository) RecordChange(ctx context.Context, e AuditEntry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	switch e.Action {
+	case ActionAdd:
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO role_reward_members (guild_id, role_id, user_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`,
+			e.GuildID, e.RoleID, e.UserID,
+		)
+	case ActionRemove:
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM role_reward_members
+			WHERE guild_id = $1 AND role_id = $2 AND user_id = $3`,
+			e.GuildID, e.RoleID, e.UserID,
+		)
+	default:
+		err = fmt.Errorf("unknown action %q", e.Action)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO role_reward_audit (guild_id, role_id, user_id, action, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		e.GuildID, e.RoleID, e.UserID, e.Action, e.Reason, e.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAuditLog retrieves the most recent role changes in a guild
+func (r *Repository) GetAuditLog(ctx context.Context, guildID string, limit int) ([]AuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT role_id, user_id, action, reason, created_at
+		FROM role_reward_audit
+		WHERE guild_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`,
+		guildID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []AuditEntry
+	for rows.Next() {
+		e := AuditEntry{GuildID: guildID}
+		if err := rows.Scan(&e.RoleID, &e.UserID, &e.Action, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, e)
+	}
+	return results, rows.Err()
+}
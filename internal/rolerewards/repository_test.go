@@ -0,0 +1,194 @@
+package rolerewards
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+)
+
+var testDB *sql.DB
+
+func setupTest(t *testing.T) (*Repository, string, func()) {
+	t.Helper()
+
+	testDB = dbtest.Open(t)
+
+	guildID := "test-guild-" + time.Now().Format("20060102150405.000000000")
+
+	cleanup := func() {
+		_, _ = testDB.Exec("DELETE FROM reactions WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM reaction_aggregates WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM role_rewards WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM role_reward_members WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM role_reward_audit WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM guild_settings WHERE guild_id = $1", guildID)
+	}
+
+	return NewRepository(testDB), guildID, cleanup
+}
+
+func insertReaction(t *testing.T, guildID, senderID, receiverID string, createdAt time.Time) {
+	t.Helper()
+	_, err := testDB.Exec(`
+		INSERT INTO reactions (guild_id, emoji_id, sender_user_id, receiver_user_id, channel_id, message_id, created_at)
+		VALUES ($1, '👍', $2, $3, 'chan1', 'msg1', $4)`,
+		guildID, senderID, receiverID, createdAt)
+	require.NoError(t, err)
+}
+
+func TestRules(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	rule := Rule{GuildID: guildID, RoleID: "role1", Kind: KindTop, Metric: MetricReceived, Count: 3, WindowDays: 30}
+	require.NoError(t, repo.SaveRule(ctx, rule))
+
+	rules, err := repo.GetRules(ctx, guildID)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, KindTop, rules[0].Kind)
+	assert.Equal(t, MetricReceived, rules[0].Metric)
+	assert.Equal(t, 3, rules[0].Count)
+	assert.Equal(t, 30, rules[0].WindowDays)
+
+	require.NoError(t, repo.DeleteRule(ctx, guildID, "role1"))
+
+	rules, err = repo.GetRules(ctx, guildID)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestClaimEvaluation(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveRule(ctx, Rule{GuildID: guildID, RoleID: "role1", Kind: KindThreshold, Metric: MetricGiven, Count: 10}))
+
+	rules, err := repo.GetRules(ctx, guildID)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	claimed, err := repo.ClaimEvaluation(ctx, rules[0], now)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = repo.ClaimEvaluation(ctx, rules[0], now)
+	require.NoError(t, err)
+	assert.False(t, claimed, "a stale rule should not be claimed twice")
+}
+
+func TestGetCandidates(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -60)
+
+	insertReaction(t, guildID, "user1", "user2", now)
+	insertReaction(t, guildID, "user1", "user2", now)
+	insertReaction(t, guildID, "user1", "user3", now)
+	insertReaction(t, guildID, "user2", "user3", old)
+	insertReaction(t, guildID, "user2", "user3", old)
+
+	t.Run("top ranks everyone", func(t *testing.T) {
+		candidates, err := repo.GetCandidates(ctx, Rule{GuildID: guildID, Kind: KindTop, Metric: MetricReceived, Count: 1}, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user3", "user2"}, candidates)
+	})
+
+	t.Run("top within window", func(t *testing.T) {
+		candidates, err := repo.GetCandidates(ctx, Rule{GuildID: guildID, Kind: KindTop, Metric: MetricReceived, Count: 1, WindowDays: 30}, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user2", "user3"}, candidates)
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		candidates, err := repo.GetCandidates(ctx, Rule{GuildID: guildID, Kind: KindThreshold, Metric: MetricGiven, Count: 2}, now)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"user1", "user2"}, candidates)
+	})
+
+	t.Run("threshold within window", func(t *testing.T) {
+		candidates, err := repo.GetCandidates(ctx, Rule{GuildID: guildID, Kind: KindThreshold, Metric: MetricGiven, Count: 2, WindowDays: 30}, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user1"}, candidates)
+	})
+}
+
+func TestGetCandidates_Pruned(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	insertReaction(t, guildID, "user1", "user2", now)
+	_, err := testDB.Exec(`
+		INSERT INTO reaction_aggregates (guild_id, created_at, emoji_id, is_default, sender_user_id, receiver_user_id, count)
+		VALUES ($1, '2023-01-01', '👍', true, 'user2', 'user3', 3)`,
+		guildID)
+	require.NoError(t, err)
+
+	candidates, err := repo.GetCandidates(ctx, Rule{GuildID: guildID, Kind: KindThreshold, Metric: MetricGiven, Count: 2}, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user2"}, candidates, "pruned reactions are counted")
+}
+
+func TestGetCandidates_IgnoredChannel(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	insertReaction(t, guildID, "user1", "user2", now)
+	_, err := testDB.Exec(`
+		INSERT INTO reactions (guild_id, emoji_id, sender_user_id, receiver_user_id, channel_id, message_id, created_at)
+		VALUES ($1, '👍', 'user2', 'user3', 'chan2', 'msg2', $2), ($1, '👍', 'user2', 'user3', 'chan2', 'msg2', $2)`,
+		guildID, now)
+	require.NoError(t, err)
+
+	_, err = testDB.Exec(`INSERT INTO guild_settings (guild_id, ignored_channel_ids) VALUES ($1, ARRAY['chan2'])`, guildID)
+	require.NoError(t, err)
+
+	candidates, err := repo.GetCandidates(ctx, Rule{GuildID: guildID, Kind: KindTop, Metric: MetricReceived, Count: 1}, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user2"}, candidates, "reactions in ignored channels are not counted")
+}
+
+func TestRecordChange(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+
+	require.NoError(t, repo.RecordChange(ctx, AuditEntry{GuildID: guildID, RoleID: "role1", UserID: "user1", Action: ActionAdd, Reason: "top 1 receivers all-time", CreatedAt: now}))
+
+	members, err := repo.GetMembers(ctx, guildID, "role1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user1"}, members)
+
+	require.NoError(t, repo.RecordChange(ctx, AuditEntry{GuildID: guildID, RoleID: "role1", UserID: "user1", Action: ActionRemove, Reason: "top 1 receivers all-time", CreatedAt: now.Add(time.Hour)}))
+
+	members, err = repo.GetMembers(ctx, guildID, "role1")
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	entries, err := repo.GetAuditLog(ctx, guildID, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, ActionRemove, entries[0].Action)
+	assert.Equal(t, ActionAdd, entries[1].Action)
+	assert.Equal(t, "top 1 receivers all-time", entries[1].Reason)
+}
@@ -0,0 +1,210 @@
+package rolerewards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	checkInterval = time.Minute
+	// EvaluationInterval is how often each rule is evaluated
+	EvaluationInterval = time.Hour
+)
+
+// Scheduler periodically evaluates role reward rules and updates members' roles to match
+type Scheduler struct {
+	repo    *Repository
+	session *discordgo.Session
+	now     func() time.Time
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler(repo *Repository, s *discordgo.Session) *Scheduler {
+	return &Scheduler{
+		repo:    repo,
+		session: s,
+		now:     time.Now,
+	}
+}
+
+// Run evaluates due rules every minute until the context is done
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		s.RunDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue evaluates every rule which has not been evaluated within the evaluation interval
+func (s *Scheduler) RunDue(ctx context.Context) {
+	rules, err := s.repo.GetRules(ctx, "")
+	if err != nil {
+		slog.Error("failed to get role rewards", "error", err)
+		return
+	}
+
+	now := s.now().UTC()
+
+	for _, rule := range rules {
+		if rule.LastEvaluatedAt.Add(EvaluationInterval).After(now) {
+			continue
+		}
+
+		claimed, err := s.repo.ClaimEvaluation(ctx, rule, now)
+		if err != nil {
+			slog.Error("failed to claim role reward", "error", err, "guild_id", rule.GuildID, "role_id", rule.RoleID)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.Evaluate(ctx, rule, now); err != nil {
+			slog.Error("failed to evaluate role reward", "error", err, "guild_id", rule.GuildID, "role_id", rule.RoleID)
+		}
+	}
+}
+
+// Evaluate adds the rule's role to members who meet it and removes it from members who no longer do. Members who were
+// given the role by other means are left alone
+func (s *Scheduler) Evaluate(ctx context.Context, rule Rule, now time.Time) error {
+	candidates, err := s.repo.GetCandidates(ctx, rule, now)
+	if err != nil {
+		return fmt.Errorf("failed to get candidates: %w", err)
+	}
+
+	current, err := s.repo.GetMembers(ctx, rule.GuildID, rule.RoleID)
+	if err != nil {
+		return fmt.Errorf("failed to get members: %w", err)
+	}
+
+	eligible, err := Eligible(rule, candidates, current, func(userID string) (*discordgo.Member, error) {
+		return s.member(ctx, rule.GuildID, userID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get eligible members: %w", err)
+	}
+
+	add, remove := Diff(current, eligible)
+	reason := rule.Describe()
+
+	for _, userID := range add {
+		if err := s.session.GuildMemberRoleAdd(rule.GuildID, userID, rule.RoleID, discordgo.WithAuditLogReason(reason)); err != nil {
+			slog.Error("failed to add role", "error", err, "guild_id", rule.GuildID, "role_id", rule.RoleID, "user_id", userID)
+			continue
+		}
+
+		if err := s.record(ctx, rule, userID, ActionAdd, reason, now); err != nil {
+			return err
+		}
+	}
+
+	for _, userID := range remove {
+		// members who have left the guild have no roles to remove, so only stop tracking them
+		if err := s.session.GuildMemberRoleRemove(rule.GuildID, userID, rule.RoleID, discordgo.WithAuditLogReason(reason)); err != nil && !isUnknownMember(err) {
+			slog.Error("failed to remove role", "error", err, "guild_id", rule.GuildID, "role_id", rule.RoleID, "user_id", userID)
+			continue
+		}
+
+		if err := s.record(ctx, rule, userID, ActionRemove, reason, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// member retrieves a member of a guild, or nil if the user is not a member
+func (s *Scheduler) member(ctx context.Context, guildID, userID string) (*discordgo.Member, error) {
+	m, err := s.session.GuildMember(guildID, userID, discordgo.WithContext(ctx))
+	if isUnknownMember(err) {
+		return nil, nil
+	}
+	return m, err
+}
+
+func isUnknownMember(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMember
+}
+
+// Eligible returns the candidates who meet a rule, given the members who currently hold its role because of it.
+// Candidates who are no longer in the guild are excluded before the top members are taken. Candidates who already hold
+// the role without having been given it by the rule are excluded, so that the rule never takes it from them, though
+// they still take one of the top places
+func Eligible(rule Rule, candidates, current []string, member func(userID string) (*discordgo.Member, error)) ([]string, error) {
+	var eligible []string
+	members := 0
+
+	for _, userID := range candidates {
+		if rule.Kind == KindTop && members == rule.Count {
+			break
+		}
+
+		m, err := member(userID)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			continue
+		}
+		members++
+
+		if !slices.Contains(current, userID) && slices.Contains(m.Roles, rule.RoleID) {
+			continue
+		}
+
+		eligible = append(eligible, userID)
+	}
+
+	return eligible, nil
+}
+
+func (s *Scheduler) record(ctx context.Context, rule Rule, userID string, action Action, reason string, now time.Time) error {
+	err := s.repo.RecordChange(ctx, AuditEntry{
+		GuildID:   rule.GuildID,
+		RoleID:    rule.RoleID,
+		UserID:    userID,
+		Action:    action,
+		Reason:    reason,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record role change: %w", err)
+	}
+
+	slog.Info("role reward updated", "guild_id", rule.GuildID, "role_id", rule.RoleID, "user_id", userID, "action", action)
+
+	return nil
+}
+
+// Diff returns the users who should be given the role and those who should have it removed
+func Diff(current, eligible []string) (add, remove []string) {
+	for _, userID := range eligible {
+		if !slices.Contains(current, userID) {
+			add = append(add, userID)
+		}
+	}
+
+	for _, userID := range current {
+		if !slices.Contains(eligible, userID) {
+			remove = append(remove, userID)
+		}
+	}
+
+	return add, remove
+}
@@ -0,0 +1,58 @@
+package rolerewards
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	add, remove := Diff([]string{"user1", "user2"}, []string{"user2", "user3"})
+
+	assert.Equal(t, []string{"user3"}, add)
+	assert.Equal(t, []string{"user1"}, remove)
+}
+
+func TestDiff_Unchanged(t *testing.T) {
+	add, remove := Diff([]string{"user1"}, []string{"user1"})
+
+	assert.Empty(t, add)
+	assert.Empty(t, remove)
+}
+
+func TestEligible(t *testing.T) {
+	members := map[string]*discordgo.Member{
+		"user1": {Roles: []string{"role1"}},
+		"user2": {},
+		"user4": {Roles: []string{"role1"}},
+		"user5": {},
+	}
+	member := func(userID string) (*discordgo.Member, error) {
+		return members[userID], nil
+	}
+	candidates := []string{"user1", "user2", "user3", "user4", "user5"}
+
+	t.Run("top", func(t *testing.T) {
+		eligible, err := Eligible(Rule{RoleID: "role1", Kind: KindTop, Count: 3}, candidates, []string{"user4"}, member)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user2", "user4"}, eligible, "user1 held the role already and user3 has left")
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		eligible, err := Eligible(Rule{RoleID: "role1", Kind: KindThreshold, Count: 10}, candidates, nil, member)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user2", "user5"}, eligible)
+	})
+}
+
+func TestEligible_Error(t *testing.T) {
+	errLookup := errors.New("lookup failed")
+
+	_, err := Eligible(Rule{Kind: KindThreshold}, []string{"user1"}, nil, func(string) (*discordgo.Member, error) {
+		return nil, errLookup
+	})
+	assert.ErrorIs(t, err, errLookup)
+}
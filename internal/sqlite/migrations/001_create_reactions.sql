@@ -0,0 +1,23 @@
+-- +goose Up
+-- created_at is stored in Unix milliseconds so that it compares and filters numerically
+CREATE TABLE reactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    emoji_id TEXT,
+    sender_user_id TEXT NOT NULL,
+    receiver_user_id TEXT NOT NULL,
+    channel_id TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    guild_id TEXT NOT NULL,
+    is_default INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX idx_reactions_guild_emoji ON reactions (guild_id, emoji_id);
+CREATE INDEX idx_reactions_guild_sender ON reactions (guild_id, sender_user_id);
+CREATE INDEX idx_reactions_guild_receiver ON reactions (guild_id, receiver_user_id);
+CREATE INDEX idx_reactions_guild_created ON reactions (guild_id, created_at);
+CREATE INDEX idx_reactions_emoji_message ON reactions (emoji_id, message_id);
+CREATE INDEX idx_reactions_guild_channel ON reactions (guild_id, channel_id);
+
+-- +goose Down
+DROP TABLE reactions;
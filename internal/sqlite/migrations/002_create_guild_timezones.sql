@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE guild_timezones (
+    guild_id TEXT PRIMARY KEY,
+    timezone TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE guild_timezones;
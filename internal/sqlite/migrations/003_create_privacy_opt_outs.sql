@@ -0,0 +1,9 @@
+-- +goose Up
+-- privacy_opt_outs holds the users whose reactions are not stored. Opting out applies in every guild
+CREATE TABLE privacy_opt_outs (
+    user_id TEXT PRIMARY KEY,
+    opted_out_at INTEGER NOT NULL
+);
+
+-- +goose Down
+DROP TABLE privacy_opt_outs;
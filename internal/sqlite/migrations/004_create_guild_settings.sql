@@ -0,0 +1,13 @@
+-- +goose Up
+-- excluded_channel_ids is a JSON array, as SQLite has no array type. updated_at is in Unix milliseconds
+CREATE TABLE guild_settings (
+    guild_id TEXT PRIMARY KEY,
+    public_responses INTEGER NOT NULL DEFAULT 0,
+    leaderboard_size INTEGER NOT NULL DEFAULT 10,
+    count_bots INTEGER NOT NULL DEFAULT 1,
+    excluded_channel_ids TEXT NOT NULL DEFAULT '[]',
+    updated_at INTEGER NOT NULL
+);
+
+-- +goose Down
+DROP TABLE guild_settings;
@@ -0,0 +1,36 @@
+-- +goose Up
+-- The ignored IDs are JSON arrays, as SQLite has no array type
+ALTER TABLE guild_settings RENAME COLUMN excluded_channel_ids TO ignored_channel_ids;
+ALTER TABLE guild_settings ADD COLUMN ignored_category_ids TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE guild_settings ADD COLUMN ignored_role_ids TEXT NOT NULL DEFAULT '[]';
+
+CREATE TABLE channel_parents (
+    guild_id TEXT NOT NULL,
+    channel_id TEXT NOT NULL,
+    parent_id TEXT NOT NULL,
+    PRIMARY KEY (guild_id, channel_id)
+);
+
+CREATE VIEW ignored_channels AS
+WITH RECURSIVE ancestors (guild_id, channel_id, parent_id) AS (
+    SELECT guild_id, channel_id, parent_id FROM channel_parents
+    UNION
+    SELECT a.guild_id, a.channel_id, p.parent_id
+    FROM ancestors a
+    JOIN channel_parents p ON p.guild_id = a.guild_id AND p.channel_id = a.parent_id
+)
+SELECT s.guild_id, c.value AS channel_id
+FROM guild_settings s, json_each(s.ignored_channel_ids) c
+UNION
+SELECT a.guild_id, a.channel_id
+FROM ancestors a
+JOIN guild_settings s ON s.guild_id = a.guild_id
+WHERE a.parent_id IN (SELECT value FROM json_each(s.ignored_channel_ids))
+   OR a.parent_id IN (SELECT value FROM json_each(s.ignored_category_ids));
+
+-- +goose Down
+DROP VIEW ignored_channels;
+DROP TABLE channel_parents;
+ALTER TABLE guild_settings DROP COLUMN ignored_role_ids;
+ALTER TABLE guild_settings DROP COLUMN ignored_category_ids;
+ALTER TABLE guild_settings RENAME COLUMN ignored_channel_ids TO excluded_channel_ids;
@@ -0,0 +1,63 @@
+// Package sqlite stores reactions in a SQLite database, for running the bot without Postgres. It implements ingestion
+// and the stats queries; features such as the starboard, digests and role rewards still require Postgres
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Open opens the SQLite database at path, creating it if it does not exist. ":memory:" opens a temporary database
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite allows a single writer, and each connection to ":memory:" would be a separate database
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+// Provider returns a migration provider for the SQLite migrations, which are separate from the Postgres migrations
+func Provider(db *sql.DB) (*goose.Provider, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, fsys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration provider: %w", err)
+	}
+
+	return provider, nil
+}
+
+// Migrate runs the SQLite migrations
+func Migrate(db *sql.DB) error {
+	provider, err := Provider(db)
+	if err != nil {
+		return err
+	}
+
+	if _, err := provider.Up(context.Background()); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return nil
+}
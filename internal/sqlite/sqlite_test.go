@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+func TestMigrate_Idempotent(t *testing.T) {
+	db, err := Open(":memory:")
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	require.NoError(t, Migrate(db))
+	require.NoError(t, Migrate(db))
+}
+
+func TestOpen_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "emojistats.db")
+	ctx := context.Background()
+
+	db, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, Migrate(db))
+
+	var mode string
+	require.NoError(t, db.QueryRow(`PRAGMA journal_mode`).Scan(&mode))
+	assert.Equal(t, "wal", mode)
+
+	require.NoError(t, NewStore(db).AddReaction(ctx, stats.Reaction{
+		GuildID:        "guild1",
+		ChannelID:      "chan1",
+		MessageID:      "msg1",
+		EmojiID:        "👍",
+		IsDefault:      true,
+		SenderUserID:   "sender1",
+		ReceiverUserID: "receiver1",
+		CreatedAt:      time.Now(),
+	}))
+	require.NoError(t, db.Close())
+
+	// reopening the file keeps its reactions and does not reapply its migrations
+	db, err = Open(path)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	require.NoError(t, Migrate(db))
+
+	result, err := NewStore(db).GetGuildStats(ctx, "guild1", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.TotalReactions)
+}
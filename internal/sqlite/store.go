@@ -0,0 +1,915 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// Store is a stats.Store backed by SQLite. Its queries match those of stats.Repository, except that streaks are
+// computed in Go. SQLite has no retention pruning, so there are no aggregates or rollups to read from
+type Store struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+var _ stats.Store = (*Store)(nil)
+
+// NewStore creates a new SQLite store. The database must have been migrated with Migrate
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db, now: time.Now}
+}
+
+// AddReaction stores a reaction. A zero CreatedAt is stored as the current time
+func (s *Store) AddReaction(ctx context.Context, reaction stats.Reaction) error {
+	createdAt := reaction.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = s.now()
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO reactions (emoji_id, sender_user_id, receiver_user_id, channel_id, message_id, guild_id, is_default, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		reaction.EmojiID,
+		reaction.SenderUserID,
+		reaction.ReceiverUserID,
+		reaction.ChannelID,
+		reaction.MessageID,
+		reaction.GuildID,
+		reaction.IsDefault,
+		createdAt.UnixMilli(),
+	)
+	return err
+}
+
+// RemoveReaction deletes a user's reaction to a message and returns the number of reactions deleted
+func (s *Store) RemoveReaction(ctx context.Context, guildID, messageID, emojiID, senderUserID string) (int, error) {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM reactions
+		WHERE emoji_id = $1 AND sender_user_id = $2 AND message_id = $3 AND guild_id = $4`,
+		emojiID,
+		senderUserID,
+		messageID,
+		guildID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// OptedOut returns which of the given users have opted out of having their reactions stored
+func (s *Store) OptedOut(ctx context.Context, userIDs ...string) (map[string]bool, error) {
+	results := make(map[string]bool)
+	if len(userIDs) == 0 {
+		return results, nil
+	}
+
+	args := make([]any, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id FROM privacy_opt_outs WHERE user_id IN (`+placeholders(1, len(args))+`)`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		results[userID] = true
+	}
+	return results, rows.Err()
+}
+
+// OptOut stops a user's reactions from being stored in every guild. SQLite keeps no audit log of privacy requests
+func (s *Store) OptOut(ctx context.Context, _, userID string) error {
+	return optOut(ctx, s.db, userID, s.now())
+}
+
+// OptIn resumes storing a user's reactions
+func (s *Store) OptIn(ctx context.Context, _, userID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM privacy_opt_outs WHERE user_id = $1`, userID)
+	return err
+}
+
+// Forget deletes the reactions a user gave and anonymises those they received in every guild, then opts them out
+func (s *Store) Forget(ctx context.Context, _, userID string) (privacy.Forgotten, error) {
+	var f privacy.Forgotten
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return f, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM reactions WHERE sender_user_id = $1`, userID)
+	if err != nil {
+		return f, err
+	}
+	f.Deleted, err = rowsAffected(res)
+	if err != nil {
+		return f, err
+	}
+
+	res, err = tx.ExecContext(ctx, `UPDATE reactions SET receiver_user_id = $2 WHERE receiver_user_id = $1`, userID, privacy.AnonymousUserID)
+	if err != nil {
+		return f, err
+	}
+	f.Anonymised, err = rowsAffected(res)
+	if err != nil {
+		return f, err
+	}
+
+	if err := optOut(ctx, tx, userID, s.now()); err != nil {
+		return f, err
+	}
+
+	return f, tx.Commit()
+}
+
+// GetGuildStats retrieves aggregated stats for a guild
+func (s *Store) GetGuildStats(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) (*stats.GuildStats, error) {
+	result := &stats.GuildStats{}
+
+	total, err := s.getTotalReactions(ctx, guildID, dateRange)
+	if err != nil {
+		return nil, err
+	}
+	result.TotalReactions = total
+
+	result.TopEmojis, err = s.getTopEmojis(ctx, guildID, dateRange, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result.TopSenders, err = s.getTopUsers(ctx, guildID, "sender_user_id", "", dateRange, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result.TopReceivers, err = s.getTopUsers(ctx, guildID, "receiver_user_id", "", dateRange, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.anonymise(ctx, append(userIDs(result.TopSenders), userIDs(result.TopReceivers)...)...); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetEmojiStats retrieves detailed stats for a specific emoji
+func (s *Store) GetEmojiStats(ctx context.Context, guildID, emojiID string, dateRange stats.DateRange, limit int) (*stats.EmojiStats, error) {
+	result := &stats.EmojiStats{
+		EmojiID: emojiID,
+	}
+
+	query := `SELECT COUNT(*), COALESCE(MAX(is_default), 0) FROM reactions WHERE guild_id = $1 AND emoji_id = $2`
+	args := []any{guildID, emojiID}
+	query, args = appendFilters(query, args, dateRange)
+
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&result.TotalUses, &result.IsDefault); err != nil {
+		return nil, err
+	}
+
+	var err error
+	result.TopMessages, err = s.getTopMessages(ctx, guildID, emojiID, dateRange, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result.TopSenders, err = s.getTopUsers(ctx, guildID, "sender_user_id", emojiID, dateRange, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result.TopReceivers, err = s.getTopUsers(ctx, guildID, "receiver_user_id", emojiID, dateRange, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.anonymise(ctx, append(userIDs(result.TopSenders), userIDs(result.TopReceivers)...)...); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetTopEmojis retrieves the most used emojis in a guild
+func (s *Store) GetTopEmojis(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) ([]stats.EmojiCount, error) {
+	return s.getTopEmojis(ctx, guildID, dateRange, limit)
+}
+
+// GetTopChannels retrieves the channels with the most reactions in a guild
+func (s *Store) GetTopChannels(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) ([]stats.ChannelCount, error) {
+	query := `SELECT channel_id, COUNT(*) as count FROM reactions WHERE guild_id = $1`
+	args := []any{guildID}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY channel_id ORDER BY count DESC, channel_id LIMIT $` + argNum(len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []stats.ChannelCount
+	for rows.Next() {
+		var cc stats.ChannelCount
+		if err := rows.Scan(&cc.ChannelID, &cc.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, cc)
+	}
+	return results, rows.Err()
+}
+
+// GetTopMessages retrieves the most reacted messages in a guild across all emojis, optionally filtered by channel
+func (s *Store) GetTopMessages(ctx context.Context, guildID, channelID string, dateRange stats.DateRange, limit int) ([]stats.TopMessage, error) {
+	messages, err := s.getTopMessagesFor(ctx, guildID, channelID, "", dateRange, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]*string, len(messages))
+	for i := range messages {
+		ids[i] = &messages[i].AuthorID
+	}
+
+	return messages, s.anonymise(ctx, ids...)
+}
+
+// GetWrapped retrieves a year in review recap for a guild. If userID is set the recap is for that user. The year and its
+// months are in the guild's timezone
+func (s *Store) GetWrapped(ctx context.Context, guildID, userID string, year int) (*stats.Wrapped, error) {
+	loc, err := s.GetTimezone(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(1, 0, 0)
+	dateRange := stats.DateRange{Start: &start, End: &end}
+
+	w := &stats.Wrapped{Year: year, UserID: userID}
+
+	if userID == "" {
+		w.TotalReactions, err = s.getTotalReactions(ctx, guildID, dateRange)
+	} else {
+		w.TotalReactions, err = s.countWhere(ctx, guildID, "sender_user_id", userID, dateRange)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if w.TotalReactions > 0 {
+		w.FavouriteEmoji, err = s.getFavouriteEmoji(ctx, guildID, userID, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		w.MonthlyCounts, err = s.getMonthlyCounts(ctx, guildID, userID, dateRange, loc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if userID == "" {
+		topSenders, err := s.getTopUsers(ctx, guildID, "sender_user_id", "", dateRange, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(topSenders) > 0 {
+			w.TopFan = &topSenders[0]
+		}
+	} else {
+		w.TotalReceived, err = s.countWhere(ctx, guildID, "receiver_user_id", userID, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		w.TopFan, err = s.getTopFan(ctx, guildID, userID, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		w.SenderRank, err = s.getRank(ctx, guildID, "sender_user_id", w.TotalReactions, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		w.ReceiverRank, err = s.getRank(ctx, guildID, "receiver_user_id", w.TotalReceived, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		streaks, err := s.getStreaks(ctx, guildID, userID, dateRange, false, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(streaks) > 0 {
+			w.Streak = streaks[0]
+		}
+	}
+
+	topMessages, err := s.getTopMessagesFor(ctx, guildID, "", userID, dateRange, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(topMessages) > 0 {
+		w.TopMessage = &topMessages[0]
+	}
+
+	ids := []*string{&w.UserID}
+	if w.TopFan != nil {
+		ids = append(ids, &w.TopFan.UserID)
+	}
+	if w.TopMessage != nil {
+		ids = append(ids, &w.TopMessage.AuthorID)
+	}
+
+	return w, s.anonymise(ctx, ids...)
+}
+
+// GetStreak retrieves a user's current and longest streaks, counting days in the guild's timezone
+func (s *Store) GetStreak(ctx context.Context, guildID, userID string) (stats.Streak, error) {
+	streaks, err := s.getStreaks(ctx, guildID, userID, stats.DateRange{}, false, 1)
+	if err != nil {
+		return stats.Streak{}, err
+	}
+	streak := stats.Streak{UserID: userID}
+	if len(streaks) > 0 {
+		streak = streaks[0]
+	}
+
+	if err := s.anonymise(ctx, &streak.UserID); err != nil {
+		return stats.Streak{}, err
+	}
+	return streak, nil
+}
+
+// GetStreaks retrieves the users with the highest current streaks, or the highest longest streaks if byLongest is set
+func (s *Store) GetStreaks(ctx context.Context, guildID string, byLongest bool, limit int) ([]stats.Streak, error) {
+	streaks, err := s.getStreaks(ctx, guildID, "", stats.DateRange{}, byLongest, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]*string, len(streaks))
+	for i := range streaks {
+		ids[i] = &streaks[i].UserID
+	}
+
+	return streaks, s.anonymise(ctx, ids...)
+}
+
+// GetTimezone retrieves the timezone used to group a guild's reactions into days. It defaults to UTC
+func (s *Store) GetTimezone(ctx context.Context, guildID string) (*time.Location, error) {
+	var name string
+	err := s.db.QueryRowContext(ctx, `SELECT timezone FROM guild_timezones WHERE guild_id = $1`, guildID).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return time.LoadLocation(name)
+}
+
+// SetTimezone sets the timezone used to group a guild's reactions into days
+func (s *Store) SetTimezone(ctx context.Context, guildID string, loc *time.Location) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO guild_timezones (guild_id, timezone)
+		VALUES ($1, $2)
+		ON CONFLICT (guild_id) DO UPDATE SET timezone = excluded.timezone`,
+		guildID,
+		loc.String(),
+	)
+	return err
+}
+
+// GetSettings retrieves a guild's settings. A guild which has not configured any has stats.DefaultSettings
+func (s *Store) GetSettings(ctx context.Context, guildID string) (stats.Settings, error) {
+	var settings stats.Settings
+	var channels, categories, roles string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT public_responses, leaderboard_size, count_bots, ignored_channel_ids, ignored_category_ids, ignored_role_ids
+		FROM guild_settings
+		WHERE guild_id = $1`, guildID).
+		Scan(&settings.PublicResponses, &settings.LeaderboardSize, &settings.CountBots, &channels, &categories, &roles)
+	if errors.Is(err, sql.ErrNoRows) {
+		return stats.DefaultSettings(), nil
+	}
+	if err != nil {
+		return stats.Settings{}, err
+	}
+
+	return settings, errors.Join(
+		json.Unmarshal([]byte(channels), &settings.IgnoredChannels),
+		json.Unmarshal([]byte(categories), &settings.IgnoredCategories),
+		json.Unmarshal([]byte(roles), &settings.IgnoredRoles),
+	)
+}
+
+// SetSettings stores a guild's settings
+func (s *Store) SetSettings(ctx context.Context, guildID string, settings stats.Settings) error {
+	channels, err := marshalIDs(settings.IgnoredChannels)
+	if err != nil {
+		return err
+	}
+	categories, err := marshalIDs(settings.IgnoredCategories)
+	if err != nil {
+		return err
+	}
+	roles, err := marshalIDs(settings.IgnoredRoles)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO guild_settings (
+			guild_id, public_responses, leaderboard_size, count_bots,
+			ignored_channel_ids, ignored_category_ids, ignored_role_ids, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (guild_id) DO UPDATE SET
+			public_responses = excluded.public_responses,
+			leaderboard_size = excluded.leaderboard_size,
+			count_bots = excluded.count_bots,
+			ignored_channel_ids = excluded.ignored_channel_ids,
+			ignored_category_ids = excluded.ignored_category_ids,
+			ignored_role_ids = excluded.ignored_role_ids,
+			updated_at = excluded.updated_at`,
+		guildID,
+		settings.PublicResponses,
+		settings.LeaderboardSize,
+		settings.CountBots,
+		channels,
+		categories,
+		roles,
+		s.now().UnixMilli(),
+	)
+	return err
+}
+
+// SetChannelParent records a channel's parent, so that its reactions are excluded from the stats while an ancestor is
+// ignored. It returns whether the recorded parent changed
+func (s *Store) SetChannelParent(ctx context.Context, guildID, channelID, parentID string) (bool, error) {
+	var res sql.Result
+	var err error
+	if parentID == "" {
+		res, err = s.db.ExecContext(ctx, `DELETE FROM channel_parents WHERE guild_id = $1 AND channel_id = $2`, guildID, channelID)
+	} else {
+		res, err = s.db.ExecContext(ctx, `
+			INSERT INTO channel_parents (guild_id, channel_id, parent_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (guild_id, channel_id) DO UPDATE SET parent_id = excluded.parent_id
+			WHERE channel_parents.parent_id <> excluded.parent_id`,
+			guildID,
+			channelID,
+			parentID,
+		)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
+
+// marshalIDs encodes IDs as a JSON array, encoding nil as an empty array to match the column default
+func marshalIDs(ids []string) (string, error) {
+	if ids == nil {
+		ids = []string{}
+	}
+	b, err := json.Marshal(ids)
+	return string(b), err
+}
+
+// getStreaks reads the times each user gave or received a reaction and computes their streaks from the days these fall
+// on in the guild's timezone
+func (s *Store) getStreaks(ctx context.Context, guildID, userID string, dateRange stats.DateRange, byLongest bool, limit int) ([]stats.Streak, error) {
+	loc, err := s.GetTimezone(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{guildID}
+	filter, args := appendDateFilter("", args, dateRange)
+	if userID != "" {
+		args = append(args, userID)
+		filter += ` AND user_id = $` + argNum(len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT user_id, created_at
+		FROM (
+			SELECT sender_user_id AS user_id, created_at FROM reactions WHERE guild_id = $1`+notIgnored+`
+			UNION ALL
+			SELECT receiver_user_id AS user_id, created_at FROM reactions WHERE guild_id = $1`+notIgnored+`
+		) activity
+		WHERE TRUE`+filter,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	days := make(map[string][]time.Time)
+	for rows.Next() {
+		var id string
+		var createdAt int64
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			return nil, err
+		}
+		days[id] = append(days[id], stats.Day(time.UnixMilli(createdAt), loc))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats.ComputeStreaks(days, stats.Day(s.now(), loc), byLongest, limit), nil
+}
+
+func (s *Store) getTopMessagesFor(ctx context.Context, guildID, channelID, receiverID string, dateRange stats.DateRange, limit int) ([]stats.TopMessage, error) {
+	query := `
+		SELECT message_id, channel_id, MAX(receiver_user_id), COUNT(*) as count, COUNT(DISTINCT sender_user_id) as reactors
+		FROM reactions
+		WHERE guild_id = $1`
+	args := []any{guildID}
+
+	if channelID != "" {
+		args = append(args, channelID)
+		query += ` AND channel_id = $` + argNum(len(args))
+	}
+
+	if receiverID != "" {
+		args = append(args, receiverID)
+		query += ` AND receiver_user_id = $` + argNum(len(args))
+	}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY message_id, channel_id ORDER BY count DESC, reactors DESC, message_id LIMIT $` + argNum(len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []stats.TopMessage
+	for rows.Next() {
+		var m stats.TopMessage
+		if err := rows.Scan(&m.MessageID, &m.ChannelID, &m.AuthorID, &m.TotalReactions, &m.UniqueReactors); err != nil {
+			return nil, err
+		}
+		results = append(results, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// the store has a single connection, so the rows must be closed before the next query
+	_ = rows.Close()
+
+	for i := range results {
+		query := `SELECT emoji_id, is_default, COUNT(*) as count FROM reactions WHERE guild_id = $1 AND message_id = $2`
+		args := []any{guildID, results[i].MessageID}
+
+		query, args = appendFilters(query, args, dateRange)
+		query += ` GROUP BY emoji_id, is_default ORDER BY count DESC, emoji_id, is_default LIMIT 3`
+
+		results[i].TopEmojis, err = s.queryEmojiCounts(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func (s *Store) getTotalReactions(ctx context.Context, guildID string, dateRange stats.DateRange) (int, error) {
+	query := `SELECT COUNT(*) FROM reactions WHERE guild_id = $1`
+	args := []any{guildID}
+
+	query, args = appendFilters(query, args, dateRange)
+
+	var count int
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+func (s *Store) getTopEmojis(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) ([]stats.EmojiCount, error) {
+	query := `SELECT emoji_id, is_default, COUNT(*) as count FROM reactions WHERE guild_id = $1`
+	args := []any{guildID}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY emoji_id, is_default ORDER BY count DESC, emoji_id, is_default LIMIT $` + argNum(len(args)+1)
+	args = append(args, limit)
+
+	return s.queryEmojiCounts(ctx, query, args...)
+}
+
+// getTopUsers counts the reactions for each user in the given user column, optionally filtered by emoji
+func (s *Store) getTopUsers(ctx context.Context, guildID, column, emojiID string, dateRange stats.DateRange, limit int) ([]stats.UserCount, error) {
+	query := `SELECT ` + column + ` AS user_id, COUNT(*) as count FROM reactions WHERE guild_id = $1`
+	args := []any{guildID}
+
+	if emojiID != "" {
+		args = append(args, emojiID)
+		query += ` AND emoji_id = $` + argNum(len(args))
+	}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY user_id ORDER BY count DESC, user_id LIMIT $` + argNum(len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []stats.UserCount
+	for rows.Next() {
+		var uc stats.UserCount
+		if err := rows.Scan(&uc.UserID, &uc.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, uc)
+	}
+	return results, rows.Err()
+}
+
+func (s *Store) getTopMessages(ctx context.Context, guildID, emojiID string, dateRange stats.DateRange, limit int) ([]stats.MessageCount, error) {
+	query := `
+		SELECT message_id, channel_id, COUNT(*) as count
+		FROM reactions
+		WHERE guild_id = $1 AND emoji_id = $2`
+	args := []any{guildID, emojiID}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY message_id, channel_id ORDER BY count DESC, message_id LIMIT $` + argNum(len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []stats.MessageCount
+	for rows.Next() {
+		var mc stats.MessageCount
+		if err := rows.Scan(&mc.MessageID, &mc.ChannelID, &mc.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, mc)
+	}
+	return results, rows.Err()
+}
+
+// countWhere counts the reactions in a guild where the given user column matches the user
+func (s *Store) countWhere(ctx context.Context, guildID, column, userID string, dateRange stats.DateRange) (int, error) {
+	query := `SELECT COUNT(*) FROM reactions WHERE guild_id = $1 AND ` + column + ` = $2`
+	args := []any{guildID, userID}
+
+	query, args = appendFilters(query, args, dateRange)
+
+	var count int
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+func (s *Store) getFavouriteEmoji(ctx context.Context, guildID, userID string, dateRange stats.DateRange) (*stats.EmojiCount, error) {
+	query := `SELECT emoji_id, is_default, COUNT(*) as count FROM reactions WHERE guild_id = $1`
+	args := []any{guildID}
+
+	if userID != "" {
+		args = append(args, userID)
+		query += ` AND sender_user_id = $` + argNum(len(args))
+	}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY emoji_id, is_default ORDER BY count DESC, emoji_id, is_default LIMIT 1`
+
+	var ec stats.EmojiCount
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&ec.EmojiID, &ec.IsDefault, &ec.Count)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ec, nil
+}
+
+// getMonthlyCounts counts reactions by month in the given timezone. SQLite has no timezone database, so the reactions
+// are grouped into quarter hours, which no timezone offset splits, and the quarter hours assigned to months here
+func (s *Store) getMonthlyCounts(ctx context.Context, guildID, userID string, dateRange stats.DateRange, loc *time.Location) ([12]int, error) {
+	var counts [12]int
+
+	query := `
+		SELECT created_at / 900000 AS quarter, COUNT(*) as count
+		FROM reactions
+		WHERE guild_id = $1`
+	args := []any{guildID}
+
+	if userID != "" {
+		args = append(args, userID)
+		query += ` AND sender_user_id = $` + argNum(len(args))
+	}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY quarter`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return counts, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var quarter int64
+		var count int
+		if err := rows.Scan(&quarter, &count); err != nil {
+			return counts, err
+		}
+		counts[time.UnixMilli(quarter*900000).In(loc).Month()-1] += count
+	}
+	return counts, rows.Err()
+}
+
+func (s *Store) getTopFan(ctx context.Context, guildID, userID string, dateRange stats.DateRange) (*stats.UserCount, error) {
+	query := `
+		SELECT sender_user_id, COUNT(*) as count
+		FROM reactions
+		WHERE guild_id = $1 AND receiver_user_id = $2 AND sender_user_id <> $2`
+	args := []any{guildID, userID}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY sender_user_id ORDER BY count DESC, sender_user_id LIMIT 1`
+
+	var uc stats.UserCount
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&uc.UserID, &uc.Count)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &uc, nil
+}
+
+// getRank ranks a count against the per-user counts of the given user column
+func (s *Store) getRank(ctx context.Context, guildID, column string, count int, dateRange stats.DateRange) (stats.Rank, error) {
+	var rank stats.Rank
+
+	query := `SELECT ` + column + `, COUNT(*) as count FROM reactions WHERE guild_id = $1`
+	args := []any{guildID}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY ` + column
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(CASE WHEN count > $`+argNum(len(args)+1)+` THEN 1 END)
+		FROM (`+query+`) counts`,
+		append(args, count)...,
+	).Scan(&rank.Total, &rank.Position)
+	if err != nil {
+		return rank, err
+	}
+
+	rank.Position++
+	// users without any reactions are not included in the counts, so are ranked last
+	if rank.Position > rank.Total {
+		rank.Total = rank.Position
+	}
+	return rank, nil
+}
+
+func (s *Store) queryEmojiCounts(ctx context.Context, query string, args ...any) ([]stats.EmojiCount, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []stats.EmojiCount
+	for rows.Next() {
+		var ec stats.EmojiCount
+		if err := rows.Scan(&ec.EmojiID, &ec.IsDefault, &ec.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, ec)
+	}
+	return results, rows.Err()
+}
+
+// anonymise replaces the IDs of users who have opted out with privacy.AnonymousUserID
+func (s *Store) anonymise(ctx context.Context, ids ...*string) error {
+	var lookup []string
+	for _, id := range ids {
+		if *id != "" && *id != privacy.AnonymousUserID {
+			lookup = append(lookup, *id)
+		}
+	}
+
+	optedOut, err := s.OptedOut(ctx, lookup...)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if optedOut[*id] {
+			*id = privacy.AnonymousUserID
+		}
+	}
+	return nil
+}
+
+func userIDs(counts []stats.UserCount) []*string {
+	ids := make([]*string, len(counts))
+	for i := range counts {
+		ids[i] = &counts[i].UserID
+	}
+	return ids
+}
+
+// notIgnored excludes reactions in the channels a guild ignores. It must follow a filter on the guild ID, which must be
+// the first argument
+const notIgnored = ` AND channel_id NOT IN (SELECT channel_id FROM ignored_channels WHERE guild_id = $1)`
+
+// appendFilters excludes the ignored channels from a query and filters it by date range
+func appendFilters(query string, args []any, dateRange stats.DateRange) (string, []any) {
+	return appendDateFilter(query+notIgnored, args, dateRange)
+}
+
+// appendDateFilter filters a query by date range on created_at, which is stored in Unix milliseconds
+func appendDateFilter(query string, args []any, dateRange stats.DateRange) (string, []any) {
+	if dateRange.Start != nil {
+		args = append(args, dateRange.Start.UnixMilli())
+		query += ` AND created_at >= $` + argNum(len(args))
+	}
+	if dateRange.End != nil {
+		args = append(args, dateRange.End.UnixMilli())
+		query += ` AND created_at < $` + argNum(len(args))
+	}
+	return query, args
+}
+
+// placeholders returns n comma separated placeholders starting from $from
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func optOut(ctx context.Context, db execer, userID string, now time.Time) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO privacy_opt_outs (user_id, opted_out_at)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`,
+		userID,
+		now.UnixMilli(),
+	)
+	return err
+}
+
+func rowsAffected(res sql.Result) (int, error) {
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func placeholders(from, n int) string {
+	p := make([]string, n)
+	for i := range p {
+		p[i] = "$" + argNum(from+i)
+	}
+	return strings.Join(p, ", ")
+}
+
+func argNum(n int) string {
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,31 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/stats"
+	"github.com/elliotwms/emojistats/internal/stats/storetest"
+)
+
+// setupTest returns a store on a new in-memory database
+func setupTest(t *testing.T) *Store {
+	t.Helper()
+
+	db, err := Open(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	require.NoError(t, Migrate(db))
+
+	return NewStore(db)
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, storetest.Backend{
+		NewStore: func(t *testing.T, _ string) stats.Store {
+			return setupTest(t)
+		},
+	})
+}
@@ -0,0 +1,19 @@
+package starboard
+
+// Config is the starboard configuration for a guild
+type Config struct {
+	GuildID   string
+	ChannelID string
+	// EmojiID is the emoji in message format which counts towards the threshold. Empty counts any emoji
+	EmojiID   string
+	Threshold int
+}
+
+// Entry is a message which has been reposted to the starboard
+type Entry struct {
+	GuildID            string
+	MessageID          string
+	ChannelID          string
+	StarboardMessageID string
+	ReactionCount      int
+}
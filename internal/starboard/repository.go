@@ -0,0 +1,146 @@
+package starboard
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Repository handles database queries for the starboard
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository creates a new starboard repository
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetConfig retrieves the starboard config for a guild, returning nil if the starboard is not configured
+func (r *Repository) GetConfig(ctx context.Context, guildID string) (*Config, error) {
+	c := &Config{GuildID: guildID}
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT channel_id, emoji_id, threshold
+		FROM starboard_configs
+		WHERE guild_id = $1`,
+		guildID,
+	).Scan(&c.ChannelID, &c.EmojiID, &c.Threshold)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// SaveConfig creates or replaces the starboard config for a guild
+func (r *Repository) SaveConfig(ctx context.Context, c Config) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO starboard_configs (guild_id, channel_id, emoji_id, threshold)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (guild_id) DO UPDATE
+		SET channel_id = EXCLUDED.channel_id, emoji_id = EXCLUDED.emoji_id, threshold = EXCLUDED.threshold, updated_at = NOW()`,
+		c.GuildID,
+		c.ChannelID,
+		c.EmojiID,
+		c.Threshold,
+	)
+	return err
+}
+
+// DeleteConfig disables the starboard for a guild
+func (r *Repository) DeleteConfig(ctx context.Context, guildID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM starboard_configs WHERE guild_id = $1`, guildID)
+	return err
+}
+
+// CountReactions counts the reactions on a message. If emojiID is empty all emojis are counted
+func (r *Repository) CountReactions(ctx context.Context, guildID, messageID, emojiID string) (int, error) {
+	query := `SELECT COUNT(*) FROM reactions WHERE guild_id = $1 AND message_id = $2`
+	args := []any{guildID, messageID}
+
+	if emojiID != "" {
+		args = append(args, emojiID)
+		query += ` AND emoji_id = $3`
+	}
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+// GetEntry retrieves the starboard entry for a message, returning nil if the message is not on the starboard
+func (r *Repository) GetEntry(ctx context.Context, guildID, messageID string) (*Entry, error) {
+	e := &Entry{GuildID: guildID, MessageID: messageID}
+
+	var starboardMessageID sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT channel_id, starboard_message_id, reaction_count
+		FROM starboard_messages
+		WHERE guild_id = $1 AND message_id = $2`,
+		guildID,
+		messageID,
+	).Scan(&e.ChannelID, &starboardMessageID, &e.ReactionCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.StarboardMessageID = starboardMessageID.String
+
+	return e, nil
+}
+
+// ClaimEntry records that a message is about to be posted to the starboard. It returns false if the message has
+// already been claimed, so that only one process posts it. A claim which has had no starboard message for longer than
+// the timeout was abandoned, and is claimed again
+func (r *Repository) ClaimEntry(ctx context.Context, e Entry, timeout time.Duration) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO starboard_messages (guild_id, message_id, channel_id, reaction_count)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (guild_id, message_id) DO UPDATE
+		SET channel_id = EXCLUDED.channel_id, reaction_count = EXCLUDED.reaction_count, created_at = NOW()
+		WHERE starboard_messages.starboard_message_id IS NULL
+			AND starboard_messages.created_at < NOW() - $5 * INTERVAL '1 millisecond'`,
+		e.GuildID,
+		e.MessageID,
+		e.ChannelID,
+		e.ReactionCount,
+		timeout.Milliseconds(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// UpdateEntry updates the starboard message and reaction count of an entry
+func (r *Repository) UpdateEntry(ctx context.Context, e Entry) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE starboard_messages
+		SET starboard_message_id = $3, reaction_count = $4
+		WHERE guild_id = $1 AND message_id = $2`,
+		e.GuildID,
+		e.MessageID,
+		e.StarboardMessageID,
+		e.ReactionCount,
+	)
+	return err
+}
+
+// DeleteEntry removes a message from the starboard
+func (r *Repository) DeleteEntry(ctx context.Context, guildID, messageID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM starboard_messages WHERE guild_id = $1 AND message_id = $2`, guildID, messageID)
+	return err
+}
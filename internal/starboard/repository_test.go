@@ -0,0 +1,146 @@
+package starboard
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+)
+
+var testDB *sql.DB
+
+func setupTest(t *testing.T) (*Repository, string, func()) {
+	t.Helper()
+
+	testDB = dbtest.Open(t)
+
+	guildID := "test-guild-" + time.Now().Format("20060102150405.000000000")
+
+	cleanup := func() {
+		_, _ = testDB.Exec("DELETE FROM reactions WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM starboard_messages WHERE guild_id = $1", guildID)
+		_, _ = testDB.Exec("DELETE FROM starboard_configs WHERE guild_id = $1", guildID)
+	}
+
+	return NewRepository(testDB), guildID, cleanup
+}
+
+func insertReaction(t *testing.T, guildID, emojiID, senderID, messageID string) {
+	t.Helper()
+	_, err := testDB.Exec(`
+		INSERT INTO reactions (guild_id, emoji_id, sender_user_id, receiver_user_id, channel_id, message_id)
+		VALUES ($1, $2, $3, 'receiver1', 'chan1', $4)`,
+		guildID, emojiID, senderID, messageID)
+	require.NoError(t, err)
+}
+
+func TestConfig(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	c, err := repo.GetConfig(ctx, guildID)
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	require.NoError(t, repo.SaveConfig(ctx, Config{GuildID: guildID, ChannelID: "star", Threshold: 3}))
+	require.NoError(t, repo.SaveConfig(ctx, Config{GuildID: guildID, ChannelID: "star", EmojiID: "⭐", Threshold: 5}))
+
+	c, err = repo.GetConfig(ctx, guildID)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Equal(t, "star", c.ChannelID)
+	assert.Equal(t, "⭐", c.EmojiID)
+	assert.Equal(t, 5, c.Threshold)
+
+	require.NoError(t, repo.DeleteConfig(ctx, guildID))
+
+	c, err = repo.GetConfig(ctx, guildID)
+	require.NoError(t, err)
+	assert.Nil(t, c)
+}
+
+func TestCountReactions(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	insertReaction(t, guildID, "⭐", "sender1", "msg1")
+	insertReaction(t, guildID, "⭐", "sender2", "msg1")
+	insertReaction(t, guildID, "👍", "sender1", "msg1")
+	insertReaction(t, guildID, "⭐", "sender1", "msg2")
+
+	count, err := repo.CountReactions(ctx, guildID, "msg1", "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = repo.CountReactions(ctx, guildID, "msg1", "⭐")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestEntries(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	e := Entry{GuildID: guildID, MessageID: "msg1", ChannelID: "chan1", ReactionCount: 3}
+
+	claimed, err := repo.ClaimEntry(ctx, e, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = repo.ClaimEntry(ctx, e, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed, "an entry should only be claimed once")
+
+	e.StarboardMessageID = "star1"
+	e.ReactionCount = 4
+	require.NoError(t, repo.UpdateEntry(ctx, e))
+
+	saved, err := repo.GetEntry(ctx, guildID, "msg1")
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.Equal(t, "star1", saved.StarboardMessageID)
+	assert.Equal(t, 4, saved.ReactionCount)
+
+	require.NoError(t, repo.DeleteEntry(ctx, guildID, "msg1"))
+
+	saved, err = repo.GetEntry(ctx, guildID, "msg1")
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+}
+
+func TestClaimEntry_Abandoned(t *testing.T) {
+	repo, guildID, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	e := Entry{GuildID: guildID, MessageID: "msg1", ChannelID: "chan1", ReactionCount: 3}
+
+	claimed, err := repo.ClaimEntry(ctx, e, time.Minute)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	_, err = testDB.Exec(`UPDATE starboard_messages SET created_at = NOW() - INTERVAL '2 minutes' WHERE guild_id = $1`, guildID)
+	require.NoError(t, err)
+
+	claimed, err = repo.ClaimEntry(ctx, e, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed, "a claim without a starboard message should be claimed again once it times out")
+
+	e.StarboardMessageID = "star1"
+	require.NoError(t, repo.UpdateEntry(ctx, e))
+	_, err = testDB.Exec(`UPDATE starboard_messages SET created_at = NOW() - INTERVAL '2 minutes' WHERE guild_id = $1`, guildID)
+	require.NoError(t, err)
+
+	claimed, err = repo.ClaimEntry(ctx, e, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed, "a posted entry should never be claimed again")
+}
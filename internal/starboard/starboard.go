@@ -0,0 +1,208 @@
+package starboard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/emojistats/internal/metrics"
+)
+
+const defaultEmoji = "⭐"
+
+// claimTimeout is how long an entry may be claimed without a starboard message before it is treated as abandoned, such
+// as when posting failed and the claim could not be released, and the message is posted again
+const claimTimeout = time.Minute
+
+// Starboard reposts messages to a guild's starboard channel once they cross the configured reaction threshold
+type Starboard struct {
+	repo *Repository
+
+	// mu serialises updates so that concurrent reaction events for the same message do not race
+	mu sync.Mutex
+}
+
+// New creates a new Starboard
+func New(repo *Repository) *Starboard {
+	return &Starboard{repo: repo}
+}
+
+// HandleReaction re-evaluates the starboard for the message which was reacted to. It should be called after the
+// reaction has been added to or removed from the reactions table
+func (sb *Starboard) HandleReaction(s *discordgo.Session, r *discordgo.MessageReaction) {
+	if r.GuildID == "" {
+		return
+	}
+
+	ctx := context.Background()
+
+	c, err := sb.repo.GetConfig(ctx, r.GuildID)
+	if err != nil {
+		slog.Error("failed to get starboard config", "error", err, "guild_id", r.GuildID)
+		return
+	}
+
+	if c == nil || r.ChannelID == c.ChannelID {
+		return
+	}
+
+	if c.EmojiID != "" && r.Emoji.MessageFormat() != c.EmojiID {
+		return
+	}
+
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	if err := sb.update(ctx, s, c, r.ChannelID, r.MessageID); err != nil {
+		slog.Error("failed to update starboard", "error", err, "guild_id", r.GuildID, "message_id", r.MessageID)
+	}
+}
+
+func (sb *Starboard) update(ctx context.Context, s *discordgo.Session, c *Config, channelID, messageID string) error {
+	count, err := sb.repo.CountReactions(ctx, c.GuildID, messageID, c.EmojiID)
+	if err != nil {
+		return fmt.Errorf("failed to count reactions: %w", err)
+	}
+
+	e, err := sb.repo.GetEntry(ctx, c.GuildID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	switch {
+	case (e == nil || e.StarboardMessageID == "") && count >= c.Threshold:
+		// an entry without a starboard message is still being posted, in which case the claim fails, or was abandoned
+		return sb.post(ctx, s, c, channelID, messageID, count)
+	case e != nil && count < c.Threshold:
+		return sb.remove(ctx, s, c, e)
+	case e != nil && count != e.ReactionCount:
+		return sb.edit(ctx, s, c, e, count)
+	default:
+		return nil
+	}
+}
+
+func (sb *Starboard) post(ctx context.Context, s *discordgo.Session, c *Config, channelID, messageID string, count int) error {
+	e := Entry{
+		GuildID:       c.GuildID,
+		MessageID:     messageID,
+		ChannelID:     channelID,
+		ReactionCount: count,
+	}
+
+	claimed, err := sb.repo.ClaimEntry(ctx, e, claimTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to claim entry: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	msg, err := metrics.ChannelMessage(ctx, s, channelID, messageID)
+	if err != nil {
+		sb.release(ctx, c.GuildID, messageID)
+		return fmt.Errorf("failed to get message: %w", err)
+	}
+
+	posted, err := s.ChannelMessageSendComplex(c.ChannelID, &discordgo.MessageSend{
+		Content: formatContent(c, channelID, count),
+		Embeds:  []*discordgo.MessageEmbed{buildEmbed(msg, c.GuildID)},
+	})
+	if err != nil {
+		sb.release(ctx, c.GuildID, messageID)
+		return fmt.Errorf("failed to post starboard message: %w", err)
+	}
+
+	e.StarboardMessageID = posted.ID
+	if err := sb.repo.UpdateEntry(ctx, e); err != nil {
+		return fmt.Errorf("failed to save entry: %w", err)
+	}
+
+	slog.Info("message added to starboard", "guild_id", c.GuildID, "message_id", messageID, "count", count)
+
+	return nil
+}
+
+// release deletes the claim on an entry which could not be posted, so that the next reaction tries again. If it
+// cannot be deleted, it is claimed again once the claim times out
+func (sb *Starboard) release(ctx context.Context, guildID, messageID string) {
+	if err := sb.repo.DeleteEntry(ctx, guildID, messageID); err != nil {
+		slog.Error("failed to release starboard entry", "error", err, "guild_id", guildID, "message_id", messageID)
+	}
+}
+
+func (sb *Starboard) edit(ctx context.Context, s *discordgo.Session, c *Config, e *Entry, count int) error {
+	// an entry without a starboard message is still being posted
+	if e.StarboardMessageID == "" {
+		return nil
+	}
+
+	if _, err := s.ChannelMessageEdit(c.ChannelID, e.StarboardMessageID, formatContent(c, e.ChannelID, count)); err != nil {
+		return fmt.Errorf("failed to edit starboard message: %w", err)
+	}
+
+	e.ReactionCount = count
+
+	return sb.repo.UpdateEntry(ctx, *e)
+}
+
+func (sb *Starboard) remove(ctx context.Context, s *discordgo.Session, c *Config, e *Entry) error {
+	if e.StarboardMessageID == "" {
+		return nil
+	}
+
+	if err := s.ChannelMessageDelete(c.ChannelID, e.StarboardMessageID); err != nil {
+		return fmt.Errorf("failed to delete starboard message: %w", err)
+	}
+
+	if err := sb.repo.DeleteEntry(ctx, c.GuildID, e.MessageID); err != nil {
+		return fmt.Errorf("failed to delete entry: %w", err)
+	}
+
+	slog.Info("message removed from starboard", "guild_id", c.GuildID, "message_id", e.MessageID)
+
+	return nil
+}
+
+func formatContent(c *Config, channelID string, count int) string {
+	emoji := c.EmojiID
+	if emoji == "" {
+		emoji = defaultEmoji
+	}
+
+	return fmt.Sprintf("%s **%d** <#%s>", emoji, count, channelID)
+}
+
+func buildEmbed(msg *discordgo.Message, guildID string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Description: msg.Content,
+		Timestamp:   msg.Timestamp.Format(time.RFC3339),
+		Color:       0xFFAC33,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:  "Source",
+				Value: fmt.Sprintf("[Jump to message](https://discord.com/channels/%s/%s/%s)", guildID, msg.ChannelID, msg.ID),
+			},
+		},
+	}
+
+	if msg.Author != nil {
+		embed.Author = &discordgo.MessageEmbedAuthor{
+			Name:    msg.Author.Username,
+			IconURL: msg.Author.AvatarURL(""),
+		}
+	}
+
+	for _, a := range msg.Attachments {
+		if strings.HasPrefix(a.ContentType, "image/") {
+			embed.Image = &discordgo.MessageEmbedImage{URL: a.URL}
+			break
+		}
+	}
+
+	return embed
+}
@@ -0,0 +1,47 @@
+package starboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatContent(t *testing.T) {
+	c := &Config{EmojiID: "<:pepe:123456789>"}
+
+	assert.Equal(t, "<:pepe:123456789> **5** <#chan1>", formatContent(c, "chan1", 5))
+}
+
+func TestFormatContent_AnyEmoji(t *testing.T) {
+	c := &Config{}
+
+	assert.Equal(t, "⭐ **3** <#chan1>", formatContent(c, "chan1", 3))
+}
+
+func TestBuildEmbed(t *testing.T) {
+	msg := &discordgo.Message{
+		ID:        "msg1",
+		ChannelID: "chan1",
+		Content:   "hello world",
+		Timestamp: time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC),
+		Author:    &discordgo.User{ID: "111", Username: "someone"},
+		Attachments: []*discordgo.MessageAttachment{
+			{URL: "https://example.com/file.txt", ContentType: "text/plain"},
+			{URL: "https://example.com/image.png", ContentType: "image/png"},
+		},
+	}
+
+	embed := buildEmbed(msg, "guild1")
+
+	assert.Equal(t, "hello world", embed.Description)
+	assert.Equal(t, "2024-06-15T12:00:00Z", embed.Timestamp)
+	require.NotNil(t, embed.Author)
+	assert.Equal(t, "someone", embed.Author.Name)
+	require.NotNil(t, embed.Image)
+	assert.Equal(t, "https://example.com/image.png", embed.Image.URL)
+	require.Len(t, embed.Fields, 1)
+	assert.Contains(t, embed.Fields[0].Value, "https://discord.com/channels/guild1/chan1/msg1")
+}
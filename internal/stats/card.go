@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	cardWidth  = 400
+	cardHeight = 200
+	// cardScale upscales the card so the bitmap font remains legible
+	cardScale = 2
+)
+
+var (
+	cardBackground = color.RGBA{R: 0x2B, G: 0x2D, B: 0x31, A: 0xFF}
+	cardForeground = color.RGBA{R: 0xF2, G: 0xF3, B: 0xF5, A: 0xFF}
+	cardAccent     = color.RGBA{R: 0xFF, G: 0xAC, B: 0x33, A: 0xFF}
+)
+
+// RenderWrappedCard renders a year in review recap as a PNG image card with a chart of monthly activity
+func RenderWrappedCard(w *Wrapped) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: cardBackground}, image.Point{}, draw.Src)
+
+	drawText(img, 12, 20, cardAccent, fmt.Sprintf("%d WRAPPED", w.Year))
+
+	lines := []string{fmt.Sprintf("Reactions: %d", w.TotalReactions)}
+	if w.UserID != "" {
+		lines = append(lines,
+			fmt.Sprintf("Received: %d", w.TotalReceived),
+			fmt.Sprintf("Top %d%% of givers", w.SenderRank.Percentile()),
+		)
+	}
+	if w.TotalReactions > 0 {
+		month, _ := w.BusiestMonth()
+		lines = append(lines, "Busiest: "+month.String())
+	}
+	for i, l := range lines {
+		drawText(img, 12, 44+i*16, cardForeground, l)
+	}
+
+	drawMonthlyChart(img, image.Rect(170, 16, cardWidth-12, cardHeight-12), w.MonthlyCounts)
+
+	scaled := image.NewRGBA(image.Rect(0, 0, cardWidth*cardScale, cardHeight*cardScale))
+	draw.NearestNeighbor.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaled); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawMonthlyChart(img *image.RGBA, r image.Rectangle, counts [12]int) {
+	highest := 0
+	for _, c := range counts {
+		if c > highest {
+			highest = c
+		}
+	}
+
+	labelHeight := 14
+	barWidth := r.Dx() / len(counts)
+	chartHeight := r.Dy() - labelHeight
+
+	for i, c := range counts {
+		x := r.Min.X + i*barWidth
+		if highest > 0 && c > 0 {
+			h := c * chartHeight / highest
+			if h == 0 {
+				h = 1
+			}
+			bar := image.Rect(x+2, r.Min.Y+chartHeight-h, x+barWidth-2, r.Min.Y+chartHeight)
+			draw.Draw(img, bar, &image.Uniform{C: cardAccent}, image.Point{}, draw.Src)
+		}
+
+		label := time.Month(i + 1).String()[:1]
+		drawText(img, x+barWidth/2-3, r.Max.Y, cardForeground, label)
+	}
+}
+
+func drawText(img *image.RGBA, x, y int, c color.Color, s string) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  &image.Uniform{C: c},
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
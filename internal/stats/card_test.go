@@ -0,0 +1,35 @@
+package stats
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderWrappedCard(t *testing.T) {
+	w := &Wrapped{
+		Year:           2024,
+		UserID:         "111",
+		TotalReactions: 120,
+		TotalReceived:  80,
+		MonthlyCounts:  [12]int{1, 5, 10, 20, 0, 0, 3, 4, 30, 20, 15, 12},
+		SenderRank:     Rank{Position: 2, Total: 40},
+	}
+
+	b, err := RenderWrappedCard(w)
+
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, cardWidth*cardScale, img.Bounds().Dx())
+	assert.Equal(t, cardHeight*cardScale, img.Bounds().Dy())
+}
+
+func TestRenderWrappedCard_Empty(t *testing.T) {
+	_, err := RenderWrappedCard(&Wrapped{Year: 2024})
+
+	assert.NoError(t, err)
+}
@@ -0,0 +1,26 @@
+package stats_test
+
+import (
+	"testing"
+
+	"github.com/elliotwms/emojistats/internal/stats"
+	"github.com/elliotwms/emojistats/internal/stats/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	db := stats.DB(t)
+
+	storetest.Run(t, storetest.Backend{
+		NewStore: func(t *testing.T, guildID string) stats.Store {
+			t.Cleanup(func() {
+				// the suite's opted out users are unique to the guild, and are audited in it
+				_, _ = db.Exec(`DELETE FROM privacy_opt_outs WHERE user_id IN (SELECT user_id FROM privacy_audit WHERE guild_id = $1)`, guildID)
+				_, _ = db.Exec(`DELETE FROM privacy_audit WHERE guild_id = $1`, guildID)
+				for _, table := range []string{"reactions", "guild_timezones", "guild_settings", "channel_parents", "reaction_rollups_emoji", "reaction_rollups_sender", "reaction_rollups_receiver", "reaction_rollups_channel"} {
+					_, _ = db.Exec("DELETE FROM "+table+" WHERE guild_id = $1", guildID)
+				}
+			})
+			return stats.NewRepository(db)
+		},
+	})
+}
@@ -0,0 +1,13 @@
+package stats
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/elliotwms/emojistats/internal/database/dbtest"
+)
+
+// DB returns the test database to the external tests in stats_test, skipping the test if it is not available
+func DB(t *testing.T) *sql.DB {
+	return dbtest.Open(t)
+}
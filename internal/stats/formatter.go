@@ -3,6 +3,8 @@ package stats
 import (
 	"fmt"
 	"strings"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
 )
 
 // FormatGuildStats formats guild stats as Discord markdown
@@ -13,7 +15,7 @@ func FormatGuildStats(stats *GuildStats, guildID string) string {
 	sb.WriteString(fmt.Sprintf("**Total Reactions:** %d\n\n", stats.TotalReactions))
 
 	if len(stats.TopEmojis) > 0 {
-		sb.WriteString("### Top 10 Reactions\n")
+		sb.WriteString("### Top Reactions\n")
 		for i, e := range stats.TopEmojis {
 			sb.WriteString(fmt.Sprintf("%d. %s - %d\n", i+1, formatEmoji(e.EmojiID, e.IsDefault), e.Count))
 		}
@@ -21,17 +23,17 @@ func FormatGuildStats(stats *GuildStats, guildID string) string {
 	}
 
 	if len(stats.TopSenders) > 0 {
-		sb.WriteString("### Top 3 Reaction Givers\n")
+		sb.WriteString("### Top Reaction Givers\n")
 		for i, u := range stats.TopSenders {
-			sb.WriteString(fmt.Sprintf("%s <@%s> - %d\n", formatRank(i+1), u.UserID, u.Count))
+			sb.WriteString(fmt.Sprintf("%s %s - %d\n", formatRank(i+1), privacy.FormatUser(u.UserID), u.Count))
 		}
 		sb.WriteString("\n")
 	}
 
 	if len(stats.TopReceivers) > 0 {
-		sb.WriteString("### Top 3 Reaction Receivers\n")
+		sb.WriteString("### Top Reaction Receivers\n")
 		for i, u := range stats.TopReceivers {
-			sb.WriteString(fmt.Sprintf("%s <@%s> - %d\n", formatRank(i+1), u.UserID, u.Count))
+			sb.WriteString(fmt.Sprintf("%s %s - %d\n", formatRank(i+1), privacy.FormatUser(u.UserID), u.Count))
 		}
 	}
 
@@ -46,7 +48,7 @@ func FormatEmojiStats(stats *EmojiStats, guildID string) string {
 	sb.WriteString(fmt.Sprintf("**Total Uses:** %d\n\n", stats.TotalUses))
 
 	if len(stats.TopMessages) > 0 {
-		sb.WriteString("### Top 10 Messages\n")
+		sb.WriteString("### Top Messages\n")
 		for i, m := range stats.TopMessages {
 			link := formatMessageLink(guildID, m.ChannelID, m.MessageID)
 			sb.WriteString(fmt.Sprintf("%s [Jump to message](%s) - %d\n", formatRank(i+1), link, m.Count))
@@ -55,23 +57,210 @@ func FormatEmojiStats(stats *EmojiStats, guildID string) string {
 	}
 
 	if len(stats.TopReceivers) > 0 {
-		sb.WriteString("### Top 10 Recipients\n")
+		sb.WriteString("### Top Recipients\n")
 		for i, u := range stats.TopReceivers {
-			sb.WriteString(fmt.Sprintf("%s <@%s> - %d\n", formatRank(i+1), u.UserID, u.Count))
+			sb.WriteString(fmt.Sprintf("%s %s - %d\n", formatRank(i+1), privacy.FormatUser(u.UserID), u.Count))
 		}
 		sb.WriteString("\n")
 	}
 
 	if len(stats.TopSenders) > 0 {
-		sb.WriteString("### Top 10 Senders\n")
+		sb.WriteString("### Top Senders\n")
 		for i, u := range stats.TopSenders {
-			sb.WriteString(fmt.Sprintf("%s <@%s> - %d\n", formatRank(i+1), u.UserID, u.Count))
+			sb.WriteString(fmt.Sprintf("%s %s - %d\n", formatRank(i+1), privacy.FormatUser(u.UserID), u.Count))
+		}
+	}
+
+	return sb.String()
+}
+
+// FormatHallOfFame formats the most reacted messages in a guild as Discord markdown
+func FormatHallOfFame(messages []TopMessage, guildID string) string {
+	var sb strings.Builder
+
+	sb.WriteString("## Hall of Fame\n\n")
+
+	if len(messages) == 0 {
+		sb.WriteString("No reactions found.\n")
+		return sb.String()
+	}
+
+	for i, m := range messages {
+		link := formatMessageLink(guildID, m.ChannelID, m.MessageID)
+		sb.WriteString(fmt.Sprintf("%s [Jump to message](%s) by %s - %d reactions from %d users\n",
+			formatRank(i+1), link, privacy.FormatUser(m.AuthorID), m.TotalReactions, m.UniqueReactors))
+
+		if len(m.TopEmojis) > 0 {
+			emojis := make([]string, 0, len(m.TopEmojis))
+			for _, e := range m.TopEmojis {
+				emojis = append(emojis, fmt.Sprintf("%s %d", formatEmoji(e.EmojiID, e.IsDefault), e.Count))
+			}
+			sb.WriteString("-# " + strings.Join(emojis, " · ") + "\n")
 		}
 	}
 
 	return sb.String()
 }
 
+// FormatDigest formats a scheduled digest as Discord markdown
+func FormatDigest(digest *Digest) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("## %s\n\n", digest.Title))
+	sb.WriteString(fmt.Sprintf("**Total Reactions:** %d\n\n", digest.Stats.TotalReactions))
+
+	if digest.Stats.TotalReactions == 0 {
+		sb.WriteString("No reactions this time. Get reacting!\n")
+		return sb.String()
+	}
+
+	if len(digest.Stats.TopEmojis) > 0 {
+		sb.WriteString("### Top Reactions\n")
+		for i, e := range digest.Stats.TopEmojis {
+			sb.WriteString(fmt.Sprintf("%d. %s - %d\n", i+1, formatEmoji(e.EmojiID, e.IsDefault), e.Count))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(digest.Movers) > 0 {
+		sb.WriteString("### Biggest Movers\n")
+		for _, m := range digest.Movers {
+			sb.WriteString(fmt.Sprintf("📈 %s - %d (+%d)\n", formatEmoji(m.EmojiID, m.IsDefault), m.Count, m.Count-m.PreviousCount))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(digest.Stats.TopSenders) > 0 {
+		sb.WriteString("### Top Reaction Givers\n")
+		for i, u := range digest.Stats.TopSenders {
+			sb.WriteString(fmt.Sprintf("%s %s - %d\n", formatRank(i+1), privacy.FormatUser(u.UserID), u.Count))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(digest.Stats.TopReceivers) > 0 {
+		sb.WriteString("### Top Reaction Receivers\n")
+		for i, u := range digest.Stats.TopReceivers {
+			sb.WriteString(fmt.Sprintf("%s %s - %d\n", formatRank(i+1), privacy.FormatUser(u.UserID), u.Count))
+		}
+	}
+
+	return sb.String()
+}
+
+// FormatWrappedPages formats a year in review recap as a series of Discord markdown pages
+func FormatWrappedPages(w *Wrapped, guildID string) []string {
+	subject := "This server's"
+	if w.UserID != "" {
+		subject = privacy.FormatUser(w.UserID) + "'s"
+	}
+	title := fmt.Sprintf("## 🎁 %s %d Wrapped\n\n", subject, w.Year)
+
+	if w.TotalReactions == 0 && w.TotalReceived == 0 {
+		return []string{title + fmt.Sprintf("No reactions found in %d.\n", w.Year)}
+	}
+
+	var pages []string
+
+	var sb strings.Builder
+	sb.WriteString(title)
+	if w.UserID == "" {
+		sb.WriteString(fmt.Sprintf("This server reacted **%d** times in %d.\n", w.TotalReactions, w.Year))
+	} else {
+		sb.WriteString(fmt.Sprintf("**Reactions given:** %d\n", w.TotalReactions))
+		sb.WriteString(fmt.Sprintf("**Reactions received:** %d\n", w.TotalReceived))
+	}
+	pages = append(pages, sb.String())
+
+	if w.FavouriteEmoji != nil {
+		sb.Reset()
+		sb.WriteString(title)
+		sb.WriteString(fmt.Sprintf("### Favourite Emoji\n%s used %d times\n\n",
+			formatEmoji(w.FavouriteEmoji.EmojiID, w.FavouriteEmoji.IsDefault), w.FavouriteEmoji.Count))
+		month, count := w.BusiestMonth()
+		sb.WriteString(fmt.Sprintf("### Busiest Month\n%s with %d reactions\n", month, count))
+		pages = append(pages, sb.String())
+	}
+
+	if w.TopFan != nil || w.TopMessage != nil {
+		sb.Reset()
+		sb.WriteString(title)
+		if w.TopFan != nil {
+			heading := "Top Fan"
+			if w.UserID == "" {
+				heading = "Most Generous Reactor"
+			}
+			sb.WriteString(fmt.Sprintf("### %s\n%s with %d reactions\n\n", heading, privacy.FormatUser(w.TopFan.UserID), w.TopFan.Count))
+		}
+		if w.TopMessage != nil {
+			link := formatMessageLink(guildID, w.TopMessage.ChannelID, w.TopMessage.MessageID)
+			sb.WriteString(fmt.Sprintf("### Most Reacted Message\n[Jump to message](%s) with %d reactions\n", link, w.TopMessage.TotalReactions))
+		}
+		pages = append(pages, sb.String())
+	}
+
+	if w.UserID != "" {
+		sb.Reset()
+		sb.WriteString(title)
+		sb.WriteString("### Rankings\n")
+		sb.WriteString(fmt.Sprintf("Top **%d%%** of reaction givers (#%d of %d)\n", w.SenderRank.Percentile(), w.SenderRank.Position, w.SenderRank.Total))
+		sb.WriteString(fmt.Sprintf("Top **%d%%** of reaction receivers (#%d of %d)\n", w.ReceiverRank.Percentile(), w.ReceiverRank.Position, w.ReceiverRank.Total))
+		if w.Streak.Longest > 0 {
+			sb.WriteString(fmt.Sprintf("\n### Streaks\n%s\n", formatStreakSummary(w.Streak)))
+		}
+		pages = append(pages, sb.String())
+	}
+
+	return pages
+}
+
+// FormatStreaks formats a streak leaderboard as Discord markdown
+func FormatStreaks(streaks []Streak, byLongest bool) string {
+	var sb strings.Builder
+
+	if byLongest {
+		sb.WriteString("## 🔥 Longest Streaks\n\n")
+	} else {
+		sb.WriteString("## 🔥 Current Streaks\n\n")
+	}
+
+	count := 0
+	for _, s := range streaks {
+		value, other, label := s.Current, s.Longest, "longest"
+		if byLongest {
+			value, other, label = s.Longest, s.Current, "current"
+		}
+		if value == 0 {
+			continue
+		}
+
+		count++
+		sb.WriteString(fmt.Sprintf("%s %s - %s (%s: %s)\n", formatRank(count), privacy.FormatUser(s.UserID), formatDays(value), label, formatDays(other)))
+	}
+
+	if count == 0 {
+		sb.WriteString("No active streaks. React to something to start one!\n")
+	}
+
+	return sb.String()
+}
+
+// FormatStreak formats a user's streaks as Discord markdown
+func FormatStreak(s Streak) string {
+	return fmt.Sprintf("## 🔥 Streaks for %s\n\n%s\n", privacy.FormatUser(s.UserID), formatStreakSummary(s))
+}
+
+func formatStreakSummary(s Streak) string {
+	return fmt.Sprintf("**Current streak:** %s\n**Longest streak:** %s", formatDays(s.Current), formatDays(s.Longest))
+}
+
+func formatDays(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
 func formatEmoji(emojiID string, _ bool) string {
 	return emojiID
 }
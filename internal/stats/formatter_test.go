@@ -4,7 +4,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/elliotwms/emojistats/internal/privacy"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFormatGuildStats(t *testing.T) {
@@ -27,12 +29,12 @@ func TestFormatGuildStats(t *testing.T) {
 
 	assert.Contains(t, result, "## Reaction Statistics")
 	assert.Contains(t, result, "**Total Reactions:** 100")
-	assert.Contains(t, result, "### Top 10 Reactions")
+	assert.Contains(t, result, "### Top Reactions")
 	assert.Contains(t, result, "1. 👍 - 50")
 	assert.Contains(t, result, "2. <:pepe:123456789> - 30")
-	assert.Contains(t, result, "### Top 3 Reaction Givers")
+	assert.Contains(t, result, "### Top Reaction Givers")
 	assert.Contains(t, result, "<@111>")
-	assert.Contains(t, result, "### Top 3 Reaction Receivers")
+	assert.Contains(t, result, "### Top Reaction Receivers")
 	assert.Contains(t, result, "<@333>")
 }
 
@@ -44,8 +46,21 @@ func TestFormatGuildStats_Empty(t *testing.T) {
 	result := FormatGuildStats(stats, "guild123")
 
 	assert.Contains(t, result, "**Total Reactions:** 0")
-	assert.NotContains(t, result, "### Top 10 Reactions")
-	assert.NotContains(t, result, "### Top 3 Reaction Givers")
+	assert.NotContains(t, result, "### Top Reactions")
+	assert.NotContains(t, result, "### Top Reaction Givers")
+}
+
+func TestFormatGuildStats_Anonymous(t *testing.T) {
+	stats := &GuildStats{
+		TotalReactions: 10,
+		TopSenders:     []UserCount{{UserID: privacy.AnonymousUserID, Count: 6}, {UserID: "111", Count: 4}},
+	}
+
+	result := FormatGuildStats(stats, "guild123")
+
+	assert.Contains(t, result, "🥇 anonymous - 6")
+	assert.Contains(t, result, "🥈 <@111> - 4")
+	assert.NotContains(t, result, "<@anonymous>")
 }
 
 func TestFormatEmojiStats(t *testing.T) {
@@ -69,11 +84,11 @@ func TestFormatEmojiStats(t *testing.T) {
 
 	assert.Contains(t, result, "## 👍 Statistics")
 	assert.Contains(t, result, "**Total Uses:** 50")
-	assert.Contains(t, result, "### Top 10 Messages")
+	assert.Contains(t, result, "### Top Messages")
 	assert.Contains(t, result, "https://discord.com/channels/guild123/chan1/msg1")
-	assert.Contains(t, result, "### Top 10 Recipients")
+	assert.Contains(t, result, "### Top Recipients")
 	assert.Contains(t, result, "<@222>")
-	assert.Contains(t, result, "### Top 10 Senders")
+	assert.Contains(t, result, "### Top Senders")
 	assert.Contains(t, result, "<@111>")
 }
 
@@ -157,3 +172,170 @@ func TestFormatGuildStats_RankingOrder(t *testing.T) {
 	assert.True(t, firstIdx < secondIdx)
 	assert.True(t, secondIdx < thirdIdx)
 }
+
+func TestFormatHallOfFame(t *testing.T) {
+	messages := []TopMessage{
+		{
+			MessageID:      "msg1",
+			ChannelID:      "chan1",
+			AuthorID:       "111",
+			TotalReactions: 12,
+			UniqueReactors: 8,
+			TopEmojis: []EmojiCount{
+				{EmojiID: "👍", IsDefault: true, Count: 7},
+				{EmojiID: "<:pepe:123456789>", IsDefault: false, Count: 5},
+			},
+		},
+		{MessageID: "msg2", ChannelID: "chan2", AuthorID: "222", TotalReactions: 3, UniqueReactors: 3},
+	}
+
+	result := FormatHallOfFame(messages, "guild123")
+
+	assert.Contains(t, result, "## Hall of Fame")
+	assert.Contains(t, result, "🥇 [Jump to message](https://discord.com/channels/guild123/chan1/msg1) by <@111> - 12 reactions from 8 users")
+	assert.Contains(t, result, "👍 7 · <:pepe:123456789> 5")
+	assert.Contains(t, result, "🥈 [Jump to message](https://discord.com/channels/guild123/chan2/msg2) by <@222>")
+}
+
+func TestFormatHallOfFame_Empty(t *testing.T) {
+	result := FormatHallOfFame(nil, "guild123")
+
+	assert.Contains(t, result, "## Hall of Fame")
+	assert.Contains(t, result, "No reactions found.")
+}
+
+func TestFormatDigest(t *testing.T) {
+	digest := &Digest{
+		Title: "Weekly Reaction Digest",
+		Stats: &GuildStats{
+			TotalReactions: 42,
+			TopEmojis:      []EmojiCount{{EmojiID: "👍", IsDefault: true, Count: 20}},
+			TopSenders:     []UserCount{{UserID: "111", Count: 10}},
+			TopReceivers:   []UserCount{{UserID: "222", Count: 8}},
+		},
+		Movers: []EmojiMover{{EmojiID: "🎉", IsDefault: true, Count: 5, PreviousCount: 1}},
+	}
+
+	result := FormatDigest(digest)
+
+	assert.Contains(t, result, "## Weekly Reaction Digest")
+	assert.Contains(t, result, "**Total Reactions:** 42")
+	assert.Contains(t, result, "1. 👍 - 20")
+	assert.Contains(t, result, "### Biggest Movers")
+	assert.Contains(t, result, "📈 🎉 - 5 (+4)")
+	assert.Contains(t, result, "🥇 <@111> - 10")
+	assert.Contains(t, result, "🥇 <@222> - 8")
+}
+
+func TestFormatDigest_Empty(t *testing.T) {
+	result := FormatDigest(&Digest{Title: "Monthly Reaction Digest", Stats: &GuildStats{}})
+
+	assert.Contains(t, result, "## Monthly Reaction Digest")
+	assert.Contains(t, result, "No reactions this time.")
+	assert.NotContains(t, result, "### Biggest Movers")
+}
+
+func TestFormatWrappedPages_User(t *testing.T) {
+	w := &Wrapped{
+		Year:           2024,
+		UserID:         "111",
+		TotalReactions: 120,
+		TotalReceived:  80,
+		FavouriteEmoji: &EmojiCount{EmojiID: "👍", IsDefault: true, Count: 60},
+		MonthlyCounts:  [12]int{1, 5, 10, 20, 0, 0, 3, 4, 30, 20, 15, 12},
+		TopFan:         &UserCount{UserID: "222", Count: 25},
+		TopMessage:     &TopMessage{MessageID: "msg1", ChannelID: "chan1", TotalReactions: 14},
+		SenderRank:     Rank{Position: 2, Total: 40},
+		ReceiverRank:   Rank{Position: 10, Total: 40},
+		Streak:         Streak{UserID: "111", Current: 0, Longest: 9},
+	}
+
+	pages := FormatWrappedPages(w, "guild123")
+
+	require.Len(t, pages, 4)
+	for _, p := range pages {
+		assert.Contains(t, p, "## 🎁 <@111>'s 2024 Wrapped")
+	}
+	assert.Contains(t, pages[0], "**Reactions given:** 120")
+	assert.Contains(t, pages[0], "**Reactions received:** 80")
+	assert.Contains(t, pages[1], "👍 used 60 times")
+	assert.Contains(t, pages[1], "September with 30 reactions")
+	assert.Contains(t, pages[2], "### Top Fan\n<@222> with 25 reactions")
+	assert.Contains(t, pages[2], "https://discord.com/channels/guild123/chan1/msg1")
+	assert.Contains(t, pages[3], "Top **5%** of reaction givers (#2 of 40)")
+	assert.Contains(t, pages[3], "Top **25%** of reaction receivers (#10 of 40)")
+	assert.Contains(t, pages[3], "**Longest streak:** 9 days")
+}
+
+func TestFormatWrappedPages_Guild(t *testing.T) {
+	w := &Wrapped{
+		Year:           2024,
+		TotalReactions: 500,
+		FavouriteEmoji: &EmojiCount{EmojiID: "❤️", IsDefault: true, Count: 200},
+		TopFan:         &UserCount{UserID: "222", Count: 100},
+	}
+
+	pages := FormatWrappedPages(w, "guild123")
+
+	require.Len(t, pages, 3)
+	assert.Contains(t, pages[0], "## 🎁 This server's 2024 Wrapped")
+	assert.Contains(t, pages[0], "This server reacted **500** times in 2024.")
+	assert.Contains(t, pages[2], "### Most Generous Reactor")
+}
+
+func TestFormatWrappedPages_Empty(t *testing.T) {
+	pages := FormatWrappedPages(&Wrapped{Year: 2020, UserID: "111"}, "guild123")
+
+	require.Len(t, pages, 1)
+	assert.Contains(t, pages[0], "No reactions found in 2020.")
+}
+
+func TestRankPercentile(t *testing.T) {
+	tests := []struct {
+		rank     Rank
+		expected int
+	}{
+		{Rank{Position: 1, Total: 100}, 1},
+		{Rank{Position: 1, Total: 3}, 34},
+		{Rank{Position: 3, Total: 3}, 100},
+		{Rank{}, 100},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.rank.Percentile())
+	}
+}
+
+func TestFormatStreaks(t *testing.T) {
+	streaks := []Streak{
+		{UserID: "111", Current: 5, Longest: 12},
+		{UserID: "222", Current: 1, Longest: 1},
+		{UserID: "333", Current: 0, Longest: 30},
+	}
+
+	result := FormatStreaks(streaks, false)
+
+	assert.Contains(t, result, "## 🔥 Current Streaks")
+	assert.Contains(t, result, "🥇 <@111> - 5 days (longest: 12 days)")
+	assert.Contains(t, result, "🥈 <@222> - 1 day (longest: 1 day)")
+	assert.NotContains(t, result, "<@333>", "users without a current streak should not be listed")
+}
+
+func TestFormatStreaks_Longest(t *testing.T) {
+	result := FormatStreaks([]Streak{{UserID: "333", Current: 0, Longest: 30}}, true)
+
+	assert.Contains(t, result, "## 🔥 Longest Streaks")
+	assert.Contains(t, result, "🥇 <@333> - 30 days (current: 0 days)")
+}
+
+func TestFormatStreaks_Empty(t *testing.T) {
+	assert.Contains(t, FormatStreaks(nil, false), "No active streaks")
+}
+
+func TestFormatStreak(t *testing.T) {
+	result := FormatStreak(Streak{UserID: "111", Current: 3, Longest: 7})
+
+	assert.Contains(t, result, "## 🔥 Streaks for <@111>")
+	assert.Contains(t, result, "**Current streak:** 3 days")
+	assert.Contains(t, result, "**Longest streak:** 7 days")
+}
@@ -8,40 +8,136 @@ type DateRange struct {
 	End   *time.Time
 }
 
+// Reaction is a reaction to a message, as stored
+type Reaction struct {
+	GuildID   string
+	ChannelID string
+	MessageID string
+	// EmojiID is the emoji in message format, e.g. "👍" or "<:name:id>"
+	EmojiID        string
+	IsDefault      bool
+	SenderUserID   string
+	ReceiverUserID string
+	CreatedAt      time.Time
+}
+
 // EmojiCount represents an emoji and its usage count
 type EmojiCount struct {
-	EmojiID   string
-	IsDefault bool
-	Count     int
+	EmojiID   string `json:"emoji_id"`
+	IsDefault bool   `json:"is_default"`
+	Count     int    `json:"count"`
 }
 
 // UserCount represents a user and their reaction count
 type UserCount struct {
-	UserID string
-	Count  int
+	UserID string `json:"user_id"`
+	Count  int    `json:"count"`
+}
+
+// ChannelCount represents a channel and its reaction count
+type ChannelCount struct {
+	ChannelID string `json:"channel_id"`
+	Count     int    `json:"count"`
 }
 
 // MessageCount represents a message and its reaction count
 type MessageCount struct {
-	MessageID string
-	ChannelID string
-	Count     int
+	MessageID string `json:"message_id"`
+	ChannelID string `json:"channel_id"`
+	Count     int    `json:"count"`
+}
+
+// TopMessage represents a message and its reactions across all emojis
+type TopMessage struct {
+	MessageID      string       `json:"message_id"`
+	ChannelID      string       `json:"channel_id"`
+	AuthorID       string       `json:"author_id"`
+	TotalReactions int          `json:"total_reactions"`
+	UniqueReactors int          `json:"unique_reactors"`
+	TopEmojis      []EmojiCount `json:"top_emojis"`
 }
 
 // GuildStats contains aggregated stats for a guild
 type GuildStats struct {
-	TotalReactions int
-	TopEmojis      []EmojiCount
-	TopSenders     []UserCount
-	TopReceivers   []UserCount
+	TotalReactions int          `json:"total_reactions"`
+	TopEmojis      []EmojiCount `json:"top_emojis"`
+	TopSenders     []UserCount  `json:"top_senders"`
+	TopReceivers   []UserCount  `json:"top_receivers"`
 }
 
 // EmojiStats contains detailed stats for a specific emoji
 type EmojiStats struct {
-	EmojiID     string
-	IsDefault   bool
-	TotalUses   int
-	TopMessages []MessageCount
-	TopSenders  []UserCount
-	TopReceivers []UserCount
+	EmojiID      string         `json:"emoji_id"`
+	IsDefault    bool           `json:"is_default"`
+	TotalUses    int            `json:"total_uses"`
+	TopMessages  []MessageCount `json:"top_messages"`
+	TopSenders   []UserCount    `json:"top_senders"`
+	TopReceivers []UserCount    `json:"top_receivers"`
+}
+
+// EmojiMover represents the change in an emoji's usage between two periods
+type EmojiMover struct {
+	EmojiID       string
+	IsDefault     bool
+	Count         int
+	PreviousCount int
+}
+
+// Digest contains the stats for a scheduled digest post
+type Digest struct {
+	Title  string
+	Stats  *GuildStats
+	Movers []EmojiMover
+}
+
+// Rank represents a user's position amongst all users in a guild
+type Rank struct {
+	Position int `json:"position"`
+	Total    int `json:"total"`
+}
+
+// Percentile returns the top percentage of users the rank falls in, e.g. 5 for the top 5%
+func (r Rank) Percentile() int {
+	if r.Total == 0 {
+		return 100
+	}
+	return (r.Position*100 + r.Total - 1) / r.Total
+}
+
+// Wrapped contains a year in review recap for a guild, or for a user within a guild
+type Wrapped struct {
+	Year int `json:"year"`
+	// UserID is empty for a guild recap
+	UserID string `json:"user_id"`
+	// TotalReactions is the number of reactions given by the user, or all reactions for a guild recap
+	TotalReactions int         `json:"total_reactions"`
+	TotalReceived  int         `json:"total_received"`
+	FavouriteEmoji *EmojiCount `json:"favourite_emoji"`
+	MonthlyCounts  [12]int     `json:"monthly_counts"`
+	// TopFan is the user who reacted most to the user, or the top reaction giver for a guild recap
+	TopFan       *UserCount  `json:"top_fan"`
+	TopMessage   *TopMessage `json:"top_message"`
+	SenderRank   Rank        `json:"sender_rank"`
+	ReceiverRank Rank        `json:"receiver_rank"`
+	// Streak is the user's streak within the year. Current is only set for the current year
+	Streak Streak `json:"streak"`
+}
+
+// BusiestMonth returns the month with the most reactions and its count
+func (w *Wrapped) BusiestMonth() (time.Month, int) {
+	busiest := 0
+	for i, c := range w.MonthlyCounts {
+		if c > w.MonthlyCounts[busiest] {
+			busiest = i
+		}
+	}
+	return time.Month(busiest + 1), w.MonthlyCounts[busiest]
+}
+
+// Streak summarises the runs of consecutive days on which a user gave or received at least one reaction
+type Streak struct {
+	UserID string `json:"user_id"`
+	// Current is the length of the run ending today or yesterday, or zero if the user has not been active since
+	Current int `json:"current"`
+	Longest int `json:"longest"`
 }
@@ -0,0 +1,34 @@
+package stats
+
+import "sort"
+
+// BiggestMovers compares emoji usage between two periods and returns the emojis with the largest increase in usage
+func BiggestMovers(current, previous []EmojiCount, limit int) []EmojiMover {
+	previousCounts := make(map[string]int, len(previous))
+	for _, e := range previous {
+		previousCounts[e.EmojiID] = e.Count
+	}
+
+	var movers []EmojiMover
+	for _, e := range current {
+		m := EmojiMover{
+			EmojiID:       e.EmojiID,
+			IsDefault:     e.IsDefault,
+			Count:         e.Count,
+			PreviousCount: previousCounts[e.EmojiID],
+		}
+		if m.Count > m.PreviousCount {
+			movers = append(movers, m)
+		}
+	}
+
+	sort.SliceStable(movers, func(i, j int) bool {
+		return movers[i].Count-movers[i].PreviousCount > movers[j].Count-movers[j].PreviousCount
+	})
+
+	if len(movers) > limit {
+		movers = movers[:limit]
+	}
+
+	return movers
+}
@@ -0,0 +1,35 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBiggestMovers(t *testing.T) {
+	current := []EmojiCount{
+		{EmojiID: "👍", IsDefault: true, Count: 20},
+		{EmojiID: "❤️", IsDefault: true, Count: 15},
+		{EmojiID: "😂", IsDefault: true, Count: 10},
+		{EmojiID: "🎉", IsDefault: true, Count: 5},
+	}
+	previous := []EmojiCount{
+		{EmojiID: "👍", IsDefault: true, Count: 18},
+		{EmojiID: "😂", IsDefault: true, Count: 12},
+		{EmojiID: "🎉", IsDefault: true, Count: 1},
+	}
+
+	movers := BiggestMovers(current, previous, 2)
+
+	require.Len(t, movers, 2)
+	assert.Equal(t, EmojiMover{EmojiID: "❤️", IsDefault: true, Count: 15, PreviousCount: 0}, movers[0])
+	assert.Equal(t, EmojiMover{EmojiID: "🎉", IsDefault: true, Count: 5, PreviousCount: 1}, movers[1])
+}
+
+func TestBiggestMovers_NoIncreases(t *testing.T) {
+	current := []EmojiCount{{EmojiID: "👍", Count: 5}}
+	previous := []EmojiCount{{EmojiID: "👍", Count: 5}}
+
+	assert.Empty(t, BiggestMovers(current, previous, 3))
+}
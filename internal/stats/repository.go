@@ -3,21 +3,140 @@ package stats
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/elliotwms/emojistats/internal/database"
+	"github.com/elliotwms/emojistats/internal/privacy"
 )
 
+// Querier is the stats queries used by commands, so that they can be decorated, e.g. by a cache
+type Querier interface {
+	// GetGuildStats and GetEmojiStats return leaderboards of up to limit entries each
+	GetGuildStats(ctx context.Context, guildID string, dateRange DateRange, limit int) (*GuildStats, error)
+	GetEmojiStats(ctx context.Context, guildID, emojiID string, dateRange DateRange, limit int) (*EmojiStats, error)
+	GetTopEmojis(ctx context.Context, guildID string, dateRange DateRange, limit int) ([]EmojiCount, error)
+	GetTopChannels(ctx context.Context, guildID string, dateRange DateRange, limit int) ([]ChannelCount, error)
+	// GetTopMessages only counts reactions which have not been pruned, as the daily totals of pruned reactions have no
+	// message. The same goes for the top messages of GetWrapped
+	GetTopMessages(ctx context.Context, guildID, channelID string, dateRange DateRange, limit int) ([]TopMessage, error)
+	GetWrapped(ctx context.Context, guildID, userID string, year int) (*Wrapped, error)
+	// GetStreak and GetStreaks only count reactions which have not been pruned, as the daily totals of pruned reactions
+	// are by UTC day rather than the guild's timezone
+	GetStreak(ctx context.Context, guildID, userID string) (Streak, error)
+	GetStreaks(ctx context.Context, guildID string, byLongest bool, limit int) ([]Streak, error)
+}
+
+// Store is the storage for reactions: ingestion, the stats queries and the settings they depend on. Repository is the
+// Postgres implementation
+type Store interface {
+	Querier
+	AddReaction(ctx context.Context, reaction Reaction) error
+	// RemoveReaction deletes a user's reaction to a message and returns the number of reactions deleted
+	RemoveReaction(ctx context.Context, guildID, messageID, emojiID, senderUserID string) (int, error)
+	privacy.Store
+	SettingsStore
+}
+
+// SettingsStore stores guilds' settings
+type SettingsStore interface {
+	// GetTimezone retrieves the timezone used to group a guild's reactions into days and to parse the stats commands'
+	// dates. It defaults to UTC
+	GetTimezone(ctx context.Context, guildID string) (*time.Location, error)
+	SetTimezone(ctx context.Context, guildID string, loc *time.Location) error
+	// GetSettings retrieves a guild's settings. A guild which has not configured any has DefaultSettings
+	GetSettings(ctx context.Context, guildID string) (Settings, error)
+	SetSettings(ctx context.Context, guildID string, settings Settings) error
+	// SetChannelParent records a channel's parent: the category it is in, or for a thread the channel it is in,
+	// replacing any recorded before. An empty parentID removes it. The stats exclude reactions in channels with an
+	// ignored ancestor, as reactions do not record them. It returns whether the recorded parent changed
+	SetChannelParent(ctx context.Context, guildID, channelID, parentID string) (bool, error)
+}
+
+var _ Store = (*Repository)(nil)
+
 // Repository handles database queries for stats
 type Repository struct {
-	db *sql.DB
+	db      *sql.DB
+	privacy *privacy.Repository
+	// rollups enables answering whole-day date ranges from the daily rollups
+	rollups bool
 }
 
 // NewRepository creates a new stats repository
 func NewRepository(db *sql.DB) *Repository {
-	return &Repository{db: db}
+	return &Repository{db: db, privacy: privacy.NewRepository(db), rollups: true}
+}
+
+// notIgnored excludes reactions in the channels a guild ignores. It must follow a filter on the guild ID, which must be
+// the first argument
+const notIgnored = ` AND channel_id NOT IN (SELECT channel_id FROM ignored_channels WHERE guild_id = $1)`
+
+// AddReaction stores a reaction. A zero CreatedAt is stored as the current time
+func (r *Repository) AddReaction(ctx context.Context, reaction Reaction) error {
+	createdAt := reaction.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO reactions (emoji_id, sender_user_id, receiver_user_id, channel_id, message_id, guild_id, is_default, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		reaction.EmojiID,
+		reaction.SenderUserID,
+		reaction.ReceiverUserID,
+		reaction.ChannelID,
+		reaction.MessageID,
+		reaction.GuildID,
+		reaction.IsDefault,
+		createdAt,
+	)
+	return err
+}
+
+// RemoveReaction deletes a user's reaction to a message and returns the number of reactions deleted
+func (r *Repository) RemoveReaction(ctx context.Context, guildID, messageID, emojiID, senderUserID string) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM reactions
+		WHERE emoji_id = $1 AND sender_user_id = $2 AND message_id = $3 AND guild_id = $4`,
+		emojiID,
+		senderUserID,
+		messageID,
+		guildID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// OptedOut returns which of the given users have opted out of having their reactions stored
+func (r *Repository) OptedOut(ctx context.Context, userIDs ...string) (map[string]bool, error) {
+	return r.privacy.OptedOut(ctx, userIDs...)
+}
+
+// OptOut stops a user's reactions from being stored in every guild
+func (r *Repository) OptOut(ctx context.Context, guildID, userID string) error {
+	return r.privacy.OptOut(ctx, guildID, userID)
+}
+
+// OptIn resumes storing a user's reactions
+func (r *Repository) OptIn(ctx context.Context, guildID, userID string) error {
+	return r.privacy.OptIn(ctx, guildID, userID)
+}
+
+// Forget deletes the reactions a user gave and anonymises those they received in every guild, then opts them out
+func (r *Repository) Forget(ctx context.Context, guildID, userID string) (privacy.Forgotten, error) {
+	return r.privacy.Forget(ctx, guildID, userID)
 }
 
 // GetGuildStats retrieves aggregated stats for a guild
-func (r *Repository) GetGuildStats(ctx context.Context, guildID string, dateRange DateRange) (*GuildStats, error) {
+func (r *Repository) GetGuildStats(ctx context.Context, guildID string, dateRange DateRange, limit int) (*GuildStats, error) {
 	stats := &GuildStats{}
 
 	total, err := r.getTotalReactions(ctx, guildID, dateRange)
@@ -26,29 +145,33 @@ func (r *Repository) GetGuildStats(ctx context.Context, guildID string, dateRang
 	}
 	stats.TotalReactions = total
 
-	topEmojis, err := r.getTopEmojis(ctx, guildID, dateRange, 10)
+	topEmojis, err := r.getTopEmojis(ctx, guildID, dateRange, limit)
 	if err != nil {
 		return nil, err
 	}
 	stats.TopEmojis = topEmojis
 
-	topSenders, err := r.getTopSenders(ctx, guildID, "", dateRange, 3)
+	topSenders, err := r.getTopSenders(ctx, guildID, "", dateRange, limit)
 	if err != nil {
 		return nil, err
 	}
 	stats.TopSenders = topSenders
 
-	topReceivers, err := r.getTopReceivers(ctx, guildID, "", dateRange, 3)
+	topReceivers, err := r.getTopReceivers(ctx, guildID, "", dateRange, limit)
 	if err != nil {
 		return nil, err
 	}
 	stats.TopReceivers = topReceivers
 
+	if err := r.anonymise(ctx, append(userIDs(stats.TopSenders), userIDs(stats.TopReceivers)...)...); err != nil {
+		return nil, err
+	}
+
 	return stats, nil
 }
 
 // GetEmojiStats retrieves detailed stats for a specific emoji
-func (r *Repository) GetEmojiStats(ctx context.Context, guildID, emojiID string, dateRange DateRange) (*EmojiStats, error) {
+func (r *Repository) GetEmojiStats(ctx context.Context, guildID, emojiID string, dateRange DateRange, limit int) (*EmojiStats, error) {
 	stats := &EmojiStats{
 		EmojiID: emojiID,
 	}
@@ -60,47 +183,475 @@ func (r *Repository) GetEmojiStats(ctx context.Context, guildID, emojiID string,
 	stats.TotalUses = total
 	stats.IsDefault = isDefault
 
-	topMessages, err := r.getTopMessages(ctx, guildID, emojiID, dateRange, 10)
+	topMessages, err := r.getTopMessages(ctx, guildID, emojiID, dateRange, limit)
 	if err != nil {
 		return nil, err
 	}
 	stats.TopMessages = topMessages
 
-	topSenders, err := r.getTopSenders(ctx, guildID, emojiID, dateRange, 10)
+	topSenders, err := r.getTopSenders(ctx, guildID, emojiID, dateRange, limit)
 	if err != nil {
 		return nil, err
 	}
 	stats.TopSenders = topSenders
 
-	topReceivers, err := r.getTopReceivers(ctx, guildID, emojiID, dateRange, 10)
+	topReceivers, err := r.getTopReceivers(ctx, guildID, emojiID, dateRange, limit)
 	if err != nil {
 		return nil, err
 	}
 	stats.TopReceivers = topReceivers
 
+	if err := r.anonymise(ctx, append(userIDs(stats.TopSenders), userIDs(stats.TopReceivers)...)...); err != nil {
+		return nil, err
+	}
+
 	return stats, nil
 }
 
+// GetTopEmojis retrieves the most used emojis in a guild
+func (r *Repository) GetTopEmojis(ctx context.Context, guildID string, dateRange DateRange, limit int) ([]EmojiCount, error) {
+	return r.getTopEmojis(ctx, guildID, dateRange, limit)
+}
+
+// GetTopChannels retrieves the channels with the most reactions in a guild
+func (r *Repository) GetTopChannels(ctx context.Context, guildID string, dateRange DateRange, limit int) ([]ChannelCount, error) {
+	rollups, err := r.rollupsFor(ctx, guildID, dateRange)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT channel_id, SUM(count) as count
+		FROM ` + database.CountedReactions + `
+		WHERE guild_id = $1`
+	if rollups {
+		query = `
+		SELECT channel_id, SUM(count) as count
+		FROM reaction_rollups_channel
+		WHERE guild_id = $1`
+	}
+	args := []any{guildID}
+
+	query, args = appendRangeFilter(query, args, dateRange, rollups)
+	query += ` GROUP BY channel_id ORDER BY count DESC, channel_id LIMIT $` + argNum(len(args)+1)
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []ChannelCount
+	for rows.Next() {
+		var cc ChannelCount
+		if err := rows.Scan(&cc.ChannelID, &cc.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, cc)
+	}
+	return results, rows.Err()
+}
+
+// GetTopMessages retrieves the most reacted messages in a guild across all emojis, optionally filtered by channel
+func (r *Repository) GetTopMessages(ctx context.Context, guildID, channelID string, dateRange DateRange, limit int) ([]TopMessage, error) {
+	messages, err := r.getTopMessagesFor(ctx, guildID, channelID, "", dateRange, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]*string, len(messages))
+	for i := range messages {
+		ids[i] = &messages[i].AuthorID
+	}
+
+	return messages, r.anonymise(ctx, ids...)
+}
+
+// GetWrapped retrieves a year in review recap for a guild. If userID is set the recap is for that user. The year and its
+// months are in the guild's timezone
+func (r *Repository) GetWrapped(ctx context.Context, guildID, userID string, year int) (*Wrapped, error) {
+	loc, err := r.GetTimezone(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(1, 0, 0)
+	dateRange := DateRange{Start: &start, End: &end}
+
+	w := &Wrapped{Year: year, UserID: userID}
+
+	if userID == "" {
+		w.TotalReactions, err = r.getTotalReactions(ctx, guildID, dateRange)
+	} else {
+		w.TotalReactions, err = r.countWhere(ctx, guildID, "sender_user_id", userID, dateRange)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if w.TotalReactions > 0 {
+		favourite, err := r.getFavouriteEmoji(ctx, guildID, userID, dateRange)
+		if err != nil {
+			return nil, err
+		}
+		w.FavouriteEmoji = favourite
+
+		w.MonthlyCounts, err = r.getMonthlyCounts(ctx, guildID, userID, dateRange, loc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if userID == "" {
+		topSenders, err := r.getTopSenders(ctx, guildID, "", dateRange, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(topSenders) > 0 {
+			w.TopFan = &topSenders[0]
+		}
+	} else {
+		w.TotalReceived, err = r.countWhere(ctx, guildID, "receiver_user_id", userID, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		w.TopFan, err = r.getTopFan(ctx, guildID, userID, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		w.SenderRank, err = r.getRank(ctx, guildID, "sender_user_id", w.TotalReactions, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		w.ReceiverRank, err = r.getRank(ctx, guildID, "receiver_user_id", w.TotalReceived, dateRange)
+		if err != nil {
+			return nil, err
+		}
+
+		streaks, err := r.getStreaks(ctx, guildID, userID, dateRange, loc, time.Now(), false, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(streaks) > 0 {
+			w.Streak = streaks[0]
+		}
+	}
+
+	topMessages, err := r.getTopMessagesFor(ctx, guildID, "", userID, dateRange, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(topMessages) > 0 {
+		w.TopMessage = &topMessages[0]
+	}
+
+	ids := []*string{&w.UserID}
+	if w.TopFan != nil {
+		ids = append(ids, &w.TopFan.UserID)
+	}
+	if w.TopMessage != nil {
+		ids = append(ids, &w.TopMessage.AuthorID)
+	}
+
+	return w, r.anonymise(ctx, ids...)
+}
+
+// GetStreak retrieves a user's current and longest streaks, counting days in the guild's timezone
+func (r *Repository) GetStreak(ctx context.Context, guildID, userID string) (Streak, error) {
+	loc, err := r.GetTimezone(ctx, guildID)
+	if err != nil {
+		return Streak{}, err
+	}
+
+	streaks, err := r.getStreaks(ctx, guildID, userID, DateRange{}, loc, time.Now(), false, 1)
+	if err != nil {
+		return Streak{}, err
+	}
+	streak := Streak{UserID: userID}
+	if len(streaks) > 0 {
+		streak = streaks[0]
+	}
+
+	if err := r.anonymise(ctx, &streak.UserID); err != nil {
+		return Streak{}, err
+	}
+	return streak, nil
+}
+
+// GetStreaks retrieves the users with the highest current streaks, or the highest longest streaks if byLongest is set
+func (r *Repository) GetStreaks(ctx context.Context, guildID string, byLongest bool, limit int) ([]Streak, error) {
+	loc, err := r.GetTimezone(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	streaks, err := r.getStreaks(ctx, guildID, "", DateRange{}, loc, time.Now(), byLongest, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]*string, len(streaks))
+	for i := range streaks {
+		ids[i] = &streaks[i].UserID
+	}
+
+	return streaks, r.anonymise(ctx, ids...)
+}
+
+// GetTimezone retrieves the timezone used to group a guild's reactions into days. It defaults to UTC
+func (r *Repository) GetTimezone(ctx context.Context, guildID string) (*time.Location, error) {
+	var name string
+	err := r.db.QueryRowContext(ctx, `SELECT timezone FROM guild_timezones WHERE guild_id = $1`, guildID).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return time.LoadLocation(name)
+}
+
+// SetTimezone sets the timezone used to group a guild's reactions into days
+func (r *Repository) SetTimezone(ctx context.Context, guildID string, loc *time.Location) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO guild_timezones (guild_id, timezone)
+		VALUES ($1, $2)
+		ON CONFLICT (guild_id) DO UPDATE SET timezone = EXCLUDED.timezone`,
+		guildID,
+		loc.String(),
+	)
+	return err
+}
+
+// GetSettings retrieves a guild's settings. A guild which has not configured any has DefaultSettings
+func (r *Repository) GetSettings(ctx context.Context, guildID string) (Settings, error) {
+	var settings Settings
+	err := r.db.QueryRowContext(ctx, `
+		SELECT public_responses, leaderboard_size, count_bots, ignored_channel_ids, ignored_category_ids, ignored_role_ids
+		FROM guild_settings
+		WHERE guild_id = $1`, guildID).
+		Scan(
+			&settings.PublicResponses,
+			&settings.LeaderboardSize,
+			&settings.CountBots,
+			pq.Array(&settings.IgnoredChannels),
+			pq.Array(&settings.IgnoredCategories),
+			pq.Array(&settings.IgnoredRoles),
+		)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultSettings(), nil
+	}
+	return settings, err
+}
+
+// SetSettings stores a guild's settings
+func (r *Repository) SetSettings(ctx context.Context, guildID string, settings Settings) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO guild_settings (
+			guild_id, public_responses, leaderboard_size, count_bots,
+			ignored_channel_ids, ignored_category_ids, ignored_role_ids, updated_at
+		)
+		-- nil slices are sent as NULL
+		VALUES ($1, $2, $3, $4, COALESCE($5::TEXT[], '{}'), COALESCE($6::TEXT[], '{}'), COALESCE($7::TEXT[], '{}'), NOW())
+		ON CONFLICT (guild_id) DO UPDATE SET
+			public_responses = excluded.public_responses,
+			leaderboard_size = excluded.leaderboard_size,
+			count_bots = excluded.count_bots,
+			ignored_channel_ids = excluded.ignored_channel_ids,
+			ignored_category_ids = excluded.ignored_category_ids,
+			ignored_role_ids = excluded.ignored_role_ids,
+			updated_at = excluded.updated_at`,
+		guildID,
+		settings.PublicResponses,
+		settings.LeaderboardSize,
+		settings.CountBots,
+		pq.Array(settings.IgnoredChannels),
+		pq.Array(settings.IgnoredCategories),
+		pq.Array(settings.IgnoredRoles),
+	)
+	return err
+}
+
+// SetChannelParent records a channel's parent, so that its reactions are excluded from the stats while an ancestor is
+// ignored. It returns whether the recorded parent changed
+func (r *Repository) SetChannelParent(ctx context.Context, guildID, channelID, parentID string) (bool, error) {
+	var res sql.Result
+	var err error
+	if parentID == "" {
+		res, err = r.db.ExecContext(ctx, `DELETE FROM channel_parents WHERE guild_id = $1 AND channel_id = $2`, guildID, channelID)
+	} else {
+		res, err = r.db.ExecContext(ctx, `
+			INSERT INTO channel_parents (guild_id, channel_id, parent_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (guild_id, channel_id) DO UPDATE SET parent_id = excluded.parent_id
+			WHERE channel_parents.parent_id <> excluded.parent_id`,
+			guildID,
+			channelID,
+			parentID,
+		)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
+
+// getStreaks finds each user's runs of consecutive active days by grouping the days on their difference from the
+// day's position in the user's activity, which is constant within a run
+func (r *Repository) getStreaks(ctx context.Context, guildID, userID string, dateRange DateRange, loc *time.Location, now time.Time, byLongest bool, limit int) ([]Streak, error) {
+	args := []any{guildID, loc.String(), now.In(loc).Format(time.DateOnly)}
+
+	filter, args := appendDateFilter("", args, dateRange)
+	if userID != "" {
+		args = append(args, userID)
+		filter += ` AND user_id = $` + argNum(len(args))
+	}
+
+	order := "current DESC, longest DESC"
+	if byLongest {
+		order = "longest DESC, current DESC"
+	}
+
+	args = append(args, limit)
+	query := `
+		WITH activity AS (
+			SELECT sender_user_id AS user_id, created_at FROM reactions WHERE guild_id = $1` + notIgnored + `
+			UNION ALL
+			SELECT receiver_user_id AS user_id, created_at FROM reactions WHERE guild_id = $1` + notIgnored + `
+		), days AS (
+			SELECT DISTINCT user_id, DATE(created_at AT TIME ZONE $2) AS day
+			FROM activity
+			WHERE TRUE` + filter + `
+		), runs AS (
+			SELECT user_id, MAX(day) AS last_day, COUNT(*) AS length
+			FROM (
+				SELECT user_id, day, day - (ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY day))::int AS run
+				FROM days
+			) numbered
+			GROUP BY user_id, run
+		)
+		SELECT user_id,
+			COALESCE(MAX(length) FILTER (WHERE last_day >= $3::date - 1), 0) AS current,
+			MAX(length) AS longest
+		FROM runs
+		GROUP BY user_id
+		ORDER BY ` + order + `, user_id
+		LIMIT $` + argNum(len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []Streak
+	for rows.Next() {
+		var s Streak
+		if err := rows.Scan(&s.UserID, &s.Current, &s.Longest); err != nil {
+			return nil, err
+		}
+		results = append(results, s)
+	}
+	return results, rows.Err()
+}
+
+func (r *Repository) getTopMessagesFor(ctx context.Context, guildID, channelID, receiverID string, dateRange DateRange, limit int) ([]TopMessage, error) {
+	query := `
+		SELECT message_id, channel_id, MAX(receiver_user_id), COUNT(*) as count, COUNT(DISTINCT sender_user_id) as reactors
+		FROM reactions
+		WHERE guild_id = $1`
+	args := []any{guildID}
+
+	if channelID != "" {
+		args = append(args, channelID)
+		query += ` AND channel_id = $` + argNum(len(args))
+	}
+
+	if receiverID != "" {
+		args = append(args, receiverID)
+		query += ` AND receiver_user_id = $` + argNum(len(args))
+	}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY message_id, channel_id ORDER BY count DESC, reactors DESC, message_id LIMIT $` + argNum(len(args)+1)
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []TopMessage
+	for rows.Next() {
+		var m TopMessage
+		if err := rows.Scan(&m.MessageID, &m.ChannelID, &m.AuthorID, &m.TotalReactions, &m.UniqueReactors); err != nil {
+			return nil, err
+		}
+		results = append(results, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		topEmojis, err := r.getMessageTopEmojis(ctx, guildID, results[i].MessageID, dateRange, 3)
+		if err != nil {
+			return nil, err
+		}
+		results[i].TopEmojis = topEmojis
+	}
+
+	return results, nil
+}
+
 func (r *Repository) getTotalReactions(ctx context.Context, guildID string, dateRange DateRange) (int, error) {
-	query := `SELECT COUNT(*) FROM reactions WHERE guild_id = $1`
+	rollups, err := r.rollupsFor(ctx, guildID, dateRange)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `SELECT COALESCE(SUM(count), 0) FROM ` + database.CountedReactions + ` WHERE guild_id = $1`
+	if rollups {
+		query = `SELECT COALESCE(SUM(count), 0) FROM reaction_rollups_emoji WHERE guild_id = $1`
+	}
 	args := []any{guildID}
 
-	query, args = appendDateFilter(query, args, dateRange)
+	query, args = appendRangeFilter(query, args, dateRange, rollups)
 
 	var count int
-	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&count)
 	return count, err
 }
 
 func (r *Repository) getTopEmojis(ctx context.Context, guildID string, dateRange DateRange, limit int) ([]EmojiCount, error) {
+	rollups, err := r.rollupsFor(ctx, guildID, dateRange)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		SELECT emoji_id, is_default, COUNT(*) as count
-		FROM reactions
+		SELECT emoji_id, is_default, SUM(count) as count
+		FROM ` + database.CountedReactions + `
+		WHERE guild_id = $1`
+	if rollups {
+		query = `
+		SELECT emoji_id, is_default, SUM(count) as count
+		FROM reaction_rollups_emoji
 		WHERE guild_id = $1`
+	}
 	args := []any{guildID}
 
-	query, args = appendDateFilter(query, args, dateRange)
-	query += ` GROUP BY emoji_id, is_default ORDER BY count DESC LIMIT $` + argNum(len(args)+1)
+	query, args = appendRangeFilter(query, args, dateRange, rollups)
+	query += ` GROUP BY emoji_id, is_default ORDER BY count DESC, emoji_id, is_default LIMIT $` + argNum(len(args)+1)
 	args = append(args, limit)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -121,10 +672,21 @@ func (r *Repository) getTopEmojis(ctx context.Context, guildID string, dateRange
 }
 
 func (r *Repository) getTopSenders(ctx context.Context, guildID, emojiID string, dateRange DateRange, limit int) ([]UserCount, error) {
+	rollups, err := r.rollupsFor(ctx, guildID, dateRange)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		SELECT sender_user_id, COUNT(*) as count
-		FROM reactions
+		SELECT sender_user_id AS user_id, SUM(count) as count
+		FROM ` + database.CountedReactions + `
 		WHERE guild_id = $1`
+	if rollups {
+		query = `
+		SELECT user_id, SUM(count) as count
+		FROM reaction_rollups_sender
+		WHERE guild_id = $1`
+	}
 	args := []any{guildID}
 
 	if emojiID != "" {
@@ -132,8 +694,8 @@ func (r *Repository) getTopSenders(ctx context.Context, guildID, emojiID string,
 		query += ` AND emoji_id = $` + argNum(len(args))
 	}
 
-	query, args = appendDateFilter(query, args, dateRange)
-	query += ` GROUP BY sender_user_id ORDER BY count DESC LIMIT $` + argNum(len(args)+1)
+	query, args = appendRangeFilter(query, args, dateRange, rollups)
+	query += ` GROUP BY user_id ORDER BY count DESC, user_id LIMIT $` + argNum(len(args)+1)
 	args = append(args, limit)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -154,10 +716,21 @@ func (r *Repository) getTopSenders(ctx context.Context, guildID, emojiID string,
 }
 
 func (r *Repository) getTopReceivers(ctx context.Context, guildID, emojiID string, dateRange DateRange, limit int) ([]UserCount, error) {
+	rollups, err := r.rollupsFor(ctx, guildID, dateRange)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		SELECT receiver_user_id, COUNT(*) as count
-		FROM reactions
+		SELECT receiver_user_id AS user_id, SUM(count) as count
+		FROM ` + database.CountedReactions + `
+		WHERE guild_id = $1`
+	if rollups {
+		query = `
+		SELECT user_id, SUM(count) as count
+		FROM reaction_rollups_receiver
 		WHERE guild_id = $1`
+	}
 	args := []any{guildID}
 
 	if emojiID != "" {
@@ -165,8 +738,8 @@ func (r *Repository) getTopReceivers(ctx context.Context, guildID, emojiID strin
 		query += ` AND emoji_id = $` + argNum(len(args))
 	}
 
-	query, args = appendDateFilter(query, args, dateRange)
-	query += ` GROUP BY receiver_user_id ORDER BY count DESC LIMIT $` + argNum(len(args)+1)
+	query, args = appendRangeFilter(query, args, dateRange, rollups)
+	query += ` GROUP BY user_id ORDER BY count DESC, user_id LIMIT $` + argNum(len(args)+1)
 	args = append(args, limit)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -193,8 +766,8 @@ func (r *Repository) getTopMessages(ctx context.Context, guildID, emojiID string
 		WHERE guild_id = $1 AND emoji_id = $2`
 	args := []any{guildID, emojiID}
 
-	query, args = appendDateFilter(query, args, dateRange)
-	query += ` GROUP BY message_id, channel_id ORDER BY count DESC LIMIT $` + argNum(len(args)+1)
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY message_id, channel_id ORDER BY count DESC, message_id LIMIT $` + argNum(len(args)+1)
 	args = append(args, limit)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -214,18 +787,255 @@ func (r *Repository) getTopMessages(ctx context.Context, guildID, emojiID string
 	return results, rows.Err()
 }
 
+func (r *Repository) getMessageTopEmojis(ctx context.Context, guildID, messageID string, dateRange DateRange, limit int) ([]EmojiCount, error) {
+	query := `
+		SELECT emoji_id, is_default, COUNT(*) as count
+		FROM reactions
+		WHERE guild_id = $1 AND message_id = $2`
+	args := []any{guildID, messageID}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY emoji_id, is_default ORDER BY count DESC, emoji_id, is_default LIMIT $` + argNum(len(args)+1)
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []EmojiCount
+	for rows.Next() {
+		var ec EmojiCount
+		if err := rows.Scan(&ec.EmojiID, &ec.IsDefault, &ec.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, ec)
+	}
+	return results, rows.Err()
+}
+
+// countWhere counts the reactions in a guild where the given user column matches the user
+func (r *Repository) countWhere(ctx context.Context, guildID, column, userID string, dateRange DateRange) (int, error) {
+	query := `SELECT COALESCE(SUM(count), 0) FROM ` + database.CountedReactions + ` WHERE guild_id = $1 AND ` + column + ` = $2`
+	args := []any{guildID, userID}
+
+	query, args = appendFilters(query, args, dateRange)
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+func (r *Repository) getFavouriteEmoji(ctx context.Context, guildID, userID string, dateRange DateRange) (*EmojiCount, error) {
+	query := `
+		SELECT emoji_id, is_default, SUM(count) as count
+		FROM ` + database.CountedReactions + `
+		WHERE guild_id = $1`
+	args := []any{guildID}
+
+	if userID != "" {
+		args = append(args, userID)
+		query += ` AND sender_user_id = $` + argNum(len(args))
+	}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY emoji_id, is_default ORDER BY count DESC, emoji_id, is_default LIMIT 1`
+
+	var ec EmojiCount
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&ec.EmojiID, &ec.IsDefault, &ec.Count)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ec, nil
+}
+
+// getMonthlyCounts counts reactions by month in the given timezone
+func (r *Repository) getMonthlyCounts(ctx context.Context, guildID, userID string, dateRange DateRange, loc *time.Location) ([12]int, error) {
+	var counts [12]int
+
+	query := `
+		SELECT EXTRACT(MONTH FROM created_at AT TIME ZONE $2)::int as month, SUM(count) as count
+		FROM ` + database.CountedReactions + `
+		WHERE guild_id = $1`
+	args := []any{guildID, loc.String()}
+
+	if userID != "" {
+		args = append(args, userID)
+		query += ` AND sender_user_id = $` + argNum(len(args))
+	}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY month`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return counts, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var month, count int
+		if err := rows.Scan(&month, &count); err != nil {
+			return counts, err
+		}
+		if month >= 1 && month <= 12 {
+			counts[month-1] = count
+		}
+	}
+	return counts, rows.Err()
+}
+
+func (r *Repository) getTopFan(ctx context.Context, guildID, userID string, dateRange DateRange) (*UserCount, error) {
+	query := `
+		SELECT sender_user_id, SUM(count) as count
+		FROM ` + database.CountedReactions + `
+		WHERE guild_id = $1 AND receiver_user_id = $2 AND sender_user_id <> $2`
+	args := []any{guildID, userID}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY sender_user_id ORDER BY count DESC, sender_user_id LIMIT 1`
+
+	var uc UserCount
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&uc.UserID, &uc.Count)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &uc, nil
+}
+
+// getRank ranks a count against the per-user counts of the given user column
+func (r *Repository) getRank(ctx context.Context, guildID, column string, count int, dateRange DateRange) (Rank, error) {
+	var rank Rank
+
+	query := `SELECT ` + column + `, SUM(count) as count FROM ` + database.CountedReactions + ` WHERE guild_id = $1`
+	args := []any{guildID}
+
+	query, args = appendFilters(query, args, dateRange)
+	query += ` GROUP BY ` + column
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(CASE WHEN count > $`+argNum(len(args)+1)+` THEN 1 END)
+		FROM (`+query+`) counts`,
+		append(args, count)...,
+	).Scan(&rank.Total, &rank.Position)
+	if err != nil {
+		return rank, err
+	}
+
+	rank.Position++
+	// users without any reactions are not included in the counts, so are ranked last
+	if rank.Position > rank.Total {
+		rank.Total = rank.Position
+	}
+	return rank, nil
+}
+
 func (r *Repository) getEmojiTotalUses(ctx context.Context, guildID, emojiID string, dateRange DateRange) (int, bool, error) {
-	query := `SELECT COUNT(*), COALESCE(bool_or(is_default), false) FROM reactions WHERE guild_id = $1 AND emoji_id = $2`
+	rollups, err := r.rollupsFor(ctx, guildID, dateRange)
+	if err != nil {
+		return 0, false, err
+	}
+
+	query := `SELECT COALESCE(SUM(count), 0), COALESCE(bool_or(is_default), false) FROM ` + database.CountedReactions + ` WHERE guild_id = $1 AND emoji_id = $2`
+	if rollups {
+		query = `SELECT COALESCE(SUM(count), 0), COALESCE(bool_or(is_default), false) FROM reaction_rollups_emoji WHERE guild_id = $1 AND emoji_id = $2`
+	}
 	args := []any{guildID, emojiID}
 
-	query, args = appendDateFilter(query, args, dateRange)
+	query, args = appendRangeFilter(query, args, dateRange, rollups)
 
 	var count int
 	var isDefault bool
-	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count, &isDefault)
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&count, &isDefault)
 	return count, isDefault, err
 }
 
+// anonymise replaces the IDs of users who have opted out with privacy.AnonymousUserID
+func (r *Repository) anonymise(ctx context.Context, ids ...*string) error {
+	var lookup []string
+	for _, id := range ids {
+		if *id != "" && *id != privacy.AnonymousUserID {
+			lookup = append(lookup, *id)
+		}
+	}
+
+	optedOut, err := r.privacy.OptedOut(ctx, lookup...)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if optedOut[*id] {
+			*id = privacy.AnonymousUserID
+		}
+	}
+	return nil
+}
+
+func userIDs(counts []UserCount) []*string {
+	ids := make([]*string, len(counts))
+	for i := range counts {
+		ids[i] = &counts[i].UserID
+	}
+	return ids
+}
+
+// useRollups reports whether a date range can be answered from the daily rollups, which is when it is unbounded or
+// starts and ends on UTC day boundaries. The rollups include pruned reactions, so both paths give identical results
+func (r *Repository) useRollups(dateRange DateRange) bool {
+	return r.rollups && isDayBoundary(dateRange.Start) && isDayBoundary(dateRange.End)
+}
+
+func isDayBoundary(t *time.Time) bool {
+	if t == nil {
+		return true
+	}
+	utc := t.UTC()
+	return utc.Equal(utc.Truncate(24 * time.Hour))
+}
+
+// rollupsFor reports whether a guild's stats for a date range can be answered from the daily rollups. The rollups are
+// not split by channel, so cannot exclude the channels a guild ignores
+func (r *Repository) rollupsFor(ctx context.Context, guildID string, dateRange DateRange) (bool, error) {
+	if !r.useRollups(dateRange) {
+		return false, nil
+	}
+
+	var ignoring bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM ignored_channels WHERE guild_id = $1)`, guildID).Scan(&ignoring)
+	return !ignoring, err
+}
+
+// appendRangeFilter filters a query by date range, on the day column of the rollups or the created_at column of the
+// raw reactions. Queries on the raw reactions also exclude the ignored channels
+func appendRangeFilter(query string, args []any, dateRange DateRange, rollups bool) (string, []any) {
+	if !rollups {
+		return appendFilters(query, args, dateRange)
+	}
+
+	if dateRange.Start != nil {
+		args = append(args, dateRange.Start.UTC().Format(time.DateOnly))
+		query += ` AND day >= $` + argNum(len(args)) + `::date`
+	}
+	if dateRange.End != nil {
+		args = append(args, dateRange.End.UTC().Format(time.DateOnly))
+		query += ` AND day < $` + argNum(len(args)) + `::date`
+	}
+	return query, args
+}
+
+// appendFilters excludes the ignored channels from a query on the raw reactions and filters it by date range
+func appendFilters(query string, args []any, dateRange DateRange) (string, []any) {
+	return appendDateFilter(query+notIgnored, args, dateRange)
+}
+
 func appendDateFilter(query string, args []any, dateRange DateRange) (string, []any) {
 	if dateRange.Start != nil {
 		args = append(args, *dateRange.Start)
@@ -0,0 +1,58 @@
+package stats
+
+import "slices"
+
+const (
+	// DefaultLeaderboardSize is how many entries the leaderboards show unless a guild configures otherwise
+	DefaultLeaderboardSize = 10
+	MinLeaderboardSize     = 1
+	// MaxLeaderboardSize keeps the longest leaderboard, the hall of fame, within Discord's message length limit
+	MaxLeaderboardSize = 15
+)
+
+// Settings are a guild's preferences for the stats commands and which reactions are tracked. The guild's timezone is
+// stored separately, as the stats queries depend on it
+type Settings struct {
+	// PublicResponses makes the stats commands respond publicly when their public option is not given
+	PublicResponses bool
+	// LeaderboardSize is how many entries the leaderboards of the stats commands show
+	LeaderboardSize int
+	// CountBots tracks reactions made by bots
+	CountBots bool
+	// IgnoredChannels are the channels whose reactions, including those in their threads, are not tracked or counted
+	IgnoredChannels []string
+	// IgnoredCategories are the categories whose channels' reactions are not tracked or counted
+	IgnoredCategories []string
+	// IgnoredRoles are the roles whose members' reactions are not tracked
+	IgnoredRoles []string
+}
+
+// DefaultSettings returns the settings of a guild which has not configured any
+func DefaultSettings() Settings {
+	return Settings{
+		LeaderboardSize: DefaultLeaderboardSize,
+		CountBots:       true,
+	}
+}
+
+// IgnoringChannel returns the ignored channel or category which reactions in a channel are not tracked because of, if
+// any. parentIDs are the channel's parents, nearest first: the category it is in, or for a thread its channel and that
+// channel's category
+func (s Settings) IgnoringChannel(channelID string, parentIDs ...string) (string, bool) {
+	if slices.Contains(s.IgnoredChannels, channelID) {
+		return channelID, true
+	}
+
+	for _, id := range parentIDs {
+		if slices.Contains(s.IgnoredChannels, id) || slices.Contains(s.IgnoredCategories, id) {
+			return id, true
+		}
+	}
+
+	return "", false
+}
+
+// IgnoresRoles returns whether reactions by a member with the given roles are not tracked
+func (s Settings) IgnoresRoles(roleIDs []string) bool {
+	return slices.ContainsFunc(roleIDs, func(id string) bool { return slices.Contains(s.IgnoredRoles, id) })
+}
@@ -0,0 +1,40 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettings_IgnoringChannel(t *testing.T) {
+	s := Settings{IgnoredChannels: []string{"chan1"}, IgnoredCategories: []string{"cat1"}}
+
+	tests := map[string]struct {
+		channelID string
+		parentIDs []string
+		ignoring  string
+	}{
+		"ignored channel":             {channelID: "chan1", ignoring: "chan1"},
+		"channel in ignored category": {channelID: "chan2", parentIDs: []string{"cat1"}, ignoring: "cat1"},
+		"thread in ignored channel":   {channelID: "thread1", parentIDs: []string{"chan1", "cat2"}, ignoring: "chan1"},
+		"thread in ignored category":  {channelID: "thread1", parentIDs: []string{"chan2", "cat1"}, ignoring: "cat1"},
+		"tracked channel":             {channelID: "chan2", parentIDs: []string{"cat2"}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			id, ok := s.IgnoringChannel(tt.channelID, tt.parentIDs...)
+			assert.Equal(t, tt.ignoring != "", ok)
+			assert.Equal(t, tt.ignoring, id)
+		})
+	}
+}
+
+func TestSettings_IgnoresRoles(t *testing.T) {
+	s := Settings{IgnoredRoles: []string{"role1"}}
+
+	assert.True(t, s.IgnoresRoles([]string{"role2", "role1"}))
+	assert.False(t, s.IgnoresRoles([]string{"role2"}))
+	assert.False(t, s.IgnoresRoles(nil))
+	assert.False(t, DefaultSettings().IgnoresRoles([]string{"role1"}))
+}
@@ -0,0 +1,701 @@
+// Package storetest is a conformance suite for implementations of stats.Store, so that every backend gives identical
+// results for the same reactions, including how they filter by date, order ties and apply limits
+package storetest
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// Backend creates the stores under test
+type Backend struct {
+	// NewStore returns a store for a test. Tests only use guildID, which is unique to the test, so stores may share a
+	// database if they clean up the guild afterwards
+	NewStore func(t *testing.T, guildID string) stats.Store
+}
+
+var guildSeq atomic.Int64
+
+// Run runs the conformance suite against a backend
+func Run(t *testing.T, b Backend) {
+	tests := map[string]func(*testing.T, *fixture){
+		"GuildStats_Empty":          testGuildStatsEmpty,
+		"GuildStats":                testGuildStats,
+		"GuildStats_DateRange":      testGuildStatsDateRange,
+		"GuildStats_Ties":           testGuildStatsTies,
+		"GuildStats_OptedOut":       testGuildStatsOptedOut,
+		"OptIn":                     testOptIn,
+		"Forget":                    testForget,
+		"GuildIsolation":            testGuildIsolation,
+		"TopEmojis_Limit":           testTopEmojisLimit,
+		"TopEmojis_IsDefault":       testTopEmojisIsDefault,
+		"TopChannels":               testTopChannels,
+		"EmojiStats_Empty":          testEmojiStatsEmpty,
+		"EmojiStats":                testEmojiStats,
+		"TopMessages":               testTopMessages,
+		"TopMessages_ChannelFilter": testTopMessagesChannelFilter,
+		"TopMessages_DateRange":     testTopMessagesDateRange,
+		"RemoveReaction":            testRemoveReaction,
+		"Wrapped_User":              testWrappedUser,
+		"Wrapped_Guild":             testWrappedGuild,
+		"Wrapped_Empty":             testWrappedEmpty,
+		"Wrapped_Timezone":          testWrappedTimezone,
+		"Streak":                    testStreak,
+		"Streak_None":               testStreakNone,
+		"Streaks":                   testStreaks,
+		"Streak_Timezone":           testStreakTimezone,
+		"Settings":                  testSettings,
+		"IgnoredChannels":           testIgnoredChannels,
+		"IgnoredChannels_Moved":     testIgnoredChannelsMoved,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			guildID := "conformance-" + strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + strconv.FormatInt(guildSeq.Add(1), 10)
+			test(t, &fixture{
+				t:       t,
+				ctx:     context.Background(),
+				store:   b.NewStore(t, guildID),
+				backend: b,
+				guildID: guildID,
+			})
+		})
+	}
+}
+
+type fixture struct {
+	t       *testing.T
+	ctx     context.Context
+	store   stats.Store
+	backend Backend
+	guildID string
+}
+
+func (f *fixture) add(emojiID, senderID, receiverID, channelID, messageID string, createdAt time.Time) {
+	f.addTo(f.guildID, emojiID, senderID, receiverID, channelID, messageID, createdAt)
+}
+
+func (f *fixture) addTo(guildID, emojiID, senderID, receiverID, channelID, messageID string, createdAt time.Time) {
+	f.t.Helper()
+	require.NoError(f.t, f.store.AddReaction(f.ctx, stats.Reaction{
+		GuildID:        guildID,
+		ChannelID:      channelID,
+		MessageID:      messageID,
+		EmojiID:        emojiID,
+		IsDefault:      emojiID[0] != '<',
+		SenderUserID:   senderID,
+		ReceiverUserID: receiverID,
+		CreatedAt:      createdAt,
+	}))
+}
+
+// setParent records a channel's parent and asserts whether that changed the recorded parent
+func (f *fixture) setParent(channelID, parentID string, changed bool) {
+	f.t.Helper()
+	ok, err := f.store.SetChannelParent(f.ctx, f.guildID, channelID, parentID)
+	require.NoError(f.t, err)
+	assert.Equal(f.t, changed, ok, "%s parent %q changed", channelID, parentID)
+}
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2024, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func between(start, end time.Time) stats.DateRange {
+	return stats.DateRange{Start: &start, End: &end}
+}
+
+func testGuildStatsEmpty(t *testing.T, f *fixture) {
+	result, err := f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+
+	require.NoError(t, err)
+	assert.Zero(t, result.TotalReactions)
+	assert.Empty(t, result.TopEmojis)
+	assert.Empty(t, result.TopSenders)
+	assert.Empty(t, result.TopReceivers)
+}
+
+func testGuildStats(t *testing.T, f *fixture) {
+	f.add("👍", "sender1", "receiver1", "chan1", "msg1", date(6, 1))
+	f.add("👍", "sender1", "receiver2", "chan1", "msg2", date(6, 1))
+	f.add("👍", "sender2", "receiver1", "chan1", "msg3", date(6, 1))
+	f.add("<:custom:1>", "sender1", "receiver1", "chan1", "msg4", date(6, 1))
+
+	result, err := f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.TotalReactions)
+	assert.Equal(t, []stats.EmojiCount{
+		{EmojiID: "👍", IsDefault: true, Count: 3},
+		{EmojiID: "<:custom:1>", IsDefault: false, Count: 1},
+	}, result.TopEmojis)
+	assert.Equal(t, []stats.UserCount{{UserID: "sender1", Count: 3}, {UserID: "sender2", Count: 1}}, result.TopSenders)
+	assert.Equal(t, []stats.UserCount{{UserID: "receiver1", Count: 3}, {UserID: "receiver2", Count: 1}}, result.TopReceivers)
+}
+
+// testGuildStatsDateRange checks that the start of a range is inclusive and the end exclusive, including on ranges
+// which do not fall on day boundaries
+func testGuildStatsDateRange(t *testing.T, f *fixture) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	f.add("👍", "sender1", "receiver1", "chan1", "msg1", start.Add(-time.Second))
+	f.add("👍", "sender1", "receiver1", "chan1", "msg2", start)
+	f.add("👍", "sender1", "receiver1", "chan1", "msg3", end.Add(-time.Second))
+	f.add("👍", "sender1", "receiver1", "chan1", "msg4", end)
+
+	for name, tc := range map[string]struct {
+		dateRange stats.DateRange
+		expected  int
+	}{
+		"unbounded":    {stats.DateRange{}, 4},
+		"whole days":   {between(start, end), 2},
+		"start only":   {stats.DateRange{Start: &start}, 3},
+		"end only":     {stats.DateRange{End: &end}, 3},
+		"partial days": {between(start.Add(time.Second), end.Add(time.Second)), 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := f.store.GetGuildStats(f.ctx, f.guildID, tc.dateRange, 10)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result.TotalReactions)
+		})
+	}
+}
+
+// testGuildStatsTies checks that equal counts are ordered by ID, so that the limit cuts off the same entries every time.
+// IDs are chosen to sort the same under any collation
+func testGuildStatsTies(t *testing.T, f *fixture) {
+	f.add("<:b:2>", "user-c", "user-b", "chan1", "msg1", date(6, 1))
+	f.add("<:a:1>", "user-b", "user-c", "chan1", "msg2", date(6, 1))
+	f.add("<:c:3>", "user-a", "user-a", "chan1", "msg3", date(6, 1))
+	f.add("<:d:4>", "user-d", "user-d", "chan1", "msg4", date(6, 1))
+
+	result, err := f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 3)
+
+	require.NoError(t, err)
+	assert.Equal(t, []stats.EmojiCount{
+		{EmojiID: "<:a:1>", Count: 1},
+		{EmojiID: "<:b:2>", Count: 1},
+		{EmojiID: "<:c:3>", Count: 1},
+	}, result.TopEmojis)
+	assert.Equal(t, []stats.UserCount{{UserID: "user-a", Count: 1}, {UserID: "user-b", Count: 1}, {UserID: "user-c", Count: 1}}, result.TopSenders)
+	assert.Equal(t, []stats.UserCount{{UserID: "user-a", Count: 1}, {UserID: "user-b", Count: 1}, {UserID: "user-c", Count: 1}}, result.TopReceivers)
+}
+
+func testGuildStatsOptedOut(t *testing.T, f *fixture) {
+	optedOutID := "opted-out-" + f.guildID
+	require.NoError(t, f.store.OptOut(f.ctx, f.guildID, optedOutID))
+
+	f.add("👍", optedOutID, "receiver1", "chan1", "msg1", date(6, 1))
+	f.add("👍", optedOutID, "receiver1", "chan1", "msg2", date(6, 1))
+	f.add("👍", "sender1", optedOutID, "chan1", "msg3", date(6, 1))
+
+	result, err := f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, []stats.UserCount{{UserID: privacy.AnonymousUserID, Count: 2}, {UserID: "sender1", Count: 1}}, result.TopSenders)
+	assert.Equal(t, []stats.UserCount{{UserID: "receiver1", Count: 2}, {UserID: privacy.AnonymousUserID, Count: 1}}, result.TopReceivers)
+}
+
+func testOptIn(t *testing.T, f *fixture) {
+	userID := "opted-in-" + f.guildID
+	require.NoError(t, f.store.OptOut(f.ctx, f.guildID, userID))
+	require.NoError(t, f.store.OptIn(f.ctx, f.guildID, userID))
+
+	f.add("👍", userID, "receiver1", "chan1", "msg1", date(6, 1))
+
+	result, err := f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, []stats.UserCount{{UserID: userID, Count: 1}}, result.TopSenders)
+}
+
+func testForget(t *testing.T, f *fixture) {
+	userID := "forgotten-" + f.guildID
+	f.add("👍", userID, "receiver1", "chan1", "msg1", date(6, 1))
+	f.add("👍", userID, "receiver1", "chan1", "msg2", date(6, 1))
+	f.add("👍", "sender1", userID, "chan1", "msg3", date(6, 1))
+
+	forgotten, err := f.store.Forget(f.ctx, f.guildID, userID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, forgotten.Deleted)
+	assert.Equal(t, 1, forgotten.Anonymised)
+
+	optedOut, err := f.store.OptedOut(f.ctx, userID)
+	require.NoError(t, err)
+	assert.True(t, optedOut[userID], "forgotten users should be opted out")
+
+	result, err := f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.TotalReactions)
+	assert.Equal(t, []stats.UserCount{{UserID: "sender1", Count: 1}}, result.TopSenders)
+	assert.Equal(t, []stats.UserCount{{UserID: privacy.AnonymousUserID, Count: 1}}, result.TopReceivers)
+}
+
+func testGuildIsolation(t *testing.T, f *fixture) {
+	otherGuildID := f.guildID + "-other"
+	f.add("👍", "sender1", "receiver1", "chan1", "msg1", date(6, 1))
+	f.addTo(otherGuildID, "👍", "sender1", "receiver1", "chan1", "msg1", date(6, 1))
+	f.addTo(otherGuildID, "👍", "sender2", "receiver1", "chan1", "msg1", date(6, 1))
+	t.Cleanup(func() {
+		for _, sender := range []string{"sender1", "sender2"} {
+			_, _ = f.store.RemoveReaction(f.ctx, otherGuildID, "msg1", "👍", sender)
+		}
+	})
+
+	result, err := f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.TotalReactions)
+
+	messages, err := f.store.GetTopMessages(f.ctx, f.guildID, "", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, 1, messages[0].TotalReactions)
+}
+
+func testTopEmojisLimit(t *testing.T, f *fixture) {
+	for i, emoji := range []string{"<:a:1>", "<:b:2>", "<:c:3>"} {
+		for j := 0; j <= i; j++ {
+			f.add(emoji, "sender"+strconv.Itoa(j), "receiver1", "chan1", "msg1", date(6, 1))
+		}
+	}
+
+	emojis, err := f.store.GetTopEmojis(f.ctx, f.guildID, stats.DateRange{}, 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, []stats.EmojiCount{{EmojiID: "<:c:3>", Count: 3}, {EmojiID: "<:b:2>", Count: 2}}, emojis)
+}
+
+// testTopEmojisIsDefault checks that an emoji is counted separately for each value of IsDefault
+func testTopEmojisIsDefault(t *testing.T, f *fixture) {
+	require.NoError(t, f.store.AddReaction(f.ctx, stats.Reaction{
+		GuildID: f.guildID, ChannelID: "chan1", MessageID: "msg1", EmojiID: "👍", IsDefault: false,
+		SenderUserID: "sender1", ReceiverUserID: "receiver1", CreatedAt: date(6, 1),
+	}))
+	f.add("👍", "sender2", "receiver1", "chan1", "msg1", date(6, 1))
+
+	emojis, err := f.store.GetTopEmojis(f.ctx, f.guildID, stats.DateRange{}, 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, []stats.EmojiCount{{EmojiID: "👍", IsDefault: false, Count: 1}, {EmojiID: "👍", IsDefault: true, Count: 1}}, emojis)
+
+	emoji, err := f.store.GetEmojiStats(f.ctx, f.guildID, "👍", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, emoji.TotalUses)
+	assert.True(t, emoji.IsDefault)
+}
+
+func testTopChannels(t *testing.T, f *fixture) {
+	f.add("👍", "sender1", "receiver1", "chan-b", "msg1", date(6, 1))
+	f.add("👍", "sender1", "receiver1", "chan-b", "msg2", date(6, 1))
+	f.add("👍", "sender1", "receiver1", "chan-c", "msg3", date(6, 1))
+	f.add("👍", "sender1", "receiver1", "chan-a", "msg4", date(6, 1))
+	f.add("👍", "sender1", "receiver1", "chan-d", "msg5", date(5, 1))
+
+	channels, err := f.store.GetTopChannels(f.ctx, f.guildID, between(date(6, 1), date(7, 1)), 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, []stats.ChannelCount{{ChannelID: "chan-b", Count: 2}, {ChannelID: "chan-a", Count: 1}}, channels)
+}
+
+func testEmojiStatsEmpty(t *testing.T, f *fixture) {
+	result, err := f.store.GetEmojiStats(f.ctx, f.guildID, "👍", stats.DateRange{}, 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, "👍", result.EmojiID)
+	assert.Zero(t, result.TotalUses)
+	assert.False(t, result.IsDefault)
+	assert.Empty(t, result.TopMessages)
+	assert.Empty(t, result.TopSenders)
+	assert.Empty(t, result.TopReceivers)
+}
+
+func testEmojiStats(t *testing.T, f *fixture) {
+	f.add("<:custom:1>", "sender1", "receiver1", "chan1", "msg-b", date(6, 1))
+	f.add("<:custom:1>", "sender2", "receiver1", "chan1", "msg-b", date(6, 1))
+	f.add("<:custom:1>", "sender1", "receiver2", "chan2", "msg-c", date(6, 1))
+	f.add("<:custom:1>", "sender1", "receiver2", "chan2", "msg-a", date(6, 1))
+	f.add("<:custom:1>", "sender1", "receiver2", "chan2", "msg-d", date(1, 1))
+	f.add("👍", "sender2", "receiver1", "chan1", "msg-b", date(6, 1))
+
+	result, err := f.store.GetEmojiStats(f.ctx, f.guildID, "<:custom:1>", between(date(6, 1), date(7, 1)), 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.TotalUses)
+	assert.False(t, result.IsDefault)
+	assert.Equal(t, []stats.MessageCount{
+		{MessageID: "msg-b", ChannelID: "chan1", Count: 2},
+		{MessageID: "msg-a", ChannelID: "chan2", Count: 1},
+		{MessageID: "msg-c", ChannelID: "chan2", Count: 1},
+	}, result.TopMessages)
+	assert.Equal(t, []stats.UserCount{{UserID: "sender1", Count: 3}, {UserID: "sender2", Count: 1}}, result.TopSenders)
+	assert.Equal(t, []stats.UserCount{{UserID: "receiver1", Count: 2}, {UserID: "receiver2", Count: 2}}, result.TopReceivers)
+}
+
+func testTopMessages(t *testing.T, f *fixture) {
+	f.add("👍", "sender1", "author1", "chan1", "msg1", date(6, 1))
+	f.add("<:a:1>", "sender1", "author1", "chan1", "msg1", date(6, 1))
+	f.add("👍", "sender2", "author1", "chan1", "msg1", date(6, 1))
+	// msg2 and msg3 tie on reactions, but msg3 has more reactors
+	f.add("<:b:2>", "sender1", "author2", "chan2", "msg2", date(6, 1))
+	f.add("<:a:1>", "sender1", "author2", "chan2", "msg2", date(6, 1))
+	f.add("👍", "sender1", "author3", "chan2", "msg3", date(6, 1))
+	f.add("👍", "sender2", "author3", "chan2", "msg3", date(6, 1))
+	f.add("👍", "sender1", "author4", "chan2", "msg4", date(6, 1))
+
+	messages, err := f.store.GetTopMessages(f.ctx, f.guildID, "", stats.DateRange{}, 3)
+
+	require.NoError(t, err)
+	assert.Equal(t, []stats.TopMessage{
+		{
+			MessageID: "msg1", ChannelID: "chan1", AuthorID: "author1", TotalReactions: 3, UniqueReactors: 2,
+			TopEmojis: []stats.EmojiCount{{EmojiID: "👍", IsDefault: true, Count: 2}, {EmojiID: "<:a:1>", Count: 1}},
+		},
+		{
+			MessageID: "msg3", ChannelID: "chan2", AuthorID: "author3", TotalReactions: 2, UniqueReactors: 2,
+			TopEmojis: []stats.EmojiCount{{EmojiID: "👍", IsDefault: true, Count: 2}},
+		},
+		{
+			MessageID: "msg2", ChannelID: "chan2", AuthorID: "author2", TotalReactions: 2, UniqueReactors: 1,
+			TopEmojis: []stats.EmojiCount{{EmojiID: "<:a:1>", Count: 1}, {EmojiID: "<:b:2>", Count: 1}},
+		},
+	}, messages)
+}
+
+func testTopMessagesChannelFilter(t *testing.T, f *fixture) {
+	f.add("👍", "sender1", "author1", "chan1", "msg1", date(6, 1))
+	f.add("👍", "sender2", "author1", "chan1", "msg1", date(6, 1))
+	f.add("👍", "sender1", "author2", "chan2", "msg2", date(6, 1))
+
+	messages, err := f.store.GetTopMessages(f.ctx, f.guildID, "chan2", stats.DateRange{}, 10)
+
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "msg2", messages[0].MessageID)
+}
+
+func testTopMessagesDateRange(t *testing.T, f *fixture) {
+	f.add("👍", "sender1", "author1", "chan1", "msg1", date(1, 1))
+	f.add("👍", "sender2", "author1", "chan1", "msg1", date(1, 1))
+	f.add("<:a:1>", "sender3", "author1", "chan1", "msg1", date(6, 15))
+	f.add("👍", "sender1", "author2", "chan1", "msg2", date(6, 15))
+	f.add("👍", "sender2", "author2", "chan1", "msg2", date(6, 15))
+
+	start := date(6, 1)
+	messages, err := f.store.GetTopMessages(f.ctx, f.guildID, "", stats.DateRange{Start: &start}, 10)
+
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "msg2", messages[0].MessageID)
+	assert.Equal(t, "msg1", messages[1].MessageID)
+	assert.Equal(t, 1, messages[1].TotalReactions)
+	assert.Equal(t, []stats.EmojiCount{{EmojiID: "<:a:1>", Count: 1}}, messages[1].TopEmojis, "emojis outside the range should not be counted")
+}
+
+func testRemoveReaction(t *testing.T, f *fixture) {
+	f.add("👍", "sender1", "receiver1", "chan1", "msg1", date(6, 1))
+	f.add("👍", "sender2", "receiver1", "chan1", "msg1", date(6, 1))
+	f.add("<:a:1>", "sender1", "receiver1", "chan1", "msg1", date(6, 1))
+
+	n, err := f.store.RemoveReaction(f.ctx, f.guildID, "msg1", "👍", "sender1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = f.store.RemoveReaction(f.ctx, f.guildID, "msg1", "👍", "sender1")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	n, err = f.store.RemoveReaction(f.ctx, f.guildID+"-other", "msg1", "👍", "sender2")
+	require.NoError(t, err)
+	assert.Zero(t, n, "reactions in other guilds should not be removed")
+
+	result, err := f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.TotalReactions)
+}
+
+func testWrappedUser(t *testing.T, f *fixture) {
+	f.add("👍", "user1", "user2", "chan1", "msg1", date(3, 10))
+	f.add("👍", "user1", "user2", "chan1", "msg2", date(6, 10))
+	f.add("👍", "user1", "user3", "chan1", "msg3", date(6, 10))
+	f.add("<:a:1>", "user1", "user2", "chan1", "msg1", date(6, 10))
+	f.add("<:a:1>", "user2", "user1", "chan1", "msg4", date(6, 10))
+	f.add("<:a:1>", "user3", "user1", "chan1", "msg4", date(6, 11))
+	f.add("<:a:1>", "user3", "user1", "chan1", "msg5", date(6, 12))
+	f.add("<:a:1>", "user1", "user1", "chan1", "msg5", date(6, 12))
+	f.add("👍", "user1", "user2", "chan1", "msg6", time.Date(2023, 6, 10, 0, 0, 0, 0, time.UTC))
+
+	w, err := f.store.GetWrapped(f.ctx, f.guildID, "user1", 2024)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2024, w.Year)
+	assert.Equal(t, "user1", w.UserID)
+	assert.Equal(t, 5, w.TotalReactions)
+	assert.Equal(t, 4, w.TotalReceived)
+	assert.Equal(t, &stats.EmojiCount{EmojiID: "👍", IsDefault: true, Count: 3}, w.FavouriteEmoji)
+	assert.Equal(t, [12]int{0, 0, 1, 0, 0, 4, 0, 0, 0, 0, 0, 0}, w.MonthlyCounts)
+	assert.Equal(t, &stats.UserCount{UserID: "user3", Count: 2}, w.TopFan, "self reactions should not count towards the top fan")
+	require.NotNil(t, w.TopMessage)
+	assert.Equal(t, "msg4", w.TopMessage.MessageID)
+	assert.Equal(t, stats.Rank{Position: 1, Total: 3}, w.SenderRank)
+	assert.Equal(t, stats.Rank{Position: 1, Total: 3}, w.ReceiverRank)
+	assert.Equal(t, stats.Streak{UserID: "user1", Current: 0, Longest: 3}, w.Streak)
+}
+
+func testWrappedGuild(t *testing.T, f *fixture) {
+	f.add("👍", "user1", "user2", "chan1", "msg1", date(6, 10))
+	f.add("👍", "user1", "user2", "chan1", "msg1", date(6, 10))
+	f.add("<:a:1>", "user2", "user1", "chan1", "msg2", date(7, 10))
+
+	w, err := f.store.GetWrapped(f.ctx, f.guildID, "", 2024)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, w.TotalReactions)
+	assert.Zero(t, w.TotalReceived)
+	assert.Equal(t, &stats.EmojiCount{EmojiID: "👍", IsDefault: true, Count: 2}, w.FavouriteEmoji)
+	assert.Equal(t, [12]int{0, 0, 0, 0, 0, 2, 1, 0, 0, 0, 0, 0}, w.MonthlyCounts)
+	assert.Equal(t, &stats.UserCount{UserID: "user1", Count: 2}, w.TopFan)
+	require.NotNil(t, w.TopMessage)
+	assert.Equal(t, "msg1", w.TopMessage.MessageID)
+	assert.Equal(t, stats.Streak{}, w.Streak)
+}
+
+func testWrappedTimezone(t *testing.T, f *fixture) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	require.NoError(t, f.store.SetTimezone(f.ctx, f.guildID, ny))
+
+	// in New York these fall on 31 December 2023, 29 February 2024 and 31 December 2024
+	f.add("👍", "user1", "user2", "chan1", "msg1", time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC))
+	f.add("👍", "user1", "user2", "chan1", "msg2", time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC))
+	f.add("👍", "user1", "user2", "chan1", "msg3", time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC))
+
+	w, err := f.store.GetWrapped(f.ctx, f.guildID, "", 2024)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, w.TotalReactions)
+	assert.Equal(t, [12]int{0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, w.MonthlyCounts)
+}
+
+func testWrappedEmpty(t *testing.T, f *fixture) {
+	w, err := f.store.GetWrapped(f.ctx, f.guildID, "user1", 2024)
+
+	require.NoError(t, err)
+	assert.Zero(t, w.TotalReactions)
+	assert.Nil(t, w.FavouriteEmoji)
+	assert.Nil(t, w.TopFan)
+	assert.Nil(t, w.TopMessage)
+	assert.Equal(t, stats.Rank{Position: 1, Total: 1}, w.SenderRank)
+	assert.Equal(t, stats.Rank{Position: 1, Total: 1}, w.ReceiverRank)
+}
+
+func testStreak(t *testing.T, f *fixture) {
+	today := time.Now().UTC().Truncate(24 * time.Hour).Add(12 * time.Hour)
+	day := func(n int) time.Time { return today.AddDate(0, 0, -n) }
+
+	// a five day run which ended a week ago, then a run of three days ending yesterday, partly from received reactions
+	for n := 12; n >= 8; n-- {
+		f.add("👍", "user1", "user2", "chan1", "msg1", day(n))
+	}
+	f.add("👍", "user1", "user2", "chan1", "msg2", day(3))
+	f.add("👍", "user2", "user1", "chan1", "msg3", day(2))
+	f.add("👍", "user1", "user2", "chan1", "msg4", day(1))
+	f.add("👍", "user1", "user2", "chan1", "msg4", day(1))
+
+	streak, err := f.store.GetStreak(f.ctx, f.guildID, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, stats.Streak{UserID: "user1", Current: 3, Longest: 5}, streak)
+
+	streak, err = f.store.GetStreak(f.ctx, f.guildID, "user3")
+	require.NoError(t, err)
+	assert.Equal(t, stats.Streak{UserID: "user3"}, streak)
+}
+
+func testStreakNone(t *testing.T, f *fixture) {
+	f.add("👍", "user1", "user2", "chan1", "msg1", time.Now().AddDate(0, 0, -5))
+
+	streak, err := f.store.GetStreak(f.ctx, f.guildID, "user1")
+
+	require.NoError(t, err)
+	assert.Equal(t, stats.Streak{UserID: "user1", Current: 0, Longest: 1}, streak)
+}
+
+func testStreaks(t *testing.T, f *fixture) {
+	now := time.Now()
+	f.add("👍", "user1", "user3", "chan1", "msg1", now)
+	f.add("👍", "user1", "user3", "chan1", "msg2", now.AddDate(0, 0, -1))
+	f.add("👍", "user2", "user3", "chan1", "msg3", now.AddDate(0, 0, -20))
+	f.add("👍", "user2", "user3", "chan1", "msg4", now.AddDate(0, 0, -21))
+	f.add("👍", "user2", "user3", "chan1", "msg5", now.AddDate(0, 0, -22))
+	f.add("👍", "user4", "user5", "chan1", "msg6", now.AddDate(0, 0, -30))
+
+	current, err := f.store.GetStreaks(f.ctx, f.guildID, false, 3)
+	require.NoError(t, err)
+	// user1 and user3 share a current streak, so user3's longer longest streak ranks them first
+	assert.Equal(t, []stats.Streak{
+		{UserID: "user3", Current: 2, Longest: 3},
+		{UserID: "user1", Current: 2, Longest: 2},
+		{UserID: "user2", Current: 0, Longest: 3},
+	}, current)
+
+	longest, err := f.store.GetStreaks(f.ctx, f.guildID, true, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []stats.Streak{
+		{UserID: "user3", Current: 2, Longest: 3},
+		{UserID: "user2", Current: 0, Longest: 3},
+		{UserID: "user1", Current: 2, Longest: 2},
+		{UserID: "user4", Current: 0, Longest: 1},
+		{UserID: "user5", Current: 0, Longest: 1},
+	}, longest)
+}
+
+func testStreakTimezone(t *testing.T, f *fixture) {
+	loc, err := f.store.GetTimezone(f.ctx, f.guildID)
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", loc.String())
+
+	// 01:00 and 23:00 UTC on the same day fall on consecutive days in New York
+	day := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	f.add("👍", "user1", "user2", "chan1", "msg1", day.Add(time.Hour))
+	f.add("👍", "user1", "user2", "chan1", "msg2", day.Add(23*time.Hour))
+
+	streak, err := f.store.GetStreak(f.ctx, f.guildID, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, streak.Longest)
+
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	require.NoError(t, f.store.SetTimezone(f.ctx, f.guildID, ny))
+
+	loc, err = f.store.GetTimezone(f.ctx, f.guildID)
+	require.NoError(t, err)
+	assert.Equal(t, "America/New_York", loc.String())
+
+	streak, err = f.store.GetStreak(f.ctx, f.guildID, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, streak.Longest)
+}
+
+func testSettings(t *testing.T, f *fixture) {
+	settings, err := f.store.GetSettings(f.ctx, f.guildID)
+	require.NoError(t, err)
+	assert.Equal(t, stats.DefaultSettings(), settings)
+
+	want := stats.Settings{
+		PublicResponses:   true,
+		LeaderboardSize:   5,
+		CountBots:         false,
+		IgnoredChannels:   []string{"chan1", "chan2"},
+		IgnoredCategories: []string{"cat1"},
+		IgnoredRoles:      []string{"role1"},
+	}
+	require.NoError(t, f.store.SetSettings(f.ctx, f.guildID, want))
+
+	settings, err = f.store.GetSettings(f.ctx, f.guildID)
+	require.NoError(t, err)
+	assert.Equal(t, want, settings)
+
+	other, err := f.store.GetSettings(f.ctx, f.guildID+"-other")
+	require.NoError(t, err)
+	assert.Equal(t, stats.DefaultSettings(), other)
+
+	require.NoError(t, f.store.SetSettings(f.ctx, f.guildID, stats.DefaultSettings()))
+
+	settings, err = f.store.GetSettings(f.ctx, f.guildID)
+	require.NoError(t, err)
+	assert.False(t, settings.PublicResponses)
+	assert.Equal(t, stats.DefaultLeaderboardSize, settings.LeaderboardSize)
+	assert.True(t, settings.CountBots)
+	assert.Empty(t, settings.IgnoredChannels)
+	assert.Empty(t, settings.IgnoredCategories)
+	assert.Empty(t, settings.IgnoredRoles)
+}
+
+func testIgnoredChannels(t *testing.T, f *fixture) {
+	f.add("👍", "sender1", "receiver1", "chan1", "msg1", date(6, 1))
+	f.add("❤️", "sender2", "receiver2", "bots", "msg2", date(6, 1))
+	f.add("🎉", "sender3", "receiver3", "thread1", "msg3", date(6, 2))
+	f.setParent("thread1", "chan2", true)
+	f.setParent("chan2", "cat1", true)
+	// recording the same parent again is a no-op
+	f.setParent("chan2", "cat1", false)
+
+	settings := stats.DefaultSettings()
+	settings.IgnoredChannels = []string{"bots"}
+	settings.IgnoredCategories = []string{"cat1"}
+	require.NoError(t, f.store.SetSettings(f.ctx, f.guildID, settings))
+
+	result, err := f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.TotalReactions)
+	assert.Equal(t, []stats.EmojiCount{{EmojiID: "👍", IsDefault: true, Count: 1}}, result.TopEmojis)
+	assert.Equal(t, []stats.UserCount{{UserID: "sender1", Count: 1}}, result.TopSenders)
+
+	ranged, err := f.store.GetGuildStats(f.ctx, f.guildID, between(date(6, 1), date(7, 1)), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, ranged.TotalReactions)
+
+	channels, err := f.store.GetTopChannels(f.ctx, f.guildID, stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []stats.ChannelCount{{ChannelID: "chan1", Count: 1}}, channels)
+
+	messages, err := f.store.GetTopMessages(f.ctx, f.guildID, "", stats.DateRange{}, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "msg1", messages[0].MessageID)
+
+	streaks, err := f.store.GetStreaks(f.ctx, f.guildID, true, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []stats.Streak{
+		{UserID: "receiver1", Current: 0, Longest: 1},
+		{UserID: "sender1", Current: 0, Longest: 1},
+	}, streaks)
+
+	// the reactions are kept, so are counted again once the channels are no longer ignored
+	require.NoError(t, f.store.SetSettings(f.ctx, f.guildID, stats.DefaultSettings()))
+
+	result, err = f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.TotalReactions)
+}
+
+func testIgnoredChannelsMoved(t *testing.T, f *fixture) {
+	f.add("👍", "sender1", "receiver1", "chan1", "msg1", date(6, 1))
+	f.add("👍", "sender1", "receiver1", "thread1", "msg2", date(6, 1))
+	f.setParent("thread1", "chan1", true)
+	f.setParent("chan1", "cat1", true)
+
+	settings := stats.DefaultSettings()
+	settings.IgnoredCategories = []string{"cat1"}
+	require.NoError(t, f.store.SetSettings(f.ctx, f.guildID, settings))
+
+	result, err := f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Zero(t, result.TotalReactions)
+
+	// moving the channel out of the ignored category counts it and its threads again
+	f.setParent("chan1", "cat2", true)
+
+	result, err = f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.TotalReactions)
+
+	f.setParent("chan1", "cat1", true)
+	f.setParent("chan1", "", true)
+	f.setParent("chan1", "", false)
+
+	result, err = f.store.GetGuildStats(f.ctx, f.guildID, stats.DateRange{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.TotalReactions, "a channel without a recorded parent is counted")
+}
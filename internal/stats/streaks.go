@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Day returns the date of t in loc, as midnight UTC so that consecutive days are exactly 24 hours apart
+func Day(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// ComputeStreaks computes each user's streaks from the days on which they were active, for stores which cannot compute
+// them in SQL. days maps each user to the days they were active on, as returned by Day, in any order. A run is
+// current if it ends today or yesterday. The streaks are ordered and limited as Repository.GetStreaks orders them
+func ComputeStreaks(days map[string][]time.Time, today time.Time, byLongest bool, limit int) []Streak {
+	yesterday := today.AddDate(0, 0, -1)
+
+	streaks := make([]Streak, 0, len(days))
+	for userID, active := range days {
+		if len(active) == 0 {
+			continue
+		}
+
+		active = slices.Clone(active)
+		slices.SortFunc(active, func(a, b time.Time) int { return a.Compare(b) })
+		active = slices.CompactFunc(active, func(a, b time.Time) bool { return a.Equal(b) })
+
+		s := Streak{UserID: userID}
+		run := 0
+		for i, d := range active {
+			if i > 0 && d.Sub(active[i-1]) == 24*time.Hour {
+				run++
+			} else {
+				run = 1
+			}
+			s.Longest = max(s.Longest, run)
+		}
+		if !active[len(active)-1].Before(yesterday) {
+			s.Current = run
+		}
+
+		streaks = append(streaks, s)
+	}
+
+	slices.SortFunc(streaks, func(a, b Streak) int {
+		first, second := cmp.Compare(b.Current, a.Current), cmp.Compare(b.Longest, a.Longest)
+		if byLongest {
+			first, second = second, first
+		}
+		return cmp.Or(first, second, cmp.Compare(a.UserID, b.UserID))
+	})
+
+	if len(streaks) > limit {
+		streaks = streaks[:limit]
+	}
+	return streaks
+}
@@ -0,0 +1,44 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDay(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	assert.NoError(t, err)
+
+	instant := time.Date(2024, 6, 1, 23, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Day(instant, time.UTC))
+	assert.Equal(t, time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC), Day(instant, london))
+}
+
+func TestComputeStreaks(t *testing.T) {
+	today := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2024, 6, d, 0, 0, 0, 0, time.UTC) }
+
+	days := map[string][]time.Time{
+		// a current run of 2 ending yesterday, after a longer broken run
+		"user1": {day(9), day(1), day(2), day(3), day(8), day(2)},
+		// a current run of 3 ending today
+		"user2": {day(8), day(9), day(10)},
+		// no current run
+		"user3": {day(4), day(5), day(6), day(7)},
+		"user4": {},
+	}
+
+	assert.Equal(t, []Streak{
+		{UserID: "user2", Current: 3, Longest: 3},
+		{UserID: "user1", Current: 2, Longest: 3},
+		{UserID: "user3", Current: 0, Longest: 4},
+	}, ComputeStreaks(days, today, false, 10))
+
+	assert.Equal(t, []Streak{
+		{UserID: "user3", Current: 0, Longest: 4},
+		{UserID: "user2", Current: 3, Longest: 3},
+	}, ComputeStreaks(days, today, true, 2))
+}
@@ -126,6 +126,18 @@ func (s *CommandStage) the_user_adds_a_reaction() *CommandStage {
 	return s
 }
 
+func (s *CommandStage) the_server_responds_publicly_by_default() *CommandStage {
+	_, err := db.Exec(`INSERT INTO guild_settings (guild_id, public_responses) VALUES ($1, TRUE)`, testGuildID)
+	s.require.NoError(err)
+
+	s.t.Cleanup(func() {
+		_, err := db.Exec(`DELETE FROM guild_settings WHERE guild_id = $1`, testGuildID)
+		s.assert.NoError(err)
+	})
+
+	return s
+}
+
 func (s *CommandStage) the_stats_command_is_invoked() *CommandStage {
 	return s.the_stats_command_is_invoked_with_public(false)
 }
@@ -207,6 +219,110 @@ func (s *CommandStage) the_emoji_stats_command_is_invoked_with_emoji(emoji strin
 	return s
 }
 
+func (s *CommandStage) the_hall_of_fame_command_is_invoked() *CommandStage {
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:    s.snowflake.Generate().String(),
+			AppID: s.session.State.User.ID,
+			Type:  discordgo.InteractionApplicationCommand,
+			Data: discordgo.ApplicationCommandInteractionData{
+				ID:          s.snowflake.Generate().String(),
+				Name:        "hall-of-fame",
+				CommandType: discordgo.ChatApplicationCommand,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{
+						Name:  "channel",
+						Type:  discordgo.ApplicationCommandOptionChannel,
+						Value: s.channel.ID,
+					},
+				},
+			},
+			GuildID:   testGuildID,
+			ChannelID: s.channel.ID,
+			Member: &discordgo.Member{
+				User: &discordgo.User{
+					ID: s.userID,
+				},
+			},
+			Version: 1,
+		},
+	}
+
+	var err error
+	s.interaction, err = s.fakediscord.Interaction(i)
+	s.require.NoError(err)
+	s.require.NotEmpty(s.interaction)
+
+	return s
+}
+
+func (s *CommandStage) the_wrapped_command_is_invoked_for_the_user() *CommandStage {
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:    s.snowflake.Generate().String(),
+			AppID: s.session.State.User.ID,
+			Type:  discordgo.InteractionApplicationCommand,
+			Data: discordgo.ApplicationCommandInteractionData{
+				ID:          s.snowflake.Generate().String(),
+				Name:        "wrapped",
+				CommandType: discordgo.ChatApplicationCommand,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{
+						Name:  "user",
+						Type:  discordgo.ApplicationCommandOptionUser,
+						Value: s.userID,
+					},
+				},
+			},
+			GuildID:   testGuildID,
+			ChannelID: s.channel.ID,
+			Member: &discordgo.Member{
+				User: &discordgo.User{
+					ID: s.userID,
+				},
+			},
+			Version: 1,
+		},
+	}
+
+	var err error
+	s.interaction, err = s.fakediscord.Interaction(i)
+	s.require.NoError(err)
+	s.require.NotEmpty(s.interaction)
+
+	return s
+}
+
+func (s *CommandStage) the_badges_command_is_invoked() *CommandStage {
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:    s.snowflake.Generate().String(),
+			AppID: s.session.State.User.ID,
+			Type:  discordgo.InteractionApplicationCommand,
+			Data: discordgo.ApplicationCommandInteractionData{
+				ID:          s.snowflake.Generate().String(),
+				Name:        "badges",
+				CommandType: discordgo.ChatApplicationCommand,
+			},
+			GuildID:   testGuildID,
+			ChannelID: s.channel.ID,
+			Member: &discordgo.Member{
+				User: &discordgo.User{
+					ID: s.userID,
+				},
+			},
+			Version: 1,
+		},
+	}
+
+	var err error
+	s.interaction, err = s.fakediscord.Interaction(i)
+	s.require.NoError(err)
+	s.require.NotEmpty(s.interaction)
+
+	return s
+}
+
 func (s *CommandStage) the_response_should_contain(text string) *CommandStage {
 	s.require.Eventually(func() bool {
 		res, err := s.session.InteractionResponse(s.interaction.Interaction)
@@ -20,7 +20,7 @@ func TestStatsCommand(t *testing.T) {
 	then.
 		the_response_should_contain("## Reaction Statistics").and().
 		the_response_should_contain("**Total Reactions:**").and().
-		the_response_should_contain("### Top 10 Reactions").and().
+		the_response_should_contain("### Top Reactions").and().
 		the_response_should_contain("👍")
 }
 
@@ -106,3 +106,77 @@ func TestStatsCommandWithPublicOption(t *testing.T) {
 		the_response_should_contain("## Reaction Statistics").and().
 		the_response_should_be_public()
 }
+
+func TestStatsCommandPublicByDefault(t *testing.T) {
+	given, when, then := NewCommandStage(t)
+
+	given.
+		a_channel().and().
+		a_message().and().
+		a_default_emoji("👍").and().
+		a_user().and().
+		the_user_adds_a_reaction().and().
+		the_server_responds_publicly_by_default()
+
+	when.
+		the_stats_command_is_invoked()
+
+	then.
+		the_response_should_contain("## Reaction Statistics").and().
+		the_response_should_be_public()
+}
+
+func TestHallOfFameCommand(t *testing.T) {
+	given, when, then := NewCommandStage(t)
+
+	given.
+		a_channel().and().
+		a_message().and().
+		a_default_emoji("👍").and().
+		a_user().and().
+		the_user_adds_a_reaction()
+
+	when.
+		the_hall_of_fame_command_is_invoked()
+
+	then.
+		the_response_should_contain("## Hall of Fame").and().
+		the_response_should_contain(given.message.ID).and().
+		the_response_should_contain("👍 1")
+}
+
+func TestWrappedCommand(t *testing.T) {
+	given, when, then := NewCommandStage(t)
+
+	given.
+		a_channel().and().
+		a_message().and().
+		a_default_emoji("👍").and().
+		a_user().and().
+		the_user_adds_a_reaction()
+
+	when.
+		the_wrapped_command_is_invoked_for_the_user()
+
+	then.
+		the_response_should_contain("Wrapped").and().
+		the_response_should_contain("**Reactions given:**")
+}
+
+func TestBadgesCommand(t *testing.T) {
+	given, when, then := NewCommandStage(t)
+
+	given.
+		a_channel().and().
+		a_message().and().
+		a_default_emoji("👍").and().
+		a_user().and().
+		the_user_adds_a_reaction()
+
+	when.
+		the_badges_command_is_invoked()
+
+	then.
+		the_response_should_contain("## Badges for").and().
+		the_response_should_contain("**First Reaction**")
+}
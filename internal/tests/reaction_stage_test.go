@@ -22,10 +22,11 @@ type ReactionStage struct {
 	snowflake   *snowflake.Node
 	fakediscord *fakediscord.Client
 
-	channel *discordgo.Channel
-	message *discordgo.Message
-	emoji   string
-	userID  string
+	channel          *discordgo.Channel
+	message          *discordgo.Message
+	emoji            string
+	userID           string
+	starboardChannel *discordgo.Channel
 }
 
 func NewReactionStage(t *testing.T) (*ReactionStage, *ReactionStage, *ReactionStage) {
@@ -106,6 +107,73 @@ func (s *ReactionStage) a_user() *ReactionStage {
 	return s
 }
 
+func (s *ReactionStage) a_starboard_with_threshold(threshold int) *ReactionStage {
+	c, err := s.session.GuildChannelCreate(testGuildID, "starboard", discordgo.ChannelTypeGuildText)
+	s.require.NoError(err)
+
+	_, err = db.Exec(`
+		INSERT INTO starboard_configs (guild_id, channel_id, threshold)
+		VALUES ($1, $2, $3)`,
+		testGuildID, c.ID, threshold,
+	)
+	s.require.NoError(err)
+
+	s.t.Cleanup(func() {
+		_, err := db.Exec(`DELETE FROM starboard_configs WHERE guild_id = $1`, testGuildID)
+		s.assert.NoError(err)
+		_, err = s.session.ChannelDelete(c.ID)
+		s.assert.NoError(err)
+	})
+
+	s.starboardChannel = c
+
+	return s
+}
+
+func (s *ReactionStage) milestones_for_the_first_reaction_on_a_message() *ReactionStage {
+	_, err := db.Exec(`
+		INSERT INTO milestone_configs (guild_id, channel_id, sender_thresholds, receiver_thresholds, emoji_thresholds, message_thresholds)
+		VALUES ($1, $2, '', '', '', '1')`,
+		testGuildID, s.channel.ID,
+	)
+	s.require.NoError(err)
+
+	s.t.Cleanup(func() {
+		_, err := db.Exec(`DELETE FROM milestone_configs WHERE guild_id = $1`, testGuildID)
+		s.assert.NoError(err)
+	})
+
+	return s
+}
+
+func (s *ReactionStage) the_user_has_opted_out() *ReactionStage {
+	_, err := db.Exec(`INSERT INTO privacy_opt_outs (user_id) VALUES ($1)`, s.userID)
+	s.require.NoError(err)
+
+	s.t.Cleanup(func() {
+		_, err := db.Exec(`DELETE FROM privacy_opt_outs WHERE user_id = $1`, s.userID)
+		s.assert.NoError(err)
+	})
+
+	return s
+}
+
+func (s *ReactionStage) the_channel_is_ignored() *ReactionStage {
+	_, err := db.Exec(`
+		INSERT INTO guild_settings (guild_id, ignored_channel_ids)
+		VALUES ($1, ARRAY[$2])`,
+		testGuildID, s.channel.ID,
+	)
+	s.require.NoError(err)
+
+	s.t.Cleanup(func() {
+		_, err := db.Exec(`DELETE FROM guild_settings WHERE guild_id = $1`, testGuildID)
+		s.assert.NoError(err)
+	})
+
+	return s
+}
+
 func (s *ReactionStage) the_user_adds_a_reaction() *ReactionStage {
 	err := s.session.MessageReactionAdd(s.channel.ID, s.message.ID, s.emoji)
 	s.require.NoError(err)
@@ -135,6 +203,21 @@ func (s *ReactionStage) the_reaction_should_be_saved() *ReactionStage {
 	return s
 }
 
+func (s *ReactionStage) the_reaction_should_not_be_saved() *ReactionStage {
+	s.require.Never(func() bool {
+		var count int
+		err := db.QueryRow(`
+			SELECT COUNT(*) FROM reactions
+			WHERE message_id = $1 AND sender_user_id = $2`,
+			s.message.ID, s.userID,
+		).Scan(&count)
+
+		return err != nil || count != 0
+	}, time.Second, 100*time.Millisecond)
+
+	return s
+}
+
 func (s *ReactionStage) the_reaction_should_be_removed() *ReactionStage {
 	s.require.Eventually(func() bool {
 		var count int
@@ -180,8 +263,60 @@ func (s *ReactionStage) the_reaction_should_not_be_marked_as_default() *Reaction
 	return s
 }
 
+func (s *ReactionStage) the_message_should_be_posted_to_the_starboard() *ReactionStage {
+	s.require.Eventually(func() bool {
+		var starboardMessageID string
+		err := db.QueryRow(`
+			SELECT starboard_message_id FROM starboard_messages
+			WHERE guild_id = $1 AND message_id = $2 AND starboard_message_id IS NOT NULL`,
+			testGuildID, s.message.ID,
+		).Scan(&starboardMessageID)
+		if err != nil {
+			return false
+		}
+
+		m, err := s.session.ChannelMessage(s.starboardChannel.ID, starboardMessageID)
+
+		return err == nil && len(m.Embeds) == 1
+	}, 5*time.Second, 100*time.Millisecond)
+
+	return s
+}
+
+func (s *ReactionStage) the_message_should_be_removed_from_the_starboard() *ReactionStage {
+	s.require.Eventually(func() bool {
+		var count int
+		err := db.QueryRow(`
+			SELECT COUNT(*) FROM starboard_messages
+			WHERE guild_id = $1 AND message_id = $2`,
+			testGuildID, s.message.ID,
+		).Scan(&count)
+
+		return err == nil && count == 0
+	}, 5*time.Second, 100*time.Millisecond)
+
+	return s
+}
+
+func (s *ReactionStage) the_message_milestone_should_be_announced() *ReactionStage {
+	s.require.Eventually(func() bool {
+		var count int
+		err := db.QueryRow(`
+			SELECT COUNT(*) FROM milestones
+			WHERE guild_id = $1 AND kind = 'message' AND subject_id = $2 AND threshold = 1`,
+			testGuildID, s.message.ID,
+		).Scan(&count)
+
+		return err == nil && count == 1
+	}, 5*time.Second, 100*time.Millisecond)
+
+	return s
+}
+
 func (s *ReactionStage) cleanupReactions() {
 	if s.message != nil {
 		_, _ = db.Exec(`DELETE FROM reactions WHERE message_id = $1`, s.message.ID)
+		_, _ = db.Exec(`DELETE FROM starboard_messages WHERE message_id = $1`, s.message.ID)
+		_, _ = db.Exec(`DELETE FROM milestones WHERE subject_id = $1`, s.message.ID)
 	}
 }
@@ -38,6 +38,40 @@ func TestReactionAddCustomEmoji(t *testing.T) {
 		the_reaction_should_not_be_marked_as_default()
 }
 
+func TestReactionAddOptedOut(t *testing.T) {
+	given, when, then := NewReactionStage(t)
+
+	given.
+		a_channel().and().
+		a_message().and().
+		a_default_emoji("👍").and().
+		a_user().and().
+		the_user_has_opted_out()
+
+	when.
+		the_user_adds_a_reaction()
+
+	then.
+		the_reaction_should_not_be_saved()
+}
+
+func TestReactionAddIgnoredChannel(t *testing.T) {
+	given, when, then := NewReactionStage(t)
+
+	given.
+		a_channel().and().
+		a_message().and().
+		a_default_emoji("👍").and().
+		a_user().and().
+		the_channel_is_ignored()
+
+	when.
+		the_user_adds_a_reaction()
+
+	then.
+		the_reaction_should_not_be_saved()
+}
+
 func TestReactionRemove(t *testing.T) {
 	given, when, then := NewReactionStage(t)
 
@@ -55,3 +89,59 @@ func TestReactionRemove(t *testing.T) {
 	then.
 		the_reaction_should_be_removed()
 }
+
+func TestReactionAddStarboard(t *testing.T) {
+	given, when, then := NewReactionStage(t)
+
+	given.
+		a_channel().and().
+		a_message().and().
+		a_starboard_with_threshold(1).and().
+		a_default_emoji("⭐").and().
+		a_user()
+
+	when.
+		the_user_adds_a_reaction()
+
+	then.
+		the_reaction_should_be_saved().and().
+		the_message_should_be_posted_to_the_starboard()
+}
+
+func TestReactionRemoveStarboard(t *testing.T) {
+	given, when, then := NewReactionStage(t)
+
+	given.
+		a_channel().and().
+		a_message().and().
+		a_starboard_with_threshold(1).and().
+		a_default_emoji("⭐").and().
+		a_user().and().
+		the_user_adds_a_reaction().and().
+		the_message_should_be_posted_to_the_starboard()
+
+	when.
+		the_user_removes_the_reaction()
+
+	then.
+		the_reaction_should_be_removed().and().
+		the_message_should_be_removed_from_the_starboard()
+}
+
+func TestReactionAddMilestone(t *testing.T) {
+	given, when, then := NewReactionStage(t)
+
+	given.
+		a_channel().and().
+		a_message().and().
+		milestones_for_the_first_reaction_on_a_message().and().
+		a_default_emoji("🎉").and().
+		a_user()
+
+	when.
+		the_user_adds_a_reaction()
+
+	then.
+		the_reaction_should_be_saved().and().
+		the_message_milestone_should_be_announced()
+}
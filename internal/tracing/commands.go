@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/elliotwms/bot/interactions/router"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Commands wraps each command's handler in a span
+func Commands(commands map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler) map[*discordgo.ApplicationCommand]router.ApplicationCommandHandler {
+	for c, h := range commands {
+		commands[c] = Command(c.Name, h)
+	}
+	return commands
+}
+
+// Command wraps a command handler in a span, which the handler's queries and Discord REST calls are children of
+func Command(name string, h router.ApplicationCommandHandler) router.ApplicationCommandHandler {
+	return func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) error {
+		ctx, span := Start(ctx, "command "+name)
+		defer span.End()
+
+		span.SetAttributes(attribute.String("discord.command", name))
+		if i != nil {
+			span.SetAttributes(attribute.String("discord.guild_id", i.GuildID))
+		}
+
+		err := h(ctx, s, i, data)
+		RecordError(span, err)
+
+		return err
+	}
+}
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/elliotwms/emojistats/internal/privacy"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// Store is a stats.Store which traces each of another store's methods
+type Store struct {
+	next stats.Store
+}
+
+var _ stats.Store = (*Store)(nil)
+
+// NewStore traces the queries of next
+func NewStore(next stats.Store) *Store {
+	return &Store{next: next}
+}
+
+func (s *Store) GetGuildStats(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) (_ *stats.GuildStats, err error) {
+	ctx, span := startQuery(ctx, "GetGuildStats", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.GetGuildStats(ctx, guildID, dateRange, limit)
+}
+
+func (s *Store) GetEmojiStats(ctx context.Context, guildID, emojiID string, dateRange stats.DateRange, limit int) (_ *stats.EmojiStats, err error) {
+	ctx, span := startQuery(ctx, "GetEmojiStats", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.GetEmojiStats(ctx, guildID, emojiID, dateRange, limit)
+}
+
+func (s *Store) GetTopEmojis(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) (_ []stats.EmojiCount, err error) {
+	ctx, span := startQuery(ctx, "GetTopEmojis", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.GetTopEmojis(ctx, guildID, dateRange, limit)
+}
+
+func (s *Store) GetTopChannels(ctx context.Context, guildID string, dateRange stats.DateRange, limit int) (_ []stats.ChannelCount, err error) {
+	ctx, span := startQuery(ctx, "GetTopChannels", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.GetTopChannels(ctx, guildID, dateRange, limit)
+}
+
+func (s *Store) GetTopMessages(ctx context.Context, guildID, channelID string, dateRange stats.DateRange, limit int) (_ []stats.TopMessage, err error) {
+	ctx, span := startQuery(ctx, "GetTopMessages", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.GetTopMessages(ctx, guildID, channelID, dateRange, limit)
+}
+
+func (s *Store) GetWrapped(ctx context.Context, guildID, userID string, year int) (_ *stats.Wrapped, err error) {
+	ctx, span := startQuery(ctx, "GetWrapped", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.GetWrapped(ctx, guildID, userID, year)
+}
+
+func (s *Store) GetStreak(ctx context.Context, guildID, userID string) (_ stats.Streak, err error) {
+	ctx, span := startQuery(ctx, "GetStreak", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.GetStreak(ctx, guildID, userID)
+}
+
+func (s *Store) GetStreaks(ctx context.Context, guildID string, byLongest bool, limit int) (_ []stats.Streak, err error) {
+	ctx, span := startQuery(ctx, "GetStreaks", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.GetStreaks(ctx, guildID, byLongest, limit)
+}
+
+func (s *Store) AddReaction(ctx context.Context, reaction stats.Reaction) (err error) {
+	ctx, span := startQuery(ctx, "AddReaction", reaction.GuildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.AddReaction(ctx, reaction)
+}
+
+func (s *Store) RemoveReaction(ctx context.Context, guildID, messageID, emojiID, senderUserID string) (_ int, err error) {
+	ctx, span := startQuery(ctx, "RemoveReaction", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.RemoveReaction(ctx, guildID, messageID, emojiID, senderUserID)
+}
+
+func (s *Store) OptedOut(ctx context.Context, userIDs ...string) (_ map[string]bool, err error) {
+	ctx, span := startQuery(ctx, "OptedOut", "")
+	defer func() { endQuery(span, err) }()
+	return s.next.OptedOut(ctx, userIDs...)
+}
+
+func (s *Store) OptOut(ctx context.Context, guildID, userID string) (err error) {
+	ctx, span := startQuery(ctx, "OptOut", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.OptOut(ctx, guildID, userID)
+}
+
+func (s *Store) OptIn(ctx context.Context, guildID, userID string) (err error) {
+	ctx, span := startQuery(ctx, "OptIn", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.OptIn(ctx, guildID, userID)
+}
+
+func (s *Store) Forget(ctx context.Context, guildID, userID string) (_ privacy.Forgotten, err error) {
+	ctx, span := startQuery(ctx, "Forget", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.Forget(ctx, guildID, userID)
+}
+
+func (s *Store) GetTimezone(ctx context.Context, guildID string) (_ *time.Location, err error) {
+	ctx, span := startQuery(ctx, "GetTimezone", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.GetTimezone(ctx, guildID)
+}
+
+func (s *Store) SetTimezone(ctx context.Context, guildID string, loc *time.Location) (err error) {
+	ctx, span := startQuery(ctx, "SetTimezone", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.SetTimezone(ctx, guildID, loc)
+}
+
+func (s *Store) GetSettings(ctx context.Context, guildID string) (_ stats.Settings, err error) {
+	ctx, span := startQuery(ctx, "GetSettings", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.GetSettings(ctx, guildID)
+}
+
+func (s *Store) SetSettings(ctx context.Context, guildID string, settings stats.Settings) (err error) {
+	ctx, span := startQuery(ctx, "SetSettings", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.SetSettings(ctx, guildID, settings)
+}
+
+func (s *Store) SetChannelParent(ctx context.Context, guildID, channelID, parentID string) (_ bool, err error) {
+	ctx, span := startQuery(ctx, "SetChannelParent", guildID)
+	defer func() { endQuery(span, err) }()
+	return s.next.SetChannelParent(ctx, guildID, channelID, parentID)
+}
+
+func startQuery(ctx context.Context, method, guildID string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{semconv.DBOperationName(method)}
+	if guildID != "" {
+		attrs = append(attrs, attribute.String("discord.guild_id", guildID))
+	}
+
+	return Start(ctx, "stats."+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func endQuery(span trace.Span, err error) {
+	RecordError(span, err)
+	span.End()
+}
@@ -0,0 +1,71 @@
+// Package tracing traces command handlers, reaction ingestion, stats queries and Discord REST calls with
+// OpenTelemetry. Traces are exported with OTLP over HTTP when an OTLP endpoint is configured with the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT environment variables, and are otherwise discarded
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/elliotwms/emojistats/internal/build"
+)
+
+const (
+	name        = "github.com/elliotwms/emojistats"
+	serviceName = "emojistats"
+)
+
+// Setup exports traces if an OTLP endpoint is configured, returning a function which flushes and stops the exporter.
+// Without an endpoint the global no-op tracer provider is left in place
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.Merge(
+		resource.NewSchemaless(semconv.ServiceName(serviceName), semconv.ServiceVersion(build.Version)),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp.Shutdown, nil
+}
+
+// Start starts a span with the global tracer provider, which records nothing unless Setup has configured an exporter
+func Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(name).Start(ctx, spanName, opts...)
+}
+
+// RecordError marks a span as failed with err, if it is not nil
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
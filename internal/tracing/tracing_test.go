@@ -0,0 +1,159 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elliotwms/emojistats/internal/memory"
+	"github.com/elliotwms/emojistats/internal/stats"
+)
+
+// collector is an in-process OTLP/HTTP trace collector
+type collector struct {
+	mu    sync.Mutex
+	spans []*tracepb.Span
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil || r.URL.Path != "/v1/traces" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	c.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	b, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	_, _ = w.Write(b)
+}
+
+// span returns the collected span with the given name
+func (c *collector) span(t *testing.T, name string) *tracepb.Span {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range c.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+
+	var names []string
+	for _, s := range c.spans {
+		names = append(names, s.Name)
+	}
+	require.Failf(t, "span not found", "%q not in %v", name, names)
+	return nil
+}
+
+func TestSetup_NoOp(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	shutdown, err := Setup(context.Background())
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	assert.False(t, ok, "the no-op tracer provider should be left in place")
+}
+
+func TestSetup_Export(t *testing.T) {
+	c := &collector{}
+	collectorServer := httptest.NewServer(c)
+	defer collectorServer.Close()
+
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer discord.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collectorServer.URL)
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	shutdown, err := Setup(context.Background())
+	require.NoError(t, err)
+
+	store := NewStore(memory.NewStore())
+	client := &http.Client{Transport: Transport(nil)}
+
+	handler := Command("stats", func(ctx context.Context, _ *discordgo.Session, i *discordgo.InteractionCreate, _ discordgo.ApplicationCommandInteractionData) error {
+		if _, err := store.GetGuildStats(ctx, i.GuildID, stats.DateRange{}, 10); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPatch, discord.URL+"/api/v10/webhooks/123/secret-token/messages/@original", nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = res.Body.Close()
+
+		return errors.New("failed to respond")
+	})
+
+	i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{GuildID: "guild1"}}
+	require.Error(t, handler(context.Background(), nil, i, discordgo.ApplicationCommandInteractionData{}))
+
+	require.NoError(t, shutdown(context.Background()))
+
+	command := c.span(t, "command stats")
+	assert.Empty(t, command.ParentSpanId)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, command.Status.Code)
+
+	query := c.span(t, "stats.GetGuildStats")
+	assert.Equal(t, command.TraceId, query.TraceId)
+	assert.Equal(t, command.SpanId, query.ParentSpanId)
+
+	rest := c.span(t, "PATCH /api/v10/webhooks/:id/:token/messages/@original")
+	assert.Equal(t, command.SpanId, rest.ParentSpanId)
+	assert.NotContains(t, rest.String(), "secret-token")
+}
+
+func TestRoute(t *testing.T) {
+	tests := map[string]string{
+		"/api/v10/channels/123/messages/456":                     "/api/v10/channels/:id/messages/:id",
+		"/api/v10/interactions/123/token/callback":               "/api/v10/interactions/:id/:token/callback",
+		"/api/v10/webhooks/123/token/messages/@original":         "/api/v10/webhooks/:id/:token/messages/@original",
+		"/api/v10/channels/123/messages/456/reactions/%E2%AD%90": "/api/v10/channels/:id/messages/:id/reactions/%E2%AD%90",
+		"/api/v10/gateway/bot":                                   "/api/v10/gateway/bot",
+	}
+	for path, expected := range tests {
+		t.Run(path, func(t *testing.T) {
+			assert.Equal(t, expected, Route(path))
+		})
+	}
+}
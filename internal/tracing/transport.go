@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport wraps an HTTP transport to trace each request, e.g. the Discord REST calls made by a session's client.
+// Spans are children of the request context's span, which for discordgo is set with discordgo.WithContext
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	route := Route(req.URL.Path)
+
+	ctx, span := Start(req.Context(), req.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.HTTPRoute(route),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	res, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		RecordError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
+	if res.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, res.Status)
+	}
+
+	return res, nil
+}
+
+// Route replaces the IDs and tokens in a Discord API path with placeholders, so that span names are low cardinality
+// and interaction tokens are not recorded
+func Route(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		switch {
+		case isID(s):
+			segments[i] = ":id"
+		case i >= 2 && (segments[i-2] == "webhooks" || segments[i-2] == "interactions"):
+			segments[i] = ":token"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isID(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}